@@ -19,10 +19,17 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"os"
 	"runtime/debug"
+	"time"
 
 	"github.com/HardDie/DeckBuilder/internal/application"
+	"github.com/HardDie/DeckBuilder/internal/config"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+	"github.com/HardDie/DeckBuilder/internal/images"
 	"github.com/HardDie/DeckBuilder/internal/logger"
 	"github.com/HardDie/DeckBuilder/internal/network"
 )
@@ -38,6 +45,30 @@ func main() {
 	// - Do not request the url and don't open the browser
 	// - Do not close the application when /system/quit is requested
 	debugFlag := flag.Bool("debug", false, "")
+	historyLimit := flag.Int("history-limit", config.DefaultHistoryLimit, "The maximum number of revisions stored for each entity, 0 - unlimited")
+	trashDays := flag.Int("trash-days", config.DefaultTrashDays, "The number of days after which deleted entities are purged from the trash, 0 - never")
+	storage := flag.String("storage", config.StorageFiles, "Where the data is stored: "+config.StorageFiles+" or "+config.StorageSQLite)
+	maxExport := flag.Int64("max-export-mb", config.DefaultMaxArchiveSize, "The maximum size of the files packed into the game archive or unpacked from it in megabytes, 0 - unlimited")
+	maxImport := flag.Int64("max-import-mb", config.DefaultMaxArchiveSize, "The maximum size of the uploaded game archive in megabytes, 0 - unlimited")
+	downloadTimeout := flag.Duration("download-timeout", 30*time.Second, "The timeout of a single image download request")
+	downloadRetries := flag.Int("download-retries", 3, "The number of repeated attempts of the image download after a network or a server error")
+	maxDownload := flag.Int64("max-download-mb", config.DefaultMaxDownloadSize, "The maximum size of the downloaded image in megabytes, 0 - unlimited")
+	downloadConcurrency := flag.Int("download-concurrency", config.DefaultDownloadConcurrency, "The maximum number of images downloaded at the same time, 0 - unlimited")
+	maxImagePixels := flag.Int64("max-image-pixels", config.DefaultMaxImagePixels, "The maximum number of pixels of the uploaded image, 0 - unlimited")
+	imageFormat := flag.String("image-format", "", "The format all uploaded images are converted to: "+images.FormatPNG+" or "+images.FormatJPEG+", empty - keep the original format")
+	resultsHost := flag.String("results-host", "", "The address of this computer in the local network, e.g. 192.168.1.10. If set, the generated json loads the images from the running application, so the other players in the network can spawn the game")
+	uploader := flag.String("uploader", "", "Where the generated images are uploaded: "+config.UploaderS3+" or "+config.UploaderHTTP+", empty - no upload")
+	uploadURL := flag.String("upload-url", "", "The S3 endpoint or the URL the images are posted to")
+	uploadBucket := flag.String("upload-bucket", "", "The S3 bucket")
+	uploadRegion := flag.String("upload-region", config.DefaultUploadRegion, "The S3 region")
+	uploadACL := flag.String("upload-acl", "", "The canned ACL of the uploaded S3 objects, e.g. public-read")
+	uploadPublicURL := flag.String("upload-public-url", "", "The base of the links to the uploaded S3 objects, empty - <upload-url>/<upload-bucket>")
+	uploadField := flag.String("upload-field", config.DefaultUploadField, "The form field of the image posted to the HTTP endpoint")
+	uploadURLField := flag.String("upload-url-field", "", "The JSON field with the link in the response of the HTTP endpoint, e.g. data.link, empty - the Location header or the body")
+	ttsAddress := flag.String("tts-address", config.DefaultTTSAddress, "The address of the External Editor API of the running TTS")
+	ttsListenAddress := flag.String("tts-listen-address", config.DefaultTTSListenAddress, "The address the messages from TTS are received on")
+	ttsCallbackURL := flag.String("tts-callback-url", config.DefaultTTSCallbackURL(), "The URL of this application as TTS sees it, TTS downloads the rendered game from it")
+	ttsDataTTL := flag.Duration("tts-data-ttl", 10*time.Minute, "How long the object sent to TTS can be downloaded by TTS")
 	flag.Parse()
 
 	if info, available := debug.ReadBuildInfo(); available {
@@ -62,7 +93,56 @@ func main() {
 		version = "unknown"
 	}
 
-	app, err := application.Get(*debugFlag, version)
+	cfg := config.Get(*debugFlag, version)
+	cfg.HistoryLimit = *historyLimit
+	cfg.TrashRetentionDays = *trashDays
+	cfg.Storage = *storage
+	cfg.MaxExportSize = *maxExport << 20
+	cfg.MaxImportSize = *maxImport << 20
+	cfg.DownloadTimeout = *downloadTimeout
+	cfg.DownloadRetries = *downloadRetries
+	cfg.MaxDownloadSize = *maxDownload << 20
+	cfg.DownloadConcurrency = *downloadConcurrency
+	cfg.MaxImagePixels = *maxImagePixels
+	cfg.ImageFormat = *imageFormat
+	if cfg.ImageFormat != "" && cfg.ImageFormat != images.FormatPNG && cfg.ImageFormat != images.FormatJPEG {
+		logger.Error.Fatal("Unknown image format: " + cfg.ImageFormat)
+	}
+	cfg.ResultsHost = *resultsHost
+	cfg.Uploader = *uploader
+	cfg.UploadURL = *uploadURL
+	cfg.UploadBucket = *uploadBucket
+	cfg.UploadRegion = *uploadRegion
+	cfg.UploadACL = *uploadACL
+	cfg.UploadPublicURL = *uploadPublicURL
+	cfg.UploadField = *uploadField
+	cfg.UploadURLField = *uploadURLField
+	// The secrets are not passed as flags, so they are not visible in the list of processes
+	cfg.UploadAccessKey = os.Getenv("DECK_BUILDER_UPLOAD_ACCESS_KEY")
+	cfg.UploadSecretKey = os.Getenv("DECK_BUILDER_UPLOAD_SECRET_KEY")
+	cfg.UploadAuthorization = os.Getenv("DECK_BUILDER_UPLOAD_AUTHORIZATION")
+	cfg.TTSAddress = *ttsAddress
+	cfg.TTSListenAddress = *ttsListenAddress
+	cfg.TTSCallbackURL = *ttsCallbackURL
+	cfg.TTSDataTTL = *ttsDataTTL
+	err := cfg.ValidateTTS()
+	if err != nil {
+		logger.Error.Fatal(err.Error())
+	}
+
+	// Only one instance of the application can work with the data folder, the lock is held until the process exits
+	unlock, err := fs.LockFolder(cfg.Data)
+	if err != nil {
+		if errors.Is(err, er.DataLocked) && !*debugFlag {
+			// The application is already running, show it to the user
+			network.OpenBrowser("http://127.0.0.1:5000")
+		}
+		logger.Error.Fatal(err.Error())
+	}
+	// The function holds the only reference to the lock file, the file closed by the garbage collector releases the lock
+	defer unlock()
+
+	app, err := application.Get(cfg)
 	if err != nil {
 		logger.Error.Fatal(err.Error())
 	}
@@ -14,6 +14,8 @@ func RegisterCardServer(route *mux.Router, srv serversCard.Card) {
 	CardsRoute := route.PathPrefix("/api/games/{game}/collections/{collection}/decks/{deck}/cards").Subrouter()
 	CardsRoute.HandleFunc("", srv.ListHandler).Methods(http.MethodGet)
 	CardsRoute.HandleFunc("", srv.CreateHandler).Methods(http.MethodPost)
+	CardsRoute.HandleFunc("/copy", srv.CopyHandler).Methods(http.MethodPost)
+	CardsRoute.HandleFunc("/move", srv.MoveHandler).Methods(http.MethodPost)
 	CardsRoute.HandleFunc("/{card}", srv.DeleteHandler).Methods(http.MethodDelete)
 	CardsRoute.HandleFunc("/{card}", srv.ItemHandler).Methods(http.MethodGet)
 	CardsRoute.HandleFunc("/{card}", srv.UpdateHandler).Methods(http.MethodPatch)
@@ -114,7 +116,7 @@ type ResponseDeleteCard struct {
 //
 // # Delete card
 //
-// Allows you to delete an existing card
+// Allows you to delete an existing card. The card is moved to the trash and can be restored
 //
 //	Responses:
 //	  200: ResponseDeleteCard
@@ -270,3 +272,107 @@ type ResponseUpdateCard struct {
 //	  200: ResponseUpdateCard
 //	  default: ResponseError
 func (s *UnimplementedCardServer) UpdateHandler(w http.ResponseWriter, r *http.Request) {}
+
+// Request to copy cards
+//
+// swagger:parameters RequestCopyCards
+type RequestCopyCards struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Deck string `json:"deck"`
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Cards []int64 `json:"cards"`
+		// Required: false
+		Game string `json:"game"`
+		// Required: false
+		Collection string `json:"collection"`
+		// Required: false
+		Deck string `json:"deck"`
+	}
+}
+
+// Status of cards copying
+//
+// swagger:response ResponseCopyCards
+type ResponseCopyCards struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data []*dto.Card `json:"data"`
+		// Required: true
+		Meta *network.Meta `json:"meta"`
+	}
+}
+
+// swagger:route POST /api/games/{game}/collections/{collection}/decks/{deck}/cards/copy Cards RequestCopyCards
+//
+// # Copy cards
+//
+// Allows you to copy cards into another deck. If the destination game, collection or deck is not set, the current one is used
+//
+//	Responses:
+//	  200: ResponseCopyCards
+//	  default: ResponseError
+func (s *UnimplementedCardServer) CopyHandler(w http.ResponseWriter, r *http.Request) {}
+
+// Request to move cards
+//
+// swagger:parameters RequestMoveCards
+type RequestMoveCards struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Deck string `json:"deck"`
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Cards []int64 `json:"cards"`
+		// Required: false
+		Game string `json:"game"`
+		// Required: false
+		Collection string `json:"collection"`
+		// Required: false
+		Deck string `json:"deck"`
+	}
+}
+
+// Status of cards moving
+//
+// swagger:response ResponseMoveCards
+type ResponseMoveCards struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data []*dto.Card `json:"data"`
+		// Required: true
+		Meta *network.Meta `json:"meta"`
+	}
+}
+
+// swagger:route POST /api/games/{game}/collections/{collection}/decks/{deck}/cards/move Cards RequestMoveCards
+//
+// # Move cards
+//
+// Allows you to move cards into another deck. If the destination game, collection or deck is not set, the current one is used
+//
+//	Responses:
+//	  200: ResponseMoveCards
+//	  default: ResponseError
+func (s *UnimplementedCardServer) MoveHandler(w http.ResponseWriter, r *http.Request) {}
@@ -17,6 +17,7 @@ func RegisterCollectionServer(route *mux.Router, srv serversCollection.Collectio
 	CollectionsRoute.HandleFunc("/{collection}", srv.DeleteHandler).Methods(http.MethodDelete)
 	CollectionsRoute.HandleFunc("/{collection}", srv.ItemHandler).Methods(http.MethodGet)
 	CollectionsRoute.HandleFunc("/{collection}", srv.UpdateHandler).Methods(http.MethodPatch)
+	CollectionsRoute.HandleFunc("/{collection}/duplicate", srv.DuplicateHandler).Methods(http.MethodPost)
 }
 
 type UnimplementedCollectionServer struct {
@@ -96,13 +97,57 @@ type ResponseDeleteCollection struct {
 //
 // # Delete collection
 //
-// Allows you to delete an existing collection
+// Allows you to delete an existing collection. The collection is moved to the trash and can be restored
 //
 //	Responses:
 //	  200: ResponseDeleteCollection
 //	  default: ResponseError
 func (s *UnimplementedCollectionServer) DeleteHandler(w http.ResponseWriter, r *http.Request) {}
 
+// Request to duplicate a collection
+//
+// swagger:parameters RequestDuplicateCollection
+type RequestDuplicateCollection struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Name string `json:"name"`
+		// Required: false
+		Game string `json:"game"`
+	}
+}
+
+// Status of collection duplicate
+//
+// swagger:response ResponseDuplicateCollection
+type ResponseDuplicateCollection struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data dto.Collection `json:"data"`
+	}
+}
+
+// swagger:route POST /api/games/{game}/collections/{collection}/duplicate Collections RequestDuplicateCollection
+//
+// # Duplicate collection
+//
+// Allows you to create a copy of an existing collection with all decks and cards.
+// If the game is set, the copy will be created in that game
+//
+//	Responses:
+//	  200: ResponseDuplicateCollection
+//	  default: ResponseError
+func (s *UnimplementedCollectionServer) DuplicateHandler(w http.ResponseWriter, r *http.Request) {}
+
 // Requesting an existing collection
 //
 // swagger:parameters RequestCollection
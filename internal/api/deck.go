@@ -17,6 +17,7 @@ func RegisterDeckServer(route *mux.Router, srv serversDeck.Deck) {
 	DecksRoute.HandleFunc("/{deck}", srv.DeleteHandler).Methods(http.MethodDelete)
 	DecksRoute.HandleFunc("/{deck}", srv.ItemHandler).Methods(http.MethodGet)
 	DecksRoute.HandleFunc("/{deck}", srv.UpdateHandler).Methods(http.MethodPatch)
+	DecksRoute.HandleFunc("/{deck}/duplicate", srv.DuplicateHandler).Methods(http.MethodPost)
 	route.HandleFunc("/api/games/{game}/decks", srv.AllDecksHandler).Methods(http.MethodGet)
 }
 
@@ -135,13 +136,62 @@ type ResponseDeleteDeck struct {
 //
 // # Delete deck
 //
-// Allows you to delete an existing deck
+// Allows you to delete an existing deck. The deck is moved to the trash and can be restored
 //
 //	Responses:
 //	  200: ResponseDeleteDeck
 //	  default: ResponseError
 func (s *UnimplementedDeckServer) DeleteHandler(w http.ResponseWriter, r *http.Request) {}
 
+// Request to duplicate a deck
+//
+// swagger:parameters RequestDuplicateDeck
+type RequestDuplicateDeck struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Deck string `json:"deck"`
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Name string `json:"name"`
+		// Required: false
+		Game string `json:"game"`
+		// Required: false
+		Collection string `json:"collection"`
+	}
+}
+
+// Status of deck duplicate
+//
+// swagger:response ResponseDuplicateDeck
+type ResponseDuplicateDeck struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data dto.Deck `json:"data"`
+	}
+}
+
+// swagger:route POST /api/games/{game}/collections/{collection}/decks/{deck}/duplicate Decks RequestDuplicateDeck
+//
+// # Duplicate deck
+//
+// Allows you to create a copy of an existing deck with all cards.
+// If the game or collection is set, the copy will be created there
+//
+//	Responses:
+//	  200: ResponseDuplicateDeck
+//	  default: ResponseError
+func (s *UnimplementedDeckServer) DuplicateHandler(w http.ResponseWriter, r *http.Request) {}
+
 // Requesting an existing deck
 //
 // swagger:parameters RequestDeck
@@ -93,7 +93,7 @@ type ResponseDeleteGame struct {
 //
 // # Delete game
 //
-// Allows you to delete an existing game
+// Allows you to delete an existing game. The game is moved to the trash and can be restored
 //
 //	Responses:
 //	  200: ResponseDeleteGame
@@ -159,7 +159,11 @@ type ResponseGameArchive struct {
 //
 // # Export game to archive
 //
-// Get an existing game archive
+// Get an existing game archive. The archive is streamed as it is created.
+// The root of the archive contains the game folder and manifest.json with the format version,
+// the application version, the number of entities and the sha256 of every file.
+// If the game is larger than the export limit, error 413 is returned.
+// The progress of large archives is available in the status of the application.
 //
 //	Produces:
 //	- application/json
@@ -199,7 +203,10 @@ type ResponseGameImport struct {
 //
 // # Import game from archive
 //
-// Creat game from archive
+// Creat game from archive. If the archive is larger than the import limit, error 413 is returned.
+// The files must match the manifest of the archive. Archives with unsafe paths, symbolic links
+// or suspiciously compressed files are rejected. Archives of old versions without a manifest are accepted.
+// The progress of large archives is available in the status of the application.
 //
 //	Consumes:
 //	- multipart/form-data
@@ -33,6 +33,12 @@ type RequestGameGenerate struct {
 	Body struct {
 		SortOrder string `json:"sortOrder"`
 		Scale     int    `json:"scale"`
+		// How the game is sent to the running TTS:
+		// spawn - spawn a new bag (default),
+		// update - replace the images of the objects spawned earlier, their positions and order are kept,
+		// a new bag is spawned if the pages were changed since the last generation,
+		// respawn - replace the objects spawned earlier with the new ones at the same positions
+		Mode string `json:"mode"`
 	}
 }
 
@@ -0,0 +1,366 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/dto"
+	"github.com/HardDie/DeckBuilder/internal/network"
+	serversHistory "github.com/HardDie/DeckBuilder/internal/servers/history"
+)
+
+func RegisterHistoryServer(route *mux.Router, srv serversHistory.History) {
+	// The same handlers serve all entities, the entity is determined by the path variables
+	prefixes := []string{
+		"/api/games/{game}",
+		"/api/games/{game}/collections/{collection}",
+		"/api/games/{game}/collections/{collection}/decks/{deck}",
+		"/api/games/{game}/collections/{collection}/decks/{deck}/cards/{card}",
+	}
+	for _, prefix := range prefixes {
+		HistoryRoute := route.PathPrefix(prefix + "/history").Subrouter()
+		HistoryRoute.HandleFunc("", srv.ListHandler).Methods(http.MethodGet)
+		HistoryRoute.HandleFunc("/{revision}", srv.DiffHandler).Methods(http.MethodGet)
+		HistoryRoute.HandleFunc("/{revision}/image", srv.ImageHandler).Methods(http.MethodGet)
+		HistoryRoute.HandleFunc("/{revision}/restore", srv.RestoreHandler).Methods(http.MethodPost)
+	}
+}
+
+type UnimplementedHistoryServer struct {
+}
+
+var (
+	// Validation
+	_ serversHistory.History = &UnimplementedHistoryServer{}
+)
+
+// List of revisions
+//
+// swagger:response ResponseListOfRevisions
+type ResponseListOfRevisions struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data []*dto.Revision `json:"data"`
+		// Required: true
+		Meta *network.Meta `json:"meta"`
+	}
+}
+
+// Revision and the list of fields that differ from the current state
+//
+// swagger:response ResponseRevisionDiff
+type ResponseRevisionDiff struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data dto.RevisionDiff `json:"data"`
+	}
+}
+
+// Image saved with the revision
+//
+// swagger:response ResponseRevisionImage
+type ResponseRevisionImage struct {
+	// In: body
+	Body []byte
+}
+
+// Identifiers of the restored entity. They can change if the revision has a different name
+//
+// swagger:response ResponseRevisionRestore
+type ResponseRevisionRestore struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data dto.RevisionRestore `json:"data"`
+	}
+}
+
+// Requesting a list of game revisions
+//
+// swagger:parameters RequestGameHistory
+type RequestGameHistory struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+}
+
+// Requesting an existing game revision
+//
+// swagger:parameters RequestGameRevisionDiff RequestGameRevisionImage RequestGameRevisionRestore
+type RequestGameRevision struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Revision int64 `json:"revision"`
+}
+
+// Requesting a list of collection revisions
+//
+// swagger:parameters RequestCollectionHistory
+type RequestCollectionHistory struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+}
+
+// Requesting an existing collection revision
+//
+// swagger:parameters RequestCollectionRevisionDiff RequestCollectionRevisionImage RequestCollectionRevisionRestore
+type RequestCollectionRevision struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Revision int64 `json:"revision"`
+}
+
+// Requesting a list of deck revisions
+//
+// swagger:parameters RequestDeckHistory
+type RequestDeckHistory struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Deck string `json:"deck"`
+}
+
+// Requesting an existing deck revision
+//
+// swagger:parameters RequestDeckRevisionDiff RequestDeckRevisionImage RequestDeckRevisionRestore
+type RequestDeckRevision struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Deck string `json:"deck"`
+	// In: path
+	// Required: true
+	Revision int64 `json:"revision"`
+}
+
+// Requesting a list of card revisions
+//
+// swagger:parameters RequestCardHistory
+type RequestCardHistory struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Deck string `json:"deck"`
+	// In: path
+	// Required: true
+	Card int64 `json:"card"`
+}
+
+// Requesting an existing card revision
+//
+// swagger:parameters RequestCardRevisionDiff RequestCardRevisionImage RequestCardRevisionRestore
+type RequestCardRevision struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Deck string `json:"deck"`
+	// In: path
+	// Required: true
+	Card int64 `json:"card"`
+	// In: path
+	// Required: true
+	Revision int64 `json:"revision"`
+}
+
+// swagger:route GET /api/games/{game}/collections/{collection}/history Collections RequestCollectionHistory
+//
+// # Get collection revisions
+//
+// Get a list of previous states of the collection, newest first
+//
+//	Responses:
+//	  200: ResponseListOfRevisions
+//	  default: ResponseError
+
+// swagger:route GET /api/games/{game}/collections/{collection}/decks/{deck}/history Decks RequestDeckHistory
+//
+// # Get deck revisions
+//
+// Get a list of previous states of the deck, newest first
+//
+//	Responses:
+//	  200: ResponseListOfRevisions
+//	  default: ResponseError
+
+// swagger:route GET /api/games/{game}/collections/{collection}/decks/{deck}/cards/{card}/history Cards RequestCardHistory
+//
+// # Get card revisions
+//
+// Get a list of previous states of the card, newest first
+//
+//	Responses:
+//	  200: ResponseListOfRevisions
+//	  default: ResponseError
+
+// swagger:route GET /api/games/{game}/history Games RequestGameHistory
+//
+// # Get game revisions
+//
+// Get a list of previous states of the game, newest first
+//
+//	Responses:
+//	  200: ResponseListOfRevisions
+//	  default: ResponseError
+func (s *UnimplementedHistoryServer) ListHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:route GET /api/games/{game}/collections/{collection}/history/{revision} Collections RequestCollectionRevisionDiff
+//
+// # Get collection revision diff
+//
+// Get the revision and the fields that differ from the current state of the collection
+//
+//	Responses:
+//	  200: ResponseRevisionDiff
+//	  default: ResponseError
+
+// swagger:route GET /api/games/{game}/collections/{collection}/decks/{deck}/history/{revision} Decks RequestDeckRevisionDiff
+//
+// # Get deck revision diff
+//
+// Get the revision and the fields that differ from the current state of the deck
+//
+//	Responses:
+//	  200: ResponseRevisionDiff
+//	  default: ResponseError
+
+// swagger:route GET /api/games/{game}/collections/{collection}/decks/{deck}/cards/{card}/history/{revision} Cards RequestCardRevisionDiff
+//
+// # Get card revision diff
+//
+// Get the revision and the fields that differ from the current state of the card
+//
+//	Responses:
+//	  200: ResponseRevisionDiff
+//	  default: ResponseError
+
+// swagger:route GET /api/games/{game}/history/{revision} Games RequestGameRevisionDiff
+//
+// # Get game revision diff
+//
+// Get the revision and the fields that differ from the current state of the game
+//
+//	Responses:
+//	  200: ResponseRevisionDiff
+//	  default: ResponseError
+func (s *UnimplementedHistoryServer) DiffHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:route GET /api/games/{game}/collections/{collection}/history/{revision}/image Collections RequestCollectionRevisionImage
+//
+// # Get collection revision image
+//
+// Get the image that the collection had at the time of the revision
+//
+//	Responses:
+//	  200: ResponseRevisionImage
+//	  default: ResponseError
+
+// swagger:route GET /api/games/{game}/collections/{collection}/decks/{deck}/history/{revision}/image Decks RequestDeckRevisionImage
+//
+// # Get deck revision image
+//
+// Get the image that the deck had at the time of the revision
+//
+//	Responses:
+//	  200: ResponseRevisionImage
+//	  default: ResponseError
+
+// swagger:route GET /api/games/{game}/collections/{collection}/decks/{deck}/cards/{card}/history/{revision}/image Cards RequestCardRevisionImage
+//
+// # Get card revision image
+//
+// Get the image that the card had at the time of the revision
+//
+//	Responses:
+//	  200: ResponseRevisionImage
+//	  default: ResponseError
+
+// swagger:route GET /api/games/{game}/history/{revision}/image Games RequestGameRevisionImage
+//
+// # Get game revision image
+//
+// Get the image that the game had at the time of the revision
+//
+//	Responses:
+//	  200: ResponseRevisionImage
+//	  default: ResponseError
+func (s *UnimplementedHistoryServer) ImageHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:route POST /api/games/{game}/collections/{collection}/history/{revision}/restore Collections RequestCollectionRevisionRestore
+//
+// # Restore collection revision
+//
+// Return the collection to the state of the revision. The current state is saved as a new revision
+//
+//	Responses:
+//	  200: ResponseRevisionRestore
+//	  default: ResponseError
+
+// swagger:route POST /api/games/{game}/collections/{collection}/decks/{deck}/history/{revision}/restore Decks RequestDeckRevisionRestore
+//
+// # Restore deck revision
+//
+// Return the deck to the state of the revision. The current state is saved as a new revision
+//
+//	Responses:
+//	  200: ResponseRevisionRestore
+//	  default: ResponseError
+
+// swagger:route POST /api/games/{game}/collections/{collection}/decks/{deck}/cards/{card}/history/{revision}/restore Cards RequestCardRevisionRestore
+//
+// # Restore card revision
+//
+// Return the card to the state of the revision. The current state is saved as a new revision
+//
+//	Responses:
+//	  200: ResponseRevisionRestore
+//	  default: ResponseError
+
+// swagger:route POST /api/games/{game}/history/{revision}/restore Games RequestGameRevisionRestore
+//
+// # Restore game revision
+//
+// Return the game to the state of the revision. The current state is saved as a new revision
+//
+//	Responses:
+//	  200: ResponseRevisionRestore
+//	  default: ResponseError
+func (s *UnimplementedHistoryServer) RestoreHandler(w http.ResponseWriter, r *http.Request) {}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"github.com/HardDie/DeckBuilder/internal/dto"
 	serversImage "github.com/HardDie/DeckBuilder/internal/servers/image"
 )
 
@@ -20,6 +21,17 @@ func RegisterImageServer(route *mux.Router, srv serversImage.Image) {
 
 	CardsRoute := DecksRoute.PathPrefix("/{deck}/cards").Subrouter()
 	CardsRoute.HandleFunc("/{card}/image", srv.CardHandler).Methods(http.MethodGet)
+
+	// The same handler edits the images of all entities, the entity is determined by the path variables
+	prefixes := []string{
+		"/api/games/{game}",
+		"/api/games/{game}/collections/{collection}",
+		"/api/games/{game}/collections/{collection}/decks/{deck}",
+		"/api/games/{game}/collections/{collection}/decks/{deck}/cards/{card}",
+	}
+	for _, prefix := range prefixes {
+		route.HandleFunc(prefix+"/image/edit", srv.EditHandler).Methods(http.MethodPost)
+	}
 }
 
 type UnimplementedImageServer struct {
@@ -30,6 +42,12 @@ var (
 	_ serversImage.Image = &UnimplementedImageServer{}
 )
 
+// The image has not been changed since the previous request, the cached image can be used
+//
+// swagger:response ResponseImageNotModified
+type ResponseImageNotModified struct {
+}
+
 // Requesting an image of existing card
 //
 // swagger:parameters RequestCardImage
@@ -46,6 +64,9 @@ type RequestCardImage struct {
 	// In: path
 	// Required: true
 	Card string `json:"card"`
+	// The maximal side of the thumbnail in pixels, the original image is returned if not set
+	// In: query
+	Size int `json:"size"`
 }
 
 // Card image
@@ -60,7 +81,8 @@ type ResponseCardImage struct {
 //
 // # Get card image
 //
-// Get an image of existing card
+// Get an image of existing card or its thumbnail. The response has ETag and Last-Modified,
+// so the image is not downloaded again until it is changed
 //
 //	Produces:
 //	- application/json
@@ -70,6 +92,7 @@ type ResponseCardImage struct {
 //
 //	Responses:
 //	  200: ResponseCardImage
+//	  304: ResponseImageNotModified
 //	  default: ResponseError
 func (s *UnimplementedImageServer) CardHandler(w http.ResponseWriter, r *http.Request) {}
 
@@ -83,6 +106,9 @@ type RequestCollectionImage struct {
 	// In: path
 	// Required: true
 	Collection string `json:"collection"`
+	// The maximal side of the thumbnail in pixels, the original image is returned if not set
+	// In: query
+	Size int `json:"size"`
 }
 
 // Collection image
@@ -97,7 +123,8 @@ type ResponseCollectionImage struct {
 //
 // # Get collection image
 //
-// Get an image of existing collection
+// Get an image of existing collection or its thumbnail. The response has ETag and Last-Modified,
+// so the image is not downloaded again until it is changed
 //
 //	Produces:
 //	- application/json
@@ -107,6 +134,7 @@ type ResponseCollectionImage struct {
 //
 //	Responses:
 //	  200: ResponseCollectionImage
+//	  304: ResponseImageNotModified
 //	  default: ResponseError
 func (s *UnimplementedImageServer) CollectionHandler(w http.ResponseWriter, r *http.Request) {}
 
@@ -123,6 +151,9 @@ type RequestDeckImage struct {
 	// In: path
 	// Required: true
 	Deck string `json:"deck"`
+	// The maximal side of the thumbnail in pixels, the original image is returned if not set
+	// In: query
+	Size int `json:"size"`
 }
 
 // Deck image
@@ -137,7 +168,8 @@ type ResponseDeckImage struct {
 //
 // # Get deck image
 //
-// Get an image of existing deck
+// Get an image of existing deck or its thumbnail. The response has ETag and Last-Modified,
+// so the image is not downloaded again until it is changed
 //
 //	Produces:
 //	- application/json
@@ -147,6 +179,7 @@ type ResponseDeckImage struct {
 //
 //	Responses:
 //	  200: ResponseDeckImage
+//	  304: ResponseImageNotModified
 //	  default: ResponseError
 func (s *UnimplementedImageServer) DeckHandler(w http.ResponseWriter, r *http.Request) {}
 
@@ -157,6 +190,9 @@ type RequestGameImage struct {
 	// In: path
 	// Required: true
 	Game string `json:"game"`
+	// The maximal side of the thumbnail in pixels, the original image is returned if not set
+	// In: query
+	Size int `json:"size"`
 }
 
 // Game image
@@ -171,7 +207,8 @@ type ResponseGameImage struct {
 //
 // # Get game image
 //
-// Get an image of existing game
+// Get an image of existing game or its thumbnail. The response has ETag and Last-Modified,
+// so the image is not downloaded again until it is changed
 //
 //	Produces:
 //	- application/json
@@ -181,5 +218,158 @@ type ResponseGameImage struct {
 //
 //	Responses:
 //	  200: ResponseGameImage
+//	  304: ResponseImageNotModified
 //	  default: ResponseError
 func (s *UnimplementedImageServer) GameHandler(w http.ResponseWriter, r *http.Request) {}
+
+// Editing an image of existing game
+//
+// swagger:parameters RequestGameImageEdit
+type RequestGameImageEdit struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: body
+	// Required: true
+	Body dto.EditImage
+}
+
+// Editing an image of existing collection
+//
+// swagger:parameters RequestCollectionImageEdit
+type RequestCollectionImageEdit struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: body
+	// Required: true
+	Body dto.EditImage
+}
+
+// Editing an image of existing deck
+//
+// swagger:parameters RequestDeckImageEdit
+type RequestDeckImageEdit struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Deck string `json:"deck"`
+	// In: body
+	// Required: true
+	Body dto.EditImage
+}
+
+// Editing an image of existing card
+//
+// swagger:parameters RequestCardImageEdit
+type RequestCardImageEdit struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Deck string `json:"deck"`
+	// In: path
+	// Required: true
+	Card string `json:"card"`
+	// In: body
+	// Required: true
+	Body dto.EditImage
+}
+
+// Edited image
+//
+// swagger:response ResponseImageEdit
+type ResponseImageEdit struct {
+	// In: body
+	Body []byte
+}
+
+// swagger:route POST /api/games/{game}/image/edit Images RequestGameImageEdit
+//
+// # Edit game image
+//
+// Apply the operations to the game image one after another and save the result.
+// In preview mode the result is returned without saving
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//	- image/png
+//	- image/jpeg
+//
+//	Responses:
+//	  200: ResponseImageEdit
+//	  default: ResponseError
+
+// swagger:route POST /api/games/{game}/collections/{collection}/image/edit Images RequestCollectionImageEdit
+//
+// # Edit collection image
+//
+// Apply the operations to the collection image one after another and save the result.
+// In preview mode the result is returned without saving
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//	- image/png
+//	- image/jpeg
+//
+//	Responses:
+//	  200: ResponseImageEdit
+//	  default: ResponseError
+
+// swagger:route POST /api/games/{game}/collections/{collection}/decks/{deck}/image/edit Images RequestDeckImageEdit
+//
+// # Edit deck image
+//
+// Apply the operations to the deck image one after another and save the result.
+// In preview mode the result is returned without saving
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//	- image/png
+//	- image/jpeg
+//
+//	Responses:
+//	  200: ResponseImageEdit
+//	  default: ResponseError
+
+// swagger:route POST /api/games/{game}/collections/{collection}/decks/{deck}/cards/{card}/image/edit Images RequestCardImageEdit
+//
+// # Edit card image
+//
+// Apply the operations to the card image one after another and save the result.
+// The fit operation without the size fits the image to the size of the deck cards.
+// In preview mode the result is returned without saving
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//	- image/png
+//	- image/jpeg
+//
+//	Responses:
+//	  200: ResponseImageEdit
+//	  default: ResponseError
+func (s *UnimplementedImageServer) EditHandler(w http.ResponseWriter, r *http.Request) {}
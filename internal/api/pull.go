@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/dto"
+	"github.com/HardDie/DeckBuilder/internal/network"
+	serversPull "github.com/HardDie/DeckBuilder/internal/servers/pull"
+)
+
+func RegisterPullServer(route *mux.Router, srv serversPull.Pull) {
+	route.HandleFunc("/api/games/{game}/tts/changes", srv.ChangesHandler).Methods(http.MethodGet)
+	route.HandleFunc("/api/games/{game}/tts/changes", srv.ApplyHandler).Methods(http.MethodPost)
+}
+
+type UnimplementedPullServer struct {
+}
+
+var (
+	// Validation
+	_ serversPull.Pull = &UnimplementedPullServer{}
+)
+
+// Requesting the changes made in TTS
+//
+// swagger:parameters RequestTTSChanges
+type RequestTTSChanges struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+}
+
+// Changes of the cards made in TTS
+//
+// swagger:response ResponseTTSChanges
+type ResponseTTSChanges struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data []*dto.TTSChange `json:"data"`
+		// Required: true
+		Meta *network.Meta `json:"meta"`
+	}
+}
+
+// swagger:route GET /api/games/{game}/tts/changes TTS RequestTTSChanges
+//
+// # Get changes made in TTS
+//
+// Get the spawned objects from the running TTS, match them with the cards of the last generated game
+// by GUID or by the card sheet, and compare the names, the descriptions and the variables with the stored cards
+//
+//	Responses:
+//	  200: ResponseTTSChanges
+//	  default: ResponseError
+func (s *UnimplementedPullServer) ChangesHandler(w http.ResponseWriter, r *http.Request) {}
+
+// Applying the selected changes made in TTS
+//
+// swagger:parameters RequestTTSChangesApply
+type RequestTTSChangesApply struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: body
+	// Required: true
+	Body []*dto.TTSChange
+}
+
+// Updated cards
+//
+// swagger:response ResponseTTSChangesApply
+type ResponseTTSChangesApply struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data []*dto.Card `json:"data"`
+		// Required: true
+		Meta *network.Meta `json:"meta"`
+	}
+}
+
+// swagger:route POST /api/games/{game}/tts/changes TTS RequestTTSChangesApply
+//
+// # Apply changes made in TTS
+//
+// Save the selected changes to the stored cards, all changes of a card are saved as a single revision
+//
+//	Consumes:
+//	- application/json
+//
+//	Responses:
+//	  200: ResponseTTSChangesApply
+//	  default: ResponseError
+func (s *UnimplementedPullServer) ApplyHandler(w http.ResponseWriter, r *http.Request) {}
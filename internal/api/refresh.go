@@ -0,0 +1,136 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/dto"
+	serversRefresh "github.com/HardDie/DeckBuilder/internal/servers/refresh"
+)
+
+func RegisterRefreshServer(route *mux.Router, srv serversRefresh.Refresh) {
+	// The same handler serves all entities, the entity is determined by the path variables
+	prefixes := []string{
+		"/api/games/{game}",
+		"/api/games/{game}/collections/{collection}",
+		"/api/games/{game}/collections/{collection}/decks/{deck}",
+	}
+	for _, prefix := range prefixes {
+		route.HandleFunc(prefix+"/images/refresh", srv.StartHandler).Methods(http.MethodPost)
+	}
+	route.HandleFunc("/api/images/refresh", srv.ReportHandler).Methods(http.MethodGet)
+}
+
+type UnimplementedRefreshServer struct {
+}
+
+var (
+	// Validation
+	_ serversRefresh.Refresh = &UnimplementedRefreshServer{}
+)
+
+// Options of the image refresh
+type RequestRefreshBody struct {
+	// Replace only the images whose downloaded content differs from the stored one
+	OnlyChanged bool `json:"onlyChanged"`
+}
+
+// Requesting a refresh of the game images
+//
+// swagger:parameters RequestGameImagesRefresh
+type RequestGameImagesRefresh struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: body
+	// Required: false
+	Body RequestRefreshBody
+}
+
+// Requesting a refresh of the collection images
+//
+// swagger:parameters RequestCollectionImagesRefresh
+type RequestCollectionImagesRefresh struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: body
+	// Required: false
+	Body RequestRefreshBody
+}
+
+// Requesting a refresh of the deck images
+//
+// swagger:parameters RequestDeckImagesRefresh
+type RequestDeckImagesRefresh struct {
+	// In: path
+	// Required: true
+	Game string `json:"game"`
+	// In: path
+	// Required: true
+	Collection string `json:"collection"`
+	// In: path
+	// Required: true
+	Deck string `json:"deck"`
+	// In: body
+	// Required: false
+	Body RequestRefreshBody
+}
+
+// Report of the image refresh
+//
+// swagger:response ResponseImagesRefresh
+type ResponseImagesRefresh struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data dto.RefreshReport `json:"data"`
+	}
+}
+
+// swagger:route POST /api/games/{game}/collections/{collection}/images/refresh Collections RequestCollectionImagesRefresh
+//
+// # Refresh collection images
+//
+// Start the background download of the images of the collection, its decks and cards from their URLs
+//
+//	Responses:
+//	  200: ResponseImagesRefresh
+//	  default: ResponseError
+
+// swagger:route POST /api/games/{game}/collections/{collection}/decks/{deck}/images/refresh Decks RequestDeckImagesRefresh
+//
+// # Refresh deck images
+//
+// Start the background download of the images of the deck and its cards from their URLs
+//
+//	Responses:
+//	  200: ResponseImagesRefresh
+//	  default: ResponseError
+
+// swagger:route POST /api/games/{game}/images/refresh Games RequestGameImagesRefresh
+//
+// # Refresh game images
+//
+// Start the background download of the images of the game and all its collections, decks and cards from their URLs
+//
+//	Responses:
+//	  200: ResponseImagesRefresh
+//	  default: ResponseError
+func (s *UnimplementedRefreshServer) StartHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:route GET /api/images/refresh Images RequestImagesRefreshReport
+//
+// # Get image refresh report
+//
+// Get the images changed or failed by the last refresh, the report is updated while the refresh is running
+//
+//	Responses:
+//	  200: ResponseImagesRefresh
+//	  default: ResponseError
+func (s *UnimplementedRefreshServer) ReportHandler(w http.ResponseWriter, r *http.Request) {}
@@ -43,7 +43,8 @@ type ResponsePrepareReplace struct {
 //
 // # Map with key image and empty value for URLs
 //
-// Takes as input the generated resulting json file for a saved TTS object.
+// Takes as input the generated resulting json file for a saved TTS object, any TTS save or saved object is accepted.
+// The URLs of the custom decks, images and meshes are collected at any depth of the objects.
 // As a result, it returns a map of the files that should be uploaded to the web repository
 // and allows you to manually map those files to valid URLs.
 //
@@ -61,10 +62,17 @@ type RequestReplaceReplace struct {
 	// In: formData
 	// Required: true
 	File []byte `json:"file"`
-	// Json file
+	// Json file returned by the prepare request or written by the uploader, or CSV file with lines: local file, URL.
+	// The first line of CSV file can be the header: file,url. The local file can be set by the file name only,
+	// if the name is not the same for different files
 	// In: formData
-	// Required: true
 	Mapping []byte `json:"mapping"`
+	// The local files without the mapping are replaced with the file name under this URL, e.g. https://example.com/images
+	// In: formData
+	BaseURL string `json:"baseURL"`
+	// Spawn the replaced object in the running TTS: true. The replaced json is returned even if TTS is not running
+	// In: formData
+	Spawn bool `json:"spawn"`
 }
 
 // swagger:response ResponseReplaceReplace
@@ -81,7 +89,9 @@ type ResponseReplaceReplace struct {
 // # Replace all image paths with a mapping file
 //
 // Accepts as input the generated resulting json file for the saved TTS object and the mapped URL files.
-// This returns a saved TTS object json file with local file paths replaced by URLs.
+// This returns a saved TTS object json file with local file paths replaced by URLs, the other fields are kept as is.
+// The mapping file, the base URL or both must be set. If some local files are left without the mapping, all of them are returned in the error.
+// The remote URLs are replaced only if they are in the mapping.
 // And with these cards you can already save your table and share it with other users.
 //
 //	Consumes:
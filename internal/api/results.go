@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+// RegisterResultsServer serves the images and the json generated for TTS, so TTS can load them over the network
+func RegisterResultsServer(route *mux.Router, cfg *config.Config) {
+	fileServer := http.StripPrefix(config.ResultsURLPath, http.FileServer(filesOnly{http.Dir(cfg.Results())}))
+	route.PathPrefix(config.ResultsURLPath).Handler(fileServer).Methods(http.MethodGet, http.MethodHead)
+}
+
+// filesOnly hides the folders, so the generated files can't be listed, only downloaded by the exact name
+type filesOnly struct {
+	fs http.FileSystem
+}
+
+func (f filesOnly) Open(name string) (http.File, error) {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		errors.IfErrorLog(file.Close())
+		return nil, err
+	}
+	if info.IsDir() {
+		errors.IfErrorLog(file.Close())
+		return nil, os.ErrNotExist
+	}
+	return file, nil
+}
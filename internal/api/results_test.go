@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+)
+
+func TestResultsServer(t *testing.T) {
+	cfg := config.Get(false, "test")
+	cfg.SetDataPath(t.TempDir())
+	assert.NoError(t, os.MkdirAll(filepath.Join(cfg.Results(), "game"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(cfg.Results(), "game", "deck.json"), []byte("{}"), 0644))
+
+	route := mux.NewRouter()
+	RegisterResultsServer(route, cfg)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		route.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// The generated files are served by the exact name
+	w := get(config.ResultsURLPath + "game/deck.json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{}", w.Body.String())
+
+	// The folders are not listed
+	for _, path := range []string{config.ResultsURLPath, config.ResultsURLPath + "game/", config.ResultsURLPath + "game"} {
+		w = get(path)
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+		assert.NotContains(t, w.Body.String(), "deck.json", path)
+	}
+}
@@ -89,6 +89,13 @@ type RequestUpdateSettings struct {
 	Body struct {
 		// Required: true
 		Lang string `json:"lang"`
+		// The address of the External Editor API of the running TTS, e.g. 192.168.1.20:39999
+		TTSAddress string `json:"tts_address"`
+		// The address the messages from TTS are received on
+		TTSListenAddress string `json:"tts_listen_address"`
+		// The base URL of this application as TTS sees it, e.g. http://192.168.1.10:5000
+		// The change between this computer and the address in the network requires the restart of the application
+		TTSCallbackURL string `json:"tts_callback_url"`
 	}
 }
 
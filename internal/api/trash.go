@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/dto"
+	"github.com/HardDie/DeckBuilder/internal/network"
+	serversTrash "github.com/HardDie/DeckBuilder/internal/servers/trash"
+)
+
+func RegisterTrashServer(route *mux.Router, srv serversTrash.Trash) {
+	TrashRoute := route.PathPrefix("/api/trash").Subrouter()
+	TrashRoute.HandleFunc("", srv.ListHandler).Methods(http.MethodGet)
+	TrashRoute.HandleFunc("", srv.PurgeHandler).Methods(http.MethodDelete)
+	TrashRoute.HandleFunc("/{item}", srv.DeleteHandler).Methods(http.MethodDelete)
+	TrashRoute.HandleFunc("/{item}/restore", srv.RestoreHandler).Methods(http.MethodPost)
+}
+
+type UnimplementedTrashServer struct {
+}
+
+var (
+	// Validation
+	_ serversTrash.Trash = &UnimplementedTrashServer{}
+)
+
+// List of deleted entities
+//
+// swagger:response ResponseListOfTrashItems
+type ResponseListOfTrashItems struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data []*dto.TrashItem `json:"data"`
+		// Required: true
+		Meta *network.Meta `json:"meta"`
+	}
+}
+
+// swagger:route GET /api/trash Trash RequestListOfTrashItems
+//
+// # Get list of deleted entities
+//
+// Get a list of deleted games, collections, decks and cards, recently deleted first
+//
+//	Responses:
+//	  200: ResponseListOfTrashItems
+//	  default: ResponseError
+func (s *UnimplementedTrashServer) ListHandler(w http.ResponseWriter, r *http.Request) {}
+
+// Trash purge status
+//
+// swagger:response ResponsePurgeTrash
+type ResponsePurgeTrash struct {
+}
+
+// swagger:route DELETE /api/trash Trash RequestPurgeTrash
+//
+// # Purge trash
+//
+// Permanently delete all entities from the trash
+//
+//	Responses:
+//	  200: ResponsePurgeTrash
+//	  default: ResponseError
+func (s *UnimplementedTrashServer) PurgeHandler(w http.ResponseWriter, r *http.Request) {}
+
+// Request to permanently delete an entity
+//
+// swagger:parameters RequestDeleteTrashItem
+type RequestDeleteTrashItem struct {
+	// In: path
+	// Required: true
+	Item int64 `json:"item"`
+}
+
+// Trash item deletion status
+//
+// swagger:response ResponseDeleteTrashItem
+type ResponseDeleteTrashItem struct {
+}
+
+// swagger:route DELETE /api/trash/{item} Trash RequestDeleteTrashItem
+//
+// # Delete trash item
+//
+// Permanently delete the entity from the trash
+//
+//	Responses:
+//	  200: ResponseDeleteTrashItem
+//	  default: ResponseError
+func (s *UnimplementedTrashServer) DeleteHandler(w http.ResponseWriter, r *http.Request) {}
+
+// Request to restore a deleted entity
+//
+// swagger:parameters RequestRestoreTrashItem
+type RequestRestoreTrashItem struct {
+	// In: path
+	// Required: true
+	Item int64 `json:"item"`
+	// In: body
+	Body struct {
+		// A new name, if the original name has been reused since the entity was deleted
+		Name string `json:"name"`
+	}
+}
+
+// Identifiers of the restored entity. The card always gets a new identifier
+//
+// swagger:response ResponseRestoreTrashItem
+type ResponseRestoreTrashItem struct {
+	// In: body
+	// Required: true
+	Body struct {
+		// Required: true
+		Data dto.TrashRestore `json:"data"`
+	}
+}
+
+// swagger:route POST /api/trash/{item}/restore Trash RequestRestoreTrashItem
+//
+// # Restore trash item
+//
+// Return the entity to its original place. If the parent entity has been deleted, it must be restored first
+//
+//	Responses:
+//	  200: ResponseRestoreTrashItem
+//	  default: ResponseError
+func (s *UnimplementedTrashServer) RestoreHandler(w http.ResponseWriter, r *http.Request) {}
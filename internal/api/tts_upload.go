@@ -1,17 +1,40 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/dto"
 )
 
 type ITTSServer interface {
 	DataHandler(w http.ResponseWriter, r *http.Request)
+	HistoryHandler(w http.ResponseWriter, r *http.Request)
+	StatusHandler(w http.ResponseWriter, r *http.Request)
+	ScriptsHandler(w http.ResponseWriter, r *http.Request)
+	SaveAndPlayHandler(w http.ResponseWriter, r *http.Request)
+	MessageHandler(w http.ResponseWriter, r *http.Request)
+	ExecuteHandler(w http.ResponseWriter, r *http.Request)
+	LogHandler(w http.ResponseWriter, r *http.Request)
+	LogStreamHandler(w http.ResponseWriter, r *http.Request)
+	TestHandler(w http.ResponseWriter, r *http.Request)
+	PingHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func RegisterTTSServer(route *mux.Router, srv ITTSServer) {
-	route.HandleFunc("/api/tts/data", srv.DataHandler).Methods(http.MethodGet)
+	route.HandleFunc("/api/tts/data/{token}", srv.DataHandler).Methods(http.MethodGet)
+	route.HandleFunc("/api/tts/history", srv.HistoryHandler).Methods(http.MethodGet)
+	route.HandleFunc("/api/tts/status", srv.StatusHandler).Methods(http.MethodGet)
+	route.HandleFunc("/api/tts/scripts", srv.ScriptsHandler).Methods(http.MethodGet)
+	route.HandleFunc("/api/tts/scripts", srv.SaveAndPlayHandler).Methods(http.MethodPost)
+	route.HandleFunc("/api/tts/message", srv.MessageHandler).Methods(http.MethodPost)
+	route.HandleFunc("/api/tts/execute", srv.ExecuteHandler).Methods(http.MethodPost)
+	route.HandleFunc("/api/tts/log", srv.LogHandler).Methods(http.MethodGet)
+	route.HandleFunc("/api/tts/log/stream", srv.LogStreamHandler).Methods(http.MethodGet)
+	route.HandleFunc("/api/tts/test", srv.TestHandler).Methods(http.MethodPost)
+	route.HandleFunc("/api/tts/ping", srv.PingHandler).Methods(http.MethodGet)
 }
 
 type UnimplementedTTSServer struct {
@@ -24,6 +47,10 @@ var (
 
 // swagger:parameters RequestDataTTS
 type RequestDataTTS struct {
+	// The token of the sent object
+	// In: path
+	// Required: true
+	Token string `json:"token"`
 }
 
 // swagger:response ResponseDataTTS
@@ -32,13 +59,277 @@ type ResponseDataTTS struct {
 	Body []byte
 }
 
-// swagger:route GET /api/tts/data TTS RequestDataTTS
+// swagger:route GET /api/tts/data/{token} TTS RequestDataTTS
 //
-// # Get json file from last generator
+// # Get sent object
 //
-// API for TTS for downloading JSON file inside game
+// API for TTS for downloading JSON file inside game.
+// The object can be downloaded again until it expires
 //
 //	Responses:
 //	  200: ResponseDataTTS
 //	  default: ResponseError
 func (s *UnimplementedTTSServer) DataHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:parameters RequestHistoryTTS
+type RequestHistoryTTS struct {
+}
+
+// The objects sent to TTS, the newest first
+//
+// swagger:response ResponseHistoryTTS
+type ResponseHistoryTTS struct {
+	// In: body
+	Body struct {
+		// Required: true
+		Data []dto.TTSSent `json:"data"`
+	}
+}
+
+// swagger:route GET /api/tts/history TTS RequestHistoryTTS
+//
+// # Get sent objects
+//
+// Get the objects sent to TTS with the time they were downloaded by TTS and the time they expire
+//
+//	Responses:
+//	  200: ResponseHistoryTTS
+//	  default: ResponseError
+func (s *UnimplementedTTSServer) HistoryHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:parameters RequestStatusTTS
+type RequestStatusTTS struct {
+}
+
+// Status of the connection to TTS
+//
+// swagger:response ResponseStatusTTS
+type ResponseStatusTTS struct {
+	// In: body
+	Body struct {
+		// Required: true
+		Data dto.TTSStatus `json:"data"`
+	}
+}
+
+// swagger:route GET /api/tts/status TTS RequestStatusTTS
+//
+// # Get TTS status
+//
+// Check if TTS is running and accepts the messages, and if the messages from TTS are received
+//
+//	Responses:
+//	  200: ResponseStatusTTS
+//	  default: ResponseError
+func (s *UnimplementedTTSServer) StatusHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:parameters RequestScriptsTTS
+type RequestScriptsTTS struct {
+}
+
+// Scripts of the loaded game
+//
+// swagger:response ResponseScriptsTTS
+type ResponseScriptsTTS struct {
+	// In: body
+	Body struct {
+		// Required: true
+		Data []dto.TTSScript `json:"data"`
+	}
+}
+
+// swagger:route GET /api/tts/scripts TTS RequestScriptsTTS
+//
+// # Get scripts
+//
+// Get the scripts and the UI of the global script (guid -1) and of all objects of the game loaded in TTS
+//
+//	Responses:
+//	  200: ResponseScriptsTTS
+//	  default: ResponseError
+func (s *UnimplementedTTSServer) ScriptsHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:parameters RequestSaveAndPlayTTS
+type RequestSaveAndPlayTTS struct {
+	// In: body
+	// Required: true
+	Body []dto.TTSScript
+}
+
+// swagger:response ResponseSaveAndPlayTTS
+type ResponseSaveAndPlayTTS struct {
+}
+
+// swagger:route POST /api/tts/scripts TTS RequestSaveAndPlayTTS
+//
+// # Save and play
+//
+// Update the scripts and the UI of the objects and reload the game in TTS.
+// The objects missing in the list keep their scripts
+//
+//	Consumes:
+//	- application/json
+//
+//	Responses:
+//	  200: ResponseSaveAndPlayTTS
+//	  default: ResponseError
+func (s *UnimplementedTTSServer) SaveAndPlayHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:parameters RequestMessageTTS
+type RequestMessageTTS struct {
+	// In: body
+	// Required: true
+	Body json.RawMessage
+}
+
+// swagger:response ResponseMessageTTS
+type ResponseMessageTTS struct {
+}
+
+// swagger:route POST /api/tts/message TTS RequestMessageTTS
+//
+// # Send custom message
+//
+// Pass the JSON object as a table to the onExternalMessage function of the global script
+//
+//	Consumes:
+//	- application/json
+//
+//	Responses:
+//	  200: ResponseMessageTTS
+//	  default: ResponseError
+func (s *UnimplementedTTSServer) MessageHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:parameters RequestExecuteTTS
+type RequestExecuteTTS struct {
+	// In: body
+	// Required: true
+	Body dto.TTSExecute
+}
+
+// The value returned by the code
+//
+// swagger:response ResponseExecuteTTS
+type ResponseExecuteTTS struct {
+	// In: body
+	Body struct {
+		// Required: true
+		Data dto.TTSExecuteResult `json:"data"`
+	}
+}
+
+// swagger:route POST /api/tts/execute TTS RequestExecuteTTS
+//
+// # Execute Lua code
+//
+// Run the Lua code on the object or on the global script and return the value returned by the code.
+// TTS sends nothing if the code returns nothing, so such code ends with the timeout error
+//
+//	Consumes:
+//	- application/json
+//
+//	Responses:
+//	  200: ResponseExecuteTTS
+//	  default: ResponseError
+func (s *UnimplementedTTSServer) ExecuteHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:parameters RequestLogTTS
+type RequestLogTTS struct {
+}
+
+// The last messages received from TTS, the oldest first
+//
+// swagger:response ResponseLogTTS
+type ResponseLogTTS struct {
+	// In: body
+	Body struct {
+		// Required: true
+		Data []dto.TTSEvent `json:"data"`
+	}
+}
+
+// swagger:route GET /api/tts/log TTS RequestLogTTS
+//
+// # Get TTS log
+//
+// Get the last prints, errors, custom messages and other events received from TTS
+//
+//	Responses:
+//	  200: ResponseLogTTS
+//	  default: ResponseError
+func (s *UnimplementedTTSServer) LogHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:parameters RequestLogStreamTTS
+type RequestLogStreamTTS struct {
+}
+
+// Server-sent events, the event name is the type of the message and the data is the JSON of dto.TTSEvent
+//
+// swagger:response ResponseLogStreamTTS
+type ResponseLogStreamTTS struct {
+	// In: body
+	Body string
+}
+
+// swagger:route GET /api/tts/log/stream TTS RequestLogStreamTTS
+//
+// # Stream TTS log
+//
+// Stream the messages received from TTS as server-sent events until the client disconnects
+//
+//	Produces:
+//	- text/event-stream
+//
+//	Responses:
+//	  200: ResponseLogStreamTTS
+//	  default: ResponseError
+func (s *UnimplementedTTSServer) LogStreamHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:parameters RequestTestTTS
+type RequestTestTTS struct {
+}
+
+// The result of the connection test
+//
+// swagger:response ResponseTestTTS
+type ResponseTestTTS struct {
+	// In: body
+	Body struct {
+		// Required: true
+		Data dto.TTSTest `json:"data"`
+	}
+}
+
+// swagger:route POST /api/tts/test TTS RequestTestTTS
+//
+// # Test TTS connection
+//
+// Check that TTS accepts the messages on the configured address,
+// and that TTS can download the sent objects from the application by the configured callback URL
+//
+//	Responses:
+//	  200: ResponseTestTTS
+//	  default: ResponseError
+func (s *UnimplementedTTSServer) TestHandler(w http.ResponseWriter, r *http.Request) {}
+
+// swagger:parameters RequestPingTTS
+type RequestPingTTS struct {
+	// In: query
+	// Required: true
+	Token string `json:"token"`
+}
+
+// swagger:response ResponsePingTTS
+type ResponsePingTTS struct {
+}
+
+// swagger:route GET /api/tts/ping TTS RequestPingTTS
+//
+// # Ping from TTS
+//
+// API for TTS, it is requested inside the game during the connection test
+//
+//	Responses:
+//	  200: ResponsePingTTS
+//	  default: ResponseError
+func (s *UnimplementedTTSServer) PingHandler(w http.ResponseWriter, r *http.Request) {}
@@ -1,113 +1,196 @@
 package application
 
 import (
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/HardDie/fsentry"
 	"github.com/gorilla/mux"
 
 	"github.com/HardDie/DeckBuilder/internal/api"
 	"github.com/HardDie/DeckBuilder/internal/config"
-	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
-	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
 	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
-	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
-	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
-	dbSettings "github.com/HardDie/DeckBuilder/internal/db/settings"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
+	"github.com/HardDie/DeckBuilder/internal/errors"
+	internalFS "github.com/HardDie/DeckBuilder/internal/fs"
 	"github.com/HardDie/DeckBuilder/internal/logger"
+	"github.com/HardDie/DeckBuilder/internal/network"
 	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
 	repositoriesCollection "github.com/HardDie/DeckBuilder/internal/repositories/collection"
 	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
 	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
 	serversCard "github.com/HardDie/DeckBuilder/internal/servers/card"
 	serversCollection "github.com/HardDie/DeckBuilder/internal/servers/collection"
 	serversDeck "github.com/HardDie/DeckBuilder/internal/servers/deck"
 	serversGame "github.com/HardDie/DeckBuilder/internal/servers/game"
 	serversGenerator "github.com/HardDie/DeckBuilder/internal/servers/generator"
+	serversHistory "github.com/HardDie/DeckBuilder/internal/servers/history"
 	serversImage "github.com/HardDie/DeckBuilder/internal/servers/image"
+	serversPull "github.com/HardDie/DeckBuilder/internal/servers/pull"
+	serversRefresh "github.com/HardDie/DeckBuilder/internal/servers/refresh"
 	serversReplace "github.com/HardDie/DeckBuilder/internal/servers/replace"
 	serversSearch "github.com/HardDie/DeckBuilder/internal/servers/search"
 	serversSystem "github.com/HardDie/DeckBuilder/internal/servers/system"
+	serversTrash "github.com/HardDie/DeckBuilder/internal/servers/trash"
 	serversTTS "github.com/HardDie/DeckBuilder/internal/servers/tts"
 	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
 	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
 	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
 	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
 	servicesGenerator "github.com/HardDie/DeckBuilder/internal/services/generator"
+	servicesHistory "github.com/HardDie/DeckBuilder/internal/services/history"
+	servicesImage "github.com/HardDie/DeckBuilder/internal/services/image"
+	servicesPull "github.com/HardDie/DeckBuilder/internal/services/pull"
+	servicesRefresh "github.com/HardDie/DeckBuilder/internal/services/refresh"
 	servicesReplace "github.com/HardDie/DeckBuilder/internal/services/replace"
 	servicesSearch "github.com/HardDie/DeckBuilder/internal/services/search"
 	servicesSystem "github.com/HardDie/DeckBuilder/internal/services/system"
+	servicesTrash "github.com/HardDie/DeckBuilder/internal/services/trash"
 	servicesTTS "github.com/HardDie/DeckBuilder/internal/services/tts"
+	"github.com/HardDie/DeckBuilder/internal/uploader"
 )
 
 type Application struct {
-	router *mux.Router
+	cfg        *config.Config
+	router     *mux.Router
+	serviceTTS servicesTTS.TTS
 }
 
-func Get(debugFlag bool, version string) (*Application, error) {
-	cfg := config.Get(debugFlag, version)
-
+func Get(cfg *config.Config) (*Application, error) {
 	routes := mux.NewRouter().StrictSlash(false)
 
 	// static files
 	api.RegisterStaticServer(routes)
+	api.RegisterResultsServer(routes, cfg)
 
-	// fsentry db
-	fs := fsentry.NewFSEntry(cfg.Data, fsentry.WithPretty())
+	// fsentry db, the file storage keeps the games, the revisions and the trash in the data folder
+	fs := internalFS.NewFSEntry(cfg.Data, true)
 
 	// db methods
-	core := dbCore.New(fs)
-	settings := dbSettings.New(fs)
-	game := dbGame.New(fs)
-	collection := dbCollection.New(fs, game)
-	deck := dbDeck.New(fs, collection)
-	card := dbCard.New(fs, deck)
+	core := dbCore.New(fs, cfg.Data)
 
 	err := core.Init()
 	if err != nil {
 		logger.Error.Fatal(err)
 	}
 
+	// The images are downloaded through the cache folder
+	network.SetupDownloader(network.NewDownloader(cfg))
+
+	var store transfer.Store
+	var trash dbTrash.Trash
+	var archive dbArchive.Archive
+	switch cfg.Storage {
+	case config.StorageFiles:
+		images := dbImage.New(fs, cfg.Data)
+		store = transfer.Files(fs, images)
+		trash = dbTrash.New(fs, images, cfg.Data)
+		archive = dbArchive.New(cfg, fs, store, images)
+	case config.StorageSQLite:
+		db, err := dbSQLite.Open(cfg.SQLitePath())
+		if err != nil {
+			return nil, err
+		}
+		// The revisions stored in the files by the previous versions are moved into the database
+		err = dbCore.NewSQLite(db, fs, cfg.Data).Init()
+		if err != nil {
+			return nil, err
+		}
+		store = transfer.SQLite(db)
+		trash = dbTrash.NewSQLite(db)
+		archive = dbArchive.NewSQLite(cfg, store)
+	default:
+		return nil, errors.InternalError.AddMessage("unknown storage: " + cfg.Storage)
+	}
+	settings := store.Settings
+	game := store.Game
+	collection := store.Collection
+	deck := store.Deck
+	card := store.Card
+	history := store.History
+
+	// tts service
+	serviceTTS := servicesTTS.New(cfg)
+
 	// system
-	serviceSystem := servicesSystem.New(cfg, settings)
+	serviceSystem := servicesSystem.New(cfg, settings, serviceTTS)
 	serverSystem := serversSystem.New(cfg, serviceSystem)
 	api.RegisterSystemServer(routes, serverSystem)
 
+	// history
+	repositoryHistory := repositoriesHistory.New(cfg, history, game, collection, deck, card)
+	serviceHistory := servicesHistory.New(cfg, repositoryHistory)
+	serverHistory := serversHistory.New(serviceHistory)
+	api.RegisterHistoryServer(routes, serverHistory)
+
+	// trash
+	repositoryTrash := repositoriesTrash.New(cfg, trash, game, collection, deck, card)
+	serviceTrash := servicesTrash.New(cfg, repositoryTrash)
+	serverTrash := serversTrash.New(serviceTrash)
+	api.RegisterTrashServer(routes, serverTrash)
+	go purgeTrash(serviceTrash)
+
 	// game
-	repositoryGame := repositoriesGame.New(cfg, game)
+	repositoryGame := repositoriesGame.New(cfg, game, archive, repositoryHistory, repositoryTrash)
 	serviceGame := servicesGame.New(cfg, repositoryGame)
 	serverGame := serversGame.New(*cfg, serviceGame, serverSystem)
 	api.RegisterGameServer(routes, serverGame)
 
 	// collection
-	repositoryCollection := repositoriesCollection.New(cfg, collection)
+	repositoryDeck := repositoriesDeck.New(cfg, collection, deck, card, repositoryHistory, repositoryTrash)
+	repositoryCollection := repositoriesCollection.New(cfg, collection, repositoryDeck, repositoryHistory, repositoryTrash)
 	serviceCollection := servicesCollection.New(cfg, repositoryCollection)
 	serverCollection := serversCollection.New(*cfg, serviceCollection, serverSystem)
 	api.RegisterCollectionServer(routes, serverCollection)
 
 	// deck
-	repositoryDeck := repositoriesDeck.New(cfg, collection, deck)
 	serviceDeck := servicesDeck.New(cfg, repositoryDeck)
 	serverDeck := serversDeck.New(*cfg, serviceDeck, serverSystem)
 	api.RegisterDeckServer(routes, serverDeck)
 
 	// card
-	repositoryCard := repositoriesCard.New(cfg, card)
+	repositoryCard := repositoriesCard.New(cfg, card, repositoryHistory, repositoryTrash)
 	serviceCard := servicesCard.New(cfg, repositoryCard)
 	serverCard := serversCard.New(*cfg, serviceCard, serverSystem)
 	api.RegisterCardServer(routes, serverCard)
 
 	// image
-	serverImage := serversImage.New(serviceGame, serviceCollection, serviceDeck, serviceCard)
+	serviceImage := servicesImage.New(cfg, serviceGame, serviceCollection, serviceDeck, serviceCard)
+	serverImage := serversImage.New(serviceImage)
 	api.RegisterImageServer(routes, serverImage)
 
-	// tts service
-	serviceTTS := servicesTTS.New()
+	// image refresh
+	serviceRefresh := servicesRefresh.New(cfg, repositoryGame, repositoryCollection, repositoryDeck, repositoryCard)
+	serverRefresh := serversRefresh.New(serviceRefresh)
+	api.RegisterRefreshServer(routes, serverRefresh)
+
+	// The addresses of TTS saved in the settings replace the flags
+	setting, err := serviceSystem.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	err = serviceTTS.Configure(setting.TTSAddress, setting.TTSListenAddress, setting.TTSCallbackURL)
+	if err != nil {
+		// The application works without the messages from TTS, only the scripts and the log are not available
+		logger.Warn.Println("Unable to receive the messages from TTS:", err.Error())
+	}
 	serverTTS := serversTTS.New(serviceTTS)
 	api.RegisterTTSServer(routes, serverTTS)
 
 	// generator
-	serviceGenerator := servicesGenerator.New(cfg, serviceGame, serviceCollection, serviceDeck, serviceCard, serviceSystem, serviceTTS)
+	imageUploader, err := uploader.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	serviceGenerator := servicesGenerator.New(cfg, serviceGame, serviceCollection, serviceDeck, serviceCard, serviceSystem, serviceTTS, imageUploader)
 	serverGenerator := serversGenerator.New(serviceGenerator)
 	api.RegisterGeneratorServer(routes, serverGenerator)
 
@@ -116,21 +199,52 @@ func Get(debugFlag bool, version string) (*Application, error) {
 	serverReplace := serversReplace.New(serviceReplace)
 	api.RegisterReplaceServer(routes, serverReplace)
 
+	// pull the changes from tts
+	servicePull := servicesPull.New(cfg, serviceTTS, serviceCard)
+	serverPull := serversPull.New(servicePull)
+	api.RegisterPullServer(routes, serverPull)
+
 	// recursive search
 	serviceSearch := servicesSearch.New(serviceGame, serviceCollection, serviceDeck, serviceCard)
 	serverSearch := serversSearch.New(serviceSearch)
 	api.RegisterSearchServer(routes, serverSearch)
 
 	routes.Use(corsMiddleware)
+	routes.Use(localMiddleware)
 	return &Application{
-		router: routes,
+		cfg:        cfg,
+		router:     routes,
+		serviceTTS: serviceTTS,
 	}, nil
 }
 
 func (app *Application) Run() error {
 	http.Handle("/", app.router)
-	logger.Info.Println("Listening on :5000...")
-	return http.ListenAndServe("127.0.0.1:5000", nil)
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(config.ServerPort))
+	// The address is chosen once at the start, the callback URL changed in the settings
+	// from this computer to the address in the network takes effect after the restart
+	if app.cfg.ResultsHost != "" || !config.IsLoopbackURL(app.serviceTTS.CallbackURL()) {
+		// The other players in the local network load the generated images from this computer,
+		// or TTS running on another computer downloads the sent objects
+		addr = net.JoinHostPort("", strconv.Itoa(config.ServerPort))
+	}
+	logger.Info.Printf("Listening on %s...", addr)
+	return http.ListenAndServe(addr, nil)
+}
+
+// Periodically remove expired items from the trash
+func purgeTrash(serviceTrash servicesTrash.Trash) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		count, err := serviceTrash.PurgeExpired()
+		if err != nil {
+			logger.Error.Println("Unable to purge the trash:", err.Error())
+		} else if count > 0 {
+			logger.Info.Printf("%d expired items have been removed from the trash", count)
+		}
+		<-ticker.C
+	}
 }
 
 // CORS headers
@@ -147,3 +261,19 @@ func corsMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// The requests from the network are only allowed to the generated files and to the API for TTS,
+// the rest of the API is available on this computer only
+func localMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, config.ResultsURLPath) &&
+			!strings.HasPrefix(r.URL.Path, config.TTSDataURLPath+"/") && r.URL.Path != config.TTSPingURLPath {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
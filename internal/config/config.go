@@ -1,9 +1,15 @@
 package config
 
 import (
+	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/HardDie/DeckBuilder/internal/logger"
 )
@@ -16,6 +22,44 @@ const (
 	MaxWidth  = 10
 	MaxHeight = 7
 	MaxCount  = MaxWidth*MaxHeight - 1
+
+	DefaultHistoryLimit = 50
+	DefaultTrashDays    = 30
+
+	// The default maximum size of the exported and imported games in megabytes
+	DefaultMaxArchiveSize = 4096
+	// The default maximum size of the downloaded images in megabytes
+	DefaultMaxDownloadSize = 50
+	// The default number of images downloaded at the same time
+	DefaultDownloadConcurrency = 4
+
+	// The default maximum number of pixels of the uploaded image, the largest texture TTS can load
+	DefaultMaxImagePixels = 8192 * 8192
+
+	// Image hostings the generated images are uploaded to
+	UploaderS3   = "s3"
+	UploaderHTTP = "http"
+	// The region used to sign the S3 requests if it is not set, the compatible storages usually ignore it
+	DefaultUploadRegion = "us-east-1"
+	// The form field of the file in the multipart upload request
+	DefaultUploadField = "file"
+
+	// The port of the web server
+	ServerPort = 5000
+	// The path the files of the result folder are served under
+	ResultsURLPath = "/results/"
+	// The path TTS downloads the sent objects from, the token of the object is added to it
+	TTSDataURLPath = "/api/tts/data"
+	// The path TTS requests to check the connection
+	TTSPingURLPath = "/api/tts/ping"
+
+	// The External Editor API of TTS: TTS accepts the messages on the first address and sends its messages to the second one
+	DefaultTTSAddress       = "127.0.0.1:39999"
+	DefaultTTSListenAddress = "127.0.0.1:39998"
+
+	// Storage backends
+	StorageFiles  = "files"
+	StorageSQLite = "sqlite"
 )
 
 type Config struct {
@@ -24,13 +68,77 @@ type Config struct {
 
 	Data   string `json:"data"`
 	Game   string `json:"game"`
+	Image  string `json:"image"`
 	Cache  string `json:"cache"`
 	Result string `json:"result"`
 
+	// Where games, collections, decks, cards and settings are stored: files or sqlite
+	Storage string `json:"storage"`
+	SQLite  string `json:"sqlite"`
+
 	CardImagePath       string `json:"cardImagePath"`
 	DeckImagePath       string `json:"deckImagePath"`
 	CollectionImagePath string `json:"collectionImagePath"`
 	GameImagePath       string `json:"gameImagePath"`
+
+	// The maximum number of revisions stored for each entity. 0 - unlimited
+	HistoryLimit int `json:"historyLimit"`
+	// The number of days after which deleted entities are purged from the trash. 0 - never
+	TrashRetentionDays int `json:"trashRetentionDays"`
+
+	// The maximum size of the files packed into the game archive in bytes, also applies to the unpacked import. 0 - unlimited
+	MaxExportSize int64 `json:"maxExportSize"`
+	// The maximum size of the uploaded game archive in bytes. 0 - unlimited
+	MaxImportSize int64 `json:"maxImportSize"`
+
+	// The timeout of a single download request
+	DownloadTimeout time.Duration `json:"downloadTimeout"`
+	// The number of repeated attempts after a network error or a server error
+	DownloadRetries int `json:"downloadRetries"`
+	// The maximum size of the downloaded image in bytes. 0 - unlimited
+	MaxDownloadSize int64 `json:"maxDownloadSize"`
+	// The maximum number of images downloaded at the same time. 0 - unlimited
+	DownloadConcurrency int `json:"downloadConcurrency"`
+	// How long the downloaded image is used without asking the server, if the server doesn't set it
+	DownloadCacheTTL time.Duration `json:"downloadCacheTTL"`
+
+	// The maximum number of pixels of the uploaded image. 0 - unlimited
+	MaxImagePixels int64 `json:"maxImagePixels"`
+	// The format all uploaded images are converted to: png or jpeg. Empty - keep the original format, if it is supported by TTS
+	ImageFormat string `json:"imageFormat"`
+
+	// The host (or host:port) of this computer in the local network. If set, the generated images are referenced
+	// by http://<host>:<port>/results/... instead of the local paths, so the other players in the network can load them.
+	ResultsHost string `json:"resultsHost"`
+
+	// Where the generated images are uploaded: s3 or http. Empty - the images are only saved to the result folder
+	Uploader string `json:"uploader"`
+	// S3: the endpoint of the storage. HTTP: the URL the files are posted to
+	UploadURL string `json:"uploadURL"`
+	// The timeout of a single upload request
+	UploadTimeout time.Duration `json:"uploadTimeout"`
+	// S3: the bucket, the region, the canned ACL of the uploaded files (e.g. public-read) and the credentials
+	UploadBucket    string `json:"uploadBucket"`
+	UploadRegion    string `json:"uploadRegion"`
+	UploadACL       string `json:"uploadACL"`
+	UploadAccessKey string `json:"-"`
+	UploadSecretKey string `json:"-"`
+	// S3: the base of the links to the uploaded files. Empty - <endpoint>/<bucket>
+	UploadPublicURL string `json:"uploadPublicURL"`
+	// HTTP: the form field of the file, the JSON field of the response with the link (e.g. data.link)
+	// and the value of the Authorization header. Without the JSON field the link is taken from the Location header or the body.
+	UploadField         string `json:"uploadField"`
+	UploadURLField      string `json:"uploadURLField"`
+	UploadAuthorization string `json:"-"`
+
+	// The address of the External Editor API of the running TTS
+	TTSAddress string `json:"ttsAddress"`
+	// The address the messages from TTS are received on
+	TTSListenAddress string `json:"ttsListenAddress"`
+	// The base URL of this application as TTS sees it, the sent objects are downloaded by TTS from it
+	TTSCallbackURL string `json:"ttsCallbackURL"`
+	// How long the sent object can be downloaded by TTS
+	TTSDataTTL time.Duration `json:"ttsDataTTL"`
 }
 
 func Get(debugFlag bool, version string) *Config {
@@ -51,23 +159,131 @@ func Get(debugFlag bool, version string) *Config {
 
 		Data:   data,
 		Game:   "games",
+		Image:  "images",
 		Cache:  "cache",
 		Result: "result",
 
+		Storage: StorageFiles,
+		SQLite:  "deck_builder.db",
+
 		CardImagePath:       "/api/games/%s/collections/%s/decks/%s/cards/%d/image",
 		DeckImagePath:       "/api/games/%s/collections/%s/decks/%s/image",
 		CollectionImagePath: "/api/games/%s/collections/%s/image",
 		GameImagePath:       "/api/games/%s/image",
+
+		HistoryLimit:       DefaultHistoryLimit,
+		TrashRetentionDays: DefaultTrashDays,
+
+		MaxExportSize: DefaultMaxArchiveSize << 20,
+		MaxImportSize: DefaultMaxArchiveSize << 20,
+
+		DownloadTimeout:     30 * time.Second,
+		DownloadRetries:     3,
+		MaxDownloadSize:     DefaultMaxDownloadSize << 20,
+		DownloadConcurrency: DefaultDownloadConcurrency,
+		DownloadCacheTTL:    24 * time.Hour,
+
+		MaxImagePixels: DefaultMaxImagePixels,
+
+		UploadTimeout: 5 * time.Minute,
+		UploadRegion:  DefaultUploadRegion,
+		UploadField:   DefaultUploadField,
+
+		TTSAddress:       DefaultTTSAddress,
+		TTSListenAddress: DefaultTTSListenAddress,
+		TTSCallbackURL:   DefaultTTSCallbackURL(),
+		TTSDataTTL:       10 * time.Minute,
 	}
 }
 
 func (c *Config) Games() string {
 	return filepath.Join(c.Data, c.Game)
 }
+func (c *Config) Images() string {
+	return filepath.Join(c.Data, c.Image)
+}
+func (c *Config) Caches() string {
+	return filepath.Join(c.Data, c.Cache)
+}
+func (c *Config) Thumbnails() string {
+	return filepath.Join(c.Caches(), "thumbnails")
+}
 func (c *Config) Results() string {
 	return filepath.Join(c.Data, c.Result)
 }
 
+// ResultsURL returns the URL the file from the result folder is served by, the host is the results host
+func (c *Config) ResultsURL(path string) string {
+	rel, err := filepath.Rel(c.Results(), path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	host := c.ResultsHost
+	if _, _, err = net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, strconv.Itoa(ServerPort))
+	}
+	res := url.URL{
+		Scheme: "http",
+		Host:   host,
+		Path:   ResultsURLPath + filepath.ToSlash(rel),
+	}
+	return res.String()
+}
+
+// TTSDataURL returns the URL TTS downloads the sent object from by the callback URL
+func TTSDataURL(callbackURL, token string) string {
+	return strings.TrimSuffix(callbackURL, "/") + TTSDataURLPath + "/" + url.PathEscape(token)
+}
+
+// TTSPingURL returns the URL TTS requests to check the connection by the callback URL
+func TTSPingURL(callbackURL, token string) string {
+	return strings.TrimSuffix(callbackURL, "/") + TTSPingURLPath + "?token=" + url.QueryEscape(token)
+}
+
+// ValidateTTS checks the addresses of TTS and the callback URL
+func (c *Config) ValidateTTS() error {
+	return ValidateTTS(c.TTSAddress, c.TTSListenAddress, c.TTSCallbackURL)
+}
+
+// ValidateTTS checks the addresses of TTS and the callback URL set in the settings or with the flags
+func ValidateTTS(address, listenAddress, callbackURL string) error {
+	for _, addr := range []string{address, listenAddress} {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("bad TTS address %q: %w", addr, err)
+		}
+	}
+	link, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("bad TTS callback URL %q: %w", callbackURL, err)
+	}
+	if (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+		return fmt.Errorf("bad TTS callback URL %q: expected http://host:port", callbackURL)
+	}
+	return nil
+}
+
+// IsLoopbackURL checks that the callback URL points to this computer, the bad URL is treated as local
+func IsLoopbackURL(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return true
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+// DefaultTTSCallbackURL returns the URL of the application for TTS running on the same computer
+func DefaultTTSCallbackURL() string {
+	return "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(ServerPort))
+}
+
+func (c *Config) SQLitePath() string {
+	return filepath.Join(c.Data, c.SQLite)
+}
+
 // SetDataPath For tests only!!!
 func (c *Config) SetDataPath(dataPath string) {
 	c.Data = dataPath
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResultsURL(t *testing.T) {
+	cfg := Get(false, "")
+	cfg.SetDataPath("data")
+
+	tests := []struct {
+		host string
+		path string
+		want string
+	}{
+		{"192.168.1.10", filepath.Join(cfg.Results(), "deck_1.png"), "http://192.168.1.10:5000/results/deck_1.png"},
+		{"builder.local:8080", filepath.Join(cfg.Results(), "my deck_2.png"), "http://builder.local:8080/results/my%20deck_2.png"},
+	}
+	for _, test := range tests {
+		cfg.ResultsHost = test.host
+		if got := cfg.ResultsURL(test.path); got != test.want {
+			t.Fatal("Bad URL [got]", got, "[want]", test.want)
+		}
+	}
+}
+
+func TestValidateTTS(t *testing.T) {
+	tests := []struct {
+		address     string
+		callbackURL string
+		valid       bool
+	}{
+		{DefaultTTSAddress, DefaultTTSCallbackURL(), true},
+		{"192.168.1.20:39999", "http://192.168.1.10:8080/", true},
+		{"192.168.1.20", DefaultTTSCallbackURL(), false},
+		{DefaultTTSAddress, "192.168.1.10:5000", false},
+		{DefaultTTSAddress, "ftp://192.168.1.10", false},
+	}
+	for _, test := range tests {
+		cfg := Get(false, "")
+		cfg.TTSAddress = test.address
+		cfg.TTSCallbackURL = test.callbackURL
+		if err := cfg.ValidateTTS(); (err == nil) != test.valid {
+			t.Fatal("Bad validation of", test.address, test.callbackURL, err)
+		}
+	}
+
+	if got, want := TTSDataURL("http://192.168.1.10:8080/", "abc"), "http://192.168.1.10:8080/api/tts/data/abc"; got != want {
+		t.Fatal("Bad data URL [got]", got, "[want]", want)
+	}
+}
@@ -0,0 +1,143 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/HardDie/fsentry"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+)
+
+func initArchives(t testing.TB) (transfer.Store, Archive, transfer.Store, Archive) {
+	dir, err := os.MkdirTemp("", "archive_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		e := os.RemoveAll(dir)
+		if e != nil {
+			t.Fatal("error RemoveAll", e)
+		}
+	})
+
+	cfg := config.Get(false, "test")
+	cfg.SetDataPath(dir)
+
+	fs := fsentry.NewFSEntry(cfg.Data)
+	err = dbCore.New(fs, cfg.Data).Init()
+	if err != nil {
+		t.Fatal("error init core", err)
+	}
+	images := dbImage.New(fs, cfg.Data)
+	files := transfer.Files(fs, images)
+
+	db, err := dbSQLite.Open(cfg.SQLitePath())
+	if err != nil {
+		t.Fatal("error open database", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	err = dbCore.NewSQLite(db, fs, cfg.Data).Init()
+	if err != nil {
+		t.Fatal("error init database", err)
+	}
+	sqlite := transfer.SQLite(db)
+
+	return files, New(cfg, fs, files, images), sqlite, NewSQLite(cfg, sqlite)
+}
+
+var testImage = []byte("image")
+
+func fillGame(t testing.TB, store transfer.Store, name string) string {
+	ctx := context.Background()
+	game, err := store.Game.Create(ctx, dbGame.CreateRequest{Name: name})
+	if err != nil {
+		t.Fatal("error create game", err)
+	}
+	collection, err := store.Collection.Create(ctx, dbCollection.CreateRequest{GameID: game.ID, Name: "Collection"})
+	if err != nil {
+		t.Fatal("error create collection", err)
+	}
+	deck, err := store.Deck.Create(ctx, dbDeck.CreateRequest{GameID: game.ID, CollectionID: collection.ID, Name: "Deck"})
+	if err != nil {
+		t.Fatal("error create deck", err)
+	}
+	// The same image is used by the deck and all the cards
+	err = store.Deck.ImageCreate(ctx, game.ID, collection.ID, deck.ID, testImage)
+	if err != nil {
+		t.Fatal("error create deck image", err)
+	}
+	for _, cardName := range []string{"first", "second"} {
+		card, err := store.Card.Create(ctx, dbCard.CreateRequest{GameID: game.ID, CollectionID: collection.ID, DeckID: deck.ID, Name: cardName, Count: 1})
+		if err != nil {
+			t.Fatal("error create card", err)
+		}
+		err = store.Card.ImageCreate(ctx, game.ID, collection.ID, deck.ID, card.ID, testImage)
+		if err != nil {
+			t.Fatal("error create card image", err)
+		}
+	}
+	return game.ID
+}
+
+func TestArchive(t *testing.T) {
+	ctx := context.Background()
+	files, filesArchive, sqlite, sqliteArchive := initArchives(t)
+
+	cases := []struct {
+		name    string
+		store   transfer.Store
+		archive Archive
+	}{
+		{name: "files", store: files, archive: filesArchive},
+		{name: "sqlite", store: sqlite, archive: sqliteArchive},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gameID := fillGame(t, c.store, "Game "+c.name)
+
+			buf := bytes.Buffer{}
+			err := c.archive.Export(ctx, &buf, gameID)
+			assert.NoError(t, err)
+
+			data := buf.Bytes()
+			resultGameID, err := c.archive.Import(ctx, bytes.NewReader(data), int64(len(data)), "copy_"+c.name)
+			assert.NoError(t, err)
+			assert.Equal(t, "copy_"+c.name, resultGameID)
+
+			counts, err := countGame(ctx, c.store, resultGameID)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, counts.Collections)
+			assert.Equal(t, 1, counts.Decks)
+			assert.Equal(t, 2, counts.Cards)
+
+			// The images are restored from the archive
+			image, err := c.store.Deck.ImageGet(ctx, resultGameID, "collection", "deck")
+			assert.NoError(t, err)
+			assert.Equal(t, testImage, image)
+			image, err = c.store.Card.ImageGet(ctx, resultGameID, "collection", "deck", 2)
+			assert.NoError(t, err)
+			assert.Equal(t, testImage, image)
+
+			// The original game and its copy are independent
+			err = c.store.Game.Delete(ctx, gameID)
+			assert.NoError(t, err)
+			image, err = c.store.Card.ImageGet(ctx, resultGameID, "collection", "deck", 1)
+			assert.NoError(t, err)
+			assert.Equal(t, testImage, image)
+		})
+	}
+}
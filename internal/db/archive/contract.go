@@ -0,0 +1,14 @@
+package archive
+
+import (
+	"context"
+	"io"
+)
+
+type Archive interface {
+	// Export packs the game into a zip archive and writes it into w
+	Export(ctx context.Context, w io.Writer, gameID string) error
+	// Import unpacks the game from the zip archive of the passed size. If the game ID is empty, the ID from the archive is used.
+	// Returns the ID of the created game.
+	Import(ctx context.Context, r io.ReaderAt, size int64, gameID string) (string, error)
+}
@@ -0,0 +1,72 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+
+	"github.com/HardDie/fsentry"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+)
+
+// The games are stored as folders, so the folder is packed as is
+type archive struct {
+	cfg    *config.Config
+	db     fsentry.IFSEntry
+	store  transfer.Store
+	images dbImage.Image
+}
+
+func New(cfg *config.Config, db fsentry.IFSEntry, store transfer.Store, images dbImage.Image) Archive {
+	return &archive{
+		cfg:    cfg,
+		db:     db,
+		store:  store,
+		images: images,
+	}
+}
+
+func (d *archive) Export(ctx context.Context, w io.Writer, gameID string) error {
+	counts, err := countGame(ctx, d.store, gameID)
+	if err != nil {
+		return err
+	}
+	manifest := fs.Manifest{
+		AppVersion: d.cfg.Version,
+		GameID:     gameID,
+		Counts:     counts,
+	}
+	images, err := gameImages(ctx, d.cfg, d.images, gameID)
+	if err != nil {
+		return err
+	}
+	return fs.ArchiveFolder(w, filepath.Join(d.cfg.Games(), gameID), images, manifest, d.cfg.MaxExportSize)
+}
+func (d *archive) Import(ctx context.Context, r io.ReaderAt, size int64, gameID string) (string, error) {
+	resultGameID, manifest, err := fs.UnarchiveFolder(r, size, gameID, d.cfg)
+	if err != nil {
+		return "", err
+	}
+	// The archive could be exported by an old version
+	err = dbCore.UpgradeGame(d.db, d.cfg.Data, resultGameID)
+	if err == nil {
+		_, err = fixGameInfo(ctx, d.store, resultGameID)
+	}
+	if err == nil {
+		err = checkCounts(ctx, d.store, resultGameID, manifest)
+	}
+	if err == nil {
+		err = importImages(ctx, d.cfg, d.images, resultGameID)
+	}
+	if err != nil {
+		er.IfErrorLog(fs.RemoveFolder(filepath.Join(d.cfg.Games(), resultGameID)))
+		return "", err
+	}
+	return resultGameID, nil
+}
@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+// The images of the game are shared with other games, so the archive gets its own copy of each image.
+// Returns the paths to the image files, the key is the hash of the image.
+func gameImages(ctx context.Context, cfg *config.Config, images dbImage.Image, gameID string) (map[string]string, error) {
+	hashes, err := images.List(ctx, cfg.Game, gameID)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]string, len(hashes))
+	for _, hash := range hashes {
+		res[hash] = filepath.Join(cfg.Images(), hash+".bin")
+	}
+	return res, nil
+}
+
+// Moving the images of the unpacked game into the shared store and adding the references of the game entities.
+// The images of the old archives are stored next to the entities, they are moved into the store too.
+func importImages(ctx context.Context, cfg *config.Config, images dbImage.Image, gameID string) error {
+	folder := filepath.Join(cfg.Games(), gameID, fs.ImagesFolder)
+	files, err := os.ReadDir(folder)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return er.InternalError.AddMessage(err.Error())
+	}
+
+	// Each image is held by the import until the entities refer to it, the images without references are removed
+	stored := make(map[string]struct{}, len(files))
+	defer func() {
+		for hash := range stored {
+			er.IfErrorLog(images.Release(ctx, hash))
+		}
+	}()
+	for _, file := range files {
+		hash := strings.TrimSuffix(file.Name(), ".bin")
+		data, err := os.ReadFile(filepath.Join(folder, file.Name()))
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		if utils.HashForData(data) != hash {
+			return er.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: the image %q is damaged", file.Name()))
+		}
+		_, err = images.Create(ctx, data)
+		if err != nil {
+			return err
+		}
+		stored[hash] = struct{}{}
+	}
+
+	// All referenced images must be in the archive
+	hashes, err := images.List(ctx, cfg.Game, gameID)
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if _, ok := stored[hash]; !ok {
+			return er.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: the image %q is missing", hash))
+		}
+	}
+	err = images.RetainFolder(ctx, cfg.Game, gameID)
+	if err != nil {
+		return err
+	}
+
+	err = images.ConvertFolder(ctx, cfg.Game, gameID)
+	if err != nil {
+		return err
+	}
+	return fs.RemoveFolder(folder)
+}
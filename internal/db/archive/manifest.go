@@ -0,0 +1,74 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+// The game is addressed by the folder name, so the info file of the unpacked game must match it.
+// Returns the name of the game.
+func fixGameInfo(ctx context.Context, store transfer.Store, gameID string) (string, error) {
+	game, err := store.Game.Get(ctx, gameID)
+	if err != nil {
+		return "", err
+	}
+	name := game.Name
+	if utils.NameToID(name) != gameID {
+		name = gameID
+		err = store.Game.UpdateInfo(ctx, gameID, name)
+		if err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// Counting the nested entities of the game for the manifest
+func countGame(ctx context.Context, store transfer.Store, gameID string) (fs.ManifestCounts, error) {
+	var counts fs.ManifestCounts
+	collections, err := store.Collection.List(ctx, gameID)
+	if err != nil {
+		return counts, err
+	}
+	counts.Collections = len(collections)
+	for _, collection := range collections {
+		decks, err := store.Deck.List(ctx, gameID, collection.ID)
+		if err != nil {
+			return counts, err
+		}
+		counts.Decks += len(decks)
+		for _, deck := range decks {
+			cards, err := store.Card.List(ctx, gameID, collection.ID, deck.ID)
+			if err != nil {
+				return counts, err
+			}
+			counts.Cards += len(cards)
+		}
+	}
+	return counts, nil
+}
+
+// Checking that the unpacked game contains as many entities as declared in the manifest.
+// Archives without a manifest are not checked.
+func checkCounts(ctx context.Context, store transfer.Store, gameID string, manifest *fs.Manifest) error {
+	counts, err := countGame(ctx, store, gameID)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		return nil
+	}
+	if counts != manifest.Counts {
+		return er.BadArchive.AddMessage(fmt.Sprintf(
+			"Bad zip archive: the manifest declares %d collections, %d decks and %d cards, but the archive contains %d collections, %d decks and %d cards",
+			manifest.Counts.Collections, manifest.Counts.Decks, manifest.Counts.Cards,
+			counts.Collections, counts.Decks, counts.Cards,
+		))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/HardDie/fsentry"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+)
+
+// The archive format is the same for all storages, so the game is unpacked into
+// a temporary file storage and copied from there
+type sqliteArchive struct {
+	cfg   *config.Config
+	store transfer.Store
+}
+
+func NewSQLite(cfg *config.Config, store transfer.Store) Archive {
+	return &sqliteArchive{
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+func (d *sqliteArchive) Export(ctx context.Context, w io.Writer, gameID string) error {
+	tmpCfg, _, files, images, err := d.tempFiles()
+	if err != nil {
+		return err
+	}
+	defer func() { er.IfErrorLog(fs.RemoveFolder(tmpCfg.Data)) }()
+
+	game, err := d.store.Game.Get(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	counts, err := countGame(ctx, d.store, game.ID)
+	if err != nil {
+		return err
+	}
+	game, err = transfer.CopyGame(ctx, d.store, files, game.ID, game.Name)
+	if err != nil {
+		return err
+	}
+	manifest := fs.Manifest{
+		AppVersion: d.cfg.Version,
+		GameID:     game.ID,
+		Counts:     counts,
+	}
+	imageFiles, err := gameImages(ctx, tmpCfg, images, game.ID)
+	if err != nil {
+		return err
+	}
+	return fs.ArchiveFolder(w, filepath.Join(tmpCfg.Games(), game.ID), imageFiles, manifest, d.cfg.MaxExportSize)
+}
+func (d *sqliteArchive) Import(ctx context.Context, r io.ReaderAt, size int64, gameID string) (string, error) {
+	tmpCfg, db, files, images, err := d.tempFiles()
+	if err != nil {
+		return "", err
+	}
+	defer func() { er.IfErrorLog(fs.RemoveFolder(tmpCfg.Data)) }()
+
+	resultGameID, manifest, err := fs.UnarchiveFolder(r, size, gameID, tmpCfg)
+	if err != nil {
+		return "", err
+	}
+	// The archive could be exported by an old version
+	err = dbCore.UpgradeGame(db, tmpCfg.Data, resultGameID)
+	if err != nil {
+		return "", err
+	}
+	name, err := fixGameInfo(ctx, files, resultGameID)
+	if err != nil {
+		return "", err
+	}
+	err = checkCounts(ctx, files, resultGameID, manifest)
+	if err != nil {
+		return "", err
+	}
+	err = importImages(ctx, tmpCfg, images, resultGameID)
+	if err != nil {
+		return "", err
+	}
+	game, err := transfer.CopyGame(ctx, files, d.store, resultGameID, name)
+	if err != nil {
+		return "", err
+	}
+	return game.ID, nil
+}
+
+func (d *sqliteArchive) tempFiles() (*config.Config, fsentry.IFSEntry, transfer.Store, dbImage.Image, error) {
+	dir, err := os.MkdirTemp("", "deck_builder_archive")
+	if err != nil {
+		return nil, nil, transfer.Store{}, nil, er.InternalError.AddMessage(err.Error())
+	}
+	tmpCfg := *d.cfg
+	tmpCfg.SetDataPath(dir)
+
+	files := fsentry.NewFSEntry(dir)
+	err = dbCore.New(files, dir).Init()
+	if err != nil {
+		er.IfErrorLog(fs.RemoveFolder(dir))
+		return nil, nil, transfer.Store{}, nil, err
+	}
+	images := dbImage.New(files, dir)
+	return &tmpCfg, files, transfer.Files(files, images), images, nil
+}
@@ -0,0 +1,173 @@
+package card
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HardDie/fsentry"
+	"github.com/HardDie/fsentry/pkg/fsentry_types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+const (
+	gameID       = "game"
+	collectionID = "collection"
+	deckID       = "deck"
+)
+
+func initCard(t testing.TB, name string) (string, fsentry.IFSEntry, Card) {
+	// Create temp dir
+	dir, err := os.MkdirTemp("", name)
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		e := os.RemoveAll(dir)
+		if e != nil {
+			t.Fatal("error RemoveAll", e)
+		}
+	})
+
+	// Init config with tmp dir
+	cfg := config.Get(false, "")
+	cfg.SetDataPath(dir)
+
+	// Init fsentry object
+	fs := fsentry.NewFSEntry(cfg.Data, fsentry.WithPretty())
+
+	// Init core directory
+	core := dbCore.New(fs, cfg.Data)
+	err = core.Init()
+	if err != nil {
+		t.Fatal("error init core", err)
+	}
+
+	// Init parent entities
+	ctx := context.Background()
+	images := dbImage.New(fs, cfg.Data)
+	game := dbGame.New(fs, images)
+	collection := dbCollection.New(fs, images, game)
+	deck := dbDeck.New(fs, images, collection)
+	_, err = game.Create(ctx, dbGame.CreateRequest{Name: gameID})
+	if err != nil {
+		t.Fatal("error create game", err)
+	}
+	_, err = collection.Create(ctx, dbCollection.CreateRequest{GameID: gameID, Name: collectionID})
+	if err != nil {
+		t.Fatal("error create collection", err)
+	}
+	_, err = deck.Create(ctx, dbDeck.CreateRequest{GameID: gameID, CollectionID: collectionID, Name: deckID})
+	if err != nil {
+		t.Fatal("error create deck", err)
+	}
+
+	return dir, fs, New(fs, images, deck)
+}
+
+func TestCardStorage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("separate_files", func(t *testing.T) {
+		_, fs, c := initCard(t, "card_storage__separate_files")
+		first, err := c.Create(ctx, CreateRequest{GameID: gameID, CollectionID: collectionID, DeckID: deckID, Name: "first"})
+		assert.NoError(t, err)
+		second, err := c.Create(ctx, CreateRequest{GameID: gameID, CollectionID: collectionID, DeckID: deckID, Name: "second"})
+		assert.NoError(t, err)
+
+		list, err := fs.List("games", gameID, collectionID, deckID, "cards")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"1", "2"}, list.Entries)
+
+		// Removing one card doesn't touch the other one
+		err = c.Delete(ctx, gameID, collectionID, deckID, first.ID)
+		assert.NoError(t, err)
+		_, err = c.Get(ctx, gameID, collectionID, deckID, first.ID)
+		assert.ErrorIs(t, err, er.CardNotExists)
+		got, err := c.Get(ctx, gameID, collectionID, deckID, second.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "second", got.Name)
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		dir, fs, c := initCard(t, "card_storage__upgrade")
+
+		// Old layout, all cards in the info file of the cards folder, e.g. the game imported from an old archive
+		createdAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		legacy := map[int64]*model{
+			1: {ID: 1, Name: fsentry_types.QS("first"), Variables: convertMapString(map[string]string{"attack": "1"}), Count: 1, CreatedAt: utils.Allocate(createdAt)},
+			3: {ID: 3, Name: fsentry_types.QS("third"), Count: 2},
+		}
+		_, err := fs.UpdateFolder("cards", legacy, "games", gameID, collectionID, deckID)
+		assert.NoError(t, err)
+		err = dbCore.UpgradeGame(fs, dir, gameID)
+		assert.NoError(t, err)
+
+		cards, err := c.List(ctx, gameID, collectionID, deckID)
+		assert.NoError(t, err)
+		assert.Len(t, cards, 2)
+
+		got, err := c.Get(ctx, gameID, collectionID, deckID, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "first", got.Name)
+		assert.Equal(t, map[string]string{"attack": "1"}, got.Variables)
+		assert.True(t, createdAt.Equal(got.CreatedAt))
+
+		// The old list has been removed
+		info, err := fs.GetFolder("cards", "games", gameID, collectionID, deckID)
+		assert.NoError(t, err)
+		assert.Equal(t, "null", string(info.Data))
+
+		// The missing time is filled in by the upgrade
+		got, err = c.Get(ctx, gameID, collectionID, deckID, 3)
+		assert.NoError(t, err)
+		assert.False(t, got.CreatedAt.IsZero())
+
+		// Identifiers continue after the migrated cards
+		created, err := c.Create(ctx, CreateRequest{GameID: gameID, CollectionID: collectionID, DeckID: deckID, Name: "fourth"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), created.ID)
+	})
+
+	t.Run("concurrent_create", func(t *testing.T) {
+		_, _, c := initCard(t, "card_storage__concurrent_create")
+
+		const count = 20
+		var wg sync.WaitGroup
+		ids := make(chan int64, count)
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				card, err := c.Create(ctx, CreateRequest{GameID: gameID, CollectionID: collectionID, DeckID: deckID, Name: strconv.Itoa(i)})
+				if assert.NoError(t, err) {
+					ids <- card.ID
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(ids)
+
+		// Each card got its own identifier and nothing has been lost
+		unique := make(map[int64]struct{})
+		for id := range ids {
+			unique[id] = struct{}{}
+		}
+		assert.Len(t, unique, count)
+		cards, err := c.List(ctx, gameID, collectionID, deckID)
+		assert.NoError(t, err)
+		assert.Len(t, cards, count)
+	})
+}
@@ -2,6 +2,7 @@ package card
 
 import (
 	"context"
+	"time"
 
 	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
 )
@@ -26,6 +27,11 @@ type CreateRequest struct {
 	Image        string
 	Variables    map[string]string
 	Count        int
+	// Optional. Allows you to keep the original identifier when the game is copied to another storage
+	ID int64
+	// Optional. Allows you to keep the original timestamps when the card is moved or copied
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
 }
 
 type UpdateRequest struct {
@@ -4,9 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/HardDie/fsentry"
@@ -14,6 +13,7 @@ import (
 	"github.com/HardDie/fsentry/pkg/fsentry_types"
 
 	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
 	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
 	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/utils"
@@ -22,32 +22,48 @@ import (
 type card struct {
 	db        fsentry.IFSEntry
 	gamesPath string
+	// The card identifiers are allocated by reading the list of the deck, so all operations are serialized for each deck
+	locks *utils.KeyMutex
 
-	deck dbDeck.Deck
+	images dbImage.Image
+	deck   dbDeck.Deck
 }
 
-func New(db fsentry.IFSEntry, deck dbDeck.Deck) Card {
+func New(db fsentry.IFSEntry, images dbImage.Image, deck dbDeck.Deck) Card {
 	return &card{
 		db:        db,
 		gamesPath: "games",
+		locks:     utils.NewKeyMutex(),
 
-		deck: deck,
+		images: images,
+		deck:   deck,
 	}
 }
 
 func (d *card) Create(ctx context.Context, req CreateRequest) (*entitiesCard.Card, error) {
-	ctx, list, err := d.rawCardList(ctx, req.GameID, req.CollectionID, req.DeckID)
+	defer d.lock(req.GameID, req.CollectionID, req.DeckID)()
+
+	ctx, ids, err := d.cardIDs(ctx, req.GameID, req.CollectionID, req.DeckID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Search for the largest card ID
 	maxID := int64(1)
-	for _, card := range list {
-		if card.ID >= maxID {
-			maxID = card.ID + 1
+	for _, id := range ids {
+		if id >= maxID {
+			maxID = id + 1
 		}
 	}
+	if req.ID != 0 {
+		maxID = req.ID
+	}
+
+	// Keep the original creation time if it was passed
+	cardCreatedAt := req.CreatedAt
+	if cardCreatedAt == nil {
+		cardCreatedAt = utils.Allocate(time.Now())
+	}
 
 	// Create a card with the found identifier
 	cardInfo := &model{
@@ -57,18 +73,15 @@ func (d *card) Create(ctx context.Context, req CreateRequest) (*entitiesCard.Car
 		Image:       fsentry_types.QS(req.Image),
 		Variables:   convertMapString(req.Variables),
 		Count:       req.Count,
-		CreatedAt:   utils.Allocate(time.Now()),
-		UpdatedAt:   nil,
+		CreatedAt:   cardCreatedAt,
+		UpdatedAt:   req.UpdatedAt,
 	}
 
-	// Add a card to the card array
-	list[cardInfo.ID] = cardInfo
-
-	// Writing an array of cards to a file again
-	_, err = d.db.UpdateFolder("cards", list, d.gamesPath, req.GameID, req.CollectionID, req.DeckID)
+	// Each card is stored in a separate file
+	err = d.db.CreateEntry(d.cardName(cardInfo.ID), cardInfo, d.cardsPath(req.GameID, req.CollectionID, req.DeckID)...)
 	if err != nil {
-		if errors.Is(err, fsentry_error.ErrorNotExist) {
-			return nil, er.CardNotExists.AddMessage(err.Error())
+		if errors.Is(err, fsentry_error.ErrorExist) {
+			return nil, er.CardExists.AddMessage(err.Error())
 		} else if errors.Is(err, fsentry_error.ErrorBadName) {
 			return nil, er.BadName
 		} else {
@@ -76,50 +89,16 @@ func (d *card) Create(ctx context.Context, req CreateRequest) (*entitiesCard.Car
 		}
 	}
 
-	createdAt, updatedAt := d.convertCreateUpdate(cardInfo.CreatedAt, cardInfo.UpdatedAt)
-	return &entitiesCard.Card{
-		ID:          cardInfo.ID,
-		Name:        cardInfo.Name.String(),
-		Description: cardInfo.Description.String(),
-		Image:       cardInfo.Image.String(),
-		Variables:   convertMapQuotedString(cardInfo.Variables),
-		Count:       cardInfo.Count,
-		CreatedAt:   createdAt,
-		UpdatedAt:   updatedAt,
-
-		GameID:       req.GameID,
-		CollectionID: req.CollectionID,
-		DeckID:       req.DeckID,
-	}, nil
+	return d.modelToCard(cardInfo, req.GameID, req.CollectionID, req.DeckID), nil
 }
 func (d *card) Get(ctx context.Context, gameID, collectionID, deckID string, cardID int64) (*entitiesCard.Card, error) {
-	ctx, list, err := d.rawCardList(ctx, gameID, collectionID, deckID)
-	if err != nil {
-		return nil, err
-	}
+	defer d.lock(gameID, collectionID, deckID)()
 
-	card, ok := list[cardID]
-	if !ok {
-		return nil, er.CardNotExists.HTTP(http.StatusBadRequest)
-	}
-
-	createdAt, updatedAt := d.convertCreateUpdate(card.CreatedAt, card.UpdatedAt)
-	return &entitiesCard.Card{
-		ID:          card.ID,
-		Name:        card.Name.String(),
-		Description: card.Description.String(),
-		Image:       card.Image.String(),
-		Variables:   convertMapQuotedString(card.Variables),
-		Count:       card.Count,
-		CreatedAt:   createdAt,
-		UpdatedAt:   updatedAt,
-
-		GameID:       gameID,
-		CollectionID: collectionID,
-		DeckID:       deckID,
-	}, nil
+	return d.get(ctx, gameID, collectionID, deckID, cardID)
 }
 func (d *card) List(ctx context.Context, gameID, collectionID, deckID string) ([]*entitiesCard.Card, error) {
+	defer d.lock(gameID, collectionID, deckID)()
+
 	ctx, list, err := d.rawCardList(ctx, gameID, collectionID, deckID)
 	if err != nil {
 		return nil, err
@@ -127,33 +106,21 @@ func (d *card) List(ctx context.Context, gameID, collectionID, deckID string) ([
 
 	var cards []*entitiesCard.Card
 	for _, item := range list {
-		createdAt, updatedAt := d.convertCreateUpdate(item.CreatedAt, item.UpdatedAt)
-		cards = append(cards, &entitiesCard.Card{
-			ID:          item.ID,
-			Name:        item.Name.String(),
-			Description: item.Description.String(),
-			Image:       item.Image.String(),
-			Variables:   convertMapQuotedString(item.Variables),
-			Count:       item.Count,
-			CreatedAt:   createdAt,
-			UpdatedAt:   updatedAt,
-
-			GameID:       gameID,
-			CollectionID: collectionID,
-			DeckID:       deckID,
-		})
+		cards = append(cards, d.modelToCard(item, gameID, collectionID, deckID))
 	}
 	return cards, nil
 }
 func (d *card) Update(ctx context.Context, req UpdateRequest) (*entitiesCard.Card, error) {
-	ctx, list, err := d.rawCardList(ctx, req.GameID, req.CollectionID, req.DeckID)
+	defer d.lock(req.GameID, req.CollectionID, req.DeckID)()
+
+	ctx, err := d.prepare(ctx, req.GameID, req.CollectionID, req.DeckID)
 	if err != nil {
 		return nil, err
 	}
 
-	card, ok := list[req.CardID]
-	if !ok {
-		return nil, er.CardNotExists
+	card, err := d.rawCard(req.GameID, req.CollectionID, req.DeckID, req.CardID)
+	if err != nil {
+		return nil, err
 	}
 
 	card.Name = fsentry_types.QS(req.Name)
@@ -163,10 +130,8 @@ func (d *card) Update(ctx context.Context, req UpdateRequest) (*entitiesCard.Car
 	card.Count = req.Count
 	card.UpdatedAt = utils.Allocate(time.Now())
 
-	list[card.ID] = card
-
-	// Writing an array of cards to a file again
-	_, err = d.db.UpdateFolder("cards", list, d.gamesPath, req.GameID, req.CollectionID, req.DeckID)
+	// Only the file of the selected card is rewritten
+	err = d.db.UpdateEntry(d.cardName(card.ID), card, d.cardsPath(req.GameID, req.CollectionID, req.DeckID)...)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
 			return nil, er.CardNotExists.AddMessage(err.Error())
@@ -177,36 +142,22 @@ func (d *card) Update(ctx context.Context, req UpdateRequest) (*entitiesCard.Car
 		}
 	}
 
-	createdAt, updatedAt := d.convertCreateUpdate(card.CreatedAt, card.UpdatedAt)
-	return &entitiesCard.Card{
-		ID:          card.ID,
-		Name:        card.Name.String(),
-		Description: card.Description.String(),
-		Image:       card.Image.String(),
-		Variables:   convertMapQuotedString(card.Variables),
-		Count:       card.Count,
-		CreatedAt:   createdAt,
-		UpdatedAt:   updatedAt,
-
-		GameID:       req.GameID,
-		CollectionID: req.CollectionID,
-		DeckID:       req.DeckID,
-	}, nil
+	return d.modelToCard(card, req.GameID, req.CollectionID, req.DeckID), nil
 }
 func (d *card) Delete(ctx context.Context, gameID, collectionID, deckID string, cardID int64) error {
-	ctx, list, err := d.rawCardList(ctx, gameID, collectionID, deckID)
+	defer d.lock(gameID, collectionID, deckID)()
+
+	ctx, err := d.prepare(ctx, gameID, collectionID, deckID)
 	if err != nil {
 		return err
 	}
 
-	if _, ok := list[cardID]; !ok {
-		return er.CardNotExists
+	card, err := d.rawCard(gameID, collectionID, deckID, cardID)
+	if err != nil {
+		return err
 	}
 
-	delete(list, cardID)
-
-	// Writing an array of cards to a file again
-	_, err = d.db.UpdateFolder("cards", list, d.gamesPath, gameID, collectionID, deckID)
+	err = d.db.RemoveEntry(d.cardName(cardID), d.cardsPath(gameID, collectionID, deckID)...)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
 			return er.CardNotExists.AddMessage(err.Error())
@@ -217,80 +168,182 @@ func (d *card) Delete(ctx context.Context, gameID, collectionID, deckID string,
 		}
 	}
 
+	if card.ImageHash != "" {
+		return d.images.Release(ctx, card.ImageHash)
+	}
 	return nil
 }
 func (d *card) ImageCreate(ctx context.Context, gameID, collectionID, deckID string, cardID int64, data []byte) error {
-	card, err := d.Get(ctx, gameID, collectionID, deckID, cardID)
+	defer d.lock(gameID, collectionID, deckID)()
+
+	card, err := d.rawImageCard(ctx, gameID, collectionID, deckID, cardID)
 	if err != nil {
 		return err
 	}
+	if card.ImageHash != "" {
+		return er.CardImageExist
+	}
 
-	err = d.db.CreateBinary(fmt.Sprintf("%d", card.ID), data, d.gamesPath, gameID, collectionID, deckID, "cards")
+	card.ImageHash, err = d.images.Create(ctx, data)
 	if err != nil {
-		if errors.Is(err, fsentry_error.ErrorExist) {
-			return er.CardImageExist.AddMessage(err.Error())
-		} else {
-			return er.InternalError.AddMessage(err.Error())
-		}
+		return err
+	}
+	err = d.db.UpdateEntry(d.cardName(card.ID), card, d.cardsPath(gameID, collectionID, deckID)...)
+	if err != nil {
+		er.IfErrorLog(d.images.Release(ctx, card.ImageHash))
+		return er.InternalError.AddMessage(err.Error())
 	}
 	return nil
 }
 func (d *card) ImageGet(ctx context.Context, gameID, collectionID, deckID string, cardID int64) ([]byte, error) {
-	card, err := d.Get(ctx, gameID, collectionID, deckID, cardID)
+	defer d.lock(gameID, collectionID, deckID)()
+
+	card, err := d.rawImageCard(ctx, gameID, collectionID, deckID, cardID)
 	if err != nil {
 		return nil, err
 	}
-
-	data, err := d.db.GetBinary(fmt.Sprintf("%d", card.ID), d.gamesPath, gameID, collectionID, deckID, "cards")
-	if err != nil {
-		if errors.Is(err, fsentry_error.ErrorNotExist) {
-			return nil, er.CardImageNotExists.AddMessage(err.Error())
-		} else {
-			return nil, er.InternalError.AddMessage(err.Error())
-		}
+	if card.ImageHash == "" {
+		return nil, er.CardImageNotExists
 	}
-	return data, nil
+	return d.images.Get(ctx, card.ImageHash)
 }
 func (d *card) ImageDelete(ctx context.Context, gameID, collectionID, deckID string, cardID int64) error {
-	card, err := d.Get(ctx, gameID, collectionID, deckID, cardID)
+	defer d.lock(gameID, collectionID, deckID)()
+
+	card, err := d.rawImageCard(ctx, gameID, collectionID, deckID, cardID)
 	if err != nil {
 		return err
 	}
+	hash := card.ImageHash
+	if hash == "" {
+		return er.CardImageNotExists
+	}
 
-	err = d.db.RemoveBinary(fmt.Sprintf("%d", card.ID), d.gamesPath, gameID, collectionID, deckID, "cards")
+	card.ImageHash = ""
+	err = d.db.UpdateEntry(d.cardName(card.ID), card, d.cardsPath(gameID, collectionID, deckID)...)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return d.images.Release(ctx, hash)
+}
+
+// lock locks the deck and returns the function that unlocks it
+func (d *card) lock(gameID, collectionID, deckID string) func() {
+	return d.locks.Lock(strings.Join([]string{utils.NameToID(gameID), utils.NameToID(collectionID), utils.NameToID(deckID)}, "/"))
+}
+func (d *card) get(ctx context.Context, gameID, collectionID, deckID string, cardID int64) (*entitiesCard.Card, error) {
+	ctx, err := d.prepare(ctx, gameID, collectionID, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	card, err := d.rawCard(gameID, collectionID, deckID, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.modelToCard(card, gameID, collectionID, deckID), nil
+}
+
+// prepare makes sure the deck exists
+func (d *card) prepare(ctx context.Context, gameID, collectionID, deckID string) (context.Context, error) {
+	_, err := d.deck.Get(ctx, gameID, collectionID, deckID)
+	if err != nil {
+		return ctx, err
+	}
+	return ctx, nil
+}
+func (d *card) rawCard(gameID, collectionID, deckID string, cardID int64) (*model, error) {
+	info, err := d.db.GetEntry(d.cardName(cardID), d.cardsPath(gameID, collectionID, deckID)...)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
-			return er.CardImageNotExists.AddMessage(err.Error())
+			return nil, er.CardNotExists.AddMessage(err.Error())
+		} else if errors.Is(err, fsentry_error.ErrorBadName) {
+			return nil, er.BadName
 		} else {
-			return er.InternalError.AddMessage(err.Error())
+			return nil, er.InternalError.AddMessage(err.Error())
 		}
 	}
-	return nil
+
+	var card model
+	err = json.Unmarshal(info.Data, &card)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return &card, nil
 }
 
+// rawImageCard returns the card to change its image, the deck must be locked
+func (d *card) rawImageCard(ctx context.Context, gameID, collectionID, deckID string, cardID int64) (*model, error) {
+	_, err := d.prepare(ctx, gameID, collectionID, deckID)
+	if err != nil {
+		return nil, err
+	}
+	return d.rawCard(gameID, collectionID, deckID, cardID)
+}
 func (d *card) rawCardList(ctx context.Context, gameID, collectionID, deckID string) (context.Context, map[int64]*model, error) {
-	deck, err := d.deck.Get(ctx, gameID, collectionID, deckID)
+	ctx, ids, err := d.cardIDs(ctx, gameID, collectionID, deckID)
 	if err != nil {
 		return ctx, nil, err
 	}
 
-	// Get all the cards
-	info, err := d.db.GetFolder("cards", d.gamesPath, gameID, collectionID, deck.ID)
+	list := make(map[int64]*model, len(ids))
+	for _, cardID := range ids {
+		card, err := d.rawCard(gameID, collectionID, deckID, cardID)
+		if err != nil {
+			return ctx, nil, err
+		}
+		list[card.ID] = card
+	}
+	return ctx, list, nil
+}
+
+// cardIDs returns the identifiers of the cards of the deck, the files of the cards are named by them
+func (d *card) cardIDs(ctx context.Context, gameID, collectionID, deckID string) (context.Context, []int64, error) {
+	ctx, err := d.prepare(ctx, gameID, collectionID, deckID)
 	if err != nil {
-		return ctx, nil, er.InternalError.AddMessage(err.Error())
+		return ctx, nil, err
 	}
 
-	// Parsing an array of cards from json
-	var list map[int64]*model
-	err = json.Unmarshal(info.Data, &list)
+	entries, err := d.db.List(d.cardsPath(gameID, collectionID, deckID)...)
 	if err != nil {
 		return ctx, nil, er.InternalError.AddMessage(err.Error())
 	}
 
-	if list == nil {
-		list = make(map[int64]*model)
+	ids := make([]int64, 0, len(entries.Entries))
+	for _, entry := range entries.Entries {
+		cardID, err := strconv.ParseInt(entry, 10, 64)
+		if err != nil {
+			// Skip files that don't belong to cards
+			continue
+		}
+		ids = append(ids, cardID)
+	}
+	return ctx, ids, nil
+}
+func (d *card) cardsPath(gameID, collectionID, deckID string) []string {
+	return []string{d.gamesPath, gameID, collectionID, deckID, "cards"}
+}
+func (d *card) cardName(cardID int64) string {
+	return strconv.FormatInt(cardID, 10)
+}
+func (d *card) modelToCard(card *model, gameID, collectionID, deckID string) *entitiesCard.Card {
+	createdAt, updatedAt := d.convertCreateUpdate(card.CreatedAt, card.UpdatedAt)
+	return &entitiesCard.Card{
+		ID:          card.ID,
+		Name:        card.Name.String(),
+		Description: card.Description.String(),
+		Image:       card.Image.String(),
+		ImageHash:   card.ImageHash,
+		Variables:   convertMapQuotedString(card.Variables),
+		Count:       card.Count,
+		CreatedAt:   createdAt,
+		UpdatedAt:   updatedAt,
+
+		GameID:       gameID,
+		CollectionID: collectionID,
+		DeckID:       deckID,
 	}
-	return ctx, list, nil
 }
 func convertMapString(in map[string]string) map[string]fsentry_types.QuotedString {
 	res := make(map[string]fsentry_types.QuotedString)
@@ -311,12 +364,14 @@ func convertMapQuotedString(in map[string]fsentry_types.QuotedString) map[string
 	return res
 }
 
+// The time of the cards is filled in by the migrations and by the import, so it is always set
 func (d *card) convertCreateUpdate(createdAt, updatedAt *time.Time) (time.Time, time.Time) {
-	if createdAt == nil {
-		createdAt = utils.Allocate(time.Now())
+	var created time.Time
+	if createdAt != nil {
+		created = *createdAt
 	}
 	if updatedAt == nil {
-		updatedAt = createdAt
+		return created, created
 	}
-	return *createdAt, *updatedAt
+	return created, *updatedAt
 }
@@ -15,4 +15,6 @@ type model struct {
 	Count       int                                   `json:"count"`
 	CreatedAt   *time.Time                            `json:"createdAt"`
 	UpdatedAt   *time.Time                            `json:"updatedAt"`
+	// The hash of the uploaded image in the shared image store
+	ImageHash string `json:"imageHash,omitempty"`
 }
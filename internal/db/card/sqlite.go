@@ -0,0 +1,251 @@
+package card
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+const sqliteColumns = "card_id, name, description, image, variables, count, image_hash, created_at, updated_at"
+
+type sqliteCard struct {
+	db *sql.DB
+}
+
+func NewSQLite(db *sql.DB) Card {
+	return &sqliteCard{
+		db: db,
+	}
+}
+
+func (d *sqliteCard) Create(ctx context.Context, req CreateRequest) (*entitiesCard.Card, error) {
+	variables, err := json.Marshal(req.Variables)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	// Keep the original timestamps if they were passed
+	createdAt := time.Now()
+	if req.CreatedAt != nil {
+		createdAt = *req.CreatedAt
+	}
+	updatedAt := createdAt
+	if req.UpdatedAt != nil {
+		updatedAt = *req.UpdatedAt
+	}
+
+	var card *entitiesCard.Card
+	err = dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		deckRowID, err := dbSQLite.DeckRowID(ctx, tx, req.GameID, req.CollectionID, req.DeckID)
+		if err != nil {
+			return err
+		}
+
+		// Search for the largest card ID
+		cardID := req.ID
+		if cardID == 0 {
+			err = tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(card_id), 0) + 1 FROM cards WHERE deck_id = ?", deckRowID).Scan(&cardID)
+			if err != nil {
+				return er.InternalError.AddMessage(err.Error())
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO cards (deck_id, card_id, name, description, image, variables, count, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			deckRowID, cardID, req.Name, req.Description, req.Image, string(variables), req.Count, createdAt, updatedAt)
+		if err != nil {
+			if dbSQLite.IsUniqueViolation(err) {
+				return er.CardExists
+			}
+			return er.InternalError.AddMessage(err.Error())
+		}
+
+		card = &entitiesCard.Card{
+			ID:          cardID,
+			Name:        req.Name,
+			Description: req.Description,
+			Image:       req.Image,
+			Variables:   req.Variables,
+			Count:       req.Count,
+			CreatedAt:   createdAt,
+			UpdatedAt:   updatedAt,
+
+			GameID:       req.GameID,
+			CollectionID: req.CollectionID,
+			DeckID:       req.DeckID,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+func (d *sqliteCard) Get(ctx context.Context, gameID, collectionID, deckID string, cardID int64) (*entitiesCard.Card, error) {
+	return d.get(ctx, d.db, gameID, collectionID, deckID, cardID)
+}
+func (d *sqliteCard) List(ctx context.Context, gameID, collectionID, deckID string) ([]*entitiesCard.Card, error) {
+	deckRowID, err := dbSQLite.DeckRowID(ctx, d.db, gameID, collectionID, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, "SELECT "+sqliteColumns+" FROM cards WHERE deck_id = ? ORDER BY card_id", deckRowID)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	defer func() { er.IfErrorLog(rows.Close()) }()
+
+	var cards []*entitiesCard.Card
+	for rows.Next() {
+		card, err := d.scan(rows, gameID, collectionID, deckID)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return cards, nil
+}
+func (d *sqliteCard) Update(ctx context.Context, req UpdateRequest) (*entitiesCard.Card, error) {
+	variables, err := json.Marshal(req.Variables)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	var card *entitiesCard.Card
+	err = dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		deckRowID, err := dbSQLite.DeckRowID(ctx, tx, req.GameID, req.CollectionID, req.DeckID)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `UPDATE cards SET name = ?, description = ?, image = ?, variables = ?, count = ?, updated_at = ?
+			WHERE deck_id = ? AND card_id = ?`,
+			req.Name, req.Description, req.Image, string(variables), req.Count, time.Now(), deckRowID, req.CardID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		err = dbSQLite.CheckAffected(res, er.CardNotExists)
+		if err != nil {
+			return err
+		}
+		card, err = d.get(ctx, tx, req.GameID, req.CollectionID, req.DeckID, req.CardID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+func (d *sqliteCard) Delete(ctx context.Context, gameID, collectionID, deckID string, cardID int64) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		deckRowID, err := dbSQLite.DeckRowID(ctx, tx, gameID, collectionID, deckID)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, "DELETE FROM cards WHERE deck_id = ? AND card_id = ?", deckRowID, cardID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return dbSQLite.CheckAffected(res, er.CardNotExists)
+	})
+}
+func (d *sqliteCard) ImageCreate(ctx context.Context, gameID, collectionID, deckID string, cardID int64, data []byte) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, imageExists, err := d.rowID(ctx, tx, gameID, collectionID, deckID, cardID)
+		if err != nil {
+			return err
+		}
+		if imageExists {
+			return er.CardImageExist
+		}
+		hash, err := dbSQLite.PutImage(ctx, tx, data)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE cards SET image_hash = ? WHERE id = ?", hash, rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return nil
+	})
+}
+func (d *sqliteCard) ImageGet(ctx context.Context, gameID, collectionID, deckID string, cardID int64) ([]byte, error) {
+	rowID, imageExists, err := d.rowID(ctx, d.db, gameID, collectionID, deckID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if !imageExists {
+		return nil, er.CardImageNotExists
+	}
+	return dbSQLite.ImageData(ctx, d.db, "cards", rowID)
+}
+func (d *sqliteCard) ImageDelete(ctx context.Context, gameID, collectionID, deckID string, cardID int64) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, imageExists, err := d.rowID(ctx, tx, gameID, collectionID, deckID, cardID)
+		if err != nil {
+			return err
+		}
+		if !imageExists {
+			return er.CardImageNotExists
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE cards SET image_hash = NULL WHERE id = ?", rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return nil
+	})
+}
+
+// rowID looks up the card row and reports whether the card has an image
+func (d *sqliteCard) rowID(ctx context.Context, q dbSQLite.Querier, gameID, collectionID, deckID string, cardID int64) (int64, bool, error) {
+	deckRowID, err := dbSQLite.DeckRowID(ctx, q, gameID, collectionID, deckID)
+	if err != nil {
+		return 0, false, err
+	}
+	var rowID int64
+	var imageExists bool
+	err = q.QueryRowContext(ctx, "SELECT id, image_hash IS NOT NULL FROM cards WHERE deck_id = ? AND card_id = ?", deckRowID, cardID).
+		Scan(&rowID, &imageExists)
+	if err != nil {
+		if dbSQLite.IsNotFound(err) {
+			return 0, false, er.CardNotExists
+		}
+		return 0, false, er.InternalError.AddMessage(err.Error())
+	}
+	return rowID, imageExists, nil
+}
+func (d *sqliteCard) get(ctx context.Context, q dbSQLite.Querier, gameID, collectionID, deckID string, cardID int64) (*entitiesCard.Card, error) {
+	rowID, _, err := d.rowID(ctx, q, gameID, collectionID, deckID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	return d.scan(q.QueryRowContext(ctx, "SELECT "+sqliteColumns+" FROM cards WHERE id = ?", rowID), gameID, collectionID, deckID)
+}
+func (d *sqliteCard) scan(row dbSQLite.Scanner, gameID, collectionID, deckID string) (*entitiesCard.Card, error) {
+	card := &entitiesCard.Card{
+		GameID:       gameID,
+		CollectionID: collectionID,
+		DeckID:       deckID,
+	}
+	var variables string
+	var imageHash sql.NullString
+	err := row.Scan(&card.ID, &card.Name, &card.Description, &card.Image, &variables, &card.Count, &imageHash, &card.CreatedAt, &card.UpdatedAt)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	card.ImageHash = imageHash.String
+	card.Variables = make(map[string]string)
+	err = json.Unmarshal([]byte(variables), &card.Variables)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return card, nil
+}
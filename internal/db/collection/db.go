@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/HardDie/fsentry"
@@ -12,6 +13,7 @@ import (
 	"github.com/HardDie/fsentry/pkg/fsentry_types"
 
 	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
 	entitiesCollection "github.com/HardDie/DeckBuilder/internal/entities/collection"
 	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/logger"
@@ -21,16 +23,21 @@ import (
 type collection struct {
 	db        fsentry.IFSEntry
 	gamesPath string
+	// The info of the collection is read, changed and written back, so the changes are serialized for each collection
+	locks *utils.KeyMutex
 
-	game dbGame.Game
+	images dbImage.Image
+	game   dbGame.Game
 }
 
-func New(db fsentry.IFSEntry, game dbGame.Game) Collection {
+func New(db fsentry.IFSEntry, images dbImage.Image, game dbGame.Game) Collection {
 	return &collection{
 		db:        db,
 		gamesPath: "games",
+		locks:     utils.NewKeyMutex(),
 
-		game: game,
+		images: images,
+		game:   game,
 	}
 }
 
@@ -95,6 +102,7 @@ func (d *collection) Get(ctx context.Context, gameID, name string) (*entitiesCol
 		Name:        info.Name.String(),
 		Description: cInfo.Description.String(),
 		Image:       cInfo.Image.String(),
+		ImageHash:   cInfo.ImageHash,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 
@@ -156,6 +164,7 @@ func (d *collection) Move(ctx context.Context, gameID, oldName, newName string)
 		Name:        info.Name.String(),
 		Description: cInfo.Description.String(),
 		Image:       cInfo.Image.String(),
+		ImageHash:   cInfo.ImageHash,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 
@@ -163,14 +172,23 @@ func (d *collection) Move(ctx context.Context, gameID, oldName, newName string)
 	}, nil
 }
 func (d *collection) Update(ctx context.Context, req UpdateRequest) (*entitiesCollection.Collection, error) {
+	defer d.lock(req.GameID, req.Name)()
+
 	game, err := d.game.Get(ctx, req.GameID)
 	if err != nil {
 		return nil, err
 	}
 
+	// The uploaded image is changed separately
+	cInfo, err := d.rawCollection(req.GameID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
 	info, err := d.db.UpdateFolder(req.Name, model{
 		Description: fsentry_types.QS(req.Description),
 		Image:       fsentry_types.QS(req.Image),
+		ImageHash:   cInfo.ImageHash,
 	}, d.gamesPath, game.ID)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
@@ -182,8 +200,8 @@ func (d *collection) Update(ctx context.Context, req UpdateRequest) (*entitiesCo
 		}
 	}
 
-	var cInfo model
-	err = json.Unmarshal(info.Data, &cInfo)
+	cInfo = &model{}
+	err = json.Unmarshal(info.Data, cInfo)
 	if err != nil {
 		return nil, er.InternalError.AddMessage(err.Error())
 	}
@@ -194,6 +212,7 @@ func (d *collection) Update(ctx context.Context, req UpdateRequest) (*entitiesCo
 		Name:        info.Name.String(),
 		Description: cInfo.Description.String(),
 		Image:       cInfo.Image.String(),
+		ImageHash:   cInfo.ImageHash,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 
@@ -201,11 +220,19 @@ func (d *collection) Update(ctx context.Context, req UpdateRequest) (*entitiesCo
 	}, nil
 }
 func (d *collection) Delete(ctx context.Context, gameID, name string) error {
+	defer d.lock(gameID, name)()
+
 	game, err := d.game.Get(ctx, gameID)
 	if err != nil {
 		return err
 	}
 
+	// The images of all nested entities are no longer used
+	err = d.images.ReleaseFolder(ctx, d.gamesPath, game.ID, utils.NameToID(name))
+	if err != nil {
+		return err
+	}
+
 	err = d.db.RemoveFolder(name, d.gamesPath, game.ID)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
@@ -219,18 +246,29 @@ func (d *collection) Delete(ctx context.Context, gameID, name string) error {
 	return nil
 }
 func (d *collection) ImageCreate(ctx context.Context, gameID, collectionID string, data []byte) error {
+	defer d.lock(gameID, collectionID)()
+
 	collection, err := d.Get(ctx, gameID, collectionID)
 	if err != nil {
 		return err
 	}
 
-	err = d.db.CreateBinary("image", data, d.gamesPath, gameID, collection.ID)
+	cInfo, err := d.rawCollection(gameID, collection.ID)
 	if err != nil {
-		if errors.Is(err, fsentry_error.ErrorExist) {
-			return er.CollectionImageExist.AddMessage(err.Error())
-		} else {
-			return er.InternalError.AddMessage(err.Error())
-		}
+		return err
+	}
+	if cInfo.ImageHash != "" {
+		return er.CollectionImageExist
+	}
+
+	cInfo.ImageHash, err = d.images.Create(ctx, data)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.UpdateFolder(collection.ID, cInfo, d.gamesPath, gameID)
+	if err != nil {
+		er.IfErrorLog(d.images.Release(ctx, cInfo.ImageHash))
+		return er.InternalError.AddMessage(err.Error())
 	}
 	return nil
 }
@@ -240,33 +278,63 @@ func (d *collection) ImageGet(ctx context.Context, gameID, collectionID string)
 		return nil, err
 	}
 
-	data, err := d.db.GetBinary("image", d.gamesPath, gameID, collection.ID)
+	cInfo, err := d.rawCollection(gameID, collection.ID)
 	if err != nil {
-		if errors.Is(err, fsentry_error.ErrorNotExist) {
-			return nil, er.CollectionImageNotExists.AddMessage(err.Error())
-		} else {
-			return nil, er.InternalError.AddMessage(err.Error())
-		}
+		return nil, err
 	}
-	return data, nil
+	if cInfo.ImageHash == "" {
+		return nil, er.CollectionImageNotExists
+	}
+	return d.images.Get(ctx, cInfo.ImageHash)
 }
 func (d *collection) ImageDelete(ctx context.Context, gameID, collectionID string) error {
+	defer d.lock(gameID, collectionID)()
+
 	collection, err := d.Get(ctx, gameID, collectionID)
 	if err != nil {
 		return err
 	}
 
-	err = d.db.RemoveBinary("image", d.gamesPath, gameID, collection.ID)
+	cInfo, err := d.rawCollection(gameID, collection.ID)
+	if err != nil {
+		return err
+	}
+	hash := cInfo.ImageHash
+	if hash == "" {
+		return er.CollectionImageNotExists
+	}
+
+	cInfo.ImageHash = ""
+	_, err = d.db.UpdateFolder(collection.ID, cInfo, d.gamesPath, gameID)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return d.images.Release(ctx, hash)
+}
+
+// lock locks the collection and returns the function that unlocks it
+func (d *collection) lock(gameID, collectionID string) func() {
+	return d.locks.Lock(strings.Join([]string{utils.NameToID(gameID), utils.NameToID(collectionID)}, "/"))
+}
+func (d *collection) rawCollection(gameID, name string) (*model, error) {
+	info, err := d.db.GetFolder(name, d.gamesPath, gameID)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
-			return er.CollectionImageNotExists.AddMessage(err.Error())
+			return nil, er.CollectionNotExists.AddMessage(err.Error()).HTTP(http.StatusBadRequest)
+		} else if errors.Is(err, fsentry_error.ErrorBadName) {
+			return nil, er.BadName
 		} else {
-			return er.InternalError.AddMessage(err.Error())
+			return nil, er.InternalError.AddMessage(err.Error())
 		}
 	}
-	return nil
-}
 
+	var cInfo model
+	err = json.Unmarshal(info.Data, &cInfo)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return &cInfo, nil
+}
 func (d *collection) convertCreateUpdate(createdAt, updatedAt *time.Time) (time.Time, time.Time) {
 	if createdAt == nil {
 		createdAt = utils.Allocate(time.Now())
@@ -0,0 +1,210 @@
+package collection
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	entitiesCollection "github.com/HardDie/DeckBuilder/internal/entities/collection"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+const sqliteColumns = "slug, name, description, image, image_hash, created_at, updated_at"
+
+type sqliteCollection struct {
+	db *sql.DB
+}
+
+func NewSQLite(db *sql.DB) Collection {
+	return &sqliteCollection{
+		db: db,
+	}
+}
+
+func (d *sqliteCollection) Create(ctx context.Context, req CreateRequest) (*entitiesCollection.Collection, error) {
+	gameRowID, err := dbSQLite.GameRowID(ctx, d.db, req.GameID)
+	if err != nil {
+		return nil, err
+	}
+	slug := utils.NameToID(req.Name)
+	if slug == "" {
+		return nil, er.BadName
+	}
+
+	now := time.Now()
+	_, err = d.db.ExecContext(ctx, "INSERT INTO collections (game_id, slug, name, description, image, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		gameRowID, slug, req.Name, req.Description, req.Image, now, now)
+	if err != nil {
+		if dbSQLite.IsUniqueViolation(err) {
+			return nil, er.CollectionExist
+		}
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	return &entitiesCollection.Collection{
+		ID:          slug,
+		Name:        req.Name,
+		Description: req.Description,
+		Image:       req.Image,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+
+		GameID: req.GameID,
+	}, nil
+}
+func (d *sqliteCollection) Get(ctx context.Context, gameID, name string) (*entitiesCollection.Collection, error) {
+	return d.get(ctx, d.db, gameID, name)
+}
+func (d *sqliteCollection) List(ctx context.Context, gameID string) ([]*entitiesCollection.Collection, error) {
+	gameRowID, err := dbSQLite.GameRowID(ctx, d.db, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, "SELECT "+sqliteColumns+" FROM collections WHERE game_id = ? AND trash_id IS NULL ORDER BY id", gameRowID)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	defer func() { er.IfErrorLog(rows.Close()) }()
+
+	var collections []*entitiesCollection.Collection
+	for rows.Next() {
+		collection, err := d.scan(rows, gameID)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return collections, nil
+}
+func (d *sqliteCollection) Move(ctx context.Context, gameID, oldName, newName string) (*entitiesCollection.Collection, error) {
+	newSlug := utils.NameToID(newName)
+	if newSlug == "" {
+		return nil, er.BadName
+	}
+
+	var collection *entitiesCollection.Collection
+	err := dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.CollectionRowID(ctx, tx, gameID, oldName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE collections SET slug = ?, name = ?, updated_at = ? WHERE id = ?",
+			newSlug, newName, time.Now(), rowID)
+		if err != nil {
+			if dbSQLite.IsUniqueViolation(err) {
+				return er.CollectionExist
+			}
+			return er.InternalError.AddMessage(err.Error())
+		}
+		collection, err = d.get(ctx, tx, gameID, newSlug)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+func (d *sqliteCollection) Update(ctx context.Context, req UpdateRequest) (*entitiesCollection.Collection, error) {
+	var collection *entitiesCollection.Collection
+	err := dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.CollectionRowID(ctx, tx, req.GameID, req.Name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE collections SET description = ?, image = ?, updated_at = ? WHERE id = ?",
+			req.Description, req.Image, time.Now(), rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		collection, err = d.get(ctx, tx, req.GameID, req.Name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+func (d *sqliteCollection) Delete(ctx context.Context, gameID, name string) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.CollectionRowID(ctx, tx, gameID, name)
+		if err != nil {
+			return err
+		}
+		// Nested entities are removed by the foreign keys
+		_, err = tx.ExecContext(ctx, "DELETE FROM collections WHERE id = ?", rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return nil
+	})
+}
+func (d *sqliteCollection) ImageCreate(ctx context.Context, gameID, collectionID string, data []byte) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.CollectionRowID(ctx, tx, gameID, collectionID)
+		if err != nil {
+			return err
+		}
+		hash, err := dbSQLite.PutImage(ctx, tx, data)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, "UPDATE collections SET image_hash = ?, updated_at = ? WHERE id = ? AND image_hash IS NULL", hash, time.Now(), rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return dbSQLite.CheckAffected(res, er.CollectionImageExist)
+	})
+}
+func (d *sqliteCollection) ImageGet(ctx context.Context, gameID, collectionID string) ([]byte, error) {
+	rowID, err := dbSQLite.CollectionRowID(ctx, d.db, gameID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := dbSQLite.ImageData(ctx, d.db, "collections", rowID)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, er.CollectionImageNotExists
+	}
+	return data, nil
+}
+func (d *sqliteCollection) ImageDelete(ctx context.Context, gameID, collectionID string) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.CollectionRowID(ctx, tx, gameID, collectionID)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, "UPDATE collections SET image_hash = NULL, updated_at = ? WHERE id = ? AND image_hash IS NOT NULL", time.Now(), rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return dbSQLite.CheckAffected(res, er.CollectionImageNotExists)
+	})
+}
+
+func (d *sqliteCollection) get(ctx context.Context, q dbSQLite.Querier, gameID, name string) (*entitiesCollection.Collection, error) {
+	rowID, err := dbSQLite.CollectionRowID(ctx, q, gameID, name)
+	if err != nil {
+		return nil, err
+	}
+	return d.scan(q.QueryRowContext(ctx, "SELECT "+sqliteColumns+" FROM collections WHERE id = ?", rowID), gameID)
+}
+func (d *sqliteCollection) scan(row dbSQLite.Scanner, gameID string) (*entitiesCollection.Collection, error) {
+	collection := &entitiesCollection.Collection{
+		GameID: gameID,
+	}
+	var imageHash sql.NullString
+	err := row.Scan(&collection.ID, &collection.Name, &collection.Description, &collection.Image, &imageHash, &collection.CreatedAt, &collection.UpdatedAt)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	collection.ImageHash = imageHash.String
+	return collection, nil
+}
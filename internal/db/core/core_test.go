@@ -0,0 +1,179 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/HardDie/fsentry"
+	"github.com/stretchr/testify/assert"
+
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+func tempDir(t testing.TB, name string) string {
+	dir, err := os.MkdirTemp("", name)
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		e := os.RemoveAll(dir)
+		if e != nil {
+			t.Fatal("error RemoveAll", e)
+		}
+	})
+	return dir
+}
+
+func writeFile(t testing.TB, data string, path ...string) {
+	err := os.MkdirAll(filepath.Join(path[:len(path)-1]...), 0755)
+	if err != nil {
+		t.Fatal("error creating dir", err)
+	}
+	err = os.WriteFile(filepath.Join(path...), []byte(data), 0644)
+	if err != nil {
+		t.Fatal("error writing file", err)
+	}
+}
+
+func TestCoreMigration(t *testing.T) {
+	t.Run("fresh", func(t *testing.T) {
+		dir := tempDir(t, "core__fresh")
+		c := New(fsentry.NewFSEntry(dir), dir).(*core)
+		assert.NoError(t, c.Init())
+
+		version, err := c.version()
+		assert.NoError(t, err)
+		assert.Equal(t, len(c.migrations()), version)
+
+		// There was nothing to back up
+		_, err = os.Stat(filepath.Join(dir, "backups"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		dir := tempDir(t, "core__legacy")
+		// The data of an old version: no schema version, no timestamps and all cards in the info file
+		writeFile(t, `{"id":"game","name":"\"game\"","data":{}}`, dir, "games", "game", ".info.json")
+		writeFile(t, `{"id":"collection","name":"\"collection\"","data":{}}`, dir, "games", "game", "collection", ".info.json")
+		writeFile(t, `{"id":"deck","name":"\"deck\"","data":{}}`, dir, "games", "game", "collection", "deck", ".info.json")
+		writeFile(t, `{"id":"cards","name":"\"cards\"","data":{"1":{"id":1,"name":"\"first\"","count":1},"2":{"id":2,"name":"\"second\"","count":2}}}`,
+			dir, "games", "game", "collection", "deck", "cards", ".info.json")
+
+		db := fsentry.NewFSEntry(dir)
+		c := New(db, dir).(*core)
+		assert.NoError(t, c.Init())
+
+		version, err := c.version()
+		assert.NoError(t, err)
+		assert.Equal(t, len(c.migrations()), version)
+
+		// The original data is backed up
+		backups, err := os.ReadDir(filepath.Join(dir, "backups"))
+		assert.NoError(t, err)
+		if assert.Len(t, backups, 1) {
+			_, err = os.Stat(filepath.Join(dir, "backups", backups[0].Name(), "games", "game", "collection", "deck", "cards", ".info.json"))
+			assert.NoError(t, err)
+		}
+
+		// The timestamps are filled in
+		info, err := db.GetFolder("game", "games")
+		assert.NoError(t, err)
+		assert.NotNil(t, info.CreatedAt)
+		assert.NotNil(t, info.UpdatedAt)
+
+		// The cards are stored in separate files
+		list, err := db.List("games", "game", "collection", "deck", "cards")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"1", "2"}, list.Entries)
+		entry, err := db.GetEntry("2", "games", "game", "collection", "deck", "cards")
+		assert.NoError(t, err)
+		var card cardV0
+		assert.NoError(t, json.Unmarshal(entry.Data, &card))
+		assert.Equal(t, 2, card.Count)
+		assert.NotNil(t, card.CreatedAt)
+		assert.NotNil(t, card.UpdatedAt)
+
+		// The second start changes nothing
+		assert.NoError(t, New(db, dir).Init())
+		backups, err = os.ReadDir(filepath.Join(dir, "backups"))
+		assert.NoError(t, err)
+		assert.Len(t, backups, 1)
+	})
+
+	t.Run("newer", func(t *testing.T) {
+		dir := tempDir(t, "core__newer")
+		c := New(fsentry.NewFSEntry(dir), dir).(*core)
+		assert.NoError(t, c.Init())
+		assert.NoError(t, c.setVersion(len(c.migrations())+1))
+
+		err := New(fsentry.NewFSEntry(dir), dir).Init()
+		assert.ErrorIs(t, err, er.SchemaTooNew)
+	})
+}
+
+func TestSQLiteCoreMigration(t *testing.T) {
+	dir := tempDir(t, "core__sqlite")
+	open := func(name string) *sqliteCore {
+		db, err := dbSQLite.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal("error open database", err)
+		}
+		t.Cleanup(func() {
+			_ = db.Close()
+		})
+		return NewSQLite(db, fsentry.NewFSEntry(dir), dir).(*sqliteCore)
+	}
+
+	c := open("test.db")
+	assert.NoError(t, c.Init())
+	version, err := c.version()
+	assert.NoError(t, err)
+	assert.Equal(t, len(c.migrations()), version)
+
+	// The database created before the versioning is backed up and upgraded
+	legacy := open("legacy.db")
+	assert.NoError(t, legacy.exec(schemaV1)())
+	for _, slug := range []string{"first", "second"} {
+		_, err = legacy.db.Exec("INSERT INTO games (slug, name, image_data, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+			slug, slug, []byte("image"), time.Now(), time.Now())
+		assert.NoError(t, err)
+	}
+	// The revisions were stored in the files for any storage
+	writeFile(t, `{"id":"1","name":"1","createdAt":"2020-01-01T00:00:00Z","data":{"id":1,"name":"\"first\""}}`, dir, "history", "first", "1.json")
+	assert.NoError(t, legacy.Init())
+	version, err = legacy.version()
+	assert.NoError(t, err)
+	assert.Equal(t, len(legacy.migrations()), version)
+	backups, err := filepath.Glob(filepath.Join(dir, "legacy.db.schema_v0_*.bak"))
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+	var revisions int
+	assert.NoError(t, legacy.db.QueryRow("SELECT count(*) FROM history WHERE game_id = ? AND revision_id = 1", "first").Scan(&revisions))
+	assert.Equal(t, 1, revisions)
+	assert.NoDirExists(t, filepath.Join(dir, "history", "first"))
+
+	// The same image is stored once and removed with the last reference
+	refs := func() int {
+		var count int
+		err := legacy.db.QueryRow("SELECT COALESCE(SUM(refs), 0) FROM images").Scan(&count)
+		assert.NoError(t, err)
+		return count
+	}
+	assert.Equal(t, 2, refs())
+	_, err = legacy.db.Exec("DELETE FROM games WHERE slug = ?", "first")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, refs())
+	_, err = legacy.db.Exec("UPDATE games SET image_hash = NULL")
+	assert.NoError(t, err)
+	var images int
+	assert.NoError(t, legacy.db.QueryRow("SELECT count(*) FROM images").Scan(&images))
+	assert.Equal(t, 0, images)
+
+	// The database of a newer version is refused
+	assert.NoError(t, c.setVersion(len(c.migrations())+1))
+	assert.ErrorIs(t, c.Init(), er.SchemaTooNew)
+}
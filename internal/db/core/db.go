@@ -1,26 +1,48 @@
 package core
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
 
 	"github.com/HardDie/fsentry"
 	"github.com/HardDie/fsentry/pkg/fsentry_error"
 
 	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
 )
 
 type core struct {
-	db        fsentry.IFSEntry
-	gamesPath string
+	db          fsentry.IFSEntry
+	root        string
+	gamesPath   string
+	historyPath string
+	trashPath   string
+	imagesPath  string
+	backupsPath string
+	schemaName  string
 }
 
-func New(db fsentry.IFSEntry) Core {
+// New returns the core of the file storage, root is the root folder of the fsentry db
+func New(db fsentry.IFSEntry, root string) Core {
 	return &core{
-		db:        db,
-		gamesPath: "games",
+		db:          db,
+		root:        root,
+		gamesPath:   "games",
+		historyPath: "history",
+		trashPath:   "trash",
+		imagesPath:  "images",
+		backupsPath: "backups",
+		schemaName:  "schema",
 	}
 }
 
+type schemaInfo struct {
+	Version int `json:"version"`
+}
+
 func (d *core) Init() error {
 	err := d.db.Init()
 	if err != nil {
@@ -32,7 +54,25 @@ func (d *core) Init() error {
 			return er.InternalError.AddMessage(err.Error())
 		}
 	}
-	return nil
+	_, err = d.db.CreateFolder(d.historyPath, nil)
+	if err != nil {
+		if !errors.Is(err, fsentry_error.ErrorExist) {
+			return er.InternalError.AddMessage(err.Error())
+		}
+	}
+	_, err = d.db.CreateFolder(d.trashPath, nil)
+	if err != nil {
+		if !errors.Is(err, fsentry_error.ErrorExist) {
+			return er.InternalError.AddMessage(err.Error())
+		}
+	}
+	_, err = d.db.CreateFolder(d.imagesPath, nil)
+	if err != nil {
+		if !errors.Is(err, fsentry_error.ErrorExist) {
+			return er.InternalError.AddMessage(err.Error())
+		}
+	}
+	return migrate("file storage", d, d.migrations())
 }
 func (d *core) Drop() error {
 	err := d.db.Drop()
@@ -41,3 +81,56 @@ func (d *core) Drop() error {
 	}
 	return nil
 }
+
+func (d *core) version() (int, error) {
+	entry, err := d.db.GetEntry(d.schemaName)
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorNotExist) {
+			// The data was created before the versioning
+			return 0, nil
+		}
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+
+	var info schemaInfo
+	err = json.Unmarshal(entry.Data, &info)
+	if err != nil {
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+	return info.Version, nil
+}
+func (d *core) setVersion(version int) error {
+	err := d.db.UpdateEntry(d.schemaName, schemaInfo{Version: version})
+	if err != nil {
+		if !errors.Is(err, fsentry_error.ErrorNotExist) {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		err = d.db.CreateEntry(d.schemaName, schemaInfo{Version: version})
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+	}
+	return nil
+}
+func (d *core) isEmpty() (bool, error) {
+	for _, path := range []string{d.gamesPath, d.historyPath, d.trashPath} {
+		list, err := d.db.List(path)
+		if err != nil {
+			return false, er.InternalError.AddMessage(err.Error())
+		}
+		if len(list.Folders) > 0 || len(list.Entries) > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+func (d *core) backup(version int) (string, error) {
+	backupPath := filepath.Join(d.root, d.backupsPath, fmt.Sprintf("schema_v%d_%s", version, time.Now().Format("20060102_150405")))
+	for _, path := range []string{d.gamesPath, d.historyPath, d.trashPath, d.imagesPath} {
+		err := fs.CopyFolder(filepath.Join(d.root, path), filepath.Join(backupPath, path))
+		if err != nil {
+			return "", err
+		}
+	}
+	return backupPath, nil
+}
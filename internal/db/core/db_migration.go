@@ -0,0 +1,344 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/HardDie/fsentry"
+	"github.com/HardDie/fsentry/pkg/fsentry_error"
+
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+)
+
+const (
+	infoFile   = ".info.json"
+	cardsPath  = "cards"
+	entryExt   = ".json"
+	deckDepth  = 3
+	cardsDepth = deckDepth + 1
+)
+
+// The migrations of the file storage, the games imported from old archives are upgraded by UpgradeGame
+func (d *core) migrations() []migration {
+	return []migration{
+		{
+			description: "fill in the missing creation and update time",
+			apply:       d.fillTimestamps,
+		},
+		{
+			description: "store each card in a separate file",
+			apply:       d.splitCards,
+		},
+		{
+			description: "move the images into the shared store",
+			apply:       d.moveImages,
+		},
+	}
+}
+
+// The layouts of the files at schema version 0
+type folderInfoV0 struct {
+	Id        string          `json:"id"`
+	Name      json.RawMessage `json:"name"`
+	CreatedAt *time.Time      `json:"createdAt"`
+	UpdatedAt *time.Time      `json:"updatedAt"`
+	Data      json.RawMessage `json:"data"`
+}
+type entryV0 struct {
+	Id        string          `json:"id"`
+	Name      string          `json:"name"`
+	CreatedAt *time.Time      `json:"createdAt"`
+	UpdatedAt *time.Time      `json:"updatedAt"`
+	Data      json.RawMessage `json:"data"`
+}
+type cardV0 struct {
+	ID          int64           `json:"id"`
+	Name        json.RawMessage `json:"name"`
+	Description json.RawMessage `json:"description"`
+	Image       json.RawMessage `json:"image"`
+	Variables   json.RawMessage `json:"variables"`
+	Count       int             `json:"count"`
+	CreatedAt   *time.Time      `json:"createdAt"`
+	UpdatedAt   *time.Time      `json:"updatedAt"`
+}
+
+// UpgradeGame brings the game unpacked from an archive of an old version to the current layout of the files.
+// The images stored next to the entities are moved into the shared store by the import itself.
+func UpgradeGame(db fsentry.IFSEntry, root, gameID string) error {
+	d := New(db, root).(*core)
+	err := d.fillGameTimestamps(gameID)
+	if err != nil {
+		return err
+	}
+	return d.splitGameCards(gameID)
+}
+
+// Old versions did not store the time, so it was replaced with the current time on every read.
+// The time of the last modification of the file is the best guess.
+func (d *core) fillTimestamps() error {
+	return d.fillGameTimestamps("")
+}
+
+// fillGameTimestamps fills in the time of the game, all games if the game is not set
+func (d *core) fillGameTimestamps(gameID string) error {
+	gamesRoot := filepath.Join(d.root, d.gamesPath)
+	return filepath.WalkDir(filepath.Join(gamesRoot, gameID), func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		if !entry.Type().IsRegular() || filepath.Ext(path) != entryExt {
+			return nil
+		}
+		rel, err := filepath.Rel(gamesRoot, path)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		// game/collection/deck/cards/1.json
+		parts := strings.Split(rel, string(filepath.Separator))
+		depth := len(parts) - 1
+		isCards := depth == cardsDepth && parts[deckDepth] == cardsPath
+		if depth > deckDepth && !isCards {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		switch {
+		case entry.Name() == infoFile:
+			return fixFolderInfo(path, info.ModTime(), isCards)
+		case isCards && !strings.HasPrefix(entry.Name(), "."):
+			return fixCardEntry(path, info.ModTime())
+		}
+		return nil
+	})
+}
+func fixFolderInfo(path string, modTime time.Time, isCards bool) error {
+	var info folderInfoV0
+	err := readJSON(path, &info)
+	if err != nil {
+		return err
+	}
+	changed := fillTime(&info.CreatedAt, &info.UpdatedAt, modTime)
+
+	// The info file of the cards folder could contain all the cards of the deck
+	if isCards && len(info.Data) > 0 && string(info.Data) != "null" {
+		var list map[string]json.RawMessage
+		err = json.Unmarshal(info.Data, &list)
+		if err != nil {
+			return er.InternalError.AddMessage(path + ": " + err.Error())
+		}
+		cardsChanged := false
+		for id, card := range list {
+			fixed, ok, err := fillCardTime(card, modTime)
+			if err != nil {
+				return er.InternalError.AddMessage(path + ": " + err.Error())
+			}
+			if ok {
+				list[id] = fixed
+				cardsChanged = true
+			}
+		}
+		if cardsChanged {
+			info.Data, err = json.Marshal(list)
+			if err != nil {
+				return er.InternalError.AddMessage(err.Error())
+			}
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	return writeJSON(path, info)
+}
+func fixCardEntry(path string, modTime time.Time) error {
+	var entry entryV0
+	err := readJSON(path, &entry)
+	if err != nil {
+		return err
+	}
+	card, changed, err := fillCardTime(entry.Data, modTime)
+	if err != nil {
+		return er.InternalError.AddMessage(path + ": " + err.Error())
+	}
+	if !changed {
+		return nil
+	}
+	entry.Data = card
+	return writeJSON(path, entry)
+}
+
+// fillCardTime fills in the time of the card, the other fields are kept as they are,
+// so the cards of the current layout can be passed too
+func fillCardTime(data json.RawMessage, modTime time.Time) (json.RawMessage, bool, error) {
+	var card cardV0
+	err := json.Unmarshal(data, &card)
+	if err != nil {
+		return nil, false, err
+	}
+	if !fillTime(&card.CreatedAt, &card.UpdatedAt, modTime) {
+		return data, false, nil
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, false, err
+	}
+	fields["createdAt"], err = json.Marshal(card.CreatedAt)
+	if err != nil {
+		return nil, false, err
+	}
+	fields["updatedAt"], err = json.Marshal(card.UpdatedAt)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+func fillTime(createdAt, updatedAt **time.Time, modTime time.Time) bool {
+	changed := false
+	if *createdAt == nil {
+		*createdAt = &modTime
+		changed = true
+	}
+	if *updatedAt == nil {
+		*updatedAt = *createdAt
+		changed = true
+	}
+	return changed
+}
+
+// Previously, all the cards of the deck were stored as a single map in the info file of the cards folder
+func (d *core) splitCards() error {
+	games, err := d.db.List(d.gamesPath)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	for _, game := range games.Folders {
+		err = d.splitGameCards(game)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (d *core) splitGameCards(gameID string) error {
+	collections, err := d.db.List(d.gamesPath, gameID)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	for _, collection := range collections.Folders {
+		decks, err := d.db.List(d.gamesPath, gameID, collection)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		for _, deck := range decks.Folders {
+			err = d.splitDeckCards(d.gamesPath, gameID, collection, deck)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+func (d *core) splitDeckCards(deckPath ...string) error {
+	info, err := d.db.GetFolder(cardsPath, deckPath...)
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorNotExist) {
+			return nil
+		}
+		return er.InternalError.AddMessage(err.Error())
+	}
+	if len(info.Data) == 0 || string(info.Data) == "null" {
+		// Nothing to migrate
+		return nil
+	}
+
+	var list map[string]json.RawMessage
+	err = json.Unmarshal(info.Data, &list)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+
+	path := append(append([]string{}, deckPath...), cardsPath)
+	for id, card := range list {
+		err = d.db.CreateEntry(id, card, path...)
+		if err != nil {
+			if !errors.Is(err, fsentry_error.ErrorExist) {
+				return er.InternalError.AddMessage(err.Error())
+			}
+			// The migration was interrupted, the file has already been created
+			err = d.db.UpdateEntry(id, card, path...)
+			if err != nil {
+				return er.InternalError.AddMessage(err.Error())
+			}
+		}
+	}
+
+	// The old list is removed only after all cards have been written
+	_, err = d.db.UpdateFolder(cardsPath, nil, deckPath...)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+
+// Previously, the image of each entity was stored next to it, so the same image was stored many times
+func (d *core) moveImages() error {
+	ctx := context.Background()
+	images := dbImage.New(d.db, d.root)
+	err := images.ConvertFolder(ctx, d.gamesPath)
+	if err != nil {
+		return err
+	}
+
+	// The deleted games, collections and decks keep their images until they are purged
+	items, err := os.ReadDir(filepath.Join(d.root, d.trashPath))
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	for _, item := range items {
+		if !item.IsDir() {
+			continue
+		}
+		err = images.ConvertFolder(ctx, d.trashPath, item.Name(), "data")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readJSON(path string, data any) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	defer func() { er.IfErrorLog(file.Close()) }()
+
+	err = json.NewDecoder(file).Decode(data)
+	if err != nil {
+		return er.InternalError.AddMessage(path + ": " + err.Error())
+	}
+	return nil
+}
+func writeJSON(path string, data any) error {
+	return fs.ReplaceFile(path, func(w io.Writer) error {
+		return fs.JsonToWriter(w, data)
+	})
+}
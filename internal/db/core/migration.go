@@ -0,0 +1,67 @@
+package core
+
+import (
+	"fmt"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/logger"
+)
+
+// migration upgrades the data to the next schema version.
+// Released migrations must never be changed, they describe the data as it was at their version.
+type migration struct {
+	description string
+	apply       func() error
+}
+
+// versioned is a storage whose data is upgraded by the migrations
+type versioned interface {
+	// version returns the schema version of the stored data, 0 - the data was created before the versioning
+	version() (int, error)
+	setVersion(version int) error
+	// isEmpty reports that there is no data yet, so there is nothing to back up
+	isEmpty() (bool, error)
+	// backup saves a copy of the data before the upgrade from the passed version, returns the path to the copy
+	backup(version int) (string, error)
+}
+
+// migrate applies the migrations that follow the current schema version, the i-th migration upgrades the data to version i+1.
+// The data is backed up first. The version is saved after every step, so an interrupted upgrade continues from the failed migration.
+func migrate(name string, storage versioned, migrations []migration) error {
+	current, err := storage.version()
+	if err != nil {
+		return err
+	}
+	latest := len(migrations)
+	if current > latest {
+		return er.SchemaTooNew.AddMessage(fmt.Sprintf("The %s schema version is %d, but this version of the application supports only up to %d. Please update the application.", name, current, latest))
+	}
+	if current == latest {
+		return nil
+	}
+
+	isEmpty, err := storage.isEmpty()
+	if err != nil {
+		return err
+	}
+	if !isEmpty {
+		path, err := storage.backup(current)
+		if err != nil {
+			return err
+		}
+		logger.Info.Printf("The %s data is backed up before the upgrade: %s", name, path)
+	}
+
+	for i := current; i < latest; i++ {
+		logger.Info.Printf("Upgrading the %s schema to version %d: %s", name, i+1, migrations[i].description)
+		err = migrations[i].apply()
+		if err != nil {
+			return err
+		}
+		err = storage.setVersion(i + 1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,424 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HardDie/fsentry"
+
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+)
+
+// Deleted games, collections and decks stay in their tables with the trash_id set,
+// so they keep all nested entities. Deleted entities are detached from the parent,
+// so they survive the purge of the parent just like the folders in the file storage.
+var schemaV1 = []string{
+	`CREATE TABLE IF NOT EXISTS games (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		slug        TEXT NOT NULL,
+		name        TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		image       TEXT NOT NULL DEFAULT '',
+		image_data  BLOB,
+		created_at  DATETIME NOT NULL,
+		updated_at  DATETIME NOT NULL,
+		trash_id    INTEGER
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS games_slug ON games (slug) WHERE trash_id IS NULL`,
+	`CREATE TABLE IF NOT EXISTS collections (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		game_id     INTEGER REFERENCES games (id) ON DELETE CASCADE,
+		slug        TEXT NOT NULL,
+		name        TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		image       TEXT NOT NULL DEFAULT '',
+		image_data  BLOB,
+		created_at  DATETIME NOT NULL,
+		updated_at  DATETIME NOT NULL,
+		trash_id    INTEGER
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS collections_slug ON collections (game_id, slug) WHERE trash_id IS NULL`,
+	`CREATE TABLE IF NOT EXISTS decks (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		collection_id INTEGER REFERENCES collections (id) ON DELETE CASCADE,
+		slug          TEXT NOT NULL,
+		name          TEXT NOT NULL,
+		description   TEXT NOT NULL DEFAULT '',
+		image         TEXT NOT NULL DEFAULT '',
+		image_data    BLOB,
+		created_at    DATETIME NOT NULL,
+		updated_at    DATETIME NOT NULL,
+		trash_id      INTEGER
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS decks_slug ON decks (collection_id, slug) WHERE trash_id IS NULL`,
+	`CREATE TABLE IF NOT EXISTS cards (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		deck_id     INTEGER NOT NULL REFERENCES decks (id) ON DELETE CASCADE,
+		card_id     INTEGER NOT NULL,
+		name        TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		image       TEXT NOT NULL DEFAULT '',
+		variables   TEXT NOT NULL DEFAULT '{}',
+		count       INTEGER NOT NULL DEFAULT 0,
+		image_data  BLOB,
+		created_at  DATETIME NOT NULL,
+		updated_at  DATETIME NOT NULL,
+		UNIQUE (deck_id, card_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS settings (
+		id   INTEGER PRIMARY KEY CHECK (id = 1),
+		data TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS trash (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		name          TEXT NOT NULL,
+		game_id       TEXT NOT NULL,
+		collection_id TEXT NOT NULL DEFAULT '',
+		deck_id       TEXT NOT NULL DEFAULT '',
+		card_id       INTEGER NOT NULL DEFAULT 0,
+		card          TEXT,
+		image_data    BLOB,
+		deleted_at    DATETIME NOT NULL
+	)`,
+}
+
+// The images are moved into a separate table, so the same image is stored once.
+// The number of references is maintained by the triggers, including the cascade deletes,
+// and the image is removed together with the last reference.
+var schemaV2 = []string{
+	`CREATE TABLE IF NOT EXISTS images (
+		hash TEXT PRIMARY KEY,
+		data BLOB NOT NULL,
+		refs INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS images_release AFTER UPDATE OF refs ON images WHEN NEW.refs <= 0 BEGIN
+		DELETE FROM images WHERE hash = NEW.hash;
+	END`,
+}
+
+// imageTables are the tables of the entities which refer to the images
+var imageTables = []string{"games", "collections", "decks", "cards"}
+
+// The revisions are stored the same way as the trash items, the entities are addressed by their identifiers,
+// so the revisions survive the renames and the removal of the entity rows. The revisions of the deleted
+// entities are kept with the trash_id set.
+var schemaV3 = append([]string{
+	`CREATE TABLE IF NOT EXISTS history (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		game_id       TEXT NOT NULL,
+		collection_id TEXT NOT NULL DEFAULT '',
+		deck_id       TEXT NOT NULL DEFAULT '',
+		card_id       INTEGER NOT NULL DEFAULT 0,
+		revision_id   INTEGER NOT NULL,
+		name          TEXT NOT NULL,
+		description   TEXT NOT NULL DEFAULT '',
+		image         TEXT NOT NULL DEFAULT '',
+		variables     TEXT,
+		count         INTEGER NOT NULL DEFAULT 0,
+		image_hash    TEXT,
+		created_at    DATETIME NOT NULL,
+		trash_id      INTEGER
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS history_revision ON history (game_id, collection_id, deck_id, card_id, revision_id) WHERE trash_id IS NULL`,
+}, imageTriggerSchema("history")...)
+
+func imageReferenceSchema(table string) []string {
+	return append([]string{
+		`ALTER TABLE ` + table + ` ADD COLUMN image_hash TEXT`,
+	}, imageTriggerSchema(table)...)
+}
+func imageTriggerSchema(table string) []string {
+	return []string{
+		`CREATE TRIGGER IF NOT EXISTS ` + table + `_image_insert AFTER INSERT ON ` + table + ` WHEN NEW.image_hash IS NOT NULL BEGIN
+			UPDATE images SET refs = refs + 1 WHERE hash = NEW.image_hash;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS ` + table + `_image_update AFTER UPDATE OF image_hash ON ` + table + ` BEGIN
+			UPDATE images SET refs = refs + 1 WHERE hash = NEW.image_hash;
+			UPDATE images SET refs = refs - 1 WHERE hash = OLD.image_hash;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS ` + table + `_image_delete AFTER DELETE ON ` + table + ` WHEN OLD.image_hash IS NOT NULL BEGIN
+			UPDATE images SET refs = refs - 1 WHERE hash = OLD.image_hash;
+		END`,
+	}
+}
+
+type sqliteCore struct {
+	db *sql.DB
+	// The revisions were stored in the files for any storage, they are moved into the database by the upgrade
+	files fsentry.IFSEntry
+	root  string
+}
+
+// NewSQLite returns the core of the database, files is the fsentry db of the data folder
+func NewSQLite(db *sql.DB, files fsentry.IFSEntry, root string) Core {
+	return &sqliteCore{
+		db:    db,
+		files: files,
+		root:  root,
+	}
+}
+
+func (d *sqliteCore) Init() error {
+	return migrate("database", d, d.migrations())
+}
+func (d *sqliteCore) Drop() error {
+	return dbSQLite.Tx(context.Background(), d.db, func(tx *sql.Tx) error {
+		for _, table := range []string{"history", "trash", "settings", "cards", "decks", "collections", "games", "images"} {
+			_, err := tx.Exec("DROP TABLE IF EXISTS " + table)
+			if err != nil {
+				return er.InternalError.AddMessage(err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+// The migrations of the database, the schema version is stored in the user_version pragma
+func (d *sqliteCore) migrations() []migration {
+	return []migration{
+		{
+			description: "create the tables",
+			apply:       d.exec(schemaV1),
+		},
+		{
+			description: "move the images into the shared table",
+			apply:       d.moveImages,
+		},
+		{
+			description: "store the history in the database",
+			apply:       d.moveHistory,
+		},
+	}
+}
+func (d *sqliteCore) exec(queries []string) func() error {
+	return func() error {
+		return dbSQLite.Tx(context.Background(), d.db, func(tx *sql.Tx) error {
+			for _, query := range queries {
+				_, err := tx.Exec(query)
+				if err != nil {
+					return er.InternalError.AddMessage(err.Error())
+				}
+			}
+			return nil
+		})
+	}
+}
+
+func (d *sqliteCore) moveImages() error {
+	return dbSQLite.Tx(context.Background(), d.db, func(tx *sql.Tx) error {
+		queries := schemaV2
+		for _, table := range imageTables {
+			queries = append(queries, imageReferenceSchema(table)...)
+		}
+		for _, query := range queries {
+			_, err := tx.Exec(query)
+			if err != nil {
+				return er.InternalError.AddMessage(err.Error())
+			}
+		}
+
+		// SQLite has no sha256, so the hashes are calculated here
+		for _, table := range imageTables {
+			err := d.moveTableImages(tx, table)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec("ALTER TABLE " + table + " DROP COLUMN image_data")
+			if err != nil {
+				return er.InternalError.AddMessage(err.Error())
+			}
+		}
+		return nil
+	})
+}
+func (d *sqliteCore) moveTableImages(tx *sql.Tx, table string) error {
+	ctx := context.Background()
+	for {
+		// The rows are processed one by one, so only one image is kept in memory
+		var rowID int64
+		var data []byte
+		err := tx.QueryRow("SELECT id, image_data FROM "+table+" WHERE image_data IS NOT NULL LIMIT 1").Scan(&rowID, &data)
+		if err != nil {
+			if dbSQLite.IsNotFound(err) {
+				return nil
+			}
+			return er.InternalError.AddMessage(err.Error())
+		}
+
+		hash, err := dbSQLite.PutImage(ctx, tx, data)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE "+table+" SET image_hash = ?, image_data = NULL WHERE id = ?", hash, rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+	}
+}
+
+// Previously, the revisions were stored in the files for any storage
+func (d *sqliteCore) moveHistory() error {
+	ctx := context.Background()
+	images := dbImage.New(d.files, d.root)
+	var imported bool
+	var trashItems []int64
+	err := dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		for _, query := range schemaV3 {
+			_, err := tx.Exec(query)
+			if err != nil {
+				return er.InternalError.AddMessage(err.Error())
+			}
+		}
+
+		// The revisions in the files belong to the file storage, if the database is new.
+		// They are copied together with the games by the storage migration.
+		var games int
+		err := tx.QueryRow("SELECT count(*) FROM games").Scan(&games)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		if games == 0 {
+			return nil
+		}
+
+		err = d.importHistory(ctx, tx, images)
+		if err != nil {
+			return err
+		}
+
+		// The revisions of the deleted entities were kept in the folders of the trash items
+		items, err := d.trashTargets(tx)
+		if err != nil {
+			return err
+		}
+		for itemID, target := range items {
+			err = dbHistory.ImportFolder(ctx, tx, images, filepath.Join(d.root, "trash", strconv.FormatInt(itemID, 10), "history"),
+				target, sql.NullInt64{Int64: itemID, Valid: true})
+			if err != nil {
+				return err
+			}
+			trashItems = append(trashItems, itemID)
+		}
+		imported = true
+		return nil
+	})
+	if err != nil || !imported {
+		return err
+	}
+
+	// The revisions are already in the database, so the files that can't be removed are only logged
+	games, err := os.ReadDir(filepath.Join(d.root, "history"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		er.IfErrorLog(err)
+	}
+	for _, game := range games {
+		if !game.IsDir() || strings.HasPrefix(game.Name(), ".") {
+			continue
+		}
+		er.IfErrorLog(images.ReleaseFolder(ctx, "history", game.Name()))
+		er.IfErrorLog(fs.RemoveFolder(filepath.Join(d.root, "history", game.Name())))
+	}
+	for _, itemID := range trashItems {
+		name := strconv.FormatInt(itemID, 10)
+		er.IfErrorLog(images.ReleaseFolder(ctx, "trash", name, "history"))
+		er.IfErrorLog(fs.RemoveFolder(filepath.Join(d.root, "trash", name)))
+	}
+	return nil
+}
+func (d *sqliteCore) trashTargets(tx *sql.Tx) (map[int64]entitiesHistory.Target, error) {
+	rows, err := tx.Query("SELECT id, game_id, collection_id, deck_id, card_id FROM trash")
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	defer func() { er.IfErrorLog(rows.Close()) }()
+
+	items := make(map[int64]entitiesHistory.Target)
+	for rows.Next() {
+		var itemID int64
+		var target entitiesHistory.Target
+		err = rows.Scan(&itemID, &target.GameID, &target.CollectionID, &target.DeckID, &target.CardID)
+		if err != nil {
+			return nil, er.InternalError.AddMessage(err.Error())
+		}
+		items[itemID] = target
+	}
+	if err = rows.Err(); err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return items, nil
+}
+func (d *sqliteCore) importHistory(ctx context.Context, tx *sql.Tx, images dbImage.Image) error {
+	games, err := os.ReadDir(filepath.Join(d.root, "history"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return er.InternalError.AddMessage(err.Error())
+	}
+	for _, game := range games {
+		if !game.IsDir() || strings.HasPrefix(game.Name(), ".") {
+			continue
+		}
+		err = dbHistory.ImportFolder(ctx, tx, images, filepath.Join(d.root, "history", game.Name()),
+			entitiesHistory.Target{GameID: game.Name()}, sql.NullInt64{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *sqliteCore) version() (int, error) {
+	var version int
+	err := d.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+	return version, nil
+}
+func (d *sqliteCore) setVersion(version int) error {
+	// Pragmas don't support the query parameters
+	_, err := d.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func (d *sqliteCore) isEmpty() (bool, error) {
+	var count int
+	err := d.db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&count)
+	if err != nil {
+		return false, er.InternalError.AddMessage(err.Error())
+	}
+	return count == 0, nil
+}
+func (d *sqliteCore) backup(version int) (string, error) {
+	var file string
+	err := d.db.QueryRow("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file)
+	if err != nil {
+		return "", er.InternalError.AddMessage(err.Error())
+	}
+	if file == "" {
+		return "", er.InternalError.AddMessage("the database is not stored in a file and can't be backed up")
+	}
+
+	// The backup is stored next to the database
+	backupPath := fmt.Sprintf("%s.schema_v%d_%s.bak", file, version, time.Now().Format("20060102_150405"))
+	_, err = d.db.Exec("VACUUM INTO ?", backupPath)
+	if err != nil {
+		return "", er.InternalError.AddMessage(err.Error())
+	}
+	return backupPath, nil
+}
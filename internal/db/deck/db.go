@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/HardDie/fsentry"
@@ -12,6 +13,7 @@ import (
 	"github.com/HardDie/fsentry/pkg/fsentry_types"
 
 	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
 	entitiesDeck "github.com/HardDie/DeckBuilder/internal/entities/deck"
 	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/logger"
@@ -21,15 +23,20 @@ import (
 type deck struct {
 	db        fsentry.IFSEntry
 	gamesPath string
+	// The info of the deck is read, changed and written back, so the changes are serialized for each deck
+	locks *utils.KeyMutex
 
+	images     dbImage.Image
 	collection dbCollection.Collection
 }
 
-func New(db fsentry.IFSEntry, collection dbCollection.Collection) Deck {
+func New(db fsentry.IFSEntry, images dbImage.Image, collection dbCollection.Collection) Deck {
 	return &deck{
 		db:        db,
 		gamesPath: "games",
+		locks:     utils.NewKeyMutex(),
 
+		images:     images,
 		collection: collection,
 	}
 }
@@ -102,6 +109,7 @@ func (d *deck) Get(ctx context.Context, gameID, collectionID, name string) (*ent
 		Name:        info.Name.String(),
 		Description: dInfo.Description.String(),
 		Image:       dInfo.Image.String(),
+		ImageHash:   dInfo.ImageHash,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 
@@ -164,6 +172,7 @@ func (d *deck) Move(ctx context.Context, gameID, collectionID, oldName, newName
 		Name:        info.Name.String(),
 		Description: dInfo.Description.String(),
 		Image:       dInfo.Image.String(),
+		ImageHash:   dInfo.ImageHash,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 
@@ -172,14 +181,23 @@ func (d *deck) Move(ctx context.Context, gameID, collectionID, oldName, newName
 	}, nil
 }
 func (d *deck) Update(ctx context.Context, req UpdateRequest) (*entitiesDeck.Deck, error) {
+	defer d.lock(req.GameID, req.CollectionID, req.Name)()
+
 	collection, err := d.collection.Get(ctx, req.GameID, req.CollectionID)
 	if err != nil {
 		return nil, err
 	}
 
+	// The uploaded image is changed separately
+	dInfo, err := d.rawDeck(req.GameID, req.CollectionID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
 	info, err := d.db.UpdateFolder(req.Name, model{
 		Description: fsentry_types.QS(req.Description),
 		Image:       fsentry_types.QS(req.Image),
+		ImageHash:   dInfo.ImageHash,
 	}, d.gamesPath, req.GameID, collection.ID)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
@@ -191,8 +209,8 @@ func (d *deck) Update(ctx context.Context, req UpdateRequest) (*entitiesDeck.Dec
 		}
 	}
 
-	var dInfo model
-	err = json.Unmarshal(info.Data, &dInfo)
+	dInfo = &model{}
+	err = json.Unmarshal(info.Data, dInfo)
 	if err != nil {
 		return nil, er.InternalError.AddMessage(err.Error())
 	}
@@ -203,6 +221,7 @@ func (d *deck) Update(ctx context.Context, req UpdateRequest) (*entitiesDeck.Dec
 		Name:        info.Name.String(),
 		Description: dInfo.Description.String(),
 		Image:       dInfo.Image.String(),
+		ImageHash:   dInfo.ImageHash,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 
@@ -211,11 +230,19 @@ func (d *deck) Update(ctx context.Context, req UpdateRequest) (*entitiesDeck.Dec
 	}, nil
 }
 func (d *deck) Delete(ctx context.Context, gameID, collectionID, name string) error {
+	defer d.lock(gameID, collectionID, name)()
+
 	collection, err := d.collection.Get(ctx, gameID, collectionID)
 	if err != nil {
 		return err
 	}
 
+	// The images of all nested entities are no longer used
+	err = d.images.ReleaseFolder(ctx, d.gamesPath, gameID, collection.ID, utils.NameToID(name))
+	if err != nil {
+		return err
+	}
+
 	err = d.db.RemoveFolder(name, d.gamesPath, gameID, collection.ID)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
@@ -229,18 +256,29 @@ func (d *deck) Delete(ctx context.Context, gameID, collectionID, name string) er
 	return nil
 }
 func (d *deck) ImageCreate(ctx context.Context, gameID, collectionID, deckID string, data []byte) error {
+	defer d.lock(gameID, collectionID, deckID)()
+
 	deck, err := d.Get(ctx, gameID, collectionID, deckID)
 	if err != nil {
 		return err
 	}
 
-	err = d.db.CreateBinary("image", data, d.gamesPath, gameID, collectionID, deck.ID)
+	dInfo, err := d.rawDeck(gameID, collectionID, deck.ID)
 	if err != nil {
-		if errors.Is(err, fsentry_error.ErrorExist) {
-			return er.DeckImageExist.AddMessage(err.Error())
-		} else {
-			return er.InternalError.AddMessage(err.Error())
-		}
+		return err
+	}
+	if dInfo.ImageHash != "" {
+		return er.DeckImageExist
+	}
+
+	dInfo.ImageHash, err = d.images.Create(ctx, data)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.UpdateFolder(deck.ID, dInfo, d.gamesPath, gameID, collectionID)
+	if err != nil {
+		er.IfErrorLog(d.images.Release(ctx, dInfo.ImageHash))
+		return er.InternalError.AddMessage(err.Error())
 	}
 	return nil
 }
@@ -250,33 +288,63 @@ func (d *deck) ImageGet(ctx context.Context, gameID, collectionID, deckID string
 		return nil, err
 	}
 
-	data, err := d.db.GetBinary("image", d.gamesPath, gameID, collectionID, deck.ID)
+	dInfo, err := d.rawDeck(gameID, collectionID, deck.ID)
 	if err != nil {
-		if errors.Is(err, fsentry_error.ErrorNotExist) {
-			return nil, er.DeckImageNotExists.AddMessage(err.Error())
-		} else {
-			return nil, er.InternalError.AddMessage(err.Error())
-		}
+		return nil, err
 	}
-	return data, nil
+	if dInfo.ImageHash == "" {
+		return nil, er.DeckImageNotExists
+	}
+	return d.images.Get(ctx, dInfo.ImageHash)
 }
 func (d *deck) ImageDelete(ctx context.Context, gameID, collectionID, deckID string) error {
+	defer d.lock(gameID, collectionID, deckID)()
+
 	deck, err := d.Get(ctx, gameID, collectionID, deckID)
 	if err != nil {
 		return err
 	}
 
-	err = d.db.RemoveBinary("image", d.gamesPath, gameID, collectionID, deck.ID)
+	dInfo, err := d.rawDeck(gameID, collectionID, deck.ID)
+	if err != nil {
+		return err
+	}
+	hash := dInfo.ImageHash
+	if hash == "" {
+		return er.DeckImageNotExists
+	}
+
+	dInfo.ImageHash = ""
+	_, err = d.db.UpdateFolder(deck.ID, dInfo, d.gamesPath, gameID, collectionID)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return d.images.Release(ctx, hash)
+}
+
+// lock locks the deck and returns the function that unlocks it
+func (d *deck) lock(gameID, collectionID, deckID string) func() {
+	return d.locks.Lock(strings.Join([]string{utils.NameToID(gameID), utils.NameToID(collectionID), utils.NameToID(deckID)}, "/"))
+}
+func (d *deck) rawDeck(gameID, collectionID, name string) (*model, error) {
+	info, err := d.db.GetFolder(name, d.gamesPath, gameID, collectionID)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
-			return er.DeckImageNotExists.AddMessage(err.Error())
+			return nil, er.DeckNotExists.AddMessage(err.Error()).HTTP(http.StatusBadRequest)
+		} else if errors.Is(err, fsentry_error.ErrorBadName) {
+			return nil, er.BadName
 		} else {
-			return er.InternalError.AddMessage(err.Error())
+			return nil, er.InternalError.AddMessage(err.Error())
 		}
 	}
-	return nil
-}
 
+	var dInfo model
+	err = json.Unmarshal(info.Data, &dInfo)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return &dInfo, nil
+}
 func (d *deck) convertCreateUpdate(createdAt, updatedAt *time.Time) (time.Time, time.Time) {
 	if createdAt == nil {
 		createdAt = utils.Allocate(time.Now())
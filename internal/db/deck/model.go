@@ -5,4 +5,6 @@ import "github.com/HardDie/fsentry/pkg/fsentry_types"
 type model struct {
 	Description fsentry_types.QuotedString `json:"description"`
 	Image       fsentry_types.QuotedString `json:"image"`
+	// The hash of the uploaded image in the shared image store
+	ImageHash string `json:"imageHash,omitempty"`
 }
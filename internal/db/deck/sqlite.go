@@ -0,0 +1,212 @@
+package deck
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	entitiesDeck "github.com/HardDie/DeckBuilder/internal/entities/deck"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+const sqliteColumns = "slug, name, description, image, image_hash, created_at, updated_at"
+
+type sqliteDeck struct {
+	db *sql.DB
+}
+
+func NewSQLite(db *sql.DB) Deck {
+	return &sqliteDeck{
+		db: db,
+	}
+}
+
+func (d *sqliteDeck) Create(ctx context.Context, req CreateRequest) (*entitiesDeck.Deck, error) {
+	collectionRowID, err := dbSQLite.CollectionRowID(ctx, d.db, req.GameID, req.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+	slug := utils.NameToID(req.Name)
+	if slug == "" {
+		return nil, er.BadName
+	}
+
+	now := time.Now()
+	_, err = d.db.ExecContext(ctx, "INSERT INTO decks (collection_id, slug, name, description, image, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		collectionRowID, slug, req.Name, req.Description, req.Image, now, now)
+	if err != nil {
+		if dbSQLite.IsUniqueViolation(err) {
+			return nil, er.DeckExist
+		}
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	return &entitiesDeck.Deck{
+		ID:          slug,
+		Name:        req.Name,
+		Description: req.Description,
+		Image:       req.Image,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+
+		GameID:       req.GameID,
+		CollectionID: req.CollectionID,
+	}, nil
+}
+func (d *sqliteDeck) Get(ctx context.Context, gameID, collectionID, name string) (*entitiesDeck.Deck, error) {
+	return d.get(ctx, d.db, gameID, collectionID, name)
+}
+func (d *sqliteDeck) List(ctx context.Context, gameID, collectionID string) ([]*entitiesDeck.Deck, error) {
+	collectionRowID, err := dbSQLite.CollectionRowID(ctx, d.db, gameID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, "SELECT "+sqliteColumns+" FROM decks WHERE collection_id = ? AND trash_id IS NULL ORDER BY id", collectionRowID)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	defer func() { er.IfErrorLog(rows.Close()) }()
+
+	var decks []*entitiesDeck.Deck
+	for rows.Next() {
+		deck, err := d.scan(rows, gameID, collectionID)
+		if err != nil {
+			return nil, err
+		}
+		decks = append(decks, deck)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return decks, nil
+}
+func (d *sqliteDeck) Move(ctx context.Context, gameID, collectionID, oldName, newName string) (*entitiesDeck.Deck, error) {
+	newSlug := utils.NameToID(newName)
+	if newSlug == "" {
+		return nil, er.BadName
+	}
+
+	var deck *entitiesDeck.Deck
+	err := dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.DeckRowID(ctx, tx, gameID, collectionID, oldName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE decks SET slug = ?, name = ?, updated_at = ? WHERE id = ?",
+			newSlug, newName, time.Now(), rowID)
+		if err != nil {
+			if dbSQLite.IsUniqueViolation(err) {
+				return er.DeckExist
+			}
+			return er.InternalError.AddMessage(err.Error())
+		}
+		deck, err = d.get(ctx, tx, gameID, collectionID, newSlug)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deck, nil
+}
+func (d *sqliteDeck) Update(ctx context.Context, req UpdateRequest) (*entitiesDeck.Deck, error) {
+	var deck *entitiesDeck.Deck
+	err := dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.DeckRowID(ctx, tx, req.GameID, req.CollectionID, req.Name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE decks SET description = ?, image = ?, updated_at = ? WHERE id = ?",
+			req.Description, req.Image, time.Now(), rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		deck, err = d.get(ctx, tx, req.GameID, req.CollectionID, req.Name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deck, nil
+}
+func (d *sqliteDeck) Delete(ctx context.Context, gameID, collectionID, name string) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.DeckRowID(ctx, tx, gameID, collectionID, name)
+		if err != nil {
+			return err
+		}
+		// Cards are removed by the foreign keys
+		_, err = tx.ExecContext(ctx, "DELETE FROM decks WHERE id = ?", rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return nil
+	})
+}
+func (d *sqliteDeck) ImageCreate(ctx context.Context, gameID, collectionID, deckID string, data []byte) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.DeckRowID(ctx, tx, gameID, collectionID, deckID)
+		if err != nil {
+			return err
+		}
+		hash, err := dbSQLite.PutImage(ctx, tx, data)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, "UPDATE decks SET image_hash = ?, updated_at = ? WHERE id = ? AND image_hash IS NULL", hash, time.Now(), rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return dbSQLite.CheckAffected(res, er.DeckImageExist)
+	})
+}
+func (d *sqliteDeck) ImageGet(ctx context.Context, gameID, collectionID, deckID string) ([]byte, error) {
+	rowID, err := dbSQLite.DeckRowID(ctx, d.db, gameID, collectionID, deckID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := dbSQLite.ImageData(ctx, d.db, "decks", rowID)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, er.DeckImageNotExists
+	}
+	return data, nil
+}
+func (d *sqliteDeck) ImageDelete(ctx context.Context, gameID, collectionID, deckID string) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.DeckRowID(ctx, tx, gameID, collectionID, deckID)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, "UPDATE decks SET image_hash = NULL, updated_at = ? WHERE id = ? AND image_hash IS NOT NULL", time.Now(), rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return dbSQLite.CheckAffected(res, er.DeckImageNotExists)
+	})
+}
+
+func (d *sqliteDeck) get(ctx context.Context, q dbSQLite.Querier, gameID, collectionID, name string) (*entitiesDeck.Deck, error) {
+	rowID, err := dbSQLite.DeckRowID(ctx, q, gameID, collectionID, name)
+	if err != nil {
+		return nil, err
+	}
+	return d.scan(q.QueryRowContext(ctx, "SELECT "+sqliteColumns+" FROM decks WHERE id = ?", rowID), gameID, collectionID)
+}
+func (d *sqliteDeck) scan(row dbSQLite.Scanner, gameID, collectionID string) (*entitiesDeck.Deck, error) {
+	deck := &entitiesDeck.Deck{
+		GameID:       gameID,
+		CollectionID: collectionID,
+	}
+	var imageHash sql.NullString
+	err := row.Scan(&deck.ID, &deck.Name, &deck.Description, &deck.Image, &imageHash, &deck.CreatedAt, &deck.UpdatedAt)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	deck.ImageHash = imageHash.String
+	return deck, nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/HardDie/fsentry/pkg/fsentry_error"
 	"github.com/HardDie/fsentry/pkg/fsentry_types"
 
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
 	entitiesGame "github.com/HardDie/DeckBuilder/internal/entities/game"
 	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/logger"
@@ -20,12 +21,19 @@ import (
 type game struct {
 	db        fsentry.IFSEntry
 	gamesPath string
+	// The info of the game is read, changed and written back, so the changes are serialized for each game
+	locks *utils.KeyMutex
+
+	images dbImage.Image
 }
 
-func New(db fsentry.IFSEntry) Game {
+func New(db fsentry.IFSEntry, images dbImage.Image) Game {
 	return &game{
 		db:        db,
 		gamesPath: "games",
+		locks:     utils.NewKeyMutex(),
+
+		images: images,
 	}
 }
 
@@ -78,6 +86,7 @@ func (d *game) Get(_ context.Context, name string) (*entitiesGame.Game, error) {
 		Name:        info.Name.String(),
 		Description: gInfo.Description.String(),
 		Image:       gInfo.Image.String(),
+		ImageHash:   gInfo.ImageHash,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 	}, nil
@@ -127,14 +136,24 @@ func (d *game) Move(_ context.Context, oldName, newName string) (*entitiesGame.G
 		Name:        info.Name.String(),
 		Description: gInfo.Description.String(),
 		Image:       gInfo.Image.String(),
+		ImageHash:   gInfo.ImageHash,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 	}, nil
 }
 func (d *game) Update(_ context.Context, req UpdateRequest) (*entitiesGame.Game, error) {
+	defer d.lock(req.Name)()
+
+	// The uploaded image is changed separately
+	gInfo, err := d.rawGame(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
 	info, err := d.db.UpdateFolder(req.Name, &model{
 		Description: fsentry_types.QS(req.Description),
 		Image:       fsentry_types.QS(req.Image),
+		ImageHash:   gInfo.ImageHash,
 	}, d.gamesPath)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
@@ -146,8 +165,8 @@ func (d *game) Update(_ context.Context, req UpdateRequest) (*entitiesGame.Game,
 		}
 	}
 
-	var gInfo model
-	err = json.Unmarshal(info.Data, &gInfo)
+	gInfo = &model{}
+	err = json.Unmarshal(info.Data, gInfo)
 	if err != nil {
 		return nil, er.InternalError.AddMessage(err.Error())
 	}
@@ -158,12 +177,26 @@ func (d *game) Update(_ context.Context, req UpdateRequest) (*entitiesGame.Game,
 		Name:        info.Name.String(),
 		Description: gInfo.Description.String(),
 		Image:       gInfo.Image.String(),
+		ImageHash:   gInfo.ImageHash,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 	}, nil
 }
-func (d *game) Delete(_ context.Context, name string) error {
-	err := d.db.RemoveFolder(name, d.gamesPath)
+func (d *game) Delete(ctx context.Context, name string) error {
+	defer d.lock(name)()
+
+	game, err := d.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	// The images of all nested entities are no longer used by this game
+	err = d.images.ReleaseFolder(ctx, d.gamesPath, game.ID)
+	if err != nil {
+		return err
+	}
+
+	err = d.db.RemoveFolder(name, d.gamesPath)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
 			return er.GameNotExists.AddMessage(err.Error()).HTTP(http.StatusBadRequest)
@@ -175,7 +208,7 @@ func (d *game) Delete(_ context.Context, name string) error {
 	}
 	return nil
 }
-func (d *game) Duplicate(_ context.Context, srcName, dstName string) (*entitiesGame.Game, error) {
+func (d *game) Duplicate(ctx context.Context, srcName, dstName string) (*entitiesGame.Game, error) {
 	info, err := d.db.DuplicateFolder(srcName, dstName, d.gamesPath)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
@@ -189,6 +222,13 @@ func (d *game) Duplicate(_ context.Context, srcName, dstName string) (*entitiesG
 		}
 	}
 
+	// The copy refers to the same images
+	err = d.images.RetainFolder(ctx, d.gamesPath, info.Id)
+	if err != nil {
+		er.IfErrorLog(d.db.RemoveFolder(info.Id, d.gamesPath))
+		return nil, err
+	}
+
 	var gInfo model
 	err = json.Unmarshal(info.Data, &gInfo)
 	if err != nil {
@@ -201,6 +241,7 @@ func (d *game) Duplicate(_ context.Context, srcName, dstName string) (*entitiesG
 		Name:        info.Name.String(),
 		Description: gInfo.Description.String(),
 		Image:       gInfo.Image.String(),
+		ImageHash:   gInfo.ImageHash,
 		CreatedAt:   createdAt,
 		UpdatedAt:   updatedAt,
 	}, nil
@@ -209,18 +250,29 @@ func (d *game) UpdateInfo(_ context.Context, name, newName string) error {
 	return d.db.UpdateFolderNameWithoutTimestamp(name, newName, d.gamesPath)
 }
 func (d *game) ImageCreate(ctx context.Context, gameID string, data []byte) error {
+	defer d.lock(gameID)()
+
 	game, err := d.Get(ctx, gameID)
 	if err != nil {
 		return err
 	}
 
-	err = d.db.CreateBinary("image", data, d.gamesPath, game.ID)
+	gInfo, err := d.rawGame(game.ID)
 	if err != nil {
-		if errors.Is(err, fsentry_error.ErrorExist) {
-			return er.GameImageExist.AddMessage(err.Error())
-		} else {
-			return er.InternalError.AddMessage(err.Error())
-		}
+		return err
+	}
+	if gInfo.ImageHash != "" {
+		return er.GameImageExist
+	}
+
+	gInfo.ImageHash, err = d.images.Create(ctx, data)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.UpdateFolder(game.ID, gInfo, d.gamesPath)
+	if err != nil {
+		er.IfErrorLog(d.images.Release(ctx, gInfo.ImageHash))
+		return er.InternalError.AddMessage(err.Error())
 	}
 	return nil
 }
@@ -230,33 +282,63 @@ func (d *game) ImageGet(ctx context.Context, gameID string) ([]byte, error) {
 		return nil, err
 	}
 
-	data, err := d.db.GetBinary("image", d.gamesPath, game.ID)
+	gInfo, err := d.rawGame(game.ID)
 	if err != nil {
-		if errors.Is(err, fsentry_error.ErrorNotExist) {
-			return nil, er.GameImageNotExists.AddMessage(err.Error())
-		} else {
-			return nil, er.InternalError.AddMessage(err.Error())
-		}
+		return nil, err
 	}
-	return data, nil
+	if gInfo.ImageHash == "" {
+		return nil, er.GameImageNotExists
+	}
+	return d.images.Get(ctx, gInfo.ImageHash)
 }
 func (d *game) ImageDelete(ctx context.Context, gameID string) error {
+	defer d.lock(gameID)()
+
 	game, err := d.Get(ctx, gameID)
 	if err != nil {
 		return err
 	}
 
-	err = d.db.RemoveBinary("image", d.gamesPath, game.ID)
+	gInfo, err := d.rawGame(game.ID)
+	if err != nil {
+		return err
+	}
+	hash := gInfo.ImageHash
+	if hash == "" {
+		return er.GameImageNotExists
+	}
+
+	gInfo.ImageHash = ""
+	_, err = d.db.UpdateFolder(game.ID, gInfo, d.gamesPath)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return d.images.Release(ctx, hash)
+}
+
+// lock locks the game and returns the function that unlocks it
+func (d *game) lock(gameID string) func() {
+	return d.locks.Lock(utils.NameToID(gameID))
+}
+func (d *game) rawGame(name string) (*model, error) {
+	info, err := d.db.GetFolder(name, d.gamesPath)
 	if err != nil {
 		if errors.Is(err, fsentry_error.ErrorNotExist) {
-			return er.GameImageNotExists.AddMessage(err.Error())
+			return nil, er.GameNotExists.AddMessage(err.Error()).HTTP(http.StatusBadRequest)
+		} else if errors.Is(err, fsentry_error.ErrorBadName) {
+			return nil, er.BadName
 		} else {
-			return er.InternalError.AddMessage(err.Error())
+			return nil, er.InternalError.AddMessage(err.Error())
 		}
 	}
-	return nil
-}
 
+	var gInfo model
+	err = json.Unmarshal(info.Data, &gInfo)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return &gInfo, nil
+}
 func (d *game) convertCreateUpdate(createdAt, updatedAt *time.Time) (time.Time, time.Time) {
 	if createdAt == nil {
 		createdAt = utils.Allocate(time.Now())
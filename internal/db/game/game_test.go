@@ -3,6 +3,8 @@ package game
 import (
 	"context"
 	"os"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 
 	"github.com/HardDie/DeckBuilder/internal/config"
 	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
 	entitiesGame "github.com/HardDie/DeckBuilder/internal/entities/game"
 	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/utils"
@@ -42,7 +45,7 @@ func initGame(t testing.TB, name string) Game {
 	fs := fsentry.NewFSEntry(cfg.Data, fsentry.WithPretty())
 
 	// Init core directory
-	core := dbCore.New(fs)
+	core := dbCore.New(fs, cfg.Data)
 	err = core.Init()
 	if err != nil {
 		t.Fatal("error init core", err)
@@ -54,7 +57,7 @@ func initGame(t testing.TB, name string) Game {
 		}
 	})
 
-	return New(fs)
+	return New(fs, dbImage.New(fs, cfg.Data))
 }
 
 func TestGameCreate(t *testing.T) {
@@ -390,6 +393,35 @@ func TestImageCreate(t *testing.T) {
 		err := g.ImageCreate(ctx, name, img)
 		assert.ErrorIs(t, err, er.GameNotExists)
 	})
+
+	t.Run("concurrent_update", func(t *testing.T) {
+		name := "concurrent_update"
+
+		g := initGame(t, "game_image_create__concurrent_update")
+		_, err := g.Create(ctx, CreateRequest{Name: name})
+		assert.NoError(t, err)
+
+		// The update keeps the image hash written by the image upload
+		const count = 20
+		var wg sync.WaitGroup
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if i == count/2 {
+					assert.NoError(t, g.ImageCreate(ctx, name, img))
+					return
+				}
+				_, err := g.Update(ctx, UpdateRequest{Name: name, Description: strconv.Itoa(i)})
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		data, err := g.ImageGet(ctx, name)
+		assert.NoError(t, err)
+		assert.Equal(t, img, data)
+	})
 }
 func TestImageGet(t *testing.T) {
 	ctx := context.Background()
@@ -0,0 +1,257 @@
+package game
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	entitiesGame "github.com/HardDie/DeckBuilder/internal/entities/game"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+const sqliteColumns = "slug, name, description, image, image_hash, created_at, updated_at"
+
+type sqliteGame struct {
+	db *sql.DB
+}
+
+func NewSQLite(db *sql.DB) Game {
+	return &sqliteGame{
+		db: db,
+	}
+}
+
+func (d *sqliteGame) Create(ctx context.Context, req CreateRequest) (*entitiesGame.Game, error) {
+	slug := utils.NameToID(req.Name)
+	if slug == "" {
+		return nil, er.BadName
+	}
+
+	now := time.Now()
+	_, err := d.db.ExecContext(ctx, "INSERT INTO games (slug, name, description, image, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		slug, req.Name, req.Description, req.Image, now, now)
+	if err != nil {
+		if dbSQLite.IsUniqueViolation(err) {
+			return nil, er.GameExist
+		}
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	return &entitiesGame.Game{
+		ID:          slug,
+		Name:        req.Name,
+		Description: req.Description,
+		Image:       req.Image,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+func (d *sqliteGame) Get(ctx context.Context, name string) (*entitiesGame.Game, error) {
+	return d.get(ctx, d.db, name)
+}
+func (d *sqliteGame) List(ctx context.Context) ([]*entitiesGame.Game, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT "+sqliteColumns+" FROM games WHERE trash_id IS NULL ORDER BY id")
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	defer func() { er.IfErrorLog(rows.Close()) }()
+
+	var games []*entitiesGame.Game
+	for rows.Next() {
+		game, err := d.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return games, nil
+}
+func (d *sqliteGame) Move(ctx context.Context, oldName, newName string) (*entitiesGame.Game, error) {
+	newSlug := utils.NameToID(newName)
+	if newSlug == "" {
+		return nil, er.BadName
+	}
+
+	var game *entitiesGame.Game
+	err := dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.GameRowID(ctx, tx, oldName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE games SET slug = ?, name = ?, updated_at = ? WHERE id = ?",
+			newSlug, newName, time.Now(), rowID)
+		if err != nil {
+			if dbSQLite.IsUniqueViolation(err) {
+				return er.GameExist
+			}
+			return er.InternalError.AddMessage(err.Error())
+		}
+		game, err = d.get(ctx, tx, newSlug)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+func (d *sqliteGame) Update(ctx context.Context, req UpdateRequest) (*entitiesGame.Game, error) {
+	var game *entitiesGame.Game
+	err := dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.GameRowID(ctx, tx, req.Name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE games SET description = ?, image = ?, updated_at = ? WHERE id = ?",
+			req.Description, req.Image, time.Now(), rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		game, err = d.get(ctx, tx, req.Name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+func (d *sqliteGame) Delete(ctx context.Context, name string) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.GameRowID(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		// Nested entities are removed by the foreign keys
+		_, err = tx.ExecContext(ctx, "DELETE FROM games WHERE id = ?", rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return nil
+	})
+}
+func (d *sqliteGame) Duplicate(ctx context.Context, srcName, dstName string) (*entitiesGame.Game, error) {
+	dstSlug := utils.NameToID(dstName)
+	if dstSlug == "" {
+		return nil, er.BadName
+	}
+
+	var game *entitiesGame.Game
+	err := dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		srcRowID, err := dbSQLite.GameRowID(ctx, tx, srcName)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		res, err := tx.ExecContext(ctx, `INSERT INTO games (slug, name, description, image, image_hash, created_at, updated_at)
+			SELECT ?, ?, description, image, image_hash, ?, ? FROM games WHERE id = ?`, dstSlug, dstName, now, now, srcRowID)
+		if err != nil {
+			if dbSQLite.IsUniqueViolation(err) {
+				return er.GameExist
+			}
+			return er.InternalError.AddMessage(err.Error())
+		}
+		dstRowID, err := res.LastInsertId()
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+
+		err = dbSQLite.CopyGameContent(ctx, tx, srcRowID, dstRowID)
+		if err != nil {
+			return err
+		}
+
+		game, err = d.get(ctx, tx, dstSlug)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+func (d *sqliteGame) UpdateInfo(ctx context.Context, name, newName string) error {
+	newSlug := utils.NameToID(newName)
+	if newSlug == "" {
+		return er.BadName
+	}
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.GameRowID(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE games SET slug = ?, name = ? WHERE id = ?", newSlug, newName, rowID)
+		if err != nil {
+			if dbSQLite.IsUniqueViolation(err) {
+				return er.GameExist
+			}
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return nil
+	})
+}
+func (d *sqliteGame) ImageCreate(ctx context.Context, gameID string, data []byte) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.GameRowID(ctx, tx, gameID)
+		if err != nil {
+			return err
+		}
+		hash, err := dbSQLite.PutImage(ctx, tx, data)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, "UPDATE games SET image_hash = ?, updated_at = ? WHERE id = ? AND image_hash IS NULL", hash, time.Now(), rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return dbSQLite.CheckAffected(res, er.GameImageExist)
+	})
+}
+func (d *sqliteGame) ImageGet(ctx context.Context, gameID string) ([]byte, error) {
+	rowID, err := dbSQLite.GameRowID(ctx, d.db, gameID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := dbSQLite.ImageData(ctx, d.db, "games", rowID)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, er.GameImageNotExists
+	}
+	return data, nil
+}
+func (d *sqliteGame) ImageDelete(ctx context.Context, gameID string) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		rowID, err := dbSQLite.GameRowID(ctx, tx, gameID)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, "UPDATE games SET image_hash = NULL, updated_at = ? WHERE id = ? AND image_hash IS NOT NULL", time.Now(), rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return dbSQLite.CheckAffected(res, er.GameImageNotExists)
+	})
+}
+
+func (d *sqliteGame) get(ctx context.Context, q dbSQLite.Querier, name string) (*entitiesGame.Game, error) {
+	rowID, err := dbSQLite.GameRowID(ctx, q, name)
+	if err != nil {
+		return nil, err
+	}
+	return d.scan(q.QueryRowContext(ctx, "SELECT "+sqliteColumns+" FROM games WHERE id = ?", rowID))
+}
+func (d *sqliteGame) scan(row dbSQLite.Scanner) (*entitiesGame.Game, error) {
+	game := &entitiesGame.Game{}
+	var imageHash sql.NullString
+	err := row.Scan(&game.ID, &game.Name, &game.Description, &game.Image, &imageHash, &game.CreatedAt, &game.UpdatedAt)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	game.ImageHash = imageHash.String
+	return game, nil
+}
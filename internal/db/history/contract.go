@@ -0,0 +1,34 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+)
+
+type History interface {
+	Create(ctx context.Context, req CreateRequest) (*entitiesHistory.Revision, error)
+	Get(ctx context.Context, target entitiesHistory.Target, revisionID int64) (*entitiesHistory.Revision, error)
+	List(ctx context.Context, target entitiesHistory.Target) ([]*entitiesHistory.Revision, error)
+	Delete(ctx context.Context, target entitiesHistory.Target, revisionID int64) error
+	// Move moves the revisions of the entity and the nested entities to the new target.
+	// The revisions left at the new target by an entity that no longer exists are replaced.
+	Move(ctx context.Context, target, newTarget entitiesHistory.Target) error
+	Drop(ctx context.Context, target entitiesHistory.Target) error
+	ImageGet(ctx context.Context, target entitiesHistory.Target, revisionID int64) ([]byte, error)
+}
+
+type CreateRequest struct {
+	Target      entitiesHistory.Target
+	Name        string
+	Description string
+	Image       string
+	Variables   map[string]string
+	Count       int
+	// Optional. Previous image binary
+	ImageFile []byte
+	// Optional. The identifier and the time of the copied revision, a new revision is created by default
+	ID        int64
+	CreatedAt *time.Time
+}
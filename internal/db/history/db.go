@@ -0,0 +1,356 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HardDie/fsentry"
+	"github.com/HardDie/fsentry/pkg/fsentry_error"
+	"github.com/HardDie/fsentry/pkg/fsentry_types"
+
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/logger"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+type history struct {
+	db          fsentry.IFSEntry
+	historyPath string
+	// The revision identifiers are allocated by reading the list, so the creation is serialized for each entity
+	locks *utils.KeyMutex
+
+	images dbImage.Image
+}
+
+func New(db fsentry.IFSEntry, images dbImage.Image) History {
+	return &history{
+		db:          db,
+		historyPath: "history",
+		locks:       utils.NewKeyMutex(),
+
+		images: images,
+	}
+}
+
+func (d *history) Create(ctx context.Context, req CreateRequest) (*entitiesHistory.Revision, error) {
+	path := d.buildPath(req.Target)
+	defer d.locks.Lock(strings.Join(path, "/"))()
+
+	// Create the folder chain for the entity, if it does not exist yet
+	for i := 1; i < len(path); i++ {
+		_, err := d.db.CreateFolder(path[i], nil, path[:i]...)
+		if err != nil && !errors.Is(err, fsentry_error.ErrorExist) {
+			return nil, er.InternalError.AddMessage(err.Error())
+		}
+	}
+
+	revisionID := req.ID
+	if revisionID == 0 {
+		list, err := d.List(ctx, req.Target)
+		if err != nil {
+			return nil, err
+		}
+
+		// Search for the largest revision ID
+		revisionID = 1
+		for _, revision := range list {
+			if revision.ID >= revisionID {
+				revisionID = revision.ID + 1
+			}
+		}
+	}
+
+	revisionInfo := model{
+		ID:          revisionID,
+		Name:        fsentry_types.QS(req.Name),
+		Description: fsentry_types.QS(req.Description),
+		Image:       fsentry_types.QS(req.Image),
+		Variables:   convertMapString(req.Variables),
+		Count:       req.Count,
+		CreatedAt:   req.CreatedAt,
+	}
+	name := strconv.FormatInt(revisionID, 10)
+
+	if req.ImageFile != nil {
+		hash, err := d.images.Create(ctx, req.ImageFile)
+		if err != nil {
+			return nil, err
+		}
+		revisionInfo.ImageHash = hash
+	}
+
+	err := d.db.CreateEntry(name, revisionInfo, path...)
+	if err != nil {
+		if revisionInfo.ImageHash != "" {
+			er.IfErrorLog(d.images.Release(ctx, revisionInfo.ImageHash))
+		}
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	return d.Get(ctx, req.Target, revisionID)
+}
+func (d *history) Get(_ context.Context, target entitiesHistory.Target, revisionID int64) (*entitiesHistory.Revision, error) {
+	rInfo, err := d.get(target, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := rInfo.CreatedAt
+	if createdAt == nil {
+		createdAt = utils.Allocate(time.Now())
+	}
+	return &entitiesHistory.Revision{
+		ID:          rInfo.ID,
+		Name:        rInfo.Name.String(),
+		Description: rInfo.Description.String(),
+		Image:       rInfo.Image.String(),
+		Variables:   convertMapQuotedString(rInfo.Variables),
+		Count:       rInfo.Count,
+		HasImage:    rInfo.ImageHash != "",
+		CreatedAt:   *createdAt,
+	}, nil
+}
+func (d *history) List(ctx context.Context, target entitiesHistory.Target) ([]*entitiesHistory.Revision, error) {
+	path := d.buildPath(target)
+
+	isExist, err := d.isExist(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isExist {
+		// Entity was never changed
+		return make([]*entitiesHistory.Revision, 0), nil
+	}
+
+	list, err := d.db.List(path...)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	revisions := make([]*entitiesHistory.Revision, 0, len(list.Entries))
+	for _, entry := range list.Entries {
+		revisionID, err := strconv.ParseInt(entry, 10, 64)
+		if err != nil {
+			logger.Error.Println("Corrupted revision file:", entry)
+			continue
+		}
+		revision, err := d.Get(ctx, target, revisionID)
+		if err != nil {
+			logger.Error.Println(entry, err.Error())
+			continue
+		}
+		revisions = append(revisions, revision)
+	}
+
+	// Newest revisions first
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].ID > revisions[j].ID
+	})
+	return revisions, nil
+}
+func (d *history) Delete(ctx context.Context, target entitiesHistory.Target, revisionID int64) error {
+	rInfo, err := d.get(target, revisionID)
+	if err != nil {
+		return err
+	}
+
+	err = d.db.RemoveEntry(strconv.FormatInt(revisionID, 10), d.buildPath(target)...)
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorNotExist) {
+			return er.RevisionNotExists.AddMessage(err.Error())
+		} else {
+			return er.InternalError.AddMessage(err.Error())
+		}
+	}
+
+	if rInfo.ImageHash == "" {
+		return nil
+	}
+	return d.images.Release(ctx, rInfo.ImageHash)
+}
+func (d *history) Move(ctx context.Context, target, newTarget entitiesHistory.Target) error {
+	path := d.buildPath(target)
+	newPath := d.buildPath(newTarget)
+	if strings.Join(path, "/") == strings.Join(newPath, "/") {
+		return nil
+	}
+
+	isExist, err := d.isExist(path)
+	if err != nil {
+		return err
+	}
+	if !isExist {
+		return nil
+	}
+
+	// The revisions left at the destination belong to an entity that no longer exists
+	err = d.Drop(ctx, newTarget)
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(path[:len(path)-1], "/") != strings.Join(newPath[:len(newPath)-1], "/") {
+		// fsentry cannot move folders between parents, so the revisions are copied one by one
+		return d.copy(ctx, target, newTarget)
+	}
+
+	// Revisions of nested entities are moved together with the folder
+	_, err = d.db.MoveFolder(path[len(path)-1], newPath[len(newPath)-1], path[:len(path)-1]...)
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorBadName) {
+			return er.BadName
+		} else {
+			return er.InternalError.AddMessage(err.Error())
+		}
+	}
+	return nil
+}
+func (d *history) Drop(ctx context.Context, target entitiesHistory.Target) error {
+	path := d.buildPath(target)
+
+	isExist, err := d.isExist(path)
+	if err != nil {
+		return err
+	}
+	if !isExist {
+		return nil
+	}
+
+	// The revisions of the nested entities refer to the images too
+	err = d.images.ReleaseFolder(ctx, path...)
+	if err != nil {
+		return err
+	}
+
+	err = d.db.RemoveFolder(path[len(path)-1], path[:len(path)-1]...)
+	if err != nil && !errors.Is(err, fsentry_error.ErrorNotExist) {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func (d *history) ImageGet(ctx context.Context, target entitiesHistory.Target, revisionID int64) ([]byte, error) {
+	rInfo, err := d.get(target, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if rInfo.ImageHash == "" {
+		return nil, er.RevisionImageNotExists
+	}
+	return d.images.Get(ctx, rInfo.ImageHash)
+}
+
+// get returns the revision as it is stored, the time of the entry is used if the revision has no own time
+func (d *history) get(target entitiesHistory.Target, revisionID int64) (*model, error) {
+	info, err := d.db.GetEntry(strconv.FormatInt(revisionID, 10), d.buildPath(target)...)
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorNotExist) {
+			return nil, er.RevisionNotExists.AddMessage(err.Error())
+		} else if errors.Is(err, fsentry_error.ErrorBadName) {
+			return nil, er.BadName
+		} else {
+			return nil, er.InternalError.AddMessage(err.Error())
+		}
+	}
+
+	var rInfo model
+	err = json.Unmarshal(info.Data, &rInfo)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	if rInfo.CreatedAt == nil {
+		rInfo.CreatedAt = info.CreatedAt
+	}
+	return &rInfo, nil
+}
+
+// copy creates the revisions of the entity at the new target with the same identifiers and removes the source
+func (d *history) copy(ctx context.Context, target, newTarget entitiesHistory.Target) error {
+	revisions, err := d.List(ctx, target)
+	if err != nil {
+		return err
+	}
+	for _, revision := range revisions {
+		var data []byte
+		if revision.HasImage {
+			data, err = d.ImageGet(ctx, target, revision.ID)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = d.Create(ctx, CreateRequest{
+			Target:      newTarget,
+			Name:        revision.Name,
+			Description: revision.Description,
+			Image:       revision.Image,
+			Variables:   revision.Variables,
+			Count:       revision.Count,
+			ImageFile:   data,
+			ID:          revision.ID,
+			CreatedAt:   utils.Allocate(revision.CreatedAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return d.Drop(ctx, target)
+}
+
+// The history folder repeats the structure of the games folder:
+// history/<game>/<collection>/<deck>/cards/<card>
+func (d *history) buildPath(target entitiesHistory.Target) []string {
+	path := []string{d.historyPath, target.GameID}
+	if target.CollectionID == "" {
+		return path
+	}
+	path = append(path, target.CollectionID)
+	if target.DeckID == "" {
+		return path
+	}
+	path = append(path, target.DeckID)
+	if target.CardID == 0 {
+		return path
+	}
+	return append(path, "cards", strconv.FormatInt(target.CardID, 10))
+}
+func (d *history) isExist(path []string) (bool, error) {
+	_, err := d.db.GetFolder(path[len(path)-1], path[:len(path)-1]...)
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorNotExist) || errors.Is(err, fsentry_error.ErrorBadPath) {
+			// Neither the entity nor its parent has any revisions
+			return false, nil
+		} else if errors.Is(err, fsentry_error.ErrorBadName) {
+			return false, er.BadName
+		} else {
+			return false, er.InternalError.AddMessage(err.Error())
+		}
+	}
+	return true, nil
+}
+
+func convertMapString(in map[string]string) map[string]fsentry_types.QuotedString {
+	if in == nil {
+		return nil
+	}
+	res := make(map[string]fsentry_types.QuotedString, len(in))
+	for key, value := range in {
+		res[key] = fsentry_types.QS(value)
+	}
+	return res
+}
+func convertMapQuotedString(in map[string]fsentry_types.QuotedString) map[string]string {
+	if in == nil {
+		return nil
+	}
+	res := make(map[string]string, len(in))
+	for key, value := range in {
+		res[key] = value.String()
+	}
+	return res
+}
@@ -0,0 +1,20 @@
+package history
+
+import (
+	"time"
+
+	"github.com/HardDie/fsentry/pkg/fsentry_types"
+)
+
+type model struct {
+	ID          int64                                 `json:"id"`
+	Name        fsentry_types.QuotedString            `json:"name"`
+	Description fsentry_types.QuotedString            `json:"description"`
+	Image       fsentry_types.QuotedString            `json:"image"`
+	Variables   map[string]fsentry_types.QuotedString `json:"variables"`
+	Count       int                                   `json:"count"`
+	// The hash of the previous image in the shared store
+	ImageHash string `json:"imageHash,omitempty"`
+	// The time of the copied revision, the time of the entry is used otherwise
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
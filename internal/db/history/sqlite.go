@@ -0,0 +1,300 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+const sqliteColumns = "id, revision_id, name, description, image, variables, count, image_hash IS NOT NULL, created_at"
+
+type sqliteHistory struct {
+	db *sql.DB
+}
+
+func NewSQLite(db *sql.DB) History {
+	return &sqliteHistory{
+		db: db,
+	}
+}
+
+func (d *sqliteHistory) Create(ctx context.Context, req CreateRequest) (*entitiesHistory.Revision, error) {
+	var variables sql.NullString
+	if req.Variables != nil {
+		data, err := json.Marshal(req.Variables)
+		if err != nil {
+			return nil, er.InternalError.AddMessage(err.Error())
+		}
+		variables = sql.NullString{String: string(data), Valid: true}
+	}
+	createdAt := time.Now()
+	if req.CreatedAt != nil {
+		createdAt = *req.CreatedAt
+	}
+
+	revisionID := req.ID
+	err := dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		if revisionID == 0 {
+			err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(revision_id), 0) + 1 FROM history WHERE "+targetFilter,
+				targetArgs(req.Target)...).Scan(&revisionID)
+			if err != nil {
+				return er.InternalError.AddMessage(err.Error())
+			}
+		}
+		return insert(ctx, tx, req.Target, sql.NullInt64{}, revisionID, req, variables, createdAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Get(ctx, req.Target, revisionID)
+}
+func (d *sqliteHistory) Get(ctx context.Context, target entitiesHistory.Target, revisionID int64) (*entitiesHistory.Revision, error) {
+	_, revision, err := d.get(ctx, target, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+func (d *sqliteHistory) List(ctx context.Context, target entitiesHistory.Target) ([]*entitiesHistory.Revision, error) {
+	// Newest revisions first
+	rows, err := d.db.QueryContext(ctx, "SELECT "+sqliteColumns+" FROM history WHERE "+targetFilter+" ORDER BY revision_id DESC",
+		targetArgs(target)...)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	defer func() { er.IfErrorLog(rows.Close()) }()
+
+	revisions := make([]*entitiesHistory.Revision, 0)
+	for rows.Next() {
+		_, revision, err := d.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return revisions, nil
+}
+func (d *sqliteHistory) Delete(ctx context.Context, target entitiesHistory.Target, revisionID int64) error {
+	res, err := d.db.ExecContext(ctx, "DELETE FROM history WHERE "+targetFilter+" AND revision_id = ?",
+		append(targetArgs(target), revisionID)...)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return dbSQLite.CheckAffected(res, er.RevisionNotExists)
+}
+func (d *sqliteHistory) Move(ctx context.Context, target, newTarget entitiesHistory.Target) error {
+	if target == newTarget {
+		return nil
+	}
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		filter, args := dbSQLite.HistoryFilter(target)
+		return dbSQLite.MoveHistory(ctx, tx, newTarget, "trash_id IS NULL AND "+filter, args...)
+	})
+}
+func (d *sqliteHistory) Drop(ctx context.Context, target entitiesHistory.Target) error {
+	filter, args := dbSQLite.HistoryFilter(target)
+	_, err := d.db.ExecContext(ctx, "DELETE FROM history WHERE trash_id IS NULL AND "+filter, args...)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func (d *sqliteHistory) ImageGet(ctx context.Context, target entitiesHistory.Target, revisionID int64) ([]byte, error) {
+	rowID, _, err := d.get(ctx, target, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := dbSQLite.ImageData(ctx, d.db, "history", rowID)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, er.RevisionImageNotExists
+	}
+	return data, nil
+}
+
+// ImportFolder inserts the revisions stored in the folder of the file storage, the previous versions stored
+// the revisions in the files for any storage. The nested folders repeat the structure of the games folder
+// below the target: <collection>/<deck>/cards/<card>. The revisions of the deleted entity are marked with the trash item.
+func ImportFolder(ctx context.Context, q dbSQLite.Querier, images dbImage.Image, folder string, target entitiesHistory.Target, trashID sql.NullInt64) error {
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return er.InternalError.AddMessage(err.Error())
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(folder, name)
+
+		if file.IsDir() {
+			switch {
+			case target.IsGame():
+				err = ImportFolder(ctx, q, images, path, entitiesHistory.Target{GameID: target.GameID, CollectionID: name}, trashID)
+			case target.IsCollection():
+				err = ImportFolder(ctx, q, images, path, entitiesHistory.Target{GameID: target.GameID, CollectionID: target.CollectionID, DeckID: name}, trashID)
+			case target.IsDeck() && name == "cards":
+				err = importCards(ctx, q, images, path, target, trashID)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		err = importRevision(ctx, q, images, path, target, trashID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The revisions of the entity itself, without the nested entities
+const targetFilter = "trash_id IS NULL AND game_id = ? AND collection_id = ? AND deck_id = ? AND card_id = ?"
+
+func targetArgs(target entitiesHistory.Target) []interface{} {
+	return []interface{}{target.GameID, target.CollectionID, target.DeckID, target.CardID}
+}
+
+func (d *sqliteHistory) get(ctx context.Context, target entitiesHistory.Target, revisionID int64) (int64, *entitiesHistory.Revision, error) {
+	row := d.db.QueryRowContext(ctx, "SELECT "+sqliteColumns+" FROM history WHERE "+targetFilter+" AND revision_id = ?",
+		append(targetArgs(target), revisionID)...)
+	rowID, revision, err := d.scan(row)
+	if err != nil {
+		if dbSQLite.IsNotFound(err) {
+			return 0, nil, er.RevisionNotExists
+		}
+		return 0, nil, err
+	}
+	return rowID, revision, nil
+}
+func (d *sqliteHistory) scan(row dbSQLite.Scanner) (int64, *entitiesHistory.Revision, error) {
+	var rowID int64
+	revision := &entitiesHistory.Revision{}
+	var variables sql.NullString
+	err := row.Scan(&rowID, &revision.ID, &revision.Name, &revision.Description, &revision.Image, &variables, &revision.Count,
+		&revision.HasImage, &revision.CreatedAt)
+	if err != nil {
+		if dbSQLite.IsNotFound(err) {
+			return 0, nil, err
+		}
+		return 0, nil, er.InternalError.AddMessage(err.Error())
+	}
+	if variables.Valid {
+		err = json.Unmarshal([]byte(variables.String), &revision.Variables)
+		if err != nil {
+			return 0, nil, er.InternalError.AddMessage(err.Error())
+		}
+	}
+	return rowID, revision, nil
+}
+
+func insert(ctx context.Context, q dbSQLite.Querier, target entitiesHistory.Target, trashID sql.NullInt64, revisionID int64,
+	req CreateRequest, variables sql.NullString, createdAt time.Time) error {
+	var hash sql.NullString
+	if req.ImageFile != nil {
+		var err error
+		hash.String, err = dbSQLite.PutImage(ctx, q, req.ImageFile)
+		if err != nil {
+			return err
+		}
+		hash.Valid = true
+	}
+
+	_, err := q.ExecContext(ctx, `INSERT INTO history (game_id, collection_id, deck_id, card_id, revision_id,
+		name, description, image, variables, count, image_hash, created_at, trash_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		target.GameID, target.CollectionID, target.DeckID, target.CardID, revisionID,
+		req.Name, req.Description, req.Image, variables, req.Count, hash, createdAt, trashID)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func importCards(ctx context.Context, q dbSQLite.Querier, images dbImage.Image, folder string, target entitiesHistory.Target, trashID sql.NullInt64) error {
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	for _, file := range files {
+		cardID, err := strconv.ParseInt(file.Name(), 10, 64)
+		if !file.IsDir() || err != nil {
+			continue
+		}
+		target.CardID = cardID
+		err = ImportFolder(ctx, q, images, filepath.Join(folder, file.Name()), target, trashID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func importRevision(ctx context.Context, q dbSQLite.Querier, images dbImage.Image, path string, target entitiesHistory.Target, trashID sql.NullInt64) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	var entry struct {
+		CreatedAt *time.Time `json:"createdAt"`
+		Data      model      `json:"data"`
+	}
+	err = json.Unmarshal(raw, &entry)
+	if err != nil {
+		return er.InternalError.AddMessage(path + ": " + err.Error())
+	}
+	rInfo := entry.Data
+	if rInfo.CreatedAt == nil {
+		rInfo.CreatedAt = entry.CreatedAt
+	}
+	if rInfo.CreatedAt == nil {
+		rInfo.CreatedAt = utils.Allocate(time.Now())
+	}
+
+	req := CreateRequest{
+		Name:        rInfo.Name.String(),
+		Description: rInfo.Description.String(),
+		Image:       rInfo.Image.String(),
+		Count:       rInfo.Count,
+	}
+	var variables sql.NullString
+	if rInfo.Variables != nil {
+		data, err := json.Marshal(convertMapQuotedString(rInfo.Variables))
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		variables = sql.NullString{String: string(data), Valid: true}
+	}
+	if rInfo.ImageHash != "" {
+		req.ImageFile, err = images.Get(ctx, rInfo.ImageHash)
+		if err != nil {
+			return err
+		}
+	}
+	return insert(ctx, q, target, trashID, rInfo.ID, req, variables, *rInfo.CreatedAt)
+}
@@ -0,0 +1,23 @@
+package image
+
+import (
+	"context"
+)
+
+// Image is the shared store of the images of the games, collections, decks and cards.
+// Every image is stored once under its hash, the entities keep only the hash.
+type Image interface {
+	// Create stores the image, if it is not stored yet, and adds a reference to it. Returns the hash of the image.
+	Create(ctx context.Context, data []byte) (string, error)
+	Get(ctx context.Context, hash string) ([]byte, error)
+	// Release removes a reference to the image, the image is removed together with the last reference
+	Release(ctx context.Context, hash string) error
+	// RetainFolder adds the references of all entities stored in the folder, e.g. after the folder has been copied
+	RetainFolder(ctx context.Context, path ...string) error
+	// ReleaseFolder removes the references of all entities stored in the folder, e.g. before the folder is removed
+	ReleaseFolder(ctx context.Context, path ...string) error
+	// List returns the hashes of the images referenced by the entities stored in the folder
+	List(ctx context.Context, path ...string) ([]string, error)
+	// ConvertFolder moves the images stored next to the entities (the layout of the old versions) into the store
+	ConvertFolder(ctx context.Context, path ...string) error
+}
@@ -0,0 +1,361 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/HardDie/fsentry"
+	"github.com/HardDie/fsentry/pkg/fsentry_error"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+	"github.com/HardDie/DeckBuilder/internal/logger"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+const (
+	infoFile   = ".info.json"
+	jsonExt    = ".json"
+	binaryExt  = ".bin"
+	folderBlob = "image"
+)
+
+// Each image is stored as images/<hash>.bin with the number of references in images/<hash>.json
+type image struct {
+	db fsentry.IFSEntry
+	// The references are read directly from the files of the entities, so the whole tree is walked at once
+	root       string
+	imagesPath string
+	// The number of references is read and written back, so the changes are serialized
+	mu sync.Mutex
+}
+
+func New(db fsentry.IFSEntry, root string) Image {
+	return &image{
+		db:         db,
+		root:       root,
+		imagesPath: "images",
+	}
+}
+
+func (d *image) Create(_ context.Context, data []byte) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	hash := utils.HashForData(data)
+	return hash, d.retain(hash, data)
+}
+func (d *image) Get(_ context.Context, hash string) ([]byte, error) {
+	data, err := d.db.GetBinary(hash, d.imagesPath)
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorNotExist) {
+			return nil, er.InternalError.AddMessage("the image " + hash + " is not stored")
+		}
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return data, nil
+}
+func (d *image) Release(_ context.Context, hash string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.release(hash)
+}
+func (d *image) RetainFolder(ctx context.Context, path ...string) error {
+	hashes, err := d.references(path...)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, hash := range hashes {
+		err = d.retain(hash, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (d *image) ReleaseFolder(ctx context.Context, path ...string) error {
+	hashes, err := d.references(path...)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, hash := range hashes {
+		err = d.release(hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (d *image) List(_ context.Context, path ...string) ([]string, error) {
+	hashes, err := d.references(path...)
+	if err != nil {
+		return nil, err
+	}
+
+	unique := make(map[string]struct{}, len(hashes))
+	var res []string
+	for _, hash := range hashes {
+		if _, ok := unique[hash]; ok {
+			continue
+		}
+		unique[hash] = struct{}{}
+		res = append(res, hash)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+func (d *image) ConvertFolder(ctx context.Context, path ...string) error {
+	return d.walk(d.folderPath(path...), func(folder string, files []os.DirEntry) error {
+		names := make(map[string]struct{}, len(files))
+		for _, file := range files {
+			names[file.Name()] = struct{}{}
+		}
+		for _, file := range files {
+			name := file.Name()
+			if !file.Type().IsRegular() || !strings.HasSuffix(name, binaryExt) {
+				continue
+			}
+
+			// The image of the folder is stored in image.bin, the image of the entry in <entry>.bin
+			refFile := strings.TrimSuffix(name, binaryExt) + jsonExt
+			if name == folderBlob+binaryExt {
+				refFile = infoFile
+			}
+			if _, ok := names[refFile]; !ok {
+				continue
+			}
+
+			err := d.convert(ctx, filepath.Join(folder, name), filepath.Join(folder, refFile))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// retain adds a reference to the image. The image is written if it is not stored yet,
+// data can be nil if the image must already be stored.
+func (d *image) retain(hash string, data []byte) error {
+	info, err := d.get(hash)
+	if err != nil {
+		return err
+	}
+	if info != nil {
+		info.Refs++
+		return d.update(hash, info)
+	}
+
+	if data == nil {
+		return er.InternalError.AddMessage("the image " + hash + " is not stored")
+	}
+	// The data is written first, so the counter never refers to a missing image
+	err = d.db.CreateBinary(hash, data, d.imagesPath)
+	if err != nil && !errors.Is(err, fsentry_error.ErrorExist) {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	err = d.db.CreateEntry(hash, model{Refs: 1}, d.imagesPath)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func (d *image) release(hash string) error {
+	info, err := d.get(hash)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		logger.Warn.Printf("The image %s is released, but it is not stored", hash)
+		return nil
+	}
+
+	info.Refs--
+	if info.Refs > 0 {
+		return d.update(hash, info)
+	}
+
+	// The counter is removed last, so the interrupted removal can be repeated
+	err = d.db.RemoveBinary(hash, d.imagesPath)
+	if err != nil && !errors.Is(err, fsentry_error.ErrorNotExist) {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	err = d.db.RemoveEntry(hash, d.imagesPath)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func (d *image) get(hash string) (*model, error) {
+	entry, err := d.db.GetEntry(hash, d.imagesPath)
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorNotExist) {
+			return nil, nil
+		} else if errors.Is(err, fsentry_error.ErrorBadName) {
+			return nil, er.BadName
+		} else {
+			return nil, er.InternalError.AddMessage(err.Error())
+		}
+	}
+
+	var info model
+	err = json.Unmarshal(entry.Data, &info)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return &info, nil
+}
+func (d *image) update(hash string, info *model) error {
+	err := d.db.UpdateEntry(hash, info, d.imagesPath)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+
+// references returns the hashes referenced by the entities in the folder, one for each entity
+func (d *image) references(path ...string) ([]string, error) {
+	var hashes []string
+	err := d.walk(d.folderPath(path...), func(folder string, files []os.DirEntry) error {
+		for _, file := range files {
+			name := file.Name()
+			if !file.Type().IsRegular() || !strings.HasSuffix(name, jsonExt) {
+				continue
+			}
+			if strings.HasPrefix(name, ".") && name != infoFile {
+				continue
+			}
+
+			ref, err := readReference(filepath.Join(folder, name))
+			if err != nil {
+				return err
+			}
+			if ref.Data.ImageHash != "" {
+				hashes = append(hashes, ref.Data.ImageHash)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// walk calls cb for the folder and all nested folders. The hidden folders are skipped,
+// the same way fsentry does not list them. The missing folder has nothing to walk.
+func (d *image) walk(folder string, cb func(folder string, files []os.DirEntry) error) error {
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return er.InternalError.AddMessage(err.Error())
+	}
+
+	err = cb(folder, files)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		err = d.walk(filepath.Join(folder, file.Name()), cb)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convert moves the binary into the store and writes the hash into the file of the entity.
+// The timestamps of the entity are kept, the image of the entity is not changed.
+func (d *image) convert(ctx context.Context, binPath, refPath string) error {
+	raw, err := os.ReadFile(refPath)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	var file map[string]json.RawMessage
+	err = json.Unmarshal(raw, &file)
+	if err != nil {
+		return er.InternalError.AddMessage(refPath + ": " + err.Error())
+	}
+	var data map[string]json.RawMessage
+	if len(file["data"]) > 0 {
+		err = json.Unmarshal(file["data"], &data)
+		if err != nil {
+			return er.InternalError.AddMessage(refPath + ": " + err.Error())
+		}
+	}
+	if data == nil {
+		data = make(map[string]json.RawMessage)
+	}
+
+	// The conversion was interrupted after the hash has been written, only the binary is left
+	if _, ok := data["imageHash"]; !ok {
+		image, err := os.ReadFile(binPath)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		hash, err := d.Create(ctx, image)
+		if err != nil {
+			return err
+		}
+
+		data["imageHash"], err = json.Marshal(hash)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		file["data"], err = json.Marshal(data)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		err = fs.ReplaceFile(refPath, func(w io.Writer) error {
+			return fs.JsonToWriter(w, file)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Remove(binPath)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func (d *image) folderPath(path ...string) string {
+	return filepath.Join(append([]string{d.root}, path...)...)
+}
+
+func readReference(path string) (*reference, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	var ref reference
+	err = json.Unmarshal(raw, &ref)
+	if err != nil {
+		// The data of some folders is not an object, such folders have no image
+		logger.Warn.Printf("Skip %q, the image reference can't be read: %s", path, err.Error())
+		return &ref, nil
+	}
+	return &ref, nil
+}
@@ -0,0 +1,138 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HardDie/fsentry"
+	"github.com/stretchr/testify/assert"
+)
+
+func initImage(t testing.TB, name string) (fsentry.IFSEntry, string, Image) {
+	dir, err := os.MkdirTemp("", name)
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		e := os.RemoveAll(dir)
+		if e != nil {
+			t.Fatal("error RemoveAll", e)
+		}
+	})
+
+	fs := fsentry.NewFSEntry(dir, fsentry.WithPretty())
+	err = fs.Init()
+	if err != nil {
+		t.Fatal("error init fsentry", err)
+	}
+	for _, folder := range []string{"games", "images"} {
+		_, err = fs.CreateFolder(folder, nil)
+		if err != nil {
+			t.Fatal("error create folder", err)
+		}
+	}
+	return fs, dir, New(fs, dir)
+}
+
+func storedImages(t testing.TB, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "images", "*.bin"))
+	assert.NoError(t, err)
+	return files
+}
+
+func TestImage(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("image")
+
+	t.Run("deduplicate", func(t *testing.T) {
+		_, dir, images := initImage(t, "image__deduplicate")
+
+		first, err := images.Create(ctx, data)
+		assert.NoError(t, err)
+		second, err := images.Create(ctx, data)
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Len(t, storedImages(t, dir), 1)
+
+		// The image is removed together with the last reference
+		assert.NoError(t, images.Release(ctx, first))
+		got, err := images.Get(ctx, first)
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+		assert.NoError(t, images.Release(ctx, first))
+		_, err = images.Get(ctx, first)
+		assert.Error(t, err)
+		assert.Len(t, storedImages(t, dir), 0)
+	})
+
+	t.Run("folder", func(t *testing.T) {
+		fs, dir, images := initImage(t, "image__folder")
+
+		hash, err := images.Create(ctx, data)
+		assert.NoError(t, err)
+		_, err = fs.CreateFolder("game", map[string]string{"imageHash": hash}, "games")
+		assert.NoError(t, err)
+		_, err = fs.CreateFolder("cards", nil, "games", "game")
+		assert.NoError(t, err)
+		assert.NoError(t, fs.CreateEntry("1", map[string]string{"imageHash": hash}, "games", "game", "cards"))
+		assert.NoError(t, fs.CreateEntry("2", map[string]string{}, "games", "game", "cards"))
+
+		list, err := images.List(ctx, "games", "game")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hash}, list)
+
+		// The card is the second reference, the copy of the game adds two more
+		assert.NoError(t, images.RetainFolder(ctx, "games", "game", "cards"))
+		assert.NoError(t, images.RetainFolder(ctx, "games", "game"))
+		assert.NoError(t, images.ReleaseFolder(ctx, "games", "game"))
+		assert.Len(t, storedImages(t, dir), 1)
+		assert.NoError(t, images.ReleaseFolder(ctx, "games", "game"))
+		assert.Len(t, storedImages(t, dir), 0)
+
+		// The missing folder has no references
+		assert.NoError(t, images.ReleaseFolder(ctx, "games", "missing"))
+	})
+
+	t.Run("convert", func(t *testing.T) {
+		fs, dir, images := initImage(t, "image__convert")
+
+		// The layout of the old versions, the images are stored next to the entities
+		_, err := fs.CreateFolder("game", map[string]string{"description": "desc"}, "games")
+		assert.NoError(t, err)
+		assert.NoError(t, fs.CreateBinary("image", data, "games", "game"))
+		_, err = fs.CreateFolder("cards", nil, "games", "game")
+		assert.NoError(t, err)
+		assert.NoError(t, fs.CreateEntry("1", map[string]string{}, "games", "game", "cards"))
+		assert.NoError(t, fs.CreateBinary("1", data, "games", "game", "cards"))
+		before, err := fs.GetFolder("game", "games")
+		assert.NoError(t, err)
+
+		assert.NoError(t, images.ConvertFolder(ctx, "games"))
+		assert.Len(t, storedImages(t, dir), 1)
+		_, err = fs.GetBinary("image", "games", "game")
+		assert.Error(t, err)
+		_, err = fs.GetBinary("1", "games", "game", "cards")
+		assert.Error(t, err)
+
+		// The data and the timestamps of the entities are kept
+		after, err := fs.GetFolder("game", "games")
+		assert.NoError(t, err)
+		assert.True(t, before.CreatedAt.Equal(*after.CreatedAt))
+		assert.Equal(t, before.UpdatedAt == nil, after.UpdatedAt == nil)
+		var info map[string]string
+		assert.NoError(t, json.Unmarshal(after.Data, &info))
+		assert.Equal(t, "desc", info["description"])
+		got, err := images.Get(ctx, info["imageHash"])
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+
+		// Both entities refer to the image
+		assert.NoError(t, images.ReleaseFolder(ctx, "games", "game", "cards"))
+		assert.Len(t, storedImages(t, dir), 1)
+		assert.NoError(t, images.ReleaseFolder(ctx, "games", "game"))
+		assert.Len(t, storedImages(t, dir), 0)
+	})
+}
@@ -0,0 +1,14 @@
+package image
+
+type model struct {
+	// The number of entities referring to the image
+	Refs int `json:"refs"`
+}
+
+// reference is the part of the entity files that refers to the image.
+// The folders keep it in the info file and the cards in their own files.
+type reference struct {
+	Data struct {
+		ImageHash string `json:"imageHash"`
+	} `json:"data"`
+}
@@ -15,4 +15,7 @@ type SettingInfo struct {
 	Lang             string   `json:"lang"`
 	EnableBackShadow bool     `json:"enable_back_shadow"`
 	CardSize         CardSize `json:"card_size"`
+	TTSAddress       string   `json:"tts_address"`
+	TTSListenAddress string   `json:"tts_listen_address"`
+	TTSCallbackURL   string   `json:"tts_callback_url"`
 }
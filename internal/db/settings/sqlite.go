@@ -0,0 +1,49 @@
+package settings
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+type sqliteSettings struct {
+	db *sql.DB
+}
+
+func NewSQLite(db *sql.DB) Settings {
+	return &sqliteSettings{
+		db: db,
+	}
+}
+
+func (d *sqliteSettings) Get() (*SettingInfo, error) {
+	var data string
+	err := d.db.QueryRow("SELECT data FROM settings WHERE id = 1").Scan(&data)
+	if err != nil {
+		if dbSQLite.IsNotFound(err) {
+			return nil, er.SettingsNotExists.AddMessage(err.Error())
+		}
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	setting := &SettingInfo{}
+
+	err = json.Unmarshal([]byte(data), setting)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	return setting, nil
+}
+func (d *sqliteSettings) Set(data *SettingInfo) error {
+	value, err := json.Marshal(data)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	_, err = d.db.Exec("INSERT INTO settings (id, data) VALUES (1, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data", string(value))
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
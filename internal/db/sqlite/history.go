@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+// The revisions are addressed by the identifiers of the entity, the same way as the folders of the file storage,
+// so the revisions of the nested entities are selected by the prefix of the target.
+// The revisions of the deleted entities stay in the table with the trash_id set.
+
+// HistoryFilter returns the condition selecting the revisions of the entity and the nested entities
+func HistoryFilter(target entitiesHistory.Target) (string, []interface{}) {
+	query := "game_id = ?"
+	args := []interface{}{target.GameID}
+	if target.IsGame() {
+		return query, args
+	}
+	query += " AND collection_id = ?"
+	args = append(args, target.CollectionID)
+	if target.IsCollection() {
+		return query, args
+	}
+	query += " AND deck_id = ?"
+	args = append(args, target.DeckID)
+	if target.IsDeck() {
+		return query, args
+	}
+	return query + " AND card_id = ?", append(args, target.CardID)
+}
+
+// MoveHistory moves the revisions selected by the query to the new target, the nested entities keep their identifiers
+func MoveHistory(ctx context.Context, q Querier, newTarget entitiesHistory.Target, where string, whereArgs ...interface{}) error {
+	query := "game_id = ?"
+	args := []interface{}{newTarget.GameID}
+	if !newTarget.IsGame() {
+		query += ", collection_id = ?"
+		args = append(args, newTarget.CollectionID)
+	}
+	if newTarget.IsDeck() || newTarget.IsCard() {
+		query += ", deck_id = ?"
+		args = append(args, newTarget.DeckID)
+	}
+	if newTarget.IsCard() {
+		query += ", card_id = ?"
+		args = append(args, newTarget.CardID)
+	}
+
+	// The revisions left at the destination belong to an entity that no longer exists
+	filter, filterArgs := HistoryFilter(newTarget)
+	_, err := q.ExecContext(ctx, "DELETE FROM history WHERE trash_id IS NULL AND "+filter, filterArgs...)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+
+	_, err = q.ExecContext(ctx, "UPDATE history SET "+query+" WHERE "+where, append(args, whereArgs...)...)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"context"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+// The images are stored once in the images table and the entities refer to them by the hash.
+// The references are counted by the triggers of the entity tables, see the schema migrations.
+
+// PutImage stores the image if it is not stored yet and returns its hash.
+// The image without references is removed as soon as the last reference is released,
+// so the hash must be assigned to the entity in the same transaction.
+func PutImage(ctx context.Context, q Querier, data []byte) (string, error) {
+	hash := utils.HashForData(data)
+	_, err := q.ExecContext(ctx, "INSERT OR IGNORE INTO images (hash, data) VALUES (?, ?)", hash, data)
+	if err != nil {
+		return "", er.InternalError.AddMessage(err.Error())
+	}
+	return hash, nil
+}
+
+// ImageData returns the image of the row in the table, or nil if the row has no image
+func ImageData(ctx context.Context, q Querier, table string, rowID int64) ([]byte, error) {
+	var data []byte
+	err := q.QueryRowContext(ctx, "SELECT images.data FROM "+table+" LEFT JOIN images ON images.hash = "+table+".image_hash WHERE "+table+".id = ?", rowID).
+		Scan(&data)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return data, nil
+}
@@ -0,0 +1,159 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+// Querier is implemented by both *sql.DB and *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Scanner is implemented by both *sql.Row and *sql.Rows
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// The entities are addressed by the same identifiers as in the file storage,
+// the row identifiers are only used for the relations between tables.
+
+func GameRowID(ctx context.Context, q Querier, gameID string) (int64, error) {
+	slug := utils.NameToID(gameID)
+	if slug == "" {
+		return 0, er.BadName
+	}
+	var id int64
+	err := q.QueryRowContext(ctx, "SELECT id FROM games WHERE slug = ? AND trash_id IS NULL", slug).Scan(&id)
+	if err != nil {
+		if IsNotFound(err) {
+			return 0, er.GameNotExists.AddMessage("game " + slug + " not exists")
+		}
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+	return id, nil
+}
+func CollectionRowID(ctx context.Context, q Querier, gameID, collectionID string) (int64, error) {
+	gameRowID, err := GameRowID(ctx, q, gameID)
+	if err != nil {
+		return 0, err
+	}
+	slug := utils.NameToID(collectionID)
+	if slug == "" {
+		return 0, er.BadName
+	}
+	var id int64
+	err = q.QueryRowContext(ctx, "SELECT id FROM collections WHERE game_id = ? AND slug = ? AND trash_id IS NULL", gameRowID, slug).Scan(&id)
+	if err != nil {
+		if IsNotFound(err) {
+			return 0, er.CollectionNotExists.AddMessage("collection " + slug + " not exists")
+		}
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+	return id, nil
+}
+func DeckRowID(ctx context.Context, q Querier, gameID, collectionID, deckID string) (int64, error) {
+	collectionRowID, err := CollectionRowID(ctx, q, gameID, collectionID)
+	if err != nil {
+		return 0, err
+	}
+	slug := utils.NameToID(deckID)
+	if slug == "" {
+		return 0, er.BadName
+	}
+	var id int64
+	err = q.QueryRowContext(ctx, "SELECT id FROM decks WHERE collection_id = ? AND slug = ? AND trash_id IS NULL", collectionRowID, slug).Scan(&id)
+	if err != nil {
+		if IsNotFound(err) {
+			return 0, er.DeckNotExists.AddMessage("deck " + slug + " not exists")
+		}
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+	return id, nil
+}
+
+// CopyDeck copies the deck row with all cards into the collection and returns the new row identifier
+func CopyDeck(ctx context.Context, q Querier, deckRowID, collectionRowID int64) (int64, error) {
+	res, err := q.ExecContext(ctx, `INSERT INTO decks (collection_id, slug, name, description, image, image_hash, created_at, updated_at)
+		SELECT ?, slug, name, description, image, image_hash, created_at, updated_at FROM decks WHERE id = ?`, collectionRowID, deckRowID)
+	if err != nil {
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+	newID, err := res.LastInsertId()
+	if err != nil {
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+	_, err = q.ExecContext(ctx, `INSERT INTO cards (deck_id, card_id, name, description, image, variables, count, image_hash, created_at, updated_at)
+		SELECT ?, card_id, name, description, image, variables, count, image_hash, created_at, updated_at FROM cards WHERE deck_id = ?`, newID, deckRowID)
+	if err != nil {
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+	return newID, nil
+}
+
+// CopyCollection copies the collection row with all decks into the game and returns the new row identifier
+func CopyCollection(ctx context.Context, q Querier, collectionRowID, gameRowID int64) (int64, error) {
+	res, err := q.ExecContext(ctx, `INSERT INTO collections (game_id, slug, name, description, image, image_hash, created_at, updated_at)
+		SELECT ?, slug, name, description, image, image_hash, created_at, updated_at FROM collections WHERE id = ?`, gameRowID, collectionRowID)
+	if err != nil {
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+	newID, err := res.LastInsertId()
+	if err != nil {
+		return 0, er.InternalError.AddMessage(err.Error())
+	}
+
+	deckIDs, err := childIDs(ctx, q, "SELECT id FROM decks WHERE collection_id = ? AND trash_id IS NULL", collectionRowID)
+	if err != nil {
+		return 0, err
+	}
+	for _, deckID := range deckIDs {
+		_, err = CopyDeck(ctx, q, deckID, newID)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return newID, nil
+}
+
+// CopyGameContent copies all collections of the game into another game
+func CopyGameContent(ctx context.Context, q Querier, srcGameRowID, dstGameRowID int64) error {
+	collectionIDs, err := childIDs(ctx, q, "SELECT id FROM collections WHERE game_id = ? AND trash_id IS NULL", srcGameRowID)
+	if err != nil {
+		return err
+	}
+	for _, collectionID := range collectionIDs {
+		_, err = CopyCollection(ctx, q, collectionID, dstGameRowID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func childIDs(ctx context.Context, q Querier, query string, parentID int64) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, query, parentID)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	defer func() { er.IfErrorLog(rows.Close()) }()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, er.InternalError.AddMessage(err.Error())
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return ids, nil
+}
@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	// Pure Go driver, so the application can still be built without cgo
+	_ "modernc.org/sqlite"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+// Open opens the database file, the file is created if it does not exist
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	// SQLite allows only one writer, so all requests are serialized on a single connection
+	db.SetMaxOpenConns(1)
+
+	err = db.Ping()
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return db, nil
+}
+
+// Tx runs the callback in a transaction. The transaction is committed if the callback succeeds
+func Tx(ctx context.Context, db *sql.DB, cb func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+
+	err = cb(tx)
+	if err != nil {
+		er.IfErrorLog(tx.Rollback())
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
+func IsUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// CheckAffected returns the error if the query didn't change any rows
+func CheckAffected(res sql.Result, notAffected error) error {
+	count, err := res.RowsAffected()
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	if count == 0 {
+		return notAffected
+	}
+	return nil
+}
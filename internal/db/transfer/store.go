@@ -0,0 +1,42 @@
+package transfer
+
+import (
+	"database/sql"
+
+	"github.com/HardDie/fsentry"
+
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	dbSettings "github.com/HardDie/DeckBuilder/internal/db/settings"
+)
+
+// Files returns the db methods of the file storage, the images are kept in the shared store
+func Files(fs fsentry.IFSEntry, images dbImage.Image) Store {
+	game := dbGame.New(fs, images)
+	collection := dbCollection.New(fs, images, game)
+	deck := dbDeck.New(fs, images, collection)
+	return Store{
+		Game:       game,
+		Collection: collection,
+		Deck:       deck,
+		Card:       dbCard.New(fs, images, deck),
+		Settings:   dbSettings.New(fs),
+		History:    dbHistory.New(fs, images),
+	}
+}
+
+// SQLite returns the db methods of the database storage
+func SQLite(db *sql.DB) Store {
+	return Store{
+		Game:       dbGame.NewSQLite(db),
+		Collection: dbCollection.NewSQLite(db),
+		Deck:       dbDeck.NewSQLite(db),
+		Card:       dbCard.NewSQLite(db),
+		Settings:   dbSettings.NewSQLite(db),
+		History:    dbHistory.NewSQLite(db),
+	}
+}
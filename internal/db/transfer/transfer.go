@@ -0,0 +1,308 @@
+// Package transfer copies games between the storage backends.
+//
+// The card identifiers and timestamps are preserved. The games, collections and decks get new timestamps,
+// but they are created in the original order, so the sorting by creation date is kept.
+// The revisions are copied only together with the whole storage, the archives of the games don't include them.
+package transfer
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbSettings "github.com/HardDie/DeckBuilder/internal/db/settings"
+	entitiesGame "github.com/HardDie/DeckBuilder/internal/entities/game"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+// Store is a set of db methods of a single storage backend
+type Store struct {
+	Game       dbGame.Game
+	Collection dbCollection.Collection
+	Deck       dbDeck.Deck
+	Card       dbCard.Card
+	Settings   dbSettings.Settings
+	History    dbHistory.History
+}
+
+// CopyAll copies the settings and all games with their revisions, returns the number of copied games
+func CopyAll(ctx context.Context, src, dst Store) (int, error) {
+	settings, err := src.Settings.Get()
+	if err != nil && !errors.Is(err, er.SettingsNotExists) {
+		return 0, err
+	}
+	if settings != nil {
+		err = dst.Settings.Set(settings)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	games, err := src.Game.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		return games[i].CreatedAt.Before(games[j].CreatedAt)
+	})
+	for i, game := range games {
+		newGame, err := CopyGame(ctx, src, dst, game.ID, game.Name)
+		if err != nil {
+			return i, err
+		}
+		err = copyGameHistory(ctx, src, dst, game.ID, newGame.ID)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(games), nil
+}
+
+// CopyGame copies the game with all nested entities into the destination storage under the passed name.
+// If the copying fails, the partially copied game is removed.
+func CopyGame(ctx context.Context, src, dst Store, gameID, name string) (*entitiesGame.Game, error) {
+	game, err := src.Game.Get(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	newGame, err := dst.Game.Create(ctx, dbGame.CreateRequest{
+		Name:        name,
+		Description: game.Description,
+		Image:       game.Image,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The source is addressed by the passed ID, the ID in the info file of an imported game can differ
+	err = copyGameContent(ctx, src, dst, gameID, newGame.ID)
+	if err != nil {
+		er.IfErrorLog(dst.Game.Delete(ctx, newGame.ID))
+		return nil, err
+	}
+	return newGame, nil
+}
+
+func copyGameContent(ctx context.Context, src, dst Store, gameID, newGameID string) error {
+	err := copyImage(
+		func() ([]byte, error) { return src.Game.ImageGet(ctx, gameID) },
+		func(data []byte) error { return dst.Game.ImageCreate(ctx, newGameID, data) },
+		er.GameImageNotExists,
+	)
+	if err != nil {
+		return err
+	}
+
+	collections, err := src.Collection.List(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(collections, func(i, j int) bool {
+		return collections[i].CreatedAt.Before(collections[j].CreatedAt)
+	})
+	for _, collection := range collections {
+		newCollection, err := dst.Collection.Create(ctx, dbCollection.CreateRequest{
+			GameID:      newGameID,
+			Name:        collection.Name,
+			Description: collection.Description,
+			Image:       collection.Image,
+		})
+		if err != nil {
+			return err
+		}
+		err = copyImage(
+			func() ([]byte, error) { return src.Collection.ImageGet(ctx, gameID, collection.ID) },
+			func(data []byte) error { return dst.Collection.ImageCreate(ctx, newGameID, newCollection.ID, data) },
+			er.CollectionImageNotExists,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = copyDecks(ctx, src, dst, gameID, collection.ID, newGameID, newCollection.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func copyDecks(ctx context.Context, src, dst Store, gameID, collectionID, newGameID, newCollectionID string) error {
+	decks, err := src.Deck.List(ctx, gameID, collectionID)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(decks, func(i, j int) bool {
+		return decks[i].CreatedAt.Before(decks[j].CreatedAt)
+	})
+	for _, deck := range decks {
+		newDeck, err := dst.Deck.Create(ctx, dbDeck.CreateRequest{
+			GameID:       newGameID,
+			CollectionID: newCollectionID,
+			Name:         deck.Name,
+			Description:  deck.Description,
+			Image:        deck.Image,
+		})
+		if err != nil {
+			return err
+		}
+		err = copyImage(
+			func() ([]byte, error) { return src.Deck.ImageGet(ctx, gameID, collectionID, deck.ID) },
+			func(data []byte) error {
+				return dst.Deck.ImageCreate(ctx, newGameID, newCollectionID, newDeck.ID, data)
+			},
+			er.DeckImageNotExists,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = copyCards(ctx, src, dst, gameID, collectionID, deck.ID, newGameID, newCollectionID, newDeck.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func copyCards(ctx context.Context, src, dst Store, gameID, collectionID, deckID, newGameID, newCollectionID, newDeckID string) error {
+	cards, err := src.Card.List(ctx, gameID, collectionID, deckID)
+	if err != nil {
+		return err
+	}
+	for _, card := range cards {
+		// The revisions are bound to the card identifiers, so the identifiers must not change
+		newCard, err := dst.Card.Create(ctx, dbCard.CreateRequest{
+			GameID:       newGameID,
+			CollectionID: newCollectionID,
+			DeckID:       newDeckID,
+			ID:           card.ID,
+			Name:         card.Name,
+			Description:  card.Description,
+			Image:        card.Image,
+			Variables:    card.Variables,
+			Count:        card.Count,
+			CreatedAt:    utils.Allocate(card.CreatedAt),
+			UpdatedAt:    utils.Allocate(card.UpdatedAt),
+		})
+		if err != nil {
+			return err
+		}
+		err = copyImage(
+			func() ([]byte, error) { return src.Card.ImageGet(ctx, gameID, collectionID, deckID, card.ID) },
+			func(data []byte) error {
+				return dst.Card.ImageCreate(ctx, newGameID, newCollectionID, newDeckID, newCard.ID, data)
+			},
+			er.CardImageNotExists,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyGameHistory copies the revisions of the game and all nested entities.
+// The names of the collections and decks are kept by the copying, so are their identifiers.
+func copyGameHistory(ctx context.Context, src, dst Store, gameID, newGameID string) error {
+	// The revisions left at the destination belong to a game that no longer exists
+	err := dst.History.Drop(ctx, entitiesHistory.Target{GameID: newGameID})
+	if err != nil {
+		return err
+	}
+
+	err = copyHistory(ctx, src, dst, entitiesHistory.Target{GameID: gameID}, entitiesHistory.Target{GameID: newGameID})
+	if err != nil {
+		return err
+	}
+
+	collections, err := src.Collection.List(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	for _, collection := range collections {
+		target := entitiesHistory.Target{GameID: gameID, CollectionID: collection.ID}
+		err = copyHistory(ctx, src, dst, target, entitiesHistory.Target{GameID: newGameID, CollectionID: collection.ID})
+		if err != nil {
+			return err
+		}
+
+		decks, err := src.Deck.List(ctx, gameID, collection.ID)
+		if err != nil {
+			return err
+		}
+		for _, deck := range decks {
+			target.DeckID, target.CardID = deck.ID, 0
+			newTarget := entitiesHistory.Target{GameID: newGameID, CollectionID: collection.ID, DeckID: deck.ID}
+			err = copyHistory(ctx, src, dst, target, newTarget)
+			if err != nil {
+				return err
+			}
+
+			cards, err := src.Card.List(ctx, gameID, collection.ID, deck.ID)
+			if err != nil {
+				return err
+			}
+			for _, card := range cards {
+				target.CardID, newTarget.CardID = card.ID, card.ID
+				err = copyHistory(ctx, src, dst, target, newTarget)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// copyHistory copies the revisions of the entity itself with the same identifiers and times
+func copyHistory(ctx context.Context, src, dst Store, target, newTarget entitiesHistory.Target) error {
+	revisions, err := src.History.List(ctx, target)
+	if err != nil {
+		return err
+	}
+	// The oldest revisions first, the same order they were created in
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := revisions[i]
+		var data []byte
+		if revision.HasImage {
+			data, err = src.History.ImageGet(ctx, target, revision.ID)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = dst.History.Create(ctx, dbHistory.CreateRequest{
+			Target:      newTarget,
+			Name:        revision.Name,
+			Description: revision.Description,
+			Image:       revision.Image,
+			Variables:   revision.Variables,
+			Count:       revision.Count,
+			ImageFile:   data,
+			ID:          revision.ID,
+			CreatedAt:   utils.Allocate(revision.CreatedAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyImage copies the image if it exists
+func copyImage(get func() ([]byte, error), create func(data []byte) error, notExists error) error {
+	data, err := get()
+	if err != nil {
+		if errors.Is(err, notExists) {
+			return nil
+		}
+		return err
+	}
+	return create(data)
+}
@@ -0,0 +1,173 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HardDie/fsentry"
+	"github.com/stretchr/testify/assert"
+
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	dbSettings "github.com/HardDie/DeckBuilder/internal/db/settings"
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+var (
+	img = []byte("some_image")
+)
+
+func initStores(t testing.TB) (Store, Store, Store) {
+	dir, err := os.MkdirTemp("", "transfer_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		e := os.RemoveAll(dir)
+		if e != nil {
+			t.Fatal("error RemoveAll", e)
+		}
+	})
+
+	var files []Store
+	for _, name := range []string{"src", "dst"} {
+		fs := fsentry.NewFSEntry(filepath.Join(dir, name))
+		err = dbCore.New(fs, filepath.Join(dir, name)).Init()
+		if err != nil {
+			t.Fatal("error init core", err)
+		}
+		files = append(files, Files(fs, dbImage.New(fs, filepath.Join(dir, name))))
+	}
+
+	db, err := dbSQLite.Open(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal("error open database", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	err = dbCore.NewSQLite(db, fsentry.NewFSEntry(dir), dir).Init()
+	if err != nil {
+		t.Fatal("error init database", err)
+	}
+
+	return files[0], SQLite(db), files[1]
+}
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+	src, sqlite, dst := initStores(t)
+
+	// Fill the source storage
+	assert.NoError(t, src.Settings.Set(&dbSettings.SettingInfo{Lang: "ru", EnableBackShadow: true}))
+	game, err := src.Game.Create(ctx, dbGame.CreateRequest{Name: "Some game", Description: "game desc"})
+	assert.NoError(t, err)
+	assert.NoError(t, src.Game.ImageCreate(ctx, game.ID, img))
+	collection, err := src.Collection.Create(ctx, dbCollection.CreateRequest{GameID: game.ID, Name: "Collection"})
+	assert.NoError(t, err)
+	deck, err := src.Deck.Create(ctx, dbDeck.CreateRequest{GameID: game.ID, CollectionID: collection.ID, Name: "Deck", Image: "http://some.url"})
+	assert.NoError(t, err)
+	assert.NoError(t, src.Deck.ImageCreate(ctx, game.ID, collection.ID, deck.ID, img))
+	for _, name := range []string{"first", "second", "third"} {
+		_, err = src.Card.Create(ctx, dbCard.CreateRequest{
+			GameID:       game.ID,
+			CollectionID: collection.ID,
+			DeckID:       deck.ID,
+			Name:         name,
+			Variables:    map[string]string{"key": name},
+			Count:        2,
+		})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, src.Card.ImageCreate(ctx, game.ID, collection.ID, deck.ID, 3, img))
+	// Leave a gap in the card identifiers
+	assert.NoError(t, src.Card.Delete(ctx, game.ID, collection.ID, deck.ID, 2))
+	cards, err := src.Card.List(ctx, game.ID, collection.ID, deck.ID)
+	assert.NoError(t, err)
+	cardTarget := entitiesHistory.Target{GameID: game.ID, CollectionID: collection.ID, DeckID: deck.ID, CardID: 3}
+	for _, name := range []string{"old", "older"} {
+		_, err = src.History.Create(ctx, dbHistory.CreateRequest{Target: cardTarget, Name: name, Variables: map[string]string{"key": name}, ImageFile: img})
+		assert.NoError(t, err)
+	}
+	revisions, err := src.History.List(ctx, cardTarget)
+	assert.NoError(t, err)
+
+	// Copy the files into the database and back
+	count, err := CopyAll(ctx, src, sqlite)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = CopyAll(ctx, sqlite, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	for _, store := range []Store{sqlite, dst} {
+		settings, err := store.Settings.Get()
+		assert.NoError(t, err)
+		assert.Equal(t, "ru", settings.Lang)
+		assert.True(t, settings.EnableBackShadow)
+
+		g, err := store.Game.Get(ctx, game.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, game.Name, g.Name)
+		assert.Equal(t, game.Description, g.Description)
+		data, err := store.Game.ImageGet(ctx, game.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, img, data)
+
+		_, err = store.Collection.ImageGet(ctx, game.ID, collection.ID)
+		assert.ErrorIs(t, err, er.CollectionImageNotExists)
+
+		d, err := store.Deck.Get(ctx, game.ID, collection.ID, deck.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, deck.Image, d.Image)
+		data, err = store.Deck.ImageGet(ctx, game.ID, collection.ID, deck.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, img, data)
+
+		list, err := store.Card.List(ctx, game.ID, collection.ID, deck.ID)
+		assert.NoError(t, err)
+		if !assert.Len(t, list, len(cards)) {
+			continue
+		}
+		for _, card := range cards {
+			got, err := store.Card.Get(ctx, game.ID, collection.ID, deck.ID, card.ID)
+			if !assert.NoError(t, err) {
+				continue
+			}
+			assert.Equal(t, card.Name, got.Name)
+			assert.Equal(t, card.Variables, got.Variables)
+			assert.Equal(t, card.Count, got.Count)
+			assert.True(t, card.CreatedAt.Equal(got.CreatedAt))
+			assert.True(t, card.UpdatedAt.Equal(got.UpdatedAt))
+		}
+		data, err = store.Card.ImageGet(ctx, game.ID, collection.ID, deck.ID, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, img, data)
+
+		// The revisions keep their identifiers and times
+		got, err := store.History.List(ctx, cardTarget)
+		assert.NoError(t, err)
+		if !assert.Len(t, got, len(revisions)) {
+			continue
+		}
+		for i, revision := range revisions {
+			assert.Equal(t, revision.ID, got[i].ID)
+			assert.Equal(t, revision.Name, got[i].Name)
+			assert.Equal(t, revision.Variables, got[i].Variables)
+			assert.True(t, got[i].HasImage)
+			assert.True(t, revision.CreatedAt.Equal(got[i].CreatedAt))
+		}
+		data, err = store.History.ImageGet(ctx, cardTarget, revisions[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, img, data)
+	}
+}
@@ -0,0 +1,34 @@
+package trash
+
+import (
+	"context"
+
+	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesTrash "github.com/HardDie/DeckBuilder/internal/entities/trash"
+)
+
+type Trash interface {
+	Create(ctx context.Context, req CreateRequest) (*entitiesTrash.Item, error)
+	Get(ctx context.Context, itemID int64) (*entitiesTrash.Item, error)
+	List(ctx context.Context) ([]*entitiesTrash.Item, error)
+	Restore(ctx context.Context, req RestoreRequest) error
+	Delete(ctx context.Context, itemID int64) error
+	ImageGet(ctx context.Context, itemID int64) ([]byte, error)
+}
+
+type CreateRequest struct {
+	Target entitiesHistory.Target
+	Name   string
+	// Only for cards. The folders of games, collections and decks are moved into the trash
+	Card      *entitiesCard.Card
+	ImageFile []byte
+}
+
+type RestoreRequest struct {
+	ItemID int64
+	// The new location of the entity, can differ from the original one
+	Target entitiesHistory.Target
+	// If the name differs from the original one, the entity info is updated
+	Name string
+}
@@ -0,0 +1,398 @@
+package trash
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/HardDie/fsentry"
+	"github.com/HardDie/fsentry/pkg/fsentry_error"
+	"github.com/HardDie/fsentry/pkg/fsentry_types"
+
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesTrash "github.com/HardDie/DeckBuilder/internal/entities/trash"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+	"github.com/HardDie/DeckBuilder/internal/logger"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+// Each deleted entity is stored in a separate folder:
+// trash/<item>/data    - folder of the game, collection or deck
+// trash/<item>/history - revisions of the entity
+// trash/<item>/image   - image of the card
+type trash struct {
+	db fsentry.IFSEntry
+	// fsentry cannot move folders between parents, so the folders are moved directly on the file system
+	root        string
+	gamesPath   string
+	historyPath string
+	trashPath   string
+	// The item identifiers are allocated by reading the list, so the changes are serialized
+	mu sync.Mutex
+
+	images dbImage.Image
+}
+
+func New(db fsentry.IFSEntry, images dbImage.Image, root string) Trash {
+	return &trash{
+		db:          db,
+		root:        root,
+		gamesPath:   "games",
+		historyPath: "history",
+		trashPath:   "trash",
+
+		images: images,
+	}
+}
+
+func (d *trash) Create(ctx context.Context, req CreateRequest) (*entitiesTrash.Item, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	list, err := d.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Search for the largest item ID
+	maxID := int64(1)
+	for _, item := range list {
+		if item.ID >= maxID {
+			maxID = item.ID + 1
+		}
+	}
+
+	itemInfo := model{
+		ID:           maxID,
+		Name:         fsentry_types.QS(req.Name),
+		GameID:       req.Target.GameID,
+		CollectionID: req.Target.CollectionID,
+		DeckID:       req.Target.DeckID,
+		CardID:       req.Target.CardID,
+		HasImage:     req.ImageFile != nil,
+	}
+	if req.Card != nil {
+		itemInfo.Card = &cardModel{
+			Description: fsentry_types.QS(req.Card.Description),
+			Image:       fsentry_types.QS(req.Card.Image),
+			Variables:   convertMapString(req.Card.Variables),
+			Count:       req.Card.Count,
+			CreatedAt:   utils.Allocate(req.Card.CreatedAt),
+			UpdatedAt:   utils.Allocate(req.Card.UpdatedAt),
+		}
+	}
+	name := strconv.FormatInt(maxID, 10)
+
+	_, err = d.db.CreateFolder(name, itemInfo, d.trashPath)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	if req.Target.IsCard() {
+		if req.ImageFile != nil {
+			err = d.db.CreateBinary("image", req.ImageFile, d.trashPath, name)
+		}
+	} else {
+		err = d.move(d.entityPath(req.Target), d.itemPath(name, "data"))
+	}
+	if err != nil {
+		// Don't leave an empty item in the trash
+		er.IfErrorLog(d.db.RemoveFolder(name, d.trashPath))
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	// Revisions are kept together with the deleted entity
+	err = d.moveIfExist(d.revisionsPath(req.Target), d.itemPath(name, "history"))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Get(ctx, maxID)
+}
+func (d *trash) Get(_ context.Context, itemID int64) (*entitiesTrash.Item, error) {
+	info, err := d.db.GetFolder(strconv.FormatInt(itemID, 10), d.trashPath)
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorNotExist) {
+			return nil, er.TrashItemNotExists.AddMessage(err.Error())
+		} else if errors.Is(err, fsentry_error.ErrorBadName) {
+			return nil, er.BadName
+		} else {
+			return nil, er.InternalError.AddMessage(err.Error())
+		}
+	}
+
+	var tInfo model
+	err = json.Unmarshal(info.Data, &tInfo)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	deletedAt := info.CreatedAt
+	if deletedAt == nil {
+		deletedAt = utils.Allocate(time.Now())
+	}
+	item := &entitiesTrash.Item{
+		ID:   tInfo.ID,
+		Name: tInfo.Name.String(),
+		Target: entitiesHistory.Target{
+			GameID:       tInfo.GameID,
+			CollectionID: tInfo.CollectionID,
+			DeckID:       tInfo.DeckID,
+			CardID:       tInfo.CardID,
+		},
+		HasImage:  tInfo.HasImage,
+		DeletedAt: *deletedAt,
+	}
+	if tInfo.Card != nil {
+		createdAt, updatedAt := convertCreateUpdate(tInfo.Card.CreatedAt, tInfo.Card.UpdatedAt)
+		item.Card = &entitiesCard.Card{
+			ID:          tInfo.CardID,
+			Name:        tInfo.Name.String(),
+			Description: tInfo.Card.Description.String(),
+			Image:       tInfo.Card.Image.String(),
+			Variables:   convertMapQuotedString(tInfo.Card.Variables),
+			Count:       tInfo.Card.Count,
+			CreatedAt:   createdAt,
+			UpdatedAt:   updatedAt,
+
+			GameID:       tInfo.GameID,
+			CollectionID: tInfo.CollectionID,
+			DeckID:       tInfo.DeckID,
+		}
+	}
+	return item, nil
+}
+func (d *trash) List(ctx context.Context) ([]*entitiesTrash.Item, error) {
+	list, err := d.db.List(d.trashPath)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+
+	items := make([]*entitiesTrash.Item, 0, len(list.Folders))
+	for _, folder := range list.Folders {
+		itemID, err := strconv.ParseInt(folder, 10, 64)
+		if err != nil {
+			logger.Error.Println("Corrupted trash folder:", folder)
+			continue
+		}
+		item, err := d.Get(ctx, itemID)
+		if err != nil {
+			logger.Error.Println(folder, err.Error())
+			continue
+		}
+		items = append(items, item)
+	}
+
+	// Recently deleted first
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].ID > items[j].ID
+	})
+	return items, nil
+}
+func (d *trash) Restore(ctx context.Context, req RestoreRequest) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	item, err := d.Get(ctx, req.ItemID)
+	if err != nil {
+		return err
+	}
+	name := strconv.FormatInt(item.ID, 10)
+
+	// The card itself is recreated by the caller, only the revisions are left
+	if !item.Target.IsCard() {
+		dst := d.entityPath(req.Target)
+		isExist, err := fs.IsFolderExist(dst)
+		if err != nil {
+			return err
+		}
+		if isExist {
+			return er.InternalError.AddMessage("the destination folder already exists")
+		}
+
+		err = d.move(d.itemPath(name, "data"), dst)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+
+		if req.Name != "" && req.Name != item.Name {
+			path := d.entityRelativePath(req.Target)
+			err = d.db.UpdateFolderNameWithoutTimestamp(path[len(path)-1], req.Name, path[:len(path)-1]...)
+			if err != nil {
+				return er.InternalError.AddMessage(err.Error())
+			}
+		}
+	}
+
+	err = d.restoreRevisions(name, req.Target)
+	if err != nil {
+		return err
+	}
+
+	return d.remove(item.ID)
+}
+func (d *trash) Delete(_ context.Context, itemID int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.remove(itemID)
+}
+func (d *trash) ImageGet(_ context.Context, itemID int64) ([]byte, error) {
+	data, err := d.db.GetBinary("image", d.trashPath, strconv.FormatInt(itemID, 10))
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorNotExist) {
+			return nil, er.CardImageNotExists.AddMessage(err.Error())
+		} else {
+			return nil, er.InternalError.AddMessage(err.Error())
+		}
+	}
+	return data, nil
+}
+
+func (d *trash) remove(itemID int64) error {
+	name := strconv.FormatInt(itemID, 10)
+
+	// The deleted entity keeps its images until it is purged, the restored entity has already been moved out
+	for _, folder := range []string{"data", "history"} {
+		err := d.images.ReleaseFolder(context.Background(), d.trashPath, name, folder)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := d.db.RemoveFolder(name, d.trashPath)
+	if err != nil {
+		if errors.Is(err, fsentry_error.ErrorNotExist) {
+			return er.TrashItemNotExists.AddMessage(err.Error())
+		} else if errors.Is(err, fsentry_error.ErrorBadName) {
+			return er.BadName
+		} else {
+			return er.InternalError.AddMessage(err.Error())
+		}
+	}
+	return nil
+}
+func (d *trash) restoreRevisions(name string, target entitiesHistory.Target) error {
+	src := d.itemPath(name, "history")
+	isExist, err := fs.IsFolderExist(src)
+	if err != nil || !isExist {
+		return err
+	}
+
+	// Create the folder chain for the revisions, if it does not exist yet
+	path := d.revisionsRelativePath(target)
+	for i := 1; i < len(path)-1; i++ {
+		_, err = d.db.CreateFolder(path[i], nil, path[:i]...)
+		if err != nil && !errors.Is(err, fsentry_error.ErrorExist) {
+			return er.InternalError.AddMessage(err.Error())
+		}
+	}
+
+	// The revisions left at the destination belong to an entity that no longer exists
+	err = d.images.ReleaseFolder(context.Background(), path...)
+	if err != nil {
+		return err
+	}
+	dst := d.revisionsPath(target)
+	err = fs.RemoveFolder(dst)
+	if err != nil {
+		return err
+	}
+	err = d.move(src, dst)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func (d *trash) move(src, dst string) error {
+	return os.Rename(src, dst)
+}
+func (d *trash) moveIfExist(src, dst string) error {
+	isExist, err := fs.IsFolderExist(src)
+	if err != nil || !isExist {
+		return err
+	}
+	err = d.move(src, dst)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func (d *trash) itemPath(name string, path ...string) string {
+	return filepath.Join(append([]string{d.root, d.trashPath, name}, path...)...)
+}
+
+// games/<game>/<collection>/<deck>
+func (d *trash) entityRelativePath(target entitiesHistory.Target) []string {
+	path := []string{d.gamesPath, target.GameID}
+	if target.CollectionID == "" {
+		return path
+	}
+	path = append(path, target.CollectionID)
+	if target.DeckID == "" {
+		return path
+	}
+	return append(path, target.DeckID)
+}
+func (d *trash) entityPath(target entitiesHistory.Target) string {
+	return filepath.Join(append([]string{d.root}, d.entityRelativePath(target)...)...)
+}
+
+// history/<game>/<collection>/<deck>/cards/<card>
+func (d *trash) revisionsRelativePath(target entitiesHistory.Target) []string {
+	path := []string{d.historyPath, target.GameID}
+	if target.CollectionID == "" {
+		return path
+	}
+	path = append(path, target.CollectionID)
+	if target.DeckID == "" {
+		return path
+	}
+	path = append(path, target.DeckID)
+	if target.CardID == 0 {
+		return path
+	}
+	return append(path, "cards", strconv.FormatInt(target.CardID, 10))
+}
+func (d *trash) revisionsPath(target entitiesHistory.Target) string {
+	return filepath.Join(append([]string{d.root}, d.revisionsRelativePath(target)...)...)
+}
+
+func convertCreateUpdate(createdAt, updatedAt *time.Time) (time.Time, time.Time) {
+	if createdAt == nil {
+		createdAt = utils.Allocate(time.Now())
+	}
+	if updatedAt == nil {
+		updatedAt = createdAt
+	}
+	return *createdAt, *updatedAt
+}
+func convertMapString(in map[string]string) map[string]fsentry_types.QuotedString {
+	if in == nil {
+		return nil
+	}
+	res := make(map[string]fsentry_types.QuotedString, len(in))
+	for key, value := range in {
+		res[key] = fsentry_types.QS(value)
+	}
+	return res
+}
+func convertMapQuotedString(in map[string]fsentry_types.QuotedString) map[string]string {
+	if in == nil {
+		return nil
+	}
+	res := make(map[string]string, len(in))
+	for key, value := range in {
+		res[key] = value.String()
+	}
+	return res
+}
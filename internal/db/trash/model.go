@@ -0,0 +1,27 @@
+package trash
+
+import (
+	"time"
+
+	"github.com/HardDie/fsentry/pkg/fsentry_types"
+)
+
+type model struct {
+	ID           int64                      `json:"id"`
+	Name         fsentry_types.QuotedString `json:"name"`
+	GameID       string                     `json:"gameId"`
+	CollectionID string                     `json:"collectionId,omitempty"`
+	DeckID       string                     `json:"deckId,omitempty"`
+	CardID       int64                      `json:"cardId,omitempty"`
+	Card         *cardModel                 `json:"card,omitempty"`
+	HasImage     bool                       `json:"hasImage"`
+}
+
+type cardModel struct {
+	Description fsentry_types.QuotedString            `json:"description"`
+	Image       fsentry_types.QuotedString            `json:"image"`
+	Variables   map[string]fsentry_types.QuotedString `json:"variables"`
+	Count       int                                   `json:"count"`
+	CreatedAt   *time.Time                            `json:"createdAt"`
+	UpdatedAt   *time.Time                            `json:"updatedAt"`
+}
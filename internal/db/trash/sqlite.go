@@ -0,0 +1,270 @@
+package trash
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/HardDie/fsentry/pkg/fsentry_types"
+
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
+	entitiesTrash "github.com/HardDie/DeckBuilder/internal/entities/trash"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+// Deleted games, collections and decks stay in their tables marked with the item identifier,
+// the same way as their revisions stay in the history table.
+type sqliteTrash struct {
+	db *sql.DB
+}
+
+func NewSQLite(db *sql.DB) Trash {
+	return &sqliteTrash{
+		db: db,
+	}
+}
+
+func (d *sqliteTrash) Create(ctx context.Context, req CreateRequest) (*entitiesTrash.Item, error) {
+	var card []byte
+	if req.Card != nil {
+		var err error
+		card, err = json.Marshal(cardModel{
+			Description: fsentry_types.QS(req.Card.Description),
+			Image:       fsentry_types.QS(req.Card.Image),
+			Variables:   convertMapString(req.Card.Variables),
+			Count:       req.Card.Count,
+			CreatedAt:   utils.Allocate(req.Card.CreatedAt),
+			UpdatedAt:   utils.Allocate(req.Card.UpdatedAt),
+		})
+		if err != nil {
+			return nil, er.InternalError.AddMessage(err.Error())
+		}
+	}
+
+	var itemID int64
+	err := dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `INSERT INTO trash (name, game_id, collection_id, deck_id, card_id, card, image_data, deleted_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			req.Name, req.Target.GameID, req.Target.CollectionID, req.Target.DeckID, req.Target.CardID, nullString(card), req.ImageFile, time.Now())
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		itemID, err = res.LastInsertId()
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+
+		// Revisions are kept together with the deleted entity
+		filter, args := dbSQLite.HistoryFilter(req.Target)
+		_, err = tx.ExecContext(ctx, "UPDATE history SET trash_id = ? WHERE trash_id IS NULL AND "+filter, append([]interface{}{itemID}, args...)...)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+
+		// The card itself is removed by the caller
+		if req.Target.IsCard() {
+			return nil
+		}
+
+		// The entity is detached from the parent, so it survives the purge of the parent
+		var query string
+		var rowID int64
+		switch {
+		case req.Target.IsDeck():
+			query = "UPDATE decks SET trash_id = ?, collection_id = NULL WHERE id = ?"
+			rowID, err = dbSQLite.DeckRowID(ctx, tx, req.Target.GameID, req.Target.CollectionID, req.Target.DeckID)
+		case req.Target.IsCollection():
+			query = "UPDATE collections SET trash_id = ?, game_id = NULL WHERE id = ?"
+			rowID, err = dbSQLite.CollectionRowID(ctx, tx, req.Target.GameID, req.Target.CollectionID)
+		default:
+			query = "UPDATE games SET trash_id = ? WHERE id = ?"
+			rowID, err = dbSQLite.GameRowID(ctx, tx, req.Target.GameID)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, query, itemID, rowID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Get(ctx, itemID)
+}
+func (d *sqliteTrash) Get(ctx context.Context, itemID int64) (*entitiesTrash.Item, error) {
+	row := d.db.QueryRowContext(ctx, `SELECT id, name, game_id, collection_id, deck_id, card_id, card, image_data IS NOT NULL, deleted_at
+		FROM trash WHERE id = ?`, itemID)
+	item, err := d.scan(row)
+	if err != nil {
+		if dbSQLite.IsNotFound(err) {
+			return nil, er.TrashItemNotExists
+		}
+		return nil, err
+	}
+	return item, nil
+}
+func (d *sqliteTrash) List(ctx context.Context) ([]*entitiesTrash.Item, error) {
+	// Recently deleted first
+	rows, err := d.db.QueryContext(ctx, `SELECT id, name, game_id, collection_id, deck_id, card_id, card, image_data IS NOT NULL, deleted_at
+		FROM trash ORDER BY id DESC`)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	defer func() { er.IfErrorLog(rows.Close()) }()
+
+	items := make([]*entitiesTrash.Item, 0)
+	for rows.Next() {
+		item, err := d.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	return items, nil
+}
+func (d *sqliteTrash) Restore(ctx context.Context, req RestoreRequest) error {
+	item, err := d.Get(ctx, req.ItemID)
+	if err != nil {
+		return err
+	}
+	name := req.Name
+	if name == "" {
+		name = item.Name
+	}
+
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		// The card itself is recreated by the caller, only the revisions are left
+		if !item.Target.IsCard() {
+			var query string
+			var args []interface{}
+			switch {
+			case item.Target.IsDeck():
+				parentID, err := dbSQLite.CollectionRowID(ctx, tx, req.Target.GameID, req.Target.CollectionID)
+				if err != nil {
+					return err
+				}
+				query = "UPDATE decks SET trash_id = NULL, collection_id = ?, slug = ?, name = ? WHERE trash_id = ?"
+				args = []interface{}{parentID, req.Target.DeckID, name, item.ID}
+			case item.Target.IsCollection():
+				parentID, err := dbSQLite.GameRowID(ctx, tx, req.Target.GameID)
+				if err != nil {
+					return err
+				}
+				query = "UPDATE collections SET trash_id = NULL, game_id = ?, slug = ?, name = ? WHERE trash_id = ?"
+				args = []interface{}{parentID, req.Target.CollectionID, name, item.ID}
+			default:
+				query = "UPDATE games SET trash_id = NULL, slug = ?, name = ? WHERE trash_id = ?"
+				args = []interface{}{req.Target.GameID, name, item.ID}
+			}
+			_, err := tx.ExecContext(ctx, query, args...)
+			if err != nil {
+				if dbSQLite.IsUniqueViolation(err) {
+					return er.InternalError.AddMessage("the destination already exists")
+				}
+				return er.InternalError.AddMessage(err.Error())
+			}
+		}
+
+		err := dbSQLite.MoveHistory(ctx, tx, req.Target, "trash_id = ?", item.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE history SET trash_id = NULL WHERE trash_id = ?", item.ID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM trash WHERE id = ?", item.ID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		return nil
+	})
+}
+func (d *sqliteTrash) Delete(ctx context.Context, itemID int64) error {
+	return dbSQLite.Tx(ctx, d.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM trash WHERE id = ?", itemID)
+		if err != nil {
+			return er.InternalError.AddMessage(err.Error())
+		}
+		err = dbSQLite.CheckAffected(res, er.TrashItemNotExists)
+		if err != nil {
+			return err
+		}
+
+		// Nested entities are removed by the foreign keys
+		for _, table := range []string{"games", "collections", "decks", "history"} {
+			_, err = tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE trash_id = ?", itemID)
+			if err != nil {
+				return er.InternalError.AddMessage(err.Error())
+			}
+		}
+		return nil
+	})
+}
+func (d *sqliteTrash) ImageGet(ctx context.Context, itemID int64) ([]byte, error) {
+	var data []byte
+	err := d.db.QueryRowContext(ctx, "SELECT image_data FROM trash WHERE id = ?", itemID).Scan(&data)
+	if err != nil {
+		if dbSQLite.IsNotFound(err) {
+			return nil, er.TrashItemNotExists
+		}
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	if data == nil {
+		return nil, er.CardImageNotExists
+	}
+	return data, nil
+}
+
+func (d *sqliteTrash) scan(row dbSQLite.Scanner) (*entitiesTrash.Item, error) {
+	item := &entitiesTrash.Item{}
+	var card sql.NullString
+	err := row.Scan(&item.ID, &item.Name, &item.Target.GameID, &item.Target.CollectionID, &item.Target.DeckID, &item.Target.CardID,
+		&card, &item.HasImage, &item.DeletedAt)
+	if err != nil {
+		if dbSQLite.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	if !card.Valid {
+		return item, nil
+	}
+
+	var cInfo cardModel
+	err = json.Unmarshal([]byte(card.String), &cInfo)
+	if err != nil {
+		return nil, er.InternalError.AddMessage(err.Error())
+	}
+	createdAt, updatedAt := convertCreateUpdate(cInfo.CreatedAt, cInfo.UpdatedAt)
+	item.Card = &entitiesCard.Card{
+		ID:          item.Target.CardID,
+		Name:        item.Name,
+		Description: cInfo.Description.String(),
+		Image:       cInfo.Image.String(),
+		Variables:   convertMapQuotedString(cInfo.Variables),
+		Count:       cInfo.Count,
+		CreatedAt:   createdAt,
+		UpdatedAt:   updatedAt,
+
+		GameID:       item.Target.GameID,
+		CollectionID: item.Target.CollectionID,
+		DeckID:       item.Target.DeckID,
+	}
+	return item, nil
+}
+
+func nullString(data []byte) sql.NullString {
+	return sql.NullString{String: string(data), Valid: data != nil}
+}
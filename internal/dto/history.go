@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+type Revision struct {
+	ID          int64             `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Image       string            `json:"image"`
+	Variables   map[string]string `json:"variables,omitempty"`
+	Count       int               `json:"count,omitempty"`
+	HasImage    bool              `json:"hasImage"`
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+type RevisionChange struct {
+	Field    string `json:"field"`
+	Revision string `json:"revision"`
+	Current  string `json:"current"`
+}
+
+type RevisionDiff struct {
+	Revision Revision          `json:"revision"`
+	Changes  []*RevisionChange `json:"changes"`
+}
+
+type RevisionRestore struct {
+	Game       string `json:"game"`
+	Collection string `json:"collection,omitempty"`
+	Deck       string `json:"deck,omitempty"`
+	Card       int64  `json:"card,omitempty"`
+}
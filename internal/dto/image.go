@@ -0,0 +1,24 @@
+package dto
+
+type ImageOperation struct {
+	// crop, rotate, flip, resize, fit, brightness or contrast
+	Type string `json:"type"`
+	// Crop: the rectangle to keep
+	X int `json:"x"`
+	Y int `json:"y"`
+	// Crop, resize and fit: the size of the result. For fit of card or deck image the size of the deck cards is used if not set
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Rotate: 90, 180 or 270 degrees clockwise
+	Angle int `json:"angle"`
+	// Flip: horizontal or vertical
+	Direction string `json:"direction"`
+	// Brightness and contrast: from -100 to 100
+	Percentage float64 `json:"percentage"`
+}
+
+type EditImage struct {
+	Operations []*ImageOperation `json:"operations"`
+	// Return the result without saving it
+	Preview bool `json:"preview"`
+}
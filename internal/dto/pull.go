@@ -0,0 +1,19 @@
+package dto
+
+type TTSChange struct {
+	Collection string `json:"collection"`
+	Deck       string `json:"deck"`
+	Card       int64  `json:"card"`
+	// The stored name of the card
+	CardName string `json:"cardName"`
+	// The GUID of the object in TTS
+	GUID string `json:"guid"`
+	// name, description or variable
+	Field string `json:"field"`
+	// The name of the changed variable
+	Variable string `json:"variable,omitempty"`
+	Old      string `json:"old"`
+	New      string `json:"new"`
+	// The variable was removed in TTS
+	Removed bool `json:"removed,omitempty"`
+}
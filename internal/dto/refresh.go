@@ -0,0 +1,31 @@
+package dto
+
+import "time"
+
+type RefreshItem struct {
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	Image      string `json:"image"`
+	Game       string `json:"game"`
+	Collection string `json:"collection,omitempty"`
+	Deck       string `json:"deck,omitempty"`
+	Card       int64  `json:"card,omitempty"`
+	// pending, changed, unchanged or failed
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type RefreshReport struct {
+	Game        string         `json:"game"`
+	Collection  string         `json:"collection,omitempty"`
+	Deck        string         `json:"deck,omitempty"`
+	OnlyChanged bool           `json:"onlyChanged"`
+	InProgress  bool           `json:"inProgress"`
+	Total       int            `json:"total"`
+	Changed     int            `json:"changed"`
+	Unchanged   int            `json:"unchanged"`
+	Failed      int            `json:"failed"`
+	Items       []*RefreshItem `json:"items"`
+	StartedAt   time.Time      `json:"startedAt"`
+	FinishedAt  *time.Time     `json:"finishedAt"`
+}
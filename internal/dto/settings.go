@@ -10,4 +10,11 @@ type Settings struct {
 	Lang             string   `json:"lang"`
 	EnableBackShadow bool     `json:"enable_back_shadow"`
 	CardSize         CardSize `json:"card_size"`
+	// The address of the External Editor API of the running TTS
+	TTSAddress string `json:"tts_address"`
+	// The address the messages from TTS are received on
+	TTSListenAddress string `json:"tts_listen_address"`
+	// The base URL of this application as TTS sees it
+	// The change between this computer and the address in the network requires the restart of the application
+	TTSCallbackURL string `json:"tts_callback_url"`
 }
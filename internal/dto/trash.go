@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+type TrashItem struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Original location of the entity
+	Game       string    `json:"game"`
+	Collection string    `json:"collection,omitempty"`
+	Deck       string    `json:"deck,omitempty"`
+	Card       int64     `json:"card,omitempty"`
+	DeletedAt  time.Time `json:"deletedAt"`
+}
+
+type TrashRestore struct {
+	Game       string `json:"game"`
+	Collection string `json:"collection,omitempty"`
+	Deck       string `json:"deck,omitempty"`
+	Card       int64  `json:"card,omitempty"`
+}
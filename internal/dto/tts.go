@@ -0,0 +1,65 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type TTSSent struct {
+	Token string `json:"token"`
+	// The name of the sent object
+	Name string `json:"name"`
+	Size int    `json:"size"`
+	// The message was delivered to TTS
+	Delivered bool      `json:"delivered"`
+	SentAt    time.Time `json:"sentAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// The number of downloads by TTS and the time of the last one
+	Fetches   int        `json:"fetches"`
+	FetchedAt *time.Time `json:"fetchedAt"`
+	Expired   bool       `json:"expired"`
+}
+
+type TTSStatus struct {
+	Connected     bool       `json:"connected"`
+	Listening     string     `json:"listening"`
+	LastMessageAt *time.Time `json:"lastMessageAt"`
+}
+
+type TTSTest struct {
+	// TTS is running and accepts the messages
+	Connected bool `json:"connected"`
+	// The URL TTS downloads the sent objects from
+	CallbackURL string `json:"callbackURL"`
+	// TTS could request the application by the callback URL
+	CallbackReachable bool `json:"callbackReachable"`
+}
+
+type TTSScript struct {
+	Name   string `json:"name,omitempty"`
+	GUID   string `json:"guid"`
+	Script string `json:"script"`
+	UI     string `json:"ui"`
+}
+
+type TTSExecute struct {
+	// The GUID of the object the code is run on, empty - the global script
+	GUID   string `json:"guid"`
+	Script string `json:"script"`
+}
+
+type TTSExecuteResult struct {
+	// The value returned by the code, null if the code returned nothing
+	Result json.RawMessage `json:"result"`
+}
+
+type TTSEvent struct {
+	// pushObject, newGame, print, error, customMessage, return, gameSaved or objectCreated
+	Type          string          `json:"type"`
+	Message       string          `json:"message,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	GUID          string          `json:"guid,omitempty"`
+	Scripts       []TTSScript     `json:"scripts,omitempty"`
+	CustomMessage json.RawMessage `json:"customMessage,omitempty"`
+	ReceivedAt    time.Time       `json:"receivedAt"`
+}
@@ -10,6 +10,7 @@ type Card struct {
 	Name        string
 	Description string
 	Image       string
+	ImageHash   string
 	Variables   map[string]string
 	Count       int
 	CreatedAt   time.Time
@@ -10,6 +10,7 @@ type Game struct {
 	Name        string
 	Description string
 	Image       string
+	ImageHash   string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
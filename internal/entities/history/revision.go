@@ -0,0 +1,60 @@
+package history
+
+import "time"
+
+// Target points to the entity the revisions belong to.
+// The type of the entity is determined by the deepest filled identifier.
+type Target struct {
+	GameID       string
+	CollectionID string
+	DeckID       string
+	CardID       int64
+}
+
+func (t Target) IsGame() bool {
+	return t.CollectionID == ""
+}
+func (t Target) IsCollection() bool {
+	return t.CollectionID != "" && t.DeckID == ""
+}
+func (t Target) IsDeck() bool {
+	return t.DeckID != "" && t.CardID == 0
+}
+func (t Target) IsCard() bool {
+	return t.CardID != 0
+}
+
+// Rename returns the target of the same entity under the new identifier.
+// Cards are never renamed, their target is returned as is.
+func (t Target) Rename(newID string) Target {
+	switch {
+	case t.IsDeck():
+		t.DeckID = newID
+	case t.IsCollection():
+		t.CollectionID = newID
+	case t.IsGame():
+		t.GameID = newID
+	}
+	return t
+}
+
+// Revision is a snapshot of the entity state before it was changed
+type Revision struct {
+	ID          int64
+	Name        string
+	Description string
+	Image       string
+	// Only for cards
+	Variables map[string]string
+	Count     int
+	// Whether the previous image binary was saved together with the revision
+	HasImage  bool
+	CreatedAt time.Time
+}
+
+// Change is a single field that differs between the revision and the current state
+type Change struct {
+	Field    string
+	Revision string
+	Current  string
+}
@@ -0,0 +1,60 @@
+package refresh
+
+import (
+	"time"
+
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesTrash "github.com/HardDie/DeckBuilder/internal/entities/trash"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusChanged   = "changed"
+	StatusUnchanged = "unchanged"
+	StatusFailed    = "failed"
+)
+
+// Item is an entity whose image is downloaded again from its URL
+type Item struct {
+	Target entitiesHistory.Target
+	Name   string
+	Image  string
+	Status string
+	// Why the image could not be refreshed
+	Error string
+}
+
+func (i Item) Type() string {
+	switch {
+	case i.Target.IsCard():
+		return entitiesTrash.TypeCard
+	case i.Target.IsDeck():
+		return entitiesTrash.TypeDeck
+	case i.Target.IsCollection():
+		return entitiesTrash.TypeCollection
+	default:
+		return entitiesTrash.TypeGame
+	}
+}
+
+// Report is the result of the last refresh of the images
+type Report struct {
+	// The game, collection or deck whose images are refreshed together with all nested entities
+	Target      entitiesHistory.Target
+	OnlyChanged bool
+	InProgress  bool
+	Items       []*Item
+	StartedAt   time.Time
+	FinishedAt  *time.Time
+}
+
+// Count returns the number of items with the status
+func (r *Report) Count(status string) int {
+	count := 0
+	for _, item := range r.Items {
+		if item.Status == status {
+			count++
+		}
+	}
+	return count
+}
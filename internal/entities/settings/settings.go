@@ -10,6 +10,10 @@ type Settings struct {
 	Lang             string
 	EnableBackShadow bool
 	CardSize         CardSize
+	// The addresses of TTS and the callback URL, the flags set the default values
+	TTSAddress       string
+	TTSListenAddress string
+	TTSCallbackURL   string
 }
 
 func Default() Settings {
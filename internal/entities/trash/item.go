@@ -0,0 +1,41 @@
+package trash
+
+import (
+	"time"
+
+	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+)
+
+const (
+	TypeGame       = "game"
+	TypeCollection = "collection"
+	TypeDeck       = "deck"
+	TypeCard       = "card"
+)
+
+// Item is a deleted entity together with its original location
+type Item struct {
+	ID   int64
+	Name string
+	// Where the entity was located before it was deleted
+	Target entitiesHistory.Target
+	// Only for cards. Folders are moved into the trash as is, but the card is a part of the deck file
+	Card     *entitiesCard.Card
+	HasImage bool
+
+	DeletedAt time.Time
+}
+
+func (i Item) Type() string {
+	switch {
+	case i.Target.IsCard():
+		return TypeCard
+	case i.Target.IsDeck():
+		return TypeDeck
+	case i.Target.IsCollection():
+		return TypeCollection
+	default:
+		return TypeGame
+	}
+}
@@ -10,11 +10,14 @@ import (
 var (
 	// system
 	InternalError = NewError("internal error")
+	DataLocked    = NewError("the data folder is used by another instance of the application")
+	SchemaTooNew  = NewError("the data was created by a newer version of the application")
 
 	// network errors
 	NetworkBadURL      = NewError("bad url", http.StatusBadRequest)
 	NetworkBadRequest  = NewError("bad http request", http.StatusBadRequest)
 	NetworkBadResponse = NewError("bad http response", http.StatusBadRequest)
+	UploadFailed       = NewError("image upload failed", http.StatusBadRequest)
 
 	BadName = NewError("bad name", http.StatusBadRequest)
 	BadId   = NewError("bad id", http.StatusBadRequest)
@@ -45,18 +48,43 @@ var (
 	CardImageExist     = NewError("card image already exists", http.StatusBadRequest)
 	CardImageNotExists = NewError("card image not exists", http.StatusBadRequest)
 
+	// history
+	RevisionNotExists      = NewError("revision not exists", http.StatusBadRequest)
+	RevisionImageNotExists = NewError("revision image not exists", http.StatusBadRequest)
+
+	// trash
+	TrashItemNotExists = NewError("trash item not exists", http.StatusBadRequest)
+
+	// refresh
+	RefreshInProgress = NewError("the images are already being refreshed", http.StatusBadRequest)
+	RefreshNotExists  = NewError("the images have not been refreshed yet", http.StatusBadRequest)
+
 	// settings
 	SettingsNotExists = NewError("settings file not exists", http.StatusBadRequest)
 
 	// image
-	UnknownImageType = NewError("unknown image type").HTTP(http.StatusBadRequest)
+	UnknownImageType  = NewError("unknown image type").HTTP(http.StatusBadRequest)
+	ImageTooLarge     = NewError("image is too large").HTTP(http.StatusBadRequest)
+	BadImageOperation = NewError("bad image operation").HTTP(http.StatusBadRequest)
+	BadThumbnailSize  = NewError("bad thumbnail size").HTTP(http.StatusBadRequest)
 
 	// zip
-	BadArchive = NewError("bad zip archive").HTTP(http.StatusBadRequest)
+	BadArchive      = NewError("bad zip archive").HTTP(http.StatusBadRequest)
+	ArchiveTooLarge = NewError("archive is too large").HTTP(http.StatusRequestEntityTooLarge)
 
 	// replace
 	ErrorInvalidDeckDescription = NewError("invalid deck description").HTTP(http.StatusBadRequest)
 	ErrorInvalidMapping         = NewError("invalid mapping file").HTTP(http.StatusBadRequest)
+
+	// tts
+	TTSNotConnected      = NewError("tts is not running or the game is not loaded").HTTP(http.StatusServiceUnavailable)
+	TTSTimeout           = NewError("tts did not answer in time").HTTP(http.StatusGatewayTimeout)
+	TTSScriptError       = NewError("tts script error").HTTP(http.StatusBadRequest)
+	TTSBadMode           = NewError("unknown tts update mode").HTTP(http.StatusBadRequest)
+	TTSManifestNotExists = NewError("the game was not generated, there is nothing to compare with").HTTP(http.StatusBadRequest)
+	TTSDataNotExists     = NewError("the data was not sent to tts or has expired").HTTP(http.StatusNotFound)
+	TTSQueueFull         = NewError("too many objects are waiting to be downloaded by tts").HTTP(http.StatusTooManyRequests)
+	TTSBadSettings       = NewError("bad tts address or callback url").HTTP(http.StatusBadRequest)
 )
 
 type Err struct {
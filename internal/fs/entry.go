@@ -0,0 +1,447 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/HardDie/fsentry"
+	"github.com/HardDie/fsentry/pkg/fsentry_error"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+const stagePattern = ".stage*"
+
+// atomicEntry runs every write of the fsentry storage in a temporary folder and moves the written files into place
+// with a rename, so a crash never leaves a partially written card, image or info of the game, collection or deck.
+// The files are written and named by fsentry, they are only copied and renamed here.
+// The info of the folder has the internal type of fsentry, so it is the type parameter inferred from GetFolder.
+type atomicEntry[Info any] struct {
+	fsentry.IFSEntry
+
+	root string
+	opts []func(fs *fsentry.FSEntry)
+	m    sync.Mutex
+}
+
+func NewFSEntry(root string, isPretty bool) fsentry.IFSEntry {
+	var opts []func(fs *fsentry.FSEntry)
+	if isPretty {
+		opts = append(opts, fsentry.WithPretty())
+	}
+	db := fsentry.NewFSEntry(root, opts...)
+	return newAtomicEntry(db, db.GetFolder, root, opts)
+}
+func newAtomicEntry[Info any](
+	db fsentry.IFSEntry,
+	_ func(name string, path ...string) (Info, error),
+	root string,
+	opts []func(fs *fsentry.FSEntry),
+) *atomicEntry[Info] {
+	return &atomicEntry[Info]{
+		IFSEntry: db,
+		root:     root,
+		opts:     opts,
+	}
+}
+
+// Basic
+
+func (e *atomicEntry[Info]) Init() error {
+	err := e.IFSEntry.Init()
+	if err != nil {
+		return err
+	}
+	// The stages left after a crash are never committed
+	stages, err := filepath.Glob(filepath.Join(e.root, stagePattern))
+	if err != nil {
+		return fsentry_error.Wrap(err, fsentry_error.ErrorInternal)
+	}
+	for _, dir := range stages {
+		errors.IfErrorLog(os.RemoveAll(dir))
+	}
+	return nil
+}
+
+// Folder
+
+func (e *atomicEntry[Info]) CreateFolder(name string, data interface{}, path ...string) (Info, error) {
+	var info Info
+	err := e.staged(path, func(s *stage) error {
+		res, err := s.IFSEntry.CreateFolder(name, data)
+		info = e.info(res)
+		return err
+	})
+	return info, err
+}
+func (e *atomicEntry[Info]) MoveFolder(oldName, newName string, path ...string) (Info, error) {
+	var info Info
+	err := e.staged(path, func(s *stage) error {
+		err := s.load(func() error {
+			_, err := s.IFSEntry.CreateFolder(oldName, nil)
+			return err
+		}, false)
+		if err != nil {
+			return err
+		}
+		res, err := s.IFSEntry.MoveFolder(oldName, newName)
+		info = e.info(res)
+		return err
+	})
+	return info, err
+}
+func (e *atomicEntry[Info]) UpdateFolder(name string, data interface{}, path ...string) (Info, error) {
+	var info Info
+	err := e.staged(path, func(s *stage) error {
+		err := s.load(func() error {
+			_, err := s.IFSEntry.CreateFolder(name, nil)
+			return err
+		}, false)
+		if err != nil {
+			return err
+		}
+		res, err := s.IFSEntry.UpdateFolder(name, data)
+		info = e.info(res)
+		return err
+	})
+	return info, err
+}
+func (e *atomicEntry[Info]) RemoveFolder(name string, path ...string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.IFSEntry.RemoveFolder(name, path...)
+}
+func (e *atomicEntry[Info]) DuplicateFolder(srcName, dstName string, path ...string) (Info, error) {
+	var info Info
+	err := e.staged(path, func(s *stage) error {
+		// The copy appears in the target folder at once with all its files
+		err := s.load(func() error {
+			_, err := s.IFSEntry.CreateFolder(srcName, nil)
+			return err
+		}, true)
+		if err != nil {
+			return err
+		}
+		res, err := s.IFSEntry.DuplicateFolder(srcName, dstName)
+		info = e.info(res)
+		return err
+	})
+	return info, err
+}
+func (e *atomicEntry[Info]) UpdateFolderNameWithoutTimestamp(name, newName string, path ...string) error {
+	return e.staged(path, func(s *stage) error {
+		err := s.load(func() error {
+			_, err := s.IFSEntry.CreateFolder(name, nil)
+			return err
+		}, false)
+		if err != nil {
+			return err
+		}
+		return s.IFSEntry.UpdateFolderNameWithoutTimestamp(name, newName)
+	})
+}
+
+// Entry
+
+func (e *atomicEntry[Info]) CreateEntry(name string, data interface{}, path ...string) error {
+	return e.staged(path, func(s *stage) error {
+		return s.IFSEntry.CreateEntry(name, data)
+	})
+}
+func (e *atomicEntry[Info]) MoveEntry(oldName, newName string, path ...string) error {
+	// The old entry is removed only after the new one has been written
+	return e.staged(path, func(s *stage) error {
+		err := s.load(func() error {
+			return s.IFSEntry.CreateEntry(oldName, nil)
+		}, false)
+		if err != nil {
+			return err
+		}
+		return s.IFSEntry.MoveEntry(oldName, newName)
+	})
+}
+func (e *atomicEntry[Info]) UpdateEntry(name string, data interface{}, path ...string) error {
+	return e.staged(path, func(s *stage) error {
+		err := s.load(func() error {
+			return s.IFSEntry.CreateEntry(name, nil)
+		}, false)
+		if err != nil {
+			return err
+		}
+		return s.IFSEntry.UpdateEntry(name, data)
+	})
+}
+func (e *atomicEntry[Info]) RemoveEntry(name string, path ...string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.IFSEntry.RemoveEntry(name, path...)
+}
+func (e *atomicEntry[Info]) DuplicateEntry(srcName, dstName string, path ...string) error {
+	return e.staged(path, func(s *stage) error {
+		err := s.load(func() error {
+			return s.IFSEntry.CreateEntry(srcName, nil)
+		}, true)
+		if err != nil {
+			return err
+		}
+		return s.IFSEntry.DuplicateEntry(srcName, dstName)
+	})
+}
+
+// Binary
+
+func (e *atomicEntry[Info]) CreateBinary(name string, data []byte, path ...string) error {
+	return e.staged(path, func(s *stage) error {
+		return s.IFSEntry.CreateBinary(name, data)
+	})
+}
+func (e *atomicEntry[Info]) MoveBinary(oldName, newName string, path ...string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.IFSEntry.MoveBinary(oldName, newName, path...)
+}
+func (e *atomicEntry[Info]) UpdateBinary(name string, data []byte, path ...string) error {
+	return e.staged(path, func(s *stage) error {
+		err := s.load(func() error {
+			return s.IFSEntry.CreateBinary(name, nil)
+		}, false)
+		if err != nil {
+			return err
+		}
+		return s.IFSEntry.UpdateBinary(name, data)
+	})
+}
+func (e *atomicEntry[Info]) RemoveBinary(name string, path ...string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.IFSEntry.RemoveBinary(name, path...)
+}
+
+// util
+
+// info converts the info returned by the stage, it has the same type as the info of the storage
+func (e *atomicEntry[Info]) info(res any) Info {
+	info, _ := res.(Info)
+	return info
+}
+
+// staged runs the write in the new stage of the folder and commits the written files
+func (e *atomicEntry[Info]) staged(path []string, cb func(s *stage) error) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	s, err := e.newStage(path...)
+	if err != nil {
+		return err
+	}
+	defer func() { errors.IfErrorLog(os.RemoveAll(s.dir)) }()
+
+	err = cb(s)
+	if err != nil {
+		return err
+	}
+	return s.commit()
+}
+
+// stage is the fsentry storage in the temporary folder, the files of the target folder are copied into it before the change.
+// The temporary folder is hidden in the root of the storage, so the rename never crosses the file systems.
+type stage struct {
+	fsentry.IFSEntry
+
+	dir    string
+	target string
+	// The copied files and folders, the folders copied as a whole are only read and never written back
+	loaded map[string]bool
+}
+
+func (e *atomicEntry[Info]) newStage(path ...string) (*stage, error) {
+	target := filepath.Join(append([]string{e.root}, path...)...)
+	stat, err := os.Stat(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fsentry_error.ErrorBadPath
+		}
+		return nil, fsentry_error.Wrap(err, fsentry_error.ErrorInternal)
+	}
+	if !stat.IsDir() {
+		return nil, fsentry_error.ErrorBadPath
+	}
+
+	dir, err := os.MkdirTemp(e.root, stagePattern)
+	if err != nil {
+		return nil, fsentry_error.Wrap(err, fsentry_error.ErrorInternal)
+	}
+	return &stage{
+		IFSEntry: fsentry.NewFSEntry(dir, e.opts...),
+		dir:      dir,
+		target:   target,
+		loaded:   make(map[string]bool),
+	}, nil
+}
+
+// load copies the entry, binary or folder from the target folder into the stage. The probe creates the same item
+// in the stage, so the file name is chosen by fsentry. The source of the duplicate is copied with all its files
+// and never written back, otherwise only the info of the folder is copied.
+func (s *stage) load(probe func() error, source bool) error {
+	before, err := s.items()
+	if err != nil {
+		return err
+	}
+	err = probe()
+	if err != nil {
+		return err
+	}
+	after, err := s.items()
+	if err != nil {
+		return err
+	}
+
+	for name := range after {
+		if _, ok := before[name]; ok {
+			continue
+		}
+		src, dst := filepath.Join(s.target, name), filepath.Join(s.dir, name)
+		if err = checkType(src, after[name]); err != nil {
+			return err
+		}
+
+		switch {
+		case !after[name]:
+			err = replaceCopy(src, dst)
+		case source:
+			err = os.RemoveAll(dst)
+			if err == nil {
+				err = CopyFolder(src, dst)
+			}
+		default:
+			var files []os.DirEntry
+			files, err = os.ReadDir(dst)
+			for i := 0; err == nil && i < len(files); i++ {
+				err = replaceCopy(filepath.Join(src, files[i].Name()), filepath.Join(dst, files[i].Name()))
+			}
+		}
+		if err != nil {
+			return fsentry_error.Wrap(err, fsentry_error.ErrorInternal)
+		}
+		s.loaded[name] = !source
+	}
+	return nil
+}
+
+// commit moves the written files into the target folder. The new items must not exist in the target folder,
+// the loaded files removed by fsentry are removed from the target folder and the renamed folder is renamed.
+func (s *stage) commit() error {
+	items, err := s.items()
+	if err != nil {
+		return err
+	}
+
+	var created, removed []string
+	for name := range items {
+		if _, ok := s.loaded[name]; !ok {
+			created = append(created, name)
+		}
+	}
+	for name, write := range s.loaded {
+		if _, ok := items[name]; !ok && write {
+			removed = append(removed, name)
+		}
+	}
+	for _, name := range created {
+		_, err = os.Lstat(filepath.Join(s.target, name))
+		if err == nil {
+			return fsentry_error.ErrorExist
+		}
+		if !os.IsNotExist(err) {
+			return fsentry_error.Wrap(err, fsentry_error.ErrorInternal)
+		}
+	}
+
+	// The folder is renamed with all its files, only the info is written below
+	if len(created) == 1 && len(removed) == 1 && items[created[0]] {
+		err = os.Rename(filepath.Join(s.target, removed[0]), filepath.Join(s.target, created[0]))
+		if err != nil {
+			return fsentry_error.Wrap(err, fsentry_error.ErrorInternal)
+		}
+		s.loaded[created[0]] = true
+		removed = nil
+	}
+
+	for name, isDir := range items {
+		write, ok := s.loaded[name]
+		switch {
+		case ok && !write:
+			continue
+		case ok && isDir:
+			err = moveFiles(filepath.Join(s.dir, name), filepath.Join(s.target, name))
+		default:
+			err = os.Rename(filepath.Join(s.dir, name), filepath.Join(s.target, name))
+		}
+		if err != nil {
+			return fsentry_error.Wrap(err, fsentry_error.ErrorInternal)
+		}
+	}
+	for _, name := range removed {
+		err = os.Remove(filepath.Join(s.target, name))
+		if err != nil {
+			return fsentry_error.Wrap(err, fsentry_error.ErrorInternal)
+		}
+	}
+	return nil
+}
+
+// items returns the files and folders of the stage: name = is folder
+func (s *stage) items() (map[string]bool, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fsentry_error.Wrap(err, fsentry_error.ErrorInternal)
+	}
+	res := make(map[string]bool)
+	for _, file := range files {
+		res[file.Name()] = file.IsDir()
+	}
+	return res, nil
+}
+
+// checkType checks the item of the target folder exists and has the same type as the probe
+func checkType(path string, isDir bool) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fsentry_error.ErrorNotExist
+		}
+		return fsentry_error.Wrap(err, fsentry_error.ErrorInternal)
+	}
+	if stat.IsDir() != isDir {
+		return fsentry_error.ErrorBadPath
+	}
+	return nil
+}
+
+// replaceCopy replaces the file written by the probe with the copy of the file
+func replaceCopy(src, dst string) error {
+	err := os.Remove(dst)
+	if err != nil {
+		return err
+	}
+	return copyFile(src, dst)
+}
+
+// moveFiles moves the files of the folder into the other folder, the nested folders are not moved
+func moveFiles(src, dst string) error {
+	files, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		err = os.Rename(filepath.Join(src, file.Name()), filepath.Join(dst, file.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
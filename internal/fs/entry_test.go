@@ -0,0 +1,130 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HardDie/fsentry"
+	"github.com/HardDie/fsentry/pkg/fsentry_error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicEntry(t *testing.T) {
+	dir, err := os.MkdirTemp("", "entry_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	db := NewFSEntry(dir, true)
+	assert.NoError(t, db.Init())
+	// The files are read back by the plain fsentry
+	plain := fsentry.NewFSEntry(dir, fsentry.WithPretty())
+
+	type data struct {
+		Value string `json:"value"`
+	}
+
+	t.Run("entry", func(t *testing.T) {
+		assert.NoError(t, db.CreateEntry("First card", data{Value: "one"}))
+		assert.ErrorIs(t, db.CreateEntry("first card", data{}), fsentry_error.ErrorExist)
+		assert.ErrorIs(t, db.CreateEntry("card", data{}, "unknown"), fsentry_error.ErrorBadPath)
+		assert.ErrorIs(t, db.CreateEntry("con", data{}), fsentry_error.ErrorBadName)
+
+		entry, err := plain.GetEntry("First card")
+		assert.NoError(t, err)
+		assert.Equal(t, "first_card", entry.Id)
+		assert.Equal(t, "First card", entry.Name)
+		assert.NotNil(t, entry.CreatedAt)
+		assert.Nil(t, entry.UpdatedAt)
+
+		assert.NoError(t, db.UpdateEntry("First card", data{Value: "two"}))
+		entry, err = plain.GetEntry("First card")
+		assert.NoError(t, err)
+		assert.NotNil(t, entry.UpdatedAt)
+		assert.JSONEq(t, `{"value":"two"}`, string(entry.Data))
+
+		assert.NoError(t, db.DuplicateEntry("First card", "Second card"))
+		entry, err = plain.GetEntry("Second card")
+		assert.NoError(t, err)
+		assert.Nil(t, entry.UpdatedAt)
+		assert.JSONEq(t, `{"value":"two"}`, string(entry.Data))
+
+		assert.ErrorIs(t, db.MoveEntry("First card", "Second card"), fsentry_error.ErrorExist)
+		assert.NoError(t, db.MoveEntry("First card", "Third card"))
+		_, err = plain.GetEntry("First card")
+		assert.ErrorIs(t, err, fsentry_error.ErrorNotExist)
+		entry, err = plain.GetEntry("Third card")
+		assert.NoError(t, err)
+		assert.Equal(t, "third_card", entry.Id)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		assert.NoError(t, db.CreateBinary("image", []byte("one")))
+		assert.ErrorIs(t, db.CreateBinary("image", []byte("one")), fsentry_error.ErrorExist)
+		assert.ErrorIs(t, db.UpdateBinary("unknown", []byte("one")), fsentry_error.ErrorNotExist)
+
+		assert.NoError(t, db.UpdateBinary("image", []byte("two")))
+		bin, err := plain.GetBinary("image")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("two"), bin)
+	})
+
+	t.Run("folder", func(t *testing.T) {
+		info, err := db.CreateFolder("First game", data{Value: "one"})
+		assert.NoError(t, err)
+		assert.Equal(t, "first_game", info.Id)
+		_, err = db.CreateFolder("first game", data{})
+		assert.ErrorIs(t, err, fsentry_error.ErrorExist)
+		_, err = db.UpdateFolder("unknown", data{})
+		assert.ErrorIs(t, err, fsentry_error.ErrorNotExist)
+		assert.NoError(t, db.CreateEntry("card", data{Value: "card"}, "first_game"))
+
+		info, err = db.UpdateFolder("First game", data{Value: "two"})
+		assert.NoError(t, err)
+		assert.NotNil(t, info.UpdatedAt)
+		info, err = plain.GetFolder("First game")
+		assert.NoError(t, err)
+		assert.NotNil(t, info.CreatedAt)
+		assert.JSONEq(t, `{"value":"two"}`, string(info.Data))
+
+		// The folder is copied and renamed with all files
+		info, err = db.DuplicateFolder("First game", "Second game")
+		assert.NoError(t, err)
+		assert.Equal(t, "Second game", info.Name.String())
+		_, err = plain.GetEntry("card", "second_game")
+		assert.NoError(t, err)
+
+		_, err = db.MoveFolder("First game", "Second game")
+		assert.ErrorIs(t, err, fsentry_error.ErrorExist)
+		info, err = db.MoveFolder("First game", "Third game")
+		assert.NoError(t, err)
+		assert.Equal(t, "third_game", info.Id)
+		_, err = plain.GetFolder("First game")
+		assert.ErrorIs(t, err, fsentry_error.ErrorNotExist)
+		entry, err := plain.GetEntry("card", "third_game")
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"value":"card"}`, string(entry.Data))
+
+		assert.NoError(t, db.UpdateFolderNameWithoutTimestamp("Third game", "Renamed game"))
+		info, err = plain.GetFolder("Third game")
+		assert.NoError(t, err)
+		assert.Equal(t, "Renamed game", info.Name.String())
+	})
+
+	// The stage left after a crash is removed on start
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, ".stage1"), DirPerm))
+	assert.NoError(t, db.Init())
+
+	// No temporary files are left
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	assert.ElementsMatch(t, []string{"second_card.json", "third_card.json", "image.bin", "second_game", "third_game"}, names)
+}
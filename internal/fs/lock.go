@@ -0,0 +1,46 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+const (
+	LockFile = ".lock"
+)
+
+// LockFolder takes an exclusive lock on the folder, so only one instance of the application can use it.
+// The lock is released by the returned function or by the system when the process exits.
+// The function must be kept until the lock is released, otherwise the garbage collector closes the file and releases the lock.
+func LockFolder(path string) (func(), error) {
+	err := CreateFolderIfNotExist(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(path, LockFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.InternalError.AddMessage(err.Error())
+	}
+
+	err = lockFile(file)
+	if err != nil {
+		errors.IfErrorLog(file.Close())
+		return nil, err
+	}
+
+	// The process ID helps to find the instance that holds the lock
+	err = file.Truncate(0)
+	if err == nil {
+		_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	errors.IfErrorLog(err)
+
+	return func() {
+		errors.IfErrorLog(unlockFile(file))
+		errors.IfErrorLog(file.Close())
+	}, nil
+}
@@ -0,0 +1,54 @@
+package fs
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+func TestLockFolder(t *testing.T) {
+	dir, err := os.MkdirTemp("", "lock_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	unlock, err := LockFolder(dir)
+	assert.NoError(t, err)
+
+	// The second instance is rejected
+	_, err = LockFolder(dir)
+	assert.ErrorIs(t, err, errors.DataLocked)
+
+	// The folder can be locked again after the release
+	unlock()
+	unlock, err = LockFolder(dir)
+	assert.NoError(t, err)
+	unlock()
+}
+
+func TestLockFolderGC(t *testing.T) {
+	dir, err := os.MkdirTemp("", "lock_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	unlock, err := LockFolder(dir)
+	assert.NoError(t, err)
+	defer unlock()
+
+	// The lock file is not closed by the finalizer while the function is kept
+	runtime.GC()
+	runtime.GC()
+	_, err = LockFolder(dir)
+	assert.ErrorIs(t, err, errors.DataLocked)
+}
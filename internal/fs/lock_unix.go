@@ -0,0 +1,25 @@
+//go:build !windows
+
+package fs
+
+import (
+	stdErrors "errors"
+	"os"
+	"syscall"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+func lockFile(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		if stdErrors.Is(err, syscall.EWOULDBLOCK) {
+			return errors.DataLocked
+		}
+		return errors.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
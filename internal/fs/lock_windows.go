@@ -0,0 +1,26 @@
+//go:build windows
+
+package fs
+
+import (
+	stdErrors "errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+func lockFile(file *os.File) error {
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &windows.Overlapped{})
+	if err != nil {
+		if stdErrors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+			return errors.DataLocked
+		}
+		return errors.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+func unlockFile(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
@@ -0,0 +1,64 @@
+package fs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+const (
+	// ManifestFile is stored in the root of the game archive next to the game folder
+	ManifestFile = "manifest.json"
+	// ArchiveFormatVersion is increased on every incompatible change of the archive layout.
+	// Version 2: the images are stored once in the ImagesFolder, the entities refer to them by the hash.
+	ArchiveFormatVersion = 2
+	// ImagesFolder is stored inside the game folder and contains the images of the game as <hash>.bin
+	ImagesFolder = ".images"
+
+	// Protection against zip bombs
+	maxArchiveFiles     = 1000000
+	maxCompressionRatio = 1000
+	maxManifestSize     = 256 << 20
+)
+
+type ManifestCounts struct {
+	Collections int `json:"collections"`
+	Decks       int `json:"decks"`
+	Cards       int `json:"cards"`
+}
+
+// Manifest describes the content of the game archive
+type Manifest struct {
+	FormatVersion int            `json:"formatVersion"`
+	AppVersion    string         `json:"appVersion"`
+	GameID        string         `json:"gameId"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	Counts        ManifestCounts `json:"counts"`
+	// The sha256 of every file, the key is the path inside the archive
+	Files map[string]string `json:"files"`
+}
+
+// Checking that the manifest is supported and describes exactly the files of the archive
+func (m *Manifest) check(gameID string, files map[string]struct{}) error {
+	if m.FormatVersion < 1 {
+		return errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: unknown format version %d", m.FormatVersion))
+	}
+	if m.FormatVersion > ArchiveFormatVersion {
+		return errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: the format version %d is not supported, the archive was created by a newer version of the application", m.FormatVersion))
+	}
+	if m.GameID != gameID {
+		return errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: the manifest describes the game %q, but the archive contains %q", m.GameID, gameID))
+	}
+	for name := range files {
+		if _, ok := m.Files[name]; !ok {
+			return errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: the file %q is not listed in the manifest", name))
+		}
+	}
+	for name := range m.Files {
+		if _, ok := files[name]; !ok {
+			return errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: the file %q from the manifest is missing", name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package fs
+
+import (
+	"github.com/HardDie/DeckBuilder/internal/progress"
+)
+
+const (
+	// Only large archives report their progress, small ones are processed almost instantly
+	progressMinSize = 32 << 20
+)
+
+// archiveProgress counts the processed bytes of an archive and reports the percentage as the status of the application
+type archiveProgress struct {
+	total     int64
+	processed int64
+	percent   int
+}
+
+func newArchiveProgress(title, gameID string, total int64) *archiveProgress {
+	if total < progressMinSize {
+		return nil
+	}
+
+	pr := progress.GetProgress()
+	pr.SetType(title)
+	pr.SetMessage(gameID)
+	pr.SetProgress(0)
+	pr.SetStatus(progress.StatusInProgress)
+	return &archiveProgress{
+		total: total,
+	}
+}
+
+func (p *archiveProgress) Write(data []byte) (int, error) {
+	if p == nil {
+		return len(data), nil
+	}
+
+	p.processed += int64(len(data))
+	// Update the status only when the percentage changes
+	percent := int(p.processed * 100 / p.total)
+	if percent != p.percent {
+		p.percent = percent
+		progress.GetProgress().SetProgress(float32(percent))
+	}
+	return len(data), nil
+}
+
+func (p *archiveProgress) Finish(err error) {
+	if p == nil {
+		return
+	}
+
+	pr := progress.GetProgress()
+	if err != nil {
+		pr.SetMessage(err.Error())
+		pr.SetStatus(progress.StatusError)
+		return
+	}
+	pr.SetProgress(100)
+	pr.SetStatus(progress.StatusDone)
+}
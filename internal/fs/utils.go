@@ -74,6 +74,79 @@ func CreateAndProcess[T any](path string, in T, cb func(w io.Writer, in T) error
 	return cb(file, in)
 }
 
+// ReplaceFile writes the data into a temporary file next to the destination and replaces the destination with it,
+// so the file is never left partially written
+func ReplaceFile(path string, cb func(w io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		errors.IfErrorLog(err)
+		return errors.InternalError.AddMessage(err.Error())
+	}
+	tmpPath := file.Name()
+
+	err = cb(file)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		errors.IfErrorLog(os.Remove(tmpPath))
+		return errors.InternalError.AddMessage(err.Error())
+	}
+	return nil
+}
+
+// CopyFolder recursively copies the folder with all files, the destination must not exist
+func CopyFolder(src, dst string) error {
+	err := filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, DirPerm)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		default:
+			// Symbolic links and special files are not part of the data
+			return nil
+		}
+	})
+	if err != nil {
+		errors.IfErrorLog(err)
+		return errors.InternalError.AddMessage("Error copying folder: " + err.Error())
+	}
+	return nil
+}
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { errors.IfErrorLog(in.Close()) }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
 func PathToAbsolutePath(path string) string {
 	res, err := filepath.Abs(path)
 	if err != nil {
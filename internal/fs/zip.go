@@ -2,24 +2,53 @@ package fs
 
 import (
 	"archive/zip"
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/HardDie/DeckBuilder/internal/config"
 	"github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/logger"
 	"github.com/HardDie/DeckBuilder/internal/utils"
 )
 
-func ArchiveFolder(gamePath, gameID string) (data []byte, err error) {
-	// Create buffer for result archive
-	buf := bytes.Buffer{}
+// ArchiveFolder packs the game folder and writes the zip archive into w as it is created,
+// so the whole archive is never held in memory. The root folder of the archive is named after manifest.GameID.
+// The manifest is completed with the hashes of the packed files and written at the end of the archive.
+// The images are stored outside the game folder, they are packed into the ImagesFolder, the key of images is the hash
+// and the value is the path to the image file.
+// limit is the maximum total size of the packed files in bytes, 0 - unlimited.
+func ArchiveFolder(w io.Writer, gamePath string, images map[string]string, manifest Manifest, limit int64) (err error) {
+	// Calculate the size of the game before writing anything, so the error can still be returned to the user
+	total, err := folderSize(gamePath)
+	if err != nil {
+		return err
+	}
+	imagesSize, err := filesSize(images)
+	if err != nil {
+		return err
+	}
+	total += imagesSize
+	if limit > 0 && total > limit {
+		return errors.ArchiveTooLarge.AddMessage(fmt.Sprintf("The game takes %d bytes, the export limit is %d bytes", total, limit))
+	}
+
+	pr := newArchiveProgress("Export game", manifest.GameID, total)
+	defer func() { pr.Finish(err) }()
+
+	manifest.FormatVersion = ArchiveFormatVersion
+	manifest.CreatedAt = time.Now()
+	manifest.Files = make(map[string]string)
 
 	// Create zip processor
-	zipWriter := zip.NewWriter(&buf)
+	zipWriter := zip.NewWriter(w)
 	defer func() {
 		if zipWriter != nil {
 			errors.IfErrorLog(zipWriter.Close())
@@ -27,43 +56,83 @@ func ArchiveFolder(gamePath, gameID string) (data []byte, err error) {
 	}()
 
 	// Add all filed to archive
-	err = recursiveWalk(zipWriter, gamePath, gameID)
+	err = recursiveWalk(zipWriter, gamePath, manifest.GameID, manifest.Files, pr)
+	if err != nil {
+		return
+	}
+	err = addImagesIntoArchive(zipWriter, images, manifest.GameID, manifest.Files, pr)
+	if err != nil {
+		return
+	}
+
+	// The manifest is written last, when the hashes of all files are known
+	err = addManifestIntoArchive(manifest, zipWriter)
 	if err != nil {
 		return
 	}
 
-	// Flush data from zip writer into buffer
+	// Flush the rest of the data from zip writer
 	err = zipWriter.Close()
 	if err != nil {
 		errors.IfErrorLog(err)
-		return nil, errors.InternalError.AddMessage("Error closing zip archive: " + err.Error())
+		return errors.InternalError.AddMessage("Error closing zip archive: " + err.Error())
 	}
 	// Bypassing double closing
 	zipWriter = nil
 
-	// Return zip file
-	return buf.Bytes(), nil
+	return nil
 }
 
-func UnarchiveFolder(data []byte, gameID string, cfg *config.Config) (resultGameID string, err error) {
-	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+// UnarchiveFolder unpacks the game from the zip archive of the passed size.
+// The archive is read on demand, so it can be stored in a temporary file instead of memory.
+// Every entry is checked before anything is written to disk. If the archive has a manifest,
+// the list of files and their hashes must match it. Archives of old versions without a manifest are accepted,
+// in this case the returned manifest is nil.
+func UnarchiveFolder(r io.ReaderAt, size int64, gameID string, cfg *config.Config) (resultGameID string, manifest *Manifest, err error) {
+	zipReader, err := zip.NewReader(r, size)
 	if err != nil {
 		errors.IfErrorLog(err)
-		return "", errors.InternalError.AddMessage("Error open zip archive: " + err.Error())
+		return "", nil, errors.BadArchive.AddMessage("Error open zip archive: " + err.Error())
+	}
+
+	if len(zipReader.File) > maxArchiveFiles {
+		return "", nil, errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: more than %d files", maxArchiveFiles))
 	}
 
 	// The original game ID as it is set in the zip-archive
 	var importGameID string
+	// The cleaned paths of the entries with "/" as separator
+	names := make([]string, len(zipReader.File))
+	// All files of the game, used to find duplicates and to compare with the manifest
+	files := make(map[string]struct{})
+	var manifestFile *zip.File
+	var total uint64
 
 	// Validation
-	for _, file := range zipReader.File {
-		file.Name = convertPathToPlatform(file.Name)
+	for i, file := range zipReader.File {
+		names[i], err = checkArchiveEntry(file)
+		if err != nil {
+			return "", nil, err
+		}
+		if names[i] == ManifestFile {
+			manifestFile = file
+			continue
+		}
+
 		// Split the full path to the file into parts
-		pathList := strings.Split(file.Name, string(filepath.Separator))
+		pathList := strings.Split(names[i], "/")
 
 		// The archive must not contain files outside the folder
 		if !file.Mode().IsDir() && len(pathList) < 2 {
-			return "", errors.BadArchive.AddMessage("Bad zip archive: the root of the zip file must contain only one folder")
+			return "", nil, errors.BadArchive.AddMessage("Bad zip archive: the root of the zip file must contain only one folder")
+		}
+
+		if !file.Mode().IsDir() {
+			if _, ok := files[names[i]]; ok {
+				return "", nil, errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: the file %q is duplicated", names[i]))
+			}
+			files[names[i]] = struct{}{}
+			total += file.UncompressedSize64
 		}
 
 		// If this is the first file, extract the name of the root folder
@@ -73,16 +142,37 @@ func UnarchiveFolder(data []byte, gameID string, cfg *config.Config) (resultGame
 
 			// Check that the folder name matches the required format of the ID
 			if utils.NameToID(importGameID) != importGameID {
-				return "", errors.BadArchive.AddMessage("Bad zip archive: the root folder of the game has a invalid ID")
+				return "", nil, errors.BadArchive.AddMessage("Bad zip archive: the root folder of the game has a invalid ID")
 			}
 			continue
 		}
 
 		// The root folder for all files must be the same
 		if importGameID != pathList[0] {
-			return "", errors.BadArchive.AddMessage("Bad zip archive: there should only be one folder in the root of the archive")
+			return "", nil, errors.BadArchive.AddMessage("Bad zip archive: there should only be one folder in the root of the archive")
 		}
 	}
+	if importGameID == "" {
+		return "", nil, errors.BadArchive.AddMessage("Bad zip archive: the game folder not found")
+	}
+
+	// The declared sizes are checked here, the zip reader makes sure the actual data is not larger
+	if cfg.MaxExportSize > 0 && total > uint64(cfg.MaxExportSize) {
+		return "", nil, errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: the unpacked game takes %d bytes, the limit is %d bytes", total, cfg.MaxExportSize))
+	}
+
+	if manifestFile != nil {
+		manifest, err = readManifest(manifestFile)
+		if err != nil {
+			return "", nil, err
+		}
+		err = manifest.check(importGameID, files)
+		if err != nil {
+			return "", nil, err
+		}
+	} else {
+		logger.Warn.Printf("The archive of the game %q has no manifest, the files are not verified", importGameID)
+	}
 
 	// Set the resulting game ID
 	if gameID != "" {
@@ -95,10 +185,18 @@ func UnarchiveFolder(data []byte, gameID string, cfg *config.Config) (resultGame
 
 	// Build a full relative path to the root game folder
 	gameRootPath := filepath.Join(cfg.Games(), resultGameID)
+	// The existing game must not be overwritten or removed if the import fails
+	isExist, err := IsFolderExist(gameRootPath)
+	if err != nil {
+		return "", nil, err
+	}
+	if isExist {
+		return "", nil, errors.GameExist
+	}
 	// Create the root folder of the game
 	err = CreateFolder(gameRootPath)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	defer func() {
@@ -108,40 +206,45 @@ func UnarchiveFolder(data []byte, gameID string, cfg *config.Config) (resultGame
 		}
 	}()
 
-	// Unzip files
-	for _, file := range zipReader.File {
-		file.Name = convertPathToPlatform(file.Name)
+	pr := newArchiveProgress("Import game", resultGameID, int64(total))
+	defer func() { pr.Finish(err) }()
 
-		// Backing up the original file path
-		zipFilePath := file.Name
+	// Unzip files
+	for i, file := range zipReader.File {
+		if file == manifestFile {
+			continue
+		}
 
+		pathList := strings.Split(names[i], "/")
 		if gameID != "" {
 			// If the user passed a different name of the game, replace the name of the root folder
-			pathList := strings.Split(zipFilePath, string(filepath.Separator))
 			pathList[0] = gameID
-			zipFilePath = filepath.Join(pathList...)
 		}
 
 		// Build a full relative path to the game folder
-		resultPath := filepath.Join(cfg.Games(), zipFilePath)
+		resultPath := filepath.Join(cfg.Games(), filepath.Join(pathList...))
 
 		if file.FileInfo().IsDir() {
 			err = CreateFolderIfNotExist(resultPath)
 			if err != nil {
-				return "", err
-			}
-		} else {
-			// Create file in the new game directory
-			err = createFileFromArchive(resultPath, file)
-			if err != nil {
-				return "", err
+				return "", nil, err
 			}
+			continue
+		}
+
+		// Create file in the new game directory
+		hash, err := createFileFromArchive(resultPath, file, pr)
+		if err != nil {
+			return "", nil, err
+		}
+		if manifest != nil && manifest.Files[names[i]] != hash {
+			return "", nil, errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: the file %q is damaged, the hash does not match the manifest", names[i]))
 		}
 	}
-	return resultGameID, nil
+	return resultGameID, manifest, nil
 }
 
-func recursiveWalk(zipWriter *zip.Writer, dirPath string, relatePath string) error {
+func recursiveWalk(zipWriter *zip.Writer, dirPath string, relatePath string, hashes map[string]string, pr *archiveProgress) error {
 	// Open dir
 	dirFiles, err := os.ReadDir(dirPath)
 	if err != nil {
@@ -152,31 +255,55 @@ func recursiveWalk(zipWriter *zip.Writer, dirPath string, relatePath string) err
 	// Go through all files
 	for _, file := range dirFiles {
 		filePath := filepath.Join(dirPath, file.Name())
-		newRelatePath := filepath.Join(relatePath, file.Name())
+		// Inside the zip archive the separator is always "/"
+		newRelatePath := relatePath + "/" + file.Name()
 
-		if file.IsDir() {
-			err = recursiveWalk(zipWriter, filePath, newRelatePath)
+		switch {
+		case file.IsDir():
+			err = recursiveWalk(zipWriter, filePath, newRelatePath, hashes, pr)
 			if err != nil {
 				return err
 			}
-		} else {
+		case file.Type().IsRegular():
 			// Copy file inside archive
-			err = addFileIntoArchive(filePath, newRelatePath, zipWriter)
+			hashes[newRelatePath], err = addFileIntoArchive(filePath, newRelatePath, zipWriter, pr)
 			if err != nil {
 				return err
 			}
+		default:
+			// Symbolic links and special files are not part of the game
+			logger.Warn.Printf("Skip %q, it is not a regular file", filePath)
 		}
 	}
 	return nil
 }
 
-// Adding single file into archive
-func addFileIntoArchive(filePath, zipPath string, w *zip.Writer) error {
+func addImagesIntoArchive(zipWriter *zip.Writer, images map[string]string, relatePath string, hashes map[string]string, pr *archiveProgress) error {
+	// The order of the files doesn't depend on the map
+	names := make([]string, 0, len(images))
+	for hash := range images {
+		names = append(names, hash)
+	}
+	sort.Strings(names)
+
+	for _, hash := range names {
+		zipPath := relatePath + "/" + ImagesFolder + "/" + hash + ".bin"
+		var err error
+		hashes[zipPath], err = addFileIntoArchive(images[hash], zipPath, zipWriter, pr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Adding single file into archive, returns the hash of the file
+func addFileIntoArchive(filePath, zipPath string, w *zip.Writer, pr *archiveProgress) (string, error) {
 	// Open file for reading
 	f, err := os.Open(filePath)
 	if err != nil {
 		errors.IfErrorLog(err)
-		return errors.InternalError.AddMessage("Error open file: " + err.Error())
+		return "", errors.InternalError.AddMessage("Error open file: " + err.Error())
 	}
 	defer func() {
 		errors.IfErrorLog(f.Close())
@@ -186,57 +313,147 @@ func addFileIntoArchive(filePath, zipPath string, w *zip.Writer) error {
 	zipW, err := w.Create(zipPath)
 	if err != nil {
 		errors.IfErrorLog(err)
-		return errors.InternalError.AddMessage("Error create path in zip: " + err.Error())
+		return "", errors.InternalError.AddMessage("Error create path in zip: " + err.Error())
 	}
 
 	// Copy file inside archive
-	_, err = io.Copy(zipW, f)
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(zipW, hash), io.TeeReader(f, pr))
 	if err != nil {
 		errors.IfErrorLog(err)
-		return errors.InternalError.AddMessage("Error copy file insize zip: " + err.Error())
+		return "", errors.InternalError.AddMessage("Error copy file insize zip: " + err.Error())
 	}
-	return nil
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func addManifestIntoArchive(manifest Manifest, w *zip.Writer) error {
+	zipW, err := w.Create(ManifestFile)
+	if err != nil {
+		errors.IfErrorLog(err)
+		return errors.InternalError.AddMessage("Error create path in zip: " + err.Error())
+	}
+	return JsonToWriter(zipW, manifest)
+}
+
+func readManifest(f *zip.File) (*Manifest, error) {
+	if f.UncompressedSize64 > maxManifestSize {
+		return nil, errors.BadArchive.AddMessage("Bad zip archive: the manifest is too large")
+	}
+	r, err := f.Open()
+	if err != nil {
+		errors.IfErrorLog(err)
+		return nil, errors.BadArchive.AddMessage("Error open file in zip archive: " + err.Error())
+	}
+	defer func() { errors.IfErrorLog(r.Close()) }()
+
+	manifest := &Manifest{}
+	err = json.NewDecoder(r).Decode(manifest)
+	if err != nil {
+		return nil, errors.BadArchive.AddMessage("Bad zip archive: invalid manifest: " + err.Error())
+	}
+	return manifest, nil
 }
 
-func createFileFromArchive(filePath string, f *zip.File) error {
+// Creating a file from the archive, returns the hash of the file
+func createFileFromArchive(filePath string, f *zip.File, pr *archiveProgress) (string, error) {
 	folder := filepath.Dir(filePath)
 	err := CreateFolderIfNotExist(folder)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Open file in zip archive
 	fileInArchive, err := f.Open()
 	if err != nil {
 		errors.IfErrorLog(err)
-		return errors.InternalError.AddMessage("Error open file in zip archive: " + err.Error())
+		return "", errors.BadArchive.AddMessage("Error open file in zip archive: " + err.Error())
 	}
 	defer func() { errors.IfErrorLog(fileInArchive.Close()) }()
 
-	// Create new file
-	createdFile, err := os.Create(filePath)
+	// Create new file, the validated entry never points to an existing file
+	createdFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
-		return errors.InternalError.AddMessage("Error creating new file: " + err.Error())
+		return "", errors.InternalError.AddMessage("Error creating new file: " + err.Error())
 	}
+	defer func() { errors.IfErrorLog(createdFile.Close()) }()
 
-	// Write data into created file
-	_, err = io.Copy(createdFile, fileInArchive)
+	// Write data into created file. The zip reader fails if the data is larger than the declared size.
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(createdFile, hash), io.TeeReader(fileInArchive, pr))
 	if err != nil {
 		errors.IfErrorLog(err)
-		return errors.InternalError.AddMessage("Error copy data from zip to file: " + err.Error())
+		return "", errors.BadArchive.AddMessage("Error copy data from zip to file: " + err.Error())
 	}
 
-	return nil
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Checking that the entry can be safely unpacked: only regular files and folders,
+// the path must stay inside the destination folder and the data must not be suspiciously compressed.
+// Returns the cleaned path with "/" as separator.
+func checkArchiveEntry(f *zip.File) (string, error) {
+	mode := f.Mode()
+	if mode&os.ModeSymlink != 0 {
+		return "", errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: %q is a symbolic link", f.Name))
+	}
+	if !mode.IsDir() && !mode.IsRegular() {
+		return "", errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: %q is not a regular file", f.Name))
+	}
+
+	// Archives created on windows by old versions use "\" as separator
+	name := strings.ReplaceAll(f.Name, "\\", "/")
+	if strings.HasPrefix(name, "/") {
+		return "", errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: %q has an absolute path", f.Name))
+	}
+	name = strings.TrimSuffix(name, "/")
+	for _, part := range strings.Split(name, "/") {
+		// Empty parts, links to the parent folder and windows drives are not allowed
+		if part == "" || part == "." || part == ".." || strings.Contains(part, ":") {
+			return "", errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: %q has an unsafe path", f.Name))
+		}
+	}
+
+	// Protection against zip bombs
+	if f.UncompressedSize64 > 0 && (f.CompressedSize64 == 0 || f.UncompressedSize64/f.CompressedSize64 > maxCompressionRatio) {
+		return "", errors.BadArchive.AddMessage(fmt.Sprintf("Bad zip archive: %q is compressed too much", f.Name))
+	}
+	return name, nil
 }
 
-// Converting the file path inside a zip archive to platform specific.
-// Because windows uses "\" and unix uses "/", we get errors when unzipping.
-func convertPathToPlatform(path string) string {
-	switch runtime.GOOS {
-	case "linux", "darwin":
-		return strings.ReplaceAll(path, "\\", "/")
-	case "windows":
-		return strings.ReplaceAll(path, "/", "\\")
+// Calculating the total size of the files
+func filesSize(files map[string]string) (int64, error) {
+	var size int64
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			errors.IfErrorLog(err)
+			return 0, errors.InternalError.AddMessage("Error calculating file size: " + err.Error())
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// Calculating the total size of all files inside the folder
+func folderSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		errors.IfErrorLog(err)
+		return 0, errors.InternalError.AddMessage("Error calculating folder size: " + err.Error())
 	}
-	return path
+	return size, nil
 }
@@ -0,0 +1,193 @@
+package fs
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+type zipEntry struct {
+	name string
+	data []byte
+	mode os.FileMode
+}
+
+func buildZip(t *testing.T, entries ...zipEntry) []byte {
+	buf := bytes.Buffer{}
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{
+			Name:   entry.name,
+			Method: zip.Deflate,
+		}
+		if entry.mode != 0 {
+			header.SetMode(entry.mode)
+		}
+		f, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal("error creating zip entry", err)
+		}
+		_, err = f.Write(entry.data)
+		if err != nil {
+			t.Fatal("error writing zip entry", err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal("error closing zip", err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveFolder(t *testing.T) {
+	dir, err := os.MkdirTemp("", "zip_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	cfg := config.Get(false, "")
+	cfg.SetDataPath(dir)
+
+	gamePath := filepath.Join(cfg.Games(), "game")
+	err = os.MkdirAll(filepath.Join(gamePath, "collection"), DirPerm)
+	if err != nil {
+		t.Fatal("error creating game dir", err)
+	}
+	err = os.WriteFile(filepath.Join(gamePath, ".info.json"), []byte(`{"id":"game"}`), 0644)
+	if err != nil {
+		t.Fatal("error writing file", err)
+	}
+	err = os.WriteFile(filepath.Join(gamePath, "collection", "image.bin"), bytes.Repeat([]byte{1}, 1024), 0644)
+	if err != nil {
+		t.Fatal("error writing file", err)
+	}
+
+	// The shared images are stored outside the game folder
+	imagePath := filepath.Join(cfg.Images(), "hash.bin")
+	err = os.MkdirAll(cfg.Images(), DirPerm)
+	if err != nil {
+		t.Fatal("error creating images dir", err)
+	}
+	err = os.WriteFile(imagePath, bytes.Repeat([]byte{2}, 512), 0644)
+	if err != nil {
+		t.Fatal("error writing file", err)
+	}
+	images := map[string]string{"hash": imagePath}
+
+	unarchive := func(data []byte, gameID string) (string, *Manifest, error) {
+		return UnarchiveFolder(bytes.NewReader(data), int64(len(data)), gameID, cfg)
+	}
+
+	t.Run("limit", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		err := ArchiveFolder(&buf, gamePath, images, Manifest{GameID: "game"}, 1200)
+		assert.ErrorIs(t, err, errors.ArchiveTooLarge)
+		// Nothing has been written, so the error can be returned to the user
+		assert.Equal(t, 0, buf.Len())
+	})
+
+	var archive []byte
+	t.Run("round_trip", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		err := ArchiveFolder(&buf, gamePath, images, Manifest{AppVersion: "test", GameID: "game", Counts: ManifestCounts{Collections: 1}}, 0)
+		assert.NoError(t, err)
+		archive = buf.Bytes()
+
+		gameID, manifest, err := unarchive(archive, "copy")
+		assert.NoError(t, err)
+		assert.Equal(t, "copy", gameID)
+		if assert.NotNil(t, manifest) {
+			assert.Equal(t, ArchiveFormatVersion, manifest.FormatVersion)
+			assert.Equal(t, "test", manifest.AppVersion)
+			assert.Equal(t, 1, manifest.Counts.Collections)
+			assert.Len(t, manifest.Files, 3)
+		}
+
+		image, err := os.ReadFile(filepath.Join(cfg.Games(), "copy", "collection", "image.bin"))
+		assert.NoError(t, err)
+		assert.Len(t, image, 1024)
+		// The shared images are unpacked into the game folder
+		image, err = os.ReadFile(filepath.Join(cfg.Games(), "copy", ImagesFolder, "hash.bin"))
+		assert.NoError(t, err)
+		assert.Len(t, image, 512)
+
+		// The manifest is not unpacked into the game
+		_, err = os.Stat(filepath.Join(cfg.Games(), "copy", ManifestFile))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("existing_game", func(t *testing.T) {
+		_, _, err := unarchive(archive, "")
+		assert.ErrorIs(t, err, errors.GameExist)
+		// The existing game is untouched
+		_, err = os.Stat(filepath.Join(gamePath, ".info.json"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		data := buildZip(t, zipEntry{name: "legacy/.info.json", data: []byte(`{"id":"legacy"}`)})
+		gameID, manifest, err := unarchive(data, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "legacy", gameID)
+		assert.Nil(t, manifest)
+	})
+
+	t.Run("damaged", func(t *testing.T) {
+		manifest := `{"formatVersion":1,"gameId":"damaged","files":{"damaged/.info.json":"0000"}}`
+		data := buildZip(t,
+			zipEntry{name: "damaged/.info.json", data: []byte(`{"id":"damaged"}`)},
+			zipEntry{name: ManifestFile, data: []byte(manifest)},
+		)
+		_, _, err := unarchive(data, "")
+		assert.ErrorIs(t, err, errors.BadArchive)
+		// The partially unpacked game is removed
+		_, err = os.Stat(filepath.Join(cfg.Games(), "damaged"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("newer_version", func(t *testing.T) {
+		manifest := `{"formatVersion":1000,"gameId":"newer","files":{}}`
+		data := buildZip(t,
+			zipEntry{name: "newer/.info.json", data: []byte(`{"id":"newer"}`)},
+			zipEntry{name: ManifestFile, data: []byte(manifest)},
+		)
+		_, _, err := unarchive(data, "")
+		assert.ErrorIs(t, err, errors.BadArchive)
+	})
+
+	t.Run("unsafe", func(t *testing.T) {
+		cases := map[string][]zipEntry{
+			"not_zip":      nil,
+			"zip_slip":     {{name: "game/../../evil.json", data: []byte("{}")}},
+			"absolute":     {{name: "/game/evil.json", data: []byte("{}")}},
+			"symlink":      {{name: "link/.info.json", data: []byte("/etc/passwd"), mode: os.ModeSymlink | 0777}},
+			"bomb":         {{name: "bomb/image.bin", data: make([]byte, 10<<20)}},
+			"duplicate":    {{name: "dup/a.json", data: []byte("{}")}, {name: "dup/a.json", data: []byte("{}")}},
+			"two_games":    {{name: "one/a.json", data: []byte("{}")}, {name: "two/a.json", data: []byte("{}")}},
+			"root_file":    {{name: "a.json", data: []byte("{}")}},
+			"windows_path": {{name: "C:\\game\\a.json", data: []byte("{}")}},
+		}
+		for name, entries := range cases {
+			data := []byte("not a zip")
+			if entries != nil {
+				data = buildZip(t, entries...)
+			}
+			_, _, err := unarchive(data, "")
+			assert.ErrorIs(t, err, errors.BadArchive, name)
+		}
+
+		// Nothing has been written outside the games folder
+		_, err = os.Stat(filepath.Join(dir, "evil.json"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,130 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+const (
+	OperationCrop       = "crop"
+	OperationRotate     = "rotate"
+	OperationFlip       = "flip"
+	OperationResize     = "resize"
+	OperationFit        = "fit"
+	OperationBrightness = "brightness"
+	OperationContrast   = "contrast"
+
+	FlipHorizontal = "horizontal"
+	FlipVertical   = "vertical"
+)
+
+// Operation is a single change of the image, the operations are applied one after another
+type Operation struct {
+	Type string
+	// Crop: the rectangle to keep.
+	// Resize: the new size, one of the sides can be 0 to keep the aspect ratio.
+	// Fit: the size the image is cropped to by the center and resized to.
+	X, Y, Width, Height int
+	// Rotate: 90, 180 or 270 degrees clockwise
+	Angle int
+	// Flip: horizontal or vertical
+	Direction string
+	// Brightness and contrast: the change in percent from -100 to 100
+	Percentage float64
+}
+
+// Edit applies the operations to the image and encodes the result in the format of the source image, GIF is encoded as PNG
+func Edit(data []byte, operations []Operation, maxPixels int64) ([]byte, error) {
+	img, imgType, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.UnknownImageType.AddMessage(err.Error())
+	}
+
+	for i, op := range operations {
+		img, err = applyOperation(img, op, maxPixels)
+		if err != nil {
+			return nil, errors.BadImageOperation.AddMessage(fmt.Sprintf("operation %d (%s): %s", i+1, op.Type, err.Error()))
+		}
+	}
+
+	return encode(img, imgType)
+}
+
+func applyOperation(img image.Image, op Operation, maxPixels int64) (image.Image, error) {
+	bounds := img.Bounds()
+	checkSize := func(width, height int) error {
+		if width < 1 || height < 1 {
+			return fmt.Errorf("bad size %dx%d", width, height)
+		}
+		if maxPixels > 0 && int64(width)*int64(height) > maxPixels {
+			return fmt.Errorf("the size %dx%d is more than %d pixels", width, height, maxPixels)
+		}
+		return nil
+	}
+
+	switch op.Type {
+	case OperationCrop:
+		rect := image.Rect(op.X, op.Y, op.X+op.Width, op.Y+op.Height).Add(bounds.Min)
+		if op.Width < 1 || op.Height < 1 || !rect.In(bounds) {
+			return nil, fmt.Errorf("the rectangle %dx%d at %d,%d is outside the image %dx%d",
+				op.Width, op.Height, op.X, op.Y, bounds.Dx(), bounds.Dy())
+		}
+		return imaging.Crop(img, rect), nil
+	case OperationRotate:
+		// imaging rotates counterclockwise
+		switch op.Angle {
+		case 90:
+			return imaging.Rotate270(img), nil
+		case 180:
+			return imaging.Rotate180(img), nil
+		case 270:
+			return imaging.Rotate90(img), nil
+		default:
+			return nil, fmt.Errorf("the angle must be 90, 180 or 270")
+		}
+	case OperationFlip:
+		switch op.Direction {
+		case FlipHorizontal:
+			return imaging.FlipH(img), nil
+		case FlipVertical:
+			return imaging.FlipV(img), nil
+		default:
+			return nil, fmt.Errorf("the direction must be %s or %s", FlipHorizontal, FlipVertical)
+		}
+	case OperationResize:
+		width, height := op.Width, op.Height
+		// Keep the aspect ratio for the missing side
+		switch {
+		case width == 0 && height > 0:
+			width = bounds.Dx() * height / bounds.Dy()
+		case height == 0 && width > 0:
+			height = bounds.Dy() * width / bounds.Dx()
+		}
+		if err := checkSize(width, height); err != nil {
+			return nil, err
+		}
+		return imaging.Resize(img, width, height, imaging.Lanczos), nil
+	case OperationFit:
+		if err := checkSize(op.Width, op.Height); err != nil {
+			return nil, err
+		}
+		return imaging.Fill(img, op.Width, op.Height, imaging.Center, imaging.Lanczos), nil
+	case OperationBrightness:
+		if op.Percentage < -100 || op.Percentage > 100 {
+			return nil, fmt.Errorf("the percentage must be from -100 to 100")
+		}
+		return imaging.AdjustBrightness(img, op.Percentage), nil
+	case OperationContrast:
+		if op.Percentage < -100 || op.Percentage > 100 {
+			return nil, fmt.Errorf("the percentage must be from -100 to 100")
+		}
+		return imaging.AdjustContrast(img, op.Percentage), nil
+	default:
+		return nil, fmt.Errorf("unknown operation")
+	}
+}
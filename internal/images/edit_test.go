@@ -0,0 +1,76 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"testing"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+func TestEdit(t *testing.T) {
+	t.Parallel()
+
+	pngImage, err := ImageToPng(CreateImage(40, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := func(t *testing.T, data []byte) (int, int) {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img.Bounds().Dx(), img.Bounds().Dy()
+	}
+
+	tests := []struct {
+		name          string
+		operations    []Operation
+		width, height int
+	}{
+		{"crop", []Operation{{Type: OperationCrop, X: 5, Y: 5, Width: 10, Height: 15}}, 10, 15},
+		{"rotate", []Operation{{Type: OperationRotate, Angle: 90}}, 20, 40},
+		{"rotate_180", []Operation{{Type: OperationRotate, Angle: 180}}, 40, 20},
+		{"flip", []Operation{{Type: OperationFlip, Direction: FlipVertical}}, 40, 20},
+		{"resize_keep_ratio", []Operation{{Type: OperationResize, Width: 20}}, 20, 10},
+		{"fit", []Operation{{Type: OperationFit, Width: 10, Height: 10}}, 10, 10},
+		{"brightness", []Operation{{Type: OperationBrightness, Percentage: 20}}, 40, 20},
+		{"chain", []Operation{
+			{Type: OperationRotate, Angle: 270},
+			{Type: OperationCrop, Width: 20, Height: 20},
+			{Type: OperationContrast, Percentage: -30},
+		}, 20, 20},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			res, err := Edit(pngImage, test.operations, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			width, height := size(t, res)
+			if width != test.width || height != test.height {
+				t.Fatalf("Bad size [got] %dx%d [want] %dx%d", width, height, test.width, test.height)
+			}
+		})
+	}
+
+	t.Run("bad_operations", func(t *testing.T) {
+		for _, op := range []Operation{
+			{Type: "unknown"},
+			{Type: OperationCrop, X: 30, Width: 20, Height: 10},
+			{Type: OperationRotate, Angle: 45},
+			{Type: OperationFlip, Direction: "diagonal"},
+			{Type: OperationResize},
+			{Type: OperationResize, Width: 1000, Height: 1000},
+			{Type: OperationContrast, Percentage: 200},
+		} {
+			_, err := Edit(pngImage, []Operation{op}, 100*100)
+			if !errors.Is(err, er.BadImageOperation) {
+				t.Fatal("The operation should fail", op, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/disintegration/imaging"
+	// The formats are only accepted on upload, the stored images are converted to PNG
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+
+	// The quality of the re-encoded JPEG images
+	jpegQuality = 90
+)
+
+// Normalize validates the uploaded image and converts it into the form it is stored in:
+// the EXIF orientation is applied, CMYK images are converted to RGB,
+// and the formats not supported by TTS (WebP, BMP, TIFF) are converted to PNG.
+// If format is set, every image is re-encoded to it. An image that needs no changes is returned as is.
+func Normalize(data []byte, maxPixels int64, format string) ([]byte, error) {
+	cfg, imgType, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.UnknownImageType.AddMessage(err.Error())
+	}
+	// Check the size before decoding, so a small file can't allocate gigabytes of pixels
+	if maxPixels > 0 && int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, errors.ImageTooLarge.AddMessage(fmt.Sprintf("the image is %dx%d, the maximum is %d pixels", cfg.Width, cfg.Height, maxPixels))
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.UnknownImageType.AddMessage(err.Error())
+	}
+
+	orientation := orientationNormal
+	if imgType == FormatJPEG {
+		orientation = exifOrientation(data)
+	}
+	// Phones and printing software produce CMYK JPEG, it is displayed with wrong colors by some viewers
+	_, isCMYK := img.(*image.CMYK)
+	unchanged := orientation == orientationNormal && !isCMYK
+
+	switch {
+	case format != "":
+		if format == imgType && unchanged {
+			return data, nil
+		}
+	case imgType == FormatPNG || imgType == FormatJPEG || imgType == "gif":
+		if unchanged {
+			return data, nil
+		}
+		format = imgType
+	default:
+		format = FormatPNG
+	}
+
+	img = applyOrientation(img, orientation)
+	if isCMYK {
+		img = imaging.Clone(img)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	switch format {
+	case FormatJPEG:
+		err = jpeg.Encode(buf, flatten(img), &jpeg.Options{
+			Quality: jpegQuality,
+		})
+	case FormatPNG:
+		err = png.Encode(buf, img)
+	default:
+		return nil, errors.InternalError.AddMessage("unknown image format: " + format)
+	}
+	if err != nil {
+		return nil, errors.InternalError.AddMessage(err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
+// flatten puts the image on a white background, JPEG has no transparency
+func flatten(img image.Image) image.Image {
+	if img.ColorModel() == color.YCbCrModel || img.ColorModel() == color.GrayModel {
+		return img
+	}
+	res := imaging.New(img.Bounds().Dx(), img.Bounds().Dy(), color.White)
+	draw.Draw(res, res.Bounds(), img, img.Bounds().Min, draw.Over)
+	return res
+}
+
+// The values of the EXIF orientation tag
+const (
+	orientationNormal     = 1
+	orientationFlipH      = 2
+	orientationRotate180  = 3
+	orientationFlipV      = 4
+	orientationTranspose  = 5
+	orientationRotate270  = 6
+	orientationTransverse = 7
+	orientationRotate90   = 8
+)
+
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case orientationFlipH:
+		return imaging.FlipH(img)
+	case orientationRotate180:
+		return imaging.Rotate180(img)
+	case orientationFlipV:
+		return imaging.FlipV(img)
+	case orientationTranspose:
+		return imaging.Transpose(img)
+	case orientationRotate270:
+		return imaging.Rotate270(img)
+	case orientationTransverse:
+		return imaging.Transverse(img)
+	case orientationRotate90:
+		return imaging.Rotate90(img)
+	default:
+		return img
+	}
+}
+
+// exifOrientation reads the orientation tag from the EXIF block of the JPEG image,
+// the normal orientation is returned if there is no tag or the block is broken
+func exifOrientation(data []byte) int {
+	const (
+		markerSOI  = 0xd8
+		markerAPP1 = 0xe1
+		markerSOS  = 0xda
+		tagOrient  = 0x0112
+	)
+
+	if len(data) < 2 || data[0] != 0xff || data[1] != markerSOI {
+		return orientationNormal
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return orientationNormal
+		}
+		marker := data[pos+1]
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == markerSOS || size < 2 || pos+2+size > len(data) {
+			// The image data has started, the metadata is always before it
+			return orientationNormal
+		}
+		segment := data[pos+4 : pos+2+size]
+		pos += 2 + size
+		if marker != markerAPP1 || !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			continue
+		}
+
+		tiff := segment[6:]
+		if len(tiff) < 8 {
+			return orientationNormal
+		}
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return orientationNormal
+		}
+
+		// The offset is compared before the conversion, so it can't overflow int on 32-bit builds
+		offset := order.Uint32(tiff[4:])
+		if uint64(offset)+2 > uint64(len(tiff)) {
+			return orientationNormal
+		}
+		ifd := int(offset)
+		count := int(order.Uint16(tiff[ifd:]))
+		for i := 0; i < count; i++ {
+			entry := ifd + 2 + i*12
+			if entry+12 > len(tiff) {
+				return orientationNormal
+			}
+			if order.Uint16(tiff[entry:]) != tagOrient {
+				continue
+			}
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < orientationNormal || orientation > orientationRotate90 {
+				return orientationNormal
+			}
+			return orientation
+		}
+		return orientationNormal
+	}
+	return orientationNormal
+}
@@ -0,0 +1,131 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"golang.org/x/image/bmp"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+// withOrientation inserts an EXIF block with the orientation tag right after the start of the JPEG image
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	// One entry: tag, type SHORT, count 1, value
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	res := []byte{0xff, 0xd8, 0xff, 0xe1}
+	res = binary.BigEndian.AppendUint16(res, uint16(len(segment)+2))
+	res = append(res, segment...)
+	return append(res, data[2:]...)
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	pngImage, err := ImageToPng(CreateImage(40, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jpegImage, err := ImageToJpeg(CreateImage(40, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		for _, data := range [][]byte{pngImage, jpegImage} {
+			res, err := Normalize(data, 0, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(res, data) {
+				t.Fatal("The image without changes should be stored as is")
+			}
+		}
+	})
+
+	t.Run("orientation", func(t *testing.T) {
+		data := withOrientation(jpegImage, orientationRotate270)
+		if exifOrientation(data) != orientationRotate270 {
+			t.Fatal("Bad orientation [got]", exifOrientation(data), "[want]", orientationRotate270)
+		}
+
+		res, err := Normalize(data, 0, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, format, err := image.Decode(bytes.NewReader(res))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format != FormatJPEG {
+			t.Fatal("Bad format [got]", format, "[want]", FormatJPEG)
+		}
+		if img.Bounds().Dx() != 20 || img.Bounds().Dy() != 40 {
+			t.Fatal("The image is not rotated", img.Bounds())
+		}
+
+		// The offset of the tags points far outside the block
+		data = withOrientation(jpegImage, orientationRotate270)
+		binary.BigEndian.PutUint32(data[16:], 0xffffffff)
+		if exifOrientation(data) != orientationNormal {
+			t.Fatal("Bad orientation [got]", exifOrientation(data), "[want]", orientationNormal)
+		}
+	})
+
+	t.Run("bmp", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		err := bmp.Encode(buf, CreateImage(40, 20))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := Normalize(buf.Bytes(), 0, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		imgType, err := ValidateImage(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imgType != FormatPNG {
+			t.Fatal("Bad format [got]", imgType, "[want]", FormatPNG)
+		}
+	})
+
+	t.Run("canonical_format", func(t *testing.T) {
+		res, err := Normalize(pngImage, 0, FormatJPEG)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = jpeg.Decode(bytes.NewReader(res))
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("too_large", func(t *testing.T) {
+		_, err := Normalize(pngImage, 40*20-1, "")
+		if !errors.Is(err, er.ImageTooLarge) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := Normalize([]byte("not an image"), 0, "")
+		if !errors.Is(err, er.UnknownImageType) {
+			t.Fatal(err)
+		}
+	})
+}
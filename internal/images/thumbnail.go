@@ -0,0 +1,55 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/disintegration/imaging"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+// MaxThumbnailSize limits the sizes of the thumbnails, so the cache can't be filled with every possible size
+const MaxThumbnailSize = 2048
+
+// Thumbnail reduces the image to fit into the square with the side of size pixels, the aspect ratio is kept.
+// The image smaller than the square is returned as is.
+func Thumbnail(data []byte, size int) ([]byte, error) {
+	if size < 1 || size > MaxThumbnailSize {
+		return nil, errors.BadThumbnailSize.AddMessage(fmt.Sprintf("the size must be from 1 to %d", MaxThumbnailSize))
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.UnknownImageType.AddMessage(err.Error())
+	}
+	if cfg.Width <= size && cfg.Height <= size {
+		return data, nil
+	}
+
+	img, imgType, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.UnknownImageType.AddMessage(err.Error())
+	}
+	return encode(imaging.Fit(img, size, size, imaging.Lanczos), imgType)
+}
+
+// encode keeps JPEG images in JPEG, all other formats are encoded as PNG
+func encode(img image.Image, imgType string) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	var err error
+	if imgType == FormatJPEG {
+		err = jpeg.Encode(buf, img, &jpeg.Options{
+			Quality: jpegQuality,
+		})
+	} else {
+		err = png.Encode(buf, img)
+	}
+	if err != nil {
+		return nil, errors.InternalError.AddMessage(err.Error())
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,58 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+func TestThumbnail(t *testing.T) {
+	t.Parallel()
+
+	jpegImage, err := ImageToJpeg(CreateImage(400, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("reduced", func(t *testing.T) {
+		res, err := Thumbnail(jpegImage, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		width, height, err := ImageSize(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if width != 100 || height != 50 {
+			t.Fatalf("Bad size [got] %dx%d [want] 100x50", width, height)
+		}
+		imgType, err := ValidateImage(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imgType != FormatJPEG {
+			t.Fatal("Bad format [got]", imgType, "[want]", FormatJPEG)
+		}
+	})
+
+	t.Run("small", func(t *testing.T) {
+		res, err := Thumbnail(jpegImage, 400)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(res, jpegImage) {
+			t.Fatal("The image smaller than the thumbnail should be returned as is")
+		}
+	})
+
+	t.Run("bad_size", func(t *testing.T) {
+		for _, size := range []int{0, MaxThumbnailSize + 1} {
+			_, err := Thumbnail(jpegImage, size)
+			if !errors.Is(err, er.BadThumbnailSize) {
+				t.Fatal("The size should be rejected", size, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package network
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+const (
+	cacheInfoExt = ".json"
+	cacheDataExt = ".bin"
+)
+
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// Each response is stored as cache/<hash of URL>.bin with the validators in cache/<hash of URL>.json.
+// The cache is only a speed up, so the errors are logged and the image is downloaded again.
+type cache struct {
+	path string
+}
+
+// newCache returns the cache in the folder, nil cache stores nothing
+func newCache(path string) *cache {
+	err := fs.CreateFolderIfNotExist(path)
+	if err != nil {
+		return nil
+	}
+	return &cache{
+		path: path,
+	}
+}
+
+// get returns the stored response of the URL, nil if there is none
+func (c *cache) get(url string) (*cacheEntry, []byte) {
+	if c == nil {
+		return nil, nil
+	}
+	name := c.name(url)
+
+	info, err := os.ReadFile(name + cacheInfoExt)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			errors.IfErrorLog(err)
+		}
+		return nil, nil
+	}
+	entry := &cacheEntry{}
+	err = json.Unmarshal(info, entry)
+	if err != nil || entry.URL != url {
+		errors.IfErrorLog(err)
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(name + cacheDataExt)
+	if err != nil {
+		errors.IfErrorLog(err)
+		return nil, nil
+	}
+	return entry, data
+}
+
+// put stores the response of the URL, nil data keeps the stored data and only updates the validators
+func (c *cache) put(entry *cacheEntry, data []byte) {
+	if c == nil {
+		return
+	}
+	name := c.name(entry.URL)
+
+	if data != nil {
+		err := fs.ReplaceFile(name+cacheDataExt, func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		})
+		if err != nil {
+			return
+		}
+	}
+	info, err := json.Marshal(entry)
+	if err != nil {
+		errors.IfErrorLog(err)
+		return
+	}
+	// The data is written first, so the validators never point to the old data
+	errors.IfErrorLog(fs.ReplaceFile(name+cacheInfoExt, func(w io.Writer) error {
+		_, err := w.Write(info)
+		return err
+	}))
+}
+
+func (c *cache) name(url string) string {
+	return filepath.Join(c.path, utils.HashForData([]byte(url)))
+}
@@ -5,35 +5,261 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/HardDie/DeckBuilder/internal/config"
 	"github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/logger"
+	"github.com/HardDie/DeckBuilder/internal/utils"
 )
 
+const (
+	// The delay before the first repeated attempt, doubled for each next one
+	retryDelay    = 500 * time.Millisecond
+	maxRetryDelay = 10 * time.Second
+)
+
+var (
+	// Without the configuration the images are downloaded without the cache
+	defaultDownloader = &Downloader{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		delay:   retryDelay,
+		locks:   utils.NewKeyMutex(),
+		retries: 3,
+	}
+)
+
+// Downloader fetches the images by URL. The responses are kept in the cache folder
+// and revalidated with ETag or Last-Modified, so the same URL is downloaded once.
+type Downloader struct {
+	client  *http.Client
+	cache   *cache
+	retries int
+	delay   time.Duration
+	maxSize int64
+	// Limits the number of requests at the same time, nil - unlimited
+	slots chan struct{}
+	ttl   time.Duration
+
+	// The same URL is not downloaded twice at the same time
+	locks *utils.KeyMutex
+}
+
+func NewDownloader(cfg *config.Config) *Downloader {
+	d := &Downloader{
+		client:  &http.Client{Timeout: cfg.DownloadTimeout},
+		cache:   newCache(cfg.Caches()),
+		retries: cfg.DownloadRetries,
+		delay:   retryDelay,
+		maxSize: cfg.MaxDownloadSize,
+		ttl:     cfg.DownloadCacheTTL,
+		locks:   utils.NewKeyMutex(),
+	}
+	if cfg.DownloadConcurrency > 0 {
+		d.slots = make(chan struct{}, cfg.DownloadConcurrency)
+	}
+	return d
+}
+
+// SetupDownloader replaces the downloader used by DownloadBytes
+func SetupDownloader(d *Downloader) {
+	defaultDownloader = d
+}
+
 func DownloadBytes(source string) ([]byte, error) {
+	return defaultDownloader.Download(source)
+}
+
+// RefreshBytes downloads the image even if the cached one is still fresh, the cache is only used to revalidate it
+func RefreshBytes(source string) ([]byte, error) {
+	return defaultDownloader.Refresh(source)
+}
+
+func (d *Downloader) Download(source string) ([]byte, error) {
+	return d.download(source, false)
+}
+func (d *Downloader) Refresh(source string) ([]byte, error) {
+	return d.download(source, true)
+}
+
+func (d *Downloader) download(source string, refresh bool) ([]byte, error) {
 	// Parse URL
 	imageURL, err := (&url.URL{}).Parse(source)
 	if err != nil {
 		errors.IfErrorLog(err)
 		return nil, errors.NetworkBadURL.AddMessage(err.Error())
 	}
+	if imageURL.Scheme != "http" && imageURL.Scheme != "https" {
+		return nil, errors.NetworkBadURL.AddMessage("unsupported protocol scheme: " + imageURL.Scheme)
+	}
+	source = imageURL.String()
+
+	unlock := d.locks.Lock(source)
+	defer unlock()
+
+	// The fresh image is used without asking the server
+	entry, data := d.cache.get(source)
+	if entry != nil && !refresh && time.Now().Before(entry.ExpiresAt) {
+		return data, nil
+	}
+
+	resp, err := d.fetch(source, entry)
+	if err != nil {
+		if entry != nil && !refresh {
+			// The server is not available, the stale image is better than nothing
+			logger.Warn.Printf("Using the cached image of %s: %s", source, err.Error())
+			return data, nil
+		}
+		return nil, err
+	}
+
+	if resp.notModified {
+		entry.ExpiresAt = resp.expiresAt
+		d.cache.put(entry, nil)
+		return data, nil
+	}
+
+	if resp.store {
+		d.cache.put(&cacheEntry{
+			URL:          source,
+			ETag:         resp.etag,
+			LastModified: resp.lastModified,
+			ExpiresAt:    resp.expiresAt,
+		}, resp.data)
+	}
+	return resp.data, nil
+}
+
+type download struct {
+	data         []byte
+	notModified  bool
+	etag         string
+	lastModified string
+	expiresAt    time.Time
+	// The server allows to keep the response
+	store bool
+}
+
+// fetch requests the URL, the network errors and the server errors are repeated with a growing delay
+func (d *Downloader) fetch(source string, entry *cacheEntry) (*download, error) {
+	delay := d.delay
+	for attempt := 0; ; attempt++ {
+		resp, retryAfter, err := d.request(source, entry)
+		if err == nil || retryAfter < 0 || attempt >= d.retries {
+			return resp, err
+		}
+
+		if retryAfter < delay {
+			retryAfter = delay
+		}
+		if retryAfter > maxRetryDelay {
+			retryAfter = maxRetryDelay
+		}
+		logger.Info.Printf("Download of %s failed, retry in %s: %s", source, retryAfter, err.Error())
+		time.Sleep(retryAfter)
+		delay *= 2
+	}
+}
+
+// request does one attempt. A negative retryAfter means the request must not be repeated.
+func (d *Downloader) request(source string, entry *cacheEntry) (resp *download, retryAfter time.Duration, e error) {
+	if d.slots != nil {
+		d.slots <- struct{}{}
+		defer func() { <-d.slots }()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, -1, errors.NetworkBadURL.AddMessage(err.Error())
+	}
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
 
 	// GET request for image
-	resp, err := http.Get(imageURL.String())
+	httpResp, err := d.client.Do(req)
 	if err != nil {
-		errors.IfErrorLog(err)
-		return nil, errors.NetworkBadRequest.AddMessage(err.Error())
+		return nil, 0, errors.NetworkBadRequest.AddMessage(err.Error())
 	}
-	defer func() { errors.IfErrorLog(resp.Body.Close()) }()
+	defer func() { errors.IfErrorLog(httpResp.Body.Close()) }()
 
 	// Bad response
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.NetworkBadResponse.AddMessage(fmt.Sprintf("code: %d", resp.StatusCode))
+	switch {
+	case httpResp.StatusCode == http.StatusNotModified && entry != nil:
+		resp = &download{notModified: true}
+	case httpResp.StatusCode == http.StatusOK:
+		resp = &download{
+			etag:         httpResp.Header.Get("ETag"),
+			lastModified: httpResp.Header.Get("Last-Modified"),
+		}
+	case httpResp.StatusCode == http.StatusTooManyRequests || httpResp.StatusCode >= http.StatusInternalServerError:
+		return nil, parseRetryAfter(httpResp.Header.Get("Retry-After")), errors.NetworkBadResponse.AddMessage(fmt.Sprintf("code: %d", httpResp.StatusCode))
+	default:
+		return nil, -1, errors.NetworkBadResponse.AddMessage(fmt.Sprintf("code: %d", httpResp.StatusCode))
+	}
+	resp.expiresAt, resp.store = d.expiresAt(httpResp.Header.Get("Cache-Control"))
+	if resp.notModified {
+		return resp, 0, nil
 	}
 
 	// Read response
-	data, err := io.ReadAll(resp.Body)
+	if d.maxSize > 0 && httpResp.ContentLength > d.maxSize {
+		return nil, -1, errors.NetworkBadResponse.AddMessage(d.tooLarge())
+	}
+	body := io.Reader(httpResp.Body)
+	if d.maxSize > 0 {
+		body = io.LimitReader(httpResp.Body, d.maxSize+1)
+	}
+	resp.data, err = io.ReadAll(body)
 	if err != nil {
-		return nil, errors.InternalError.AddMessage(err.Error())
+		return nil, 0, errors.NetworkBadRequest.AddMessage(err.Error())
+	}
+	if d.maxSize > 0 && int64(len(resp.data)) > d.maxSize {
+		return nil, -1, errors.NetworkBadResponse.AddMessage(d.tooLarge())
+	}
+	return resp, 0, nil
+}
+
+// expiresAt returns until when the response can be used without asking the server and whether it can be stored at all
+func (d *Downloader) expiresAt(cacheControl string) (time.Time, bool) {
+	now := time.Now()
+	ttl := d.ttl
+	noCache := false
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store":
+			return now, false
+		case directive == "no-cache":
+			noCache = true
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	if noCache {
+		// The response is stored, but revalidated every time
+		ttl = 0
+	}
+	return now.Add(ttl), true
+}
+func (d *Downloader) tooLarge() string {
+	return fmt.Sprintf("the image is larger than %d bytes", d.maxSize)
+}
+
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
 	}
-	return data, nil
+	return time.Duration(seconds) * time.Second
 }
@@ -0,0 +1,120 @@
+package network
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+func newTestDownloader(t *testing.T) *Downloader {
+	dir, err := os.MkdirTemp("", "download_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	cfg := config.Get(false, "")
+	cfg.SetDataPath(dir)
+	cfg.DownloadCacheTTL = 0
+	d := NewDownloader(cfg)
+	d.delay = time.Millisecond
+	return d
+}
+
+func TestDownloadRevalidate(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	d := newTestDownloader(t)
+	for i := 0; i < 2; i++ {
+		data, err := d.Download(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("image"), data)
+	}
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, int32(1), notModified.Load())
+
+	// The stale image is used while the server is not available
+	server.Close()
+	data, err := d.Download(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("image"), data)
+}
+
+func TestDownloadFresh(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	d := newTestDownloader(t)
+	for i := 0; i < 3; i++ {
+		_, err := d.Download(server.URL)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestDownloadRetry(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	d := newTestDownloader(t)
+	data, err := d.Download(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("image"), data)
+	assert.Equal(t, int32(3), requests.Load())
+
+	// The client errors are not repeated
+	requests.Store(0)
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer notFound.Close()
+	_, err = d.Download(notFound.URL)
+	assert.ErrorIs(t, err, errors.NetworkBadResponse)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestDownloadMaxSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 100))
+	}))
+	defer server.Close()
+
+	d := newTestDownloader(t)
+	d.maxSize = 10
+	_, err := d.Download(server.URL)
+	assert.ErrorIs(t, err, errors.NetworkBadResponse)
+}
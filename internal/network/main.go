@@ -1,6 +1,7 @@
 package network
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -118,3 +119,14 @@ func ResponseWithMeta(w http.ResponseWriter, data interface{}, meta *Meta) {
 
 	_ = response(w, http.StatusOK, resp)
 }
+
+// ResponseImage writes the image with the validators, so the client can cache it.
+// The image can be changed under the same URL, so the client has to revalidate it every time,
+// the unchanged image is answered with 304 Not Modified without the body.
+// The etag must change together with the image, e.g. the hash of the stored image.
+func ResponseImage(w http.ResponseWriter, r *http.Request, data []byte, imgType, etag string, modTime time.Time) {
+	w.Header().Set("Content-Type", "image/"+imgType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", `"`+etag+`"`)
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(data))
+}
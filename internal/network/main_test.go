@@ -0,0 +1,52 @@
+package network
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponseImage(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("image")
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	w := httptest.NewRecorder()
+	ResponseImage(w, httptest.NewRequest(http.MethodGet, "/image", nil), data, "png", "hash", modTime)
+	if w.Code != http.StatusOK || w.Body.String() != string(data) {
+		t.Fatal("Bad response", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "image/png" {
+		t.Fatal("Bad content type", w.Header().Get("Content-Type"))
+	}
+	etag := w.Header().Get("ETag")
+	lastModified := w.Header().Get("Last-Modified")
+	if etag != `"hash"` || lastModified == "" {
+		t.Fatal("The validators are not set", w.Header())
+	}
+
+	for _, header := range []string{"If-None-Match", "If-Modified-Since"} {
+		r := httptest.NewRequest(http.MethodGet, "/image", nil)
+		if header == "If-None-Match" {
+			r.Header.Set(header, etag)
+		} else {
+			r.Header.Set(header, lastModified)
+		}
+		w = httptest.NewRecorder()
+		ResponseImage(w, r, data, "png", "hash", modTime)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Fatal("The unchanged image should not be sent", header, w.Code)
+		}
+	}
+
+	// The changed image is sent again
+	r := httptest.NewRequest(http.MethodGet, "/image", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	ResponseImage(w, r, []byte("changed"), "png", "changed", modTime)
+	if w.Code != http.StatusOK {
+		t.Fatal("The changed image should be sent", w.Code)
+	}
+}
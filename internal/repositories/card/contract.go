@@ -11,6 +11,9 @@ type Card interface {
 	Update(gameID, collectionID, deckID string, cardID int64, req UpdateRequest) (*entitiesCard.Card, error)
 	DeleteByID(gameID, collectionID, deckID string, cardID int64) error
 	GetImage(gameID, collectionID, deckID string, cardID int64) ([]byte, string, error)
+	RefreshImage(gameID, collectionID, deckID string, cardID int64, onlyChanged bool) (bool, error)
+	Copy(gameID, collectionID, deckID string, cardID int64, req CopyRequest) (*entitiesCard.Card, error)
+	Move(gameID, collectionID, deckID string, cardID int64, req MoveRequest) (*entitiesCard.Card, error)
 }
 
 type CreateRequest struct {
@@ -30,3 +33,15 @@ type UpdateRequest struct {
 	Count       int
 	ImageFile   []byte
 }
+
+type CopyRequest struct {
+	GameID       string
+	CollectionID string
+	DeckID       string
+}
+
+type MoveRequest struct {
+	GameID       string
+	CollectionID string
+	DeckID       string
+}
@@ -1,28 +1,36 @@
 package card
 
 import (
+	"bytes"
 	"context"
 	"errors"
 
 	"github.com/HardDie/DeckBuilder/internal/config"
 	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
 	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
 	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/images"
 	"github.com/HardDie/DeckBuilder/internal/logger"
 	"github.com/HardDie/DeckBuilder/internal/network"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
 	"github.com/HardDie/DeckBuilder/internal/utils"
 )
 
 type card struct {
-	cfg  *config.Config
-	card dbCard.Card
+	cfg     *config.Config
+	card    dbCard.Card
+	history repositoriesHistory.History
+	trash   repositoriesTrash.Trash
 }
 
-func New(cfg *config.Config, c dbCard.Card) Card {
+func New(cfg *config.Config, c dbCard.Card, history repositoriesHistory.History, trash repositoriesTrash.Trash) Card {
 	return &card{
-		cfg:  cfg,
-		card: c,
+		cfg:     cfg,
+		card:    c,
+		history: history,
+		trash:   trash,
 	}
 }
 
@@ -79,6 +87,17 @@ func (r *card) Update(gameID, collectionID, deckID string, cardID int64, req Upd
 		req.ImageFile != nil ||
 		oldCard.Count != req.Count ||
 		!utils.CompareMaps(oldCard.Variables, req.Variables) {
+		// Save the current state, so the change can be undone
+		err = r.history.Record(entitiesHistory.Target{
+			GameID:       gameID,
+			CollectionID: collectionID,
+			DeckID:       deckID,
+			CardID:       cardID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
 		// Update data
 		newCard, err = r.card.Update(context.Background(), dbCard.UpdateRequest{
 			GameID:       gameID,
@@ -133,14 +152,12 @@ func (r *card) Update(gameID, collectionID, deckID string, cardID int64, req Upd
 	return newCard, nil
 }
 func (r *card) DeleteByID(gameID, collectionID, deckID string, cardID int64) error {
-	err := r.card.ImageDelete(context.Background(), gameID, collectionID, deckID, cardID)
-	if err != nil {
-		// Skip if image not exist
-		if !errors.Is(err, er.CardImageNotExists) {
-			return err
-		}
-	}
-	return r.card.Delete(context.Background(), gameID, collectionID, deckID, cardID)
+	return r.trash.Put(entitiesHistory.Target{
+		GameID:       gameID,
+		CollectionID: collectionID,
+		DeckID:       deckID,
+		CardID:       cardID,
+	})
 }
 func (r *card) GetImage(gameID, collectionID, deckID string, cardID int64) ([]byte, string, error) {
 	data, err := r.card.ImageGet(context.Background(), gameID, collectionID, deckID, cardID)
@@ -155,7 +172,151 @@ func (r *card) GetImage(gameID, collectionID, deckID string, cardID int64) ([]by
 
 	return data, imgType, nil
 }
+func (r *card) RefreshImage(gameID, collectionID, deckID string, cardID int64, onlyChanged bool) (bool, error) {
+	c, err := r.card.Get(context.Background(), gameID, collectionID, deckID, cardID)
+	if err != nil {
+		return false, err
+	}
+	if c.Image == "" {
+		// The image was uploaded as a file, there is nothing to download
+		return false, nil
+	}
+
+	data, err := r.downloadImage(c.Image)
+	if err != nil {
+		return false, err
+	}
+	oldData, _, _ := r.GetImage(gameID, collectionID, deckID, cardID)
+	changed := !bytes.Equal(oldData, data)
+	if !changed && onlyChanged {
+		return false, nil
+	}
+
+	if changed {
+		// Save the current state, so the change can be undone
+		err = r.history.Record(entitiesHistory.Target{
+			GameID:       gameID,
+			CollectionID: collectionID,
+			DeckID:       deckID,
+			CardID:       cardID,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	if oldData != nil {
+		err = r.card.ImageDelete(context.Background(), gameID, collectionID, deckID, cardID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return changed, r.card.ImageCreate(context.Background(), gameID, collectionID, deckID, cardID, data)
+}
+func (r *card) Copy(gameID, collectionID, deckID string, cardID int64, req CopyRequest) (*entitiesCard.Card, error) {
+	oldCard, err := r.card.Get(context.Background(), gameID, collectionID, deckID, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the image of the source card, if it exists
+	data, err := r.card.ImageGet(context.Background(), gameID, collectionID, deckID, cardID)
+	if err != nil {
+		// Skip if image not exist
+		if !errors.Is(err, er.CardImageNotExists) {
+			return nil, err
+		}
+	}
+
+	// Create a card in the destination deck, a new identifier will be allocated
+	newCard, err := r.card.Create(context.Background(), dbCard.CreateRequest{
+		GameID:       req.GameID,
+		CollectionID: req.CollectionID,
+		DeckID:       req.DeckID,
+		Name:         oldCard.Name,
+		Description:  oldCard.Description,
+		Image:        oldCard.Image,
+		Variables:    oldCard.Variables,
+		Count:        oldCard.Count,
+		CreatedAt:    utils.Allocate(oldCard.CreatedAt),
+		UpdatedAt:    utils.Allocate(oldCard.UpdatedAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if data == nil {
+		return newCard, nil
+	}
+
+	// Copy the image binary without downloading it again
+	err = r.card.ImageCreate(context.Background(), req.GameID, req.CollectionID, req.DeckID, newCard.ID, data)
+	if err != nil {
+		// Don't leave the card without the image in the destination deck
+		r.rollback(req.GameID, req.CollectionID, req.DeckID, newCard.ID)
+		return nil, err
+	}
+	return newCard, nil
+}
+func (r *card) Move(gameID, collectionID, deckID string, cardID int64, req MoveRequest) (*entitiesCard.Card, error) {
+	if gameID == req.GameID && collectionID == req.CollectionID && deckID == req.DeckID {
+		// The card is already in the destination deck
+		return r.card.Get(context.Background(), gameID, collectionID, deckID, cardID)
+	}
+
+	newCard, err := r.Copy(gameID, collectionID, deckID, cardID, CopyRequest{
+		GameID:       req.GameID,
+		CollectionID: req.CollectionID,
+		DeckID:       req.DeckID,
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	// The revisions follow the card, so the changes made before the move can still be undone
+	err = r.history.Move(entitiesHistory.Target{
+		GameID:       gameID,
+		CollectionID: collectionID,
+		DeckID:       deckID,
+		CardID:       cardID,
+	}, entitiesHistory.Target{
+		GameID:       req.GameID,
+		CollectionID: req.CollectionID,
+		DeckID:       req.DeckID,
+		CardID:       newCard.ID,
+	})
+	if err != nil {
+		// The source card is kept, so the copy is removed to not end up with the card in both decks
+		r.rollback(req.GameID, req.CollectionID, req.DeckID, newCard.ID)
+		return nil, err
+	}
+
+	// Remove the source card only after the copy has been successfully created
+	err = r.remove(gameID, collectionID, deckID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	return newCard, nil
+}
+
+// remove deletes the moved card permanently, bypassing the trash. The revisions have already been moved.
+func (r *card) remove(gameID, collectionID, deckID string, cardID int64) error {
+	err := r.card.ImageDelete(context.Background(), gameID, collectionID, deckID, cardID)
+	if err != nil {
+		// Skip if image not exist
+		if !errors.Is(err, er.CardImageNotExists) {
+			return err
+		}
+	}
+	return r.card.Delete(context.Background(), gameID, collectionID, deckID, cardID)
+}
+
+// rollback removes the card created by the failed copy or move, the original error is returned to the caller
+func (r *card) rollback(gameID, collectionID, deckID string, cardID int64) {
+	err := r.remove(gameID, collectionID, deckID, cardID)
+	if err != nil {
+		logger.Error.Printf("can't remove the card %d of the failed copy: %s", cardID, err.Error())
+	}
+}
 func (r *card) createImage(gameID, collectionID, deckID string, cardID int64, imageURL string) error {
 	// Download image
 	imageBytes, err := network.DownloadBytes(imageURL)
@@ -165,9 +326,18 @@ func (r *card) createImage(gameID, collectionID, deckID string, cardID int64, im
 
 	return r.createImageFromByte(gameID, collectionID, deckID, cardID, imageBytes)
 }
+
+// downloadImage downloads the image even if the cached copy is still fresh
+func (r *card) downloadImage(imageURL string) ([]byte, error) {
+	data, err := network.RefreshBytes(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	return images.Normalize(data, r.cfg.MaxImagePixels, r.cfg.ImageFormat)
+}
 func (r *card) createImageFromByte(gameID, collectionID, deckID string, cardID int64, data []byte) error {
-	// Validate image
-	_, err := images.ValidateImage(data)
+	// Validate image and convert it into the stored form
+	data, err := images.Normalize(data, r.cfg.MaxImagePixels, r.cfg.ImageFormat)
 	if err != nil {
 		return err
 	}
@@ -11,6 +11,8 @@ type Collection interface {
 	Update(gameID, collectionID string, req UpdateRequest) (*entitiesCollection.Collection, error)
 	DeleteByID(gameID, collectionID string) error
 	GetImage(gameID, collectionID string) ([]byte, string, error)
+	RefreshImage(gameID, collectionID string, onlyChanged bool) (bool, error)
+	Duplicate(gameID, collectionID string, req DuplicateRequest) (*entitiesCollection.Collection, error)
 }
 
 type CreateRequest struct {
@@ -26,3 +28,9 @@ type UpdateRequest struct {
 	Image       string
 	ImageFile   []byte
 }
+
+type DuplicateRequest struct {
+	Name string
+	// Destination
+	GameID string
+}
@@ -1,25 +1,38 @@
 package collection
 
 import (
+	"bytes"
 	"context"
+	"errors"
 
 	"github.com/HardDie/DeckBuilder/internal/config"
 	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
 	entitiesCollection "github.com/HardDie/DeckBuilder/internal/entities/collection"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/images"
 	"github.com/HardDie/DeckBuilder/internal/logger"
 	"github.com/HardDie/DeckBuilder/internal/network"
+	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
 )
 
 type collection struct {
-	cfg        *config.Config
-	collection dbCollection.Collection
+	cfg            *config.Config
+	collection     dbCollection.Collection
+	repositoryDeck repositoriesDeck.Deck
+	history        repositoriesHistory.History
+	trash          repositoriesTrash.Trash
 }
 
-func New(cfg *config.Config, c dbCollection.Collection) Collection {
+func New(cfg *config.Config, c dbCollection.Collection, repositoryDeck repositoriesDeck.Deck, history repositoriesHistory.History, trash repositoriesTrash.Trash) Collection {
 	return &collection{
-		cfg:        cfg,
-		collection: c,
+		cfg:            cfg,
+		collection:     c,
+		repositoryDeck: repositoryDeck,
+		history:        history,
+		trash:          trash,
 	}
 }
 
@@ -65,6 +78,18 @@ func (r *collection) Update(gameID, collectionID string, req UpdateRequest) (*en
 		return nil, err
 	}
 
+	target := entitiesHistory.Target{GameID: gameID, CollectionID: oldCollection.ID}
+	if oldCollection.Name != req.Name ||
+		oldCollection.Description != req.Description ||
+		oldCollection.Image != req.Image ||
+		req.ImageFile != nil {
+		// Save the current state, so the change can be undone
+		err = r.history.Record(target)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var newCollection *entitiesCollection.Collection
 	if oldCollection.Name != req.Name {
 		// Rename folder
@@ -72,6 +97,10 @@ func (r *collection) Update(gameID, collectionID string, req UpdateRequest) (*en
 		if err != nil {
 			return nil, err
 		}
+		err = r.history.Move(target, target.Rename(newCollection.ID))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if oldCollection.Description != req.Description ||
@@ -127,7 +156,7 @@ func (r *collection) Update(gameID, collectionID string, req UpdateRequest) (*en
 	return newCollection, nil
 }
 func (r *collection) DeleteByID(gameID, collectionID string) error {
-	return r.collection.Delete(context.Background(), gameID, collectionID)
+	return r.trash.Put(entitiesHistory.Target{GameID: gameID, CollectionID: collectionID})
 }
 func (r *collection) GetImage(gameID, collectionID string) ([]byte, string, error) {
 	data, err := r.collection.ImageGet(context.Background(), gameID, collectionID)
@@ -142,6 +171,95 @@ func (r *collection) GetImage(gameID, collectionID string) ([]byte, string, erro
 
 	return data, imgType, nil
 }
+func (r *collection) RefreshImage(gameID, collectionID string, onlyChanged bool) (bool, error) {
+	c, err := r.collection.Get(context.Background(), gameID, collectionID)
+	if err != nil {
+		return false, err
+	}
+	if c.Image == "" {
+		// The image was uploaded as a file, there is nothing to download
+		return false, nil
+	}
+
+	data, err := r.downloadImage(c.Image)
+	if err != nil {
+		return false, err
+	}
+	oldData, _, _ := r.GetImage(gameID, c.ID)
+	changed := !bytes.Equal(oldData, data)
+	if !changed && onlyChanged {
+		return false, nil
+	}
+
+	if changed {
+		// Save the current state, so the change can be undone
+		err = r.history.Record(entitiesHistory.Target{GameID: gameID, CollectionID: c.ID})
+		if err != nil {
+			return false, err
+		}
+	}
+	if oldData != nil {
+		err = r.collection.ImageDelete(context.Background(), gameID, c.ID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return changed, r.collection.ImageCreate(context.Background(), gameID, c.ID, data)
+}
+func (r *collection) Duplicate(gameID, collectionID string, req DuplicateRequest) (*entitiesCollection.Collection, error) {
+	oldCollection, err := r.collection.Get(context.Background(), gameID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	newCollection, err := r.collection.Create(context.Background(), dbCollection.CreateRequest{
+		GameID:      req.GameID,
+		Name:        req.Name,
+		Description: oldCollection.Description,
+		Image:       oldCollection.Image,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.duplicateContent(gameID, oldCollection.ID, req.GameID, newCollection.ID)
+	if err != nil {
+		// Don't leave a half-copied collection behind
+		er.IfErrorLog(r.collection.Delete(context.Background(), req.GameID, newCollection.ID))
+		return nil, err
+	}
+	return newCollection, nil
+}
+
+// Copy the image of the collection and all nested decks
+func (r *collection) duplicateContent(gameID, collectionID, dstGameID, dstCollectionID string) error {
+	data, err := r.collection.ImageGet(context.Background(), gameID, collectionID)
+	if err != nil && !errors.Is(err, er.CollectionImageNotExists) {
+		return err
+	}
+	if data != nil {
+		err = r.collection.ImageCreate(context.Background(), dstGameID, dstCollectionID, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	decks, err := r.repositoryDeck.GetAll(gameID, collectionID)
+	if err != nil {
+		return err
+	}
+	for _, d := range decks {
+		_, err = r.repositoryDeck.Duplicate(gameID, collectionID, d.ID, repositoriesDeck.DuplicateRequest{
+			Name:         d.Name,
+			GameID:       dstGameID,
+			CollectionID: dstCollectionID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
 func (r *collection) createImage(gameID, collectionID, imageURL string) error {
 	// Download image
@@ -152,9 +270,18 @@ func (r *collection) createImage(gameID, collectionID, imageURL string) error {
 
 	return r.createImageFromByte(gameID, collectionID, imageBytes)
 }
+
+// downloadImage downloads the image even if the cached copy is still fresh
+func (r *collection) downloadImage(imageURL string) ([]byte, error) {
+	data, err := network.RefreshBytes(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	return images.Normalize(data, r.cfg.MaxImagePixels, r.cfg.ImageFormat)
+}
 func (r *collection) createImageFromByte(gameID, collectionID string, data []byte) error {
-	// Validate image
-	_, err := images.ValidateImage(data)
+	// Validate image and convert it into the stored form
+	data, err := images.Normalize(data, r.cfg.MaxImagePixels, r.cfg.ImageFormat)
 	if err != nil {
 		return err
 	}
@@ -11,7 +11,9 @@ type Deck interface {
 	Update(gameID, collectionID, deckID string, req UpdateRequest) (*entitiesDeck.Deck, error)
 	DeleteByID(gameID, collectionID, deckID string) error
 	GetImage(gameID, collectionID, deckID string) ([]byte, string, error)
+	RefreshImage(gameID, collectionID, deckID string, onlyChanged bool) (bool, error)
 	GetAllDecksInGame(gameID string) ([]*entitiesDeck.Deck, error)
+	Duplicate(gameID, collectionID, deckID string, req DuplicateRequest) (*entitiesDeck.Deck, error)
 }
 
 type CreateRequest struct {
@@ -27,3 +29,10 @@ type UpdateRequest struct {
 	Image       string
 	ImageFile   []byte
 }
+
+type DuplicateRequest struct {
+	Name string
+	// Destination
+	GameID       string
+	CollectionID string
+}
@@ -1,28 +1,42 @@
 package deck
 
 import (
+	"bytes"
 	"context"
+	"errors"
 
 	"github.com/HardDie/DeckBuilder/internal/config"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
 	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
 	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
 	entitiesDeck "github.com/HardDie/DeckBuilder/internal/entities/deck"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/images"
 	"github.com/HardDie/DeckBuilder/internal/logger"
 	"github.com/HardDie/DeckBuilder/internal/network"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
+	"github.com/HardDie/DeckBuilder/internal/utils"
 )
 
 type deck struct {
 	cfg        *config.Config
 	collection dbCollection.Collection
 	deck       dbDeck.Deck
+	card       dbCard.Card
+	history    repositoriesHistory.History
+	trash      repositoriesTrash.Trash
 }
 
-func New(cfg *config.Config, c dbCollection.Collection, d dbDeck.Deck) Deck {
+func New(cfg *config.Config, c dbCollection.Collection, d dbDeck.Deck, cd dbCard.Card, history repositoriesHistory.History, trash repositoriesTrash.Trash) Deck {
 	return &deck{
 		cfg:        cfg,
 		collection: c,
 		deck:       d,
+		card:       cd,
+		history:    history,
+		trash:      trash,
 	}
 }
 
@@ -69,6 +83,18 @@ func (r *deck) Update(gameID, collectionID, deckID string, req UpdateRequest) (*
 		return nil, err
 	}
 
+	target := entitiesHistory.Target{GameID: gameID, CollectionID: collectionID, DeckID: oldDeck.ID}
+	if oldDeck.Name != req.Name ||
+		oldDeck.Description != req.Description ||
+		oldDeck.Image != req.Image ||
+		req.ImageFile != nil {
+		// Save the current state, so the change can be undone
+		err = r.history.Record(target)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var newDeck *entitiesDeck.Deck
 	if oldDeck.Name != req.Name {
 		// Rename folder
@@ -76,6 +102,10 @@ func (r *deck) Update(gameID, collectionID, deckID string, req UpdateRequest) (*
 		if err != nil {
 			return nil, err
 		}
+		err = r.history.Move(target, target.Rename(newDeck.ID))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if oldDeck.Description != req.Description ||
@@ -132,7 +162,7 @@ func (r *deck) Update(gameID, collectionID, deckID string, req UpdateRequest) (*
 	return newDeck, nil
 }
 func (r *deck) DeleteByID(gameID, collectionID, deckID string) error {
-	return r.deck.Delete(context.Background(), gameID, collectionID, deckID)
+	return r.trash.Put(entitiesHistory.Target{GameID: gameID, CollectionID: collectionID, DeckID: deckID})
 }
 func (r *deck) GetImage(gameID, collectionID, deckID string) ([]byte, string, error) {
 	data, err := r.deck.ImageGet(context.Background(), gameID, collectionID, deckID)
@@ -147,6 +177,41 @@ func (r *deck) GetImage(gameID, collectionID, deckID string) ([]byte, string, er
 
 	return data, imgType, nil
 }
+func (r *deck) RefreshImage(gameID, collectionID, deckID string, onlyChanged bool) (bool, error) {
+	d, err := r.deck.Get(context.Background(), gameID, collectionID, deckID)
+	if err != nil {
+		return false, err
+	}
+	if d.Image == "" {
+		// The image was uploaded as a file, there is nothing to download
+		return false, nil
+	}
+
+	data, err := r.downloadImage(d.Image)
+	if err != nil {
+		return false, err
+	}
+	oldData, _, _ := r.GetImage(gameID, collectionID, d.ID)
+	changed := !bytes.Equal(oldData, data)
+	if !changed && onlyChanged {
+		return false, nil
+	}
+
+	if changed {
+		// Save the current state, so the change can be undone
+		err = r.history.Record(entitiesHistory.Target{GameID: gameID, CollectionID: collectionID, DeckID: d.ID})
+		if err != nil {
+			return false, err
+		}
+	}
+	if oldData != nil {
+		err = r.deck.ImageDelete(context.Background(), gameID, collectionID, d.ID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return changed, r.deck.ImageCreate(context.Background(), gameID, collectionID, d.ID, data)
+}
 func (r *deck) GetAllDecksInGame(gameID string) ([]*entitiesDeck.Deck, error) {
 	// Get all collections in selected game
 	listCollections, err := r.collection.List(context.Background(), gameID)
@@ -179,6 +244,82 @@ func (r *deck) GetAllDecksInGame(gameID string) ([]*entitiesDeck.Deck, error) {
 	}
 	return decks, nil
 }
+func (r *deck) Duplicate(gameID, collectionID, deckID string, req DuplicateRequest) (*entitiesDeck.Deck, error) {
+	oldDeck, err := r.deck.Get(context.Background(), gameID, collectionID, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	newDeck, err := r.deck.Create(context.Background(), dbDeck.CreateRequest{
+		GameID:       req.GameID,
+		CollectionID: req.CollectionID,
+		Name:         req.Name,
+		Description:  oldDeck.Description,
+		Image:        oldDeck.Image,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.duplicateContent(gameID, collectionID, oldDeck.ID, req.GameID, req.CollectionID, newDeck.ID)
+	if err != nil {
+		// Don't leave a half-copied deck behind
+		er.IfErrorLog(r.deck.Delete(context.Background(), req.GameID, req.CollectionID, newDeck.ID))
+		return nil, err
+	}
+	return newDeck, nil
+}
+
+// Copy the image of the deck and all its cards with images
+func (r *deck) duplicateContent(gameID, collectionID, deckID, dstGameID, dstCollectionID, dstDeckID string) error {
+	data, err := r.deck.ImageGet(context.Background(), gameID, collectionID, deckID)
+	if err != nil && !errors.Is(err, er.DeckImageNotExists) {
+		return err
+	}
+	if data != nil {
+		err = r.deck.ImageCreate(context.Background(), dstGameID, dstCollectionID, dstDeckID, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	cards, err := r.card.List(context.Background(), gameID, collectionID, deckID)
+	if err != nil {
+		return err
+	}
+	for _, c := range cards {
+		// The deck is new, so the cards keep their identifiers and order
+		newCard, err := r.card.Create(context.Background(), dbCard.CreateRequest{
+			ID:           c.ID,
+			GameID:       dstGameID,
+			CollectionID: dstCollectionID,
+			DeckID:       dstDeckID,
+			Name:         c.Name,
+			Description:  c.Description,
+			Image:        c.Image,
+			Variables:    c.Variables,
+			Count:        c.Count,
+			CreatedAt:    utils.Allocate(c.CreatedAt),
+			UpdatedAt:    utils.Allocate(c.UpdatedAt),
+		})
+		if err != nil {
+			return err
+		}
+
+		data, err = r.card.ImageGet(context.Background(), gameID, collectionID, deckID, c.ID)
+		if err != nil {
+			if errors.Is(err, er.CardImageNotExists) {
+				continue
+			}
+			return err
+		}
+		err = r.card.ImageCreate(context.Background(), dstGameID, dstCollectionID, dstDeckID, newCard.ID, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
 func (r *deck) createImage(gameID, collectionID, deckID, imageURL string) error {
 	// Download image
@@ -189,9 +330,18 @@ func (r *deck) createImage(gameID, collectionID, deckID, imageURL string) error
 
 	return r.createImageFromByte(gameID, collectionID, deckID, imageBytes)
 }
+
+// downloadImage downloads the image even if the cached copy is still fresh
+func (r *deck) downloadImage(imageURL string) ([]byte, error) {
+	data, err := network.RefreshBytes(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	return images.Normalize(data, r.cfg.MaxImagePixels, r.cfg.ImageFormat)
+}
 func (r *deck) createImageFromByte(gameID, collectionID, deckID string, data []byte) error {
-	// Validate image
-	_, err := images.ValidateImage(data)
+	// Validate image and convert it into the stored form
+	data, err := images.Normalize(data, r.cfg.MaxImagePixels, r.cfg.ImageFormat)
 	if err != nil {
 		return err
 	}
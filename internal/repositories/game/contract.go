@@ -1,6 +1,8 @@
 package game
 
 import (
+	"io"
+
 	entitiesGame "github.com/HardDie/DeckBuilder/internal/entities/game"
 )
 
@@ -11,9 +13,10 @@ type Game interface {
 	Update(gameID string, req UpdateRequest) (*entitiesGame.Game, error)
 	DeleteByID(gameID string) error
 	GetImage(gameID string) ([]byte, string, error)
+	RefreshImage(gameID string, onlyChanged bool) (bool, error)
 	Duplicate(gameID string, req DuplicateRequest) (*entitiesGame.Game, error)
-	Export(gameID string) ([]byte, error)
-	Import(data []byte, name string) (*entitiesGame.Game, error)
+	Export(gameID string, w io.Writer) error
+	Import(r io.ReaderAt, size int64, name string) (*entitiesGame.Game, error)
 }
 
 type CreateRequest struct {
@@ -1,29 +1,39 @@
 package game
 
 import (
+	"bytes"
 	"context"
-	"path/filepath"
+	"io"
 
 	"github.com/HardDie/DeckBuilder/internal/config"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
 	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
 	entitiesGame "github.com/HardDie/DeckBuilder/internal/entities/game"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
 	"github.com/HardDie/DeckBuilder/internal/errors"
-	"github.com/HardDie/DeckBuilder/internal/fs"
 	"github.com/HardDie/DeckBuilder/internal/images"
 	"github.com/HardDie/DeckBuilder/internal/logger"
 	"github.com/HardDie/DeckBuilder/internal/network"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
 	"github.com/HardDie/DeckBuilder/internal/utils"
 )
 
 type game struct {
-	cfg  *config.Config
-	game dbGame.Game
+	cfg     *config.Config
+	game    dbGame.Game
+	archive dbArchive.Archive
+	history repositoriesHistory.History
+	trash   repositoriesTrash.Trash
 }
 
-func New(cfg *config.Config, g dbGame.Game) Game {
+func New(cfg *config.Config, g dbGame.Game, a dbArchive.Archive, history repositoriesHistory.History, trash repositoriesTrash.Trash) Game {
 	return &game{
-		cfg:  cfg,
-		game: g,
+		cfg:     cfg,
+		game:    g,
+		archive: a,
+		history: history,
+		trash:   trash,
 	}
 }
 
@@ -68,6 +78,17 @@ func (r *game) Update(gameID string, req UpdateRequest) (*entitiesGame.Game, err
 		return nil, err
 	}
 
+	if oldGame.Name != req.Name ||
+		oldGame.Description != req.Description ||
+		oldGame.Image != req.Image ||
+		req.ImageFile != nil {
+		// Save the current state, so the change can be undone
+		err = r.history.Record(entitiesHistory.Target{GameID: oldGame.ID})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var newGame *entitiesGame.Game
 	if oldGame.Name != req.Name {
 		// Rename folder
@@ -75,6 +96,10 @@ func (r *game) Update(gameID string, req UpdateRequest) (*entitiesGame.Game, err
 		if err != nil {
 			return nil, err
 		}
+		err = r.history.Move(entitiesHistory.Target{GameID: oldGame.ID}, entitiesHistory.Target{GameID: newGame.ID})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if oldGame.Description != req.Description ||
@@ -129,7 +154,7 @@ func (r *game) Update(gameID string, req UpdateRequest) (*entitiesGame.Game, err
 	return newGame, nil
 }
 func (r *game) DeleteByID(gameID string) error {
-	return r.game.Delete(context.Background(), gameID)
+	return r.trash.Put(entitiesHistory.Target{GameID: gameID})
 }
 func (r *game) GetImage(gameID string) ([]byte, string, error) {
 	data, err := r.game.ImageGet(context.Background(), gameID)
@@ -144,6 +169,41 @@ func (r *game) GetImage(gameID string) ([]byte, string, error) {
 
 	return data, imgType, nil
 }
+func (r *game) RefreshImage(gameID string, onlyChanged bool) (bool, error) {
+	g, err := r.game.Get(context.Background(), gameID)
+	if err != nil {
+		return false, err
+	}
+	if g.Image == "" {
+		// The image was uploaded as a file, there is nothing to download
+		return false, nil
+	}
+
+	data, err := r.downloadImage(g.Image)
+	if err != nil {
+		return false, err
+	}
+	oldData, _, _ := r.GetImage(g.ID)
+	changed := !bytes.Equal(oldData, data)
+	if !changed && onlyChanged {
+		return false, nil
+	}
+
+	if changed {
+		// Save the current state, so the change can be undone
+		err = r.history.Record(entitiesHistory.Target{GameID: g.ID})
+		if err != nil {
+			return false, err
+		}
+	}
+	if oldData != nil {
+		err = r.game.ImageDelete(context.Background(), g.ID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return changed, r.game.ImageCreate(context.Background(), g.ID, data)
+}
 func (r *game) Duplicate(gameID string, req DuplicateRequest) (*entitiesGame.Game, error) {
 	g, err := r.game.Duplicate(context.Background(), gameID, req.Name)
 	if err != nil {
@@ -151,23 +211,23 @@ func (r *game) Duplicate(gameID string, req DuplicateRequest) (*entitiesGame.Gam
 	}
 	return g, nil
 }
-func (r *game) Export(gameID string) ([]byte, error) {
+func (r *game) Export(gameID string, w io.Writer) error {
 	// Check if such an object exists
 	g, err := r.GetByID(gameID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return fs.ArchiveFolder(filepath.Join(r.cfg.Games(), g.ID), g.ID)
+	return r.archive.Export(context.Background(), w, g.ID)
 }
-func (r *game) Import(data []byte, name string) (*entitiesGame.Game, error) {
+func (r *game) Import(data io.ReaderAt, size int64, name string) (*entitiesGame.Game, error) {
 	gameID := utils.NameToID(name)
 	if name != "" && gameID == "" {
 		return nil, errors.BadName
 	}
 
 	// Unpack the archive
-	resultGameID, err := fs.UnarchiveFolder(data, gameID, r.cfg)
+	resultGameID, err := r.archive.Import(context.Background(), data, size, gameID)
 	if err != nil {
 		return nil, err
 	}
@@ -211,9 +271,18 @@ func (r *game) createImage(gameID, imageURL string) error {
 
 	return r.createImageFromByte(gameID, imageBytes)
 }
+
+// downloadImage downloads the image even if the cached copy is still fresh
+func (r *game) downloadImage(imageURL string) ([]byte, error) {
+	data, err := network.RefreshBytes(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	return images.Normalize(data, r.cfg.MaxImagePixels, r.cfg.ImageFormat)
+}
 func (r *game) createImageFromByte(gameID string, data []byte) error {
-	// Validate image
-	_, err := images.ValidateImage(data)
+	// Validate image and convert it into the stored form
+	data, err := images.Normalize(data, r.cfg.MaxImagePixels, r.cfg.ImageFormat)
 	if err != nil {
 		return err
 	}
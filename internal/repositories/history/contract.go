@@ -0,0 +1,17 @@
+package history
+
+import (
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+)
+
+type History interface {
+	Record(target entitiesHistory.Target) error
+	GetAll(target entitiesHistory.Target) ([]*entitiesHistory.Revision, error)
+	GetByID(target entitiesHistory.Target, revisionID int64) (*entitiesHistory.Revision, error)
+	GetImage(target entitiesHistory.Target, revisionID int64) ([]byte, error)
+	Current(target entitiesHistory.Target) (*entitiesHistory.Revision, []byte, error)
+	Restore(target entitiesHistory.Target, revisionID int64) (entitiesHistory.Target, error)
+	// Move moves the revisions of the entity and the nested entities to the new target, e.g. after a rename
+	Move(target, newTarget entitiesHistory.Target) error
+	Delete(target entitiesHistory.Target) error
+}
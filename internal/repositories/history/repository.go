@@ -0,0 +1,346 @@
+package history
+
+import (
+	"context"
+	"errors"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+type history struct {
+	cfg        *config.Config
+	history    dbHistory.History
+	game       dbGame.Game
+	collection dbCollection.Collection
+	deck       dbDeck.Deck
+	card       dbCard.Card
+}
+
+func New(cfg *config.Config, h dbHistory.History, g dbGame.Game, c dbCollection.Collection, d dbDeck.Deck, cd dbCard.Card) History {
+	return &history{
+		cfg:        cfg,
+		history:    h,
+		game:       g,
+		collection: c,
+		deck:       d,
+		card:       cd,
+	}
+}
+
+// Record saves the current state of the entity as a new revision.
+// Must be called before the entity is changed.
+func (r *history) Record(target entitiesHistory.Target) error {
+	current, data, err := r.Current(target)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.history.Create(context.Background(), dbHistory.CreateRequest{
+		Target:      target,
+		Name:        current.Name,
+		Description: current.Description,
+		Image:       current.Image,
+		Variables:   current.Variables,
+		Count:       current.Count,
+		ImageFile:   data,
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.cfg.HistoryLimit <= 0 {
+		return nil
+	}
+
+	// Remove the oldest revisions that exceed the limit
+	revisions, err := r.history.List(context.Background(), target)
+	if err != nil {
+		return err
+	}
+	for i := r.cfg.HistoryLimit; i < len(revisions); i++ {
+		err = r.history.Delete(context.Background(), target, revisions[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (r *history) GetAll(target entitiesHistory.Target) ([]*entitiesHistory.Revision, error) {
+	// Make sure the entity exists
+	_, _, err := r.Current(target)
+	if err != nil {
+		return nil, err
+	}
+	return r.history.List(context.Background(), target)
+}
+func (r *history) GetByID(target entitiesHistory.Target, revisionID int64) (*entitiesHistory.Revision, error) {
+	return r.history.Get(context.Background(), target, revisionID)
+}
+func (r *history) GetImage(target entitiesHistory.Target, revisionID int64) ([]byte, error) {
+	return r.history.ImageGet(context.Background(), target, revisionID)
+}
+
+// Current returns the current state of the entity in the form of a revision and the image binary
+func (r *history) Current(target entitiesHistory.Target) (*entitiesHistory.Revision, []byte, error) {
+	var current *entitiesHistory.Revision
+	var data []byte
+	var err error
+
+	switch {
+	case target.IsCard():
+		item, e := r.card.Get(context.Background(), target.GameID, target.CollectionID, target.DeckID, target.CardID)
+		if e != nil {
+			return nil, nil, e
+		}
+		current = &entitiesHistory.Revision{
+			Name:        item.Name,
+			Description: item.Description,
+			Image:       item.Image,
+			Variables:   item.Variables,
+			Count:       item.Count,
+			CreatedAt:   item.UpdatedAt,
+		}
+		data, err = r.card.ImageGet(context.Background(), target.GameID, target.CollectionID, target.DeckID, target.CardID)
+		if errors.Is(err, er.CardImageNotExists) {
+			err = nil
+		}
+	case target.IsDeck():
+		item, e := r.deck.Get(context.Background(), target.GameID, target.CollectionID, target.DeckID)
+		if e != nil {
+			return nil, nil, e
+		}
+		current = &entitiesHistory.Revision{
+			Name:        item.Name,
+			Description: item.Description,
+			Image:       item.Image,
+			CreatedAt:   item.UpdatedAt,
+		}
+		data, err = r.deck.ImageGet(context.Background(), target.GameID, target.CollectionID, target.DeckID)
+		if errors.Is(err, er.DeckImageNotExists) {
+			err = nil
+		}
+	case target.IsCollection():
+		item, e := r.collection.Get(context.Background(), target.GameID, target.CollectionID)
+		if e != nil {
+			return nil, nil, e
+		}
+		current = &entitiesHistory.Revision{
+			Name:        item.Name,
+			Description: item.Description,
+			Image:       item.Image,
+			CreatedAt:   item.UpdatedAt,
+		}
+		data, err = r.collection.ImageGet(context.Background(), target.GameID, target.CollectionID)
+		if errors.Is(err, er.CollectionImageNotExists) {
+			err = nil
+		}
+	default:
+		item, e := r.game.Get(context.Background(), target.GameID)
+		if e != nil {
+			return nil, nil, e
+		}
+		current = &entitiesHistory.Revision{
+			Name:        item.Name,
+			Description: item.Description,
+			Image:       item.Image,
+			CreatedAt:   item.UpdatedAt,
+		}
+		data, err = r.game.ImageGet(context.Background(), target.GameID)
+		if errors.Is(err, er.GameImageNotExists) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current.HasImage = data != nil
+	return current, data, nil
+}
+
+// Restore returns the entity to the state of the selected revision.
+// The current state is recorded as a new revision, so the restore can be undone too.
+// If the name of the entity has been changed, the new target is returned.
+func (r *history) Restore(target entitiesHistory.Target, revisionID int64) (entitiesHistory.Target, error) {
+	revision, err := r.history.Get(context.Background(), target, revisionID)
+	if err != nil {
+		return target, err
+	}
+
+	var data []byte
+	if revision.HasImage {
+		data, err = r.history.ImageGet(context.Background(), target, revisionID)
+		if err != nil {
+			return target, err
+		}
+	}
+
+	err = r.Record(target)
+	if err != nil {
+		return target, err
+	}
+
+	switch {
+	case target.IsCard():
+		return target, r.restoreCard(target, revision, data)
+	case target.IsDeck():
+		return r.restoreDeck(target, revision, data)
+	case target.IsCollection():
+		return r.restoreCollection(target, revision, data)
+	default:
+		return r.restoreGame(target, revision, data)
+	}
+}
+func (r *history) Move(target, newTarget entitiesHistory.Target) error {
+	return r.history.Move(context.Background(), target, newTarget)
+}
+func (r *history) Delete(target entitiesHistory.Target) error {
+	return r.history.Drop(context.Background(), target)
+}
+
+func (r *history) restoreGame(target entitiesHistory.Target, revision *entitiesHistory.Revision, data []byte) (entitiesHistory.Target, error) {
+	item, err := r.game.Get(context.Background(), target.GameID)
+	if err != nil {
+		return target, err
+	}
+
+	if item.Name != revision.Name {
+		// Rename folder
+		item, err = r.game.Move(context.Background(), item.ID, revision.Name)
+		if err != nil {
+			return target, err
+		}
+		err = r.history.Move(context.Background(), target, target.Rename(item.ID))
+		if err != nil {
+			return target, err
+		}
+		target.GameID = item.ID
+	}
+
+	_, err = r.game.Update(context.Background(), dbGame.UpdateRequest{
+		Name:        item.ID,
+		Description: revision.Description,
+		Image:       revision.Image,
+	})
+	if err != nil {
+		return target, err
+	}
+
+	err = r.game.ImageDelete(context.Background(), target.GameID)
+	if err != nil && !errors.Is(err, er.GameImageNotExists) {
+		return target, err
+	}
+	if data == nil {
+		return target, nil
+	}
+	return target, r.game.ImageCreate(context.Background(), target.GameID, data)
+}
+func (r *history) restoreCollection(target entitiesHistory.Target, revision *entitiesHistory.Revision, data []byte) (entitiesHistory.Target, error) {
+	item, err := r.collection.Get(context.Background(), target.GameID, target.CollectionID)
+	if err != nil {
+		return target, err
+	}
+
+	if item.Name != revision.Name {
+		// Rename folder
+		item, err = r.collection.Move(context.Background(), target.GameID, item.ID, revision.Name)
+		if err != nil {
+			return target, err
+		}
+		err = r.history.Move(context.Background(), target, target.Rename(item.ID))
+		if err != nil {
+			return target, err
+		}
+		target.CollectionID = item.ID
+	}
+
+	_, err = r.collection.Update(context.Background(), dbCollection.UpdateRequest{
+		GameID:      target.GameID,
+		Name:        item.ID,
+		Description: revision.Description,
+		Image:       revision.Image,
+	})
+	if err != nil {
+		return target, err
+	}
+
+	err = r.collection.ImageDelete(context.Background(), target.GameID, target.CollectionID)
+	if err != nil && !errors.Is(err, er.CollectionImageNotExists) {
+		return target, err
+	}
+	if data == nil {
+		return target, nil
+	}
+	return target, r.collection.ImageCreate(context.Background(), target.GameID, target.CollectionID, data)
+}
+func (r *history) restoreDeck(target entitiesHistory.Target, revision *entitiesHistory.Revision, data []byte) (entitiesHistory.Target, error) {
+	item, err := r.deck.Get(context.Background(), target.GameID, target.CollectionID, target.DeckID)
+	if err != nil {
+		return target, err
+	}
+
+	if item.Name != revision.Name {
+		// Rename folder
+		item, err = r.deck.Move(context.Background(), target.GameID, target.CollectionID, item.ID, revision.Name)
+		if err != nil {
+			return target, err
+		}
+		err = r.history.Move(context.Background(), target, target.Rename(item.ID))
+		if err != nil {
+			return target, err
+		}
+		target.DeckID = item.ID
+	}
+
+	_, err = r.deck.Update(context.Background(), dbDeck.UpdateRequest{
+		GameID:       target.GameID,
+		CollectionID: target.CollectionID,
+		Name:         item.ID,
+		Description:  revision.Description,
+		Image:        revision.Image,
+	})
+	if err != nil {
+		return target, err
+	}
+
+	err = r.deck.ImageDelete(context.Background(), target.GameID, target.CollectionID, target.DeckID)
+	if err != nil && !errors.Is(err, er.DeckImageNotExists) {
+		return target, err
+	}
+	if data == nil {
+		return target, nil
+	}
+	return target, r.deck.ImageCreate(context.Background(), target.GameID, target.CollectionID, target.DeckID, data)
+}
+func (r *history) restoreCard(target entitiesHistory.Target, revision *entitiesHistory.Revision, data []byte) error {
+	_, err := r.card.Update(context.Background(), dbCard.UpdateRequest{
+		GameID:       target.GameID,
+		CollectionID: target.CollectionID,
+		DeckID:       target.DeckID,
+		CardID:       target.CardID,
+		Name:         revision.Name,
+		Description:  revision.Description,
+		Image:        revision.Image,
+		Variables:    revision.Variables,
+		Count:        revision.Count,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.card.ImageDelete(context.Background(), target.GameID, target.CollectionID, target.DeckID, target.CardID)
+	if err != nil && !errors.Is(err, er.CardImageNotExists) {
+		return err
+	}
+	if data == nil {
+		return nil
+	}
+	return r.card.ImageCreate(context.Background(), target.GameID, target.CollectionID, target.DeckID, target.CardID, data)
+}
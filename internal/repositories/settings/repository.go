@@ -26,12 +26,12 @@ func (r *settings) Get() (*entitiesSettings.Settings, error) {
 	resp, err := r.dbSettings.Get()
 	if err != nil {
 		if errors.Is(err, errors2.SettingsNotExists) {
-			return utils.Allocate(entitiesSettings.Default()), nil
+			return r.withFlags(utils.Allocate(entitiesSettings.Default())), nil
 		} else {
 			return nil, err
 		}
 	}
-	return &entitiesSettings.Settings{
+	return r.withFlags(&entitiesSettings.Settings{
 		Lang:             resp.Lang,
 		EnableBackShadow: resp.EnableBackShadow,
 		CardSize: entitiesSettings.CardSize{
@@ -39,9 +39,14 @@ func (r *settings) Get() (*entitiesSettings.Settings, error) {
 			ScaleY: resp.CardSize.ScaleY,
 			ScaleZ: resp.CardSize.ScaleZ,
 		},
-	}, nil
+		TTSAddress:       resp.TTSAddress,
+		TTSListenAddress: resp.TTSListenAddress,
+		TTSCallbackURL:   resp.TTSCallbackURL,
+	}), nil
 }
 func (r *settings) Save(req *entitiesSettings.Settings) error {
+	// The values of the flags are not saved, so the changed flags still work until the value is set in the settings
+	flags := r.withFlags(&entitiesSettings.Settings{})
 	return r.dbSettings.Set(&dbSettings.SettingInfo{
 		Lang:             req.Lang,
 		EnableBackShadow: req.EnableBackShadow,
@@ -50,5 +55,29 @@ func (r *settings) Save(req *entitiesSettings.Settings) error {
 			ScaleY: req.CardSize.ScaleY,
 			ScaleZ: req.CardSize.ScaleZ,
 		},
+		TTSAddress:       notDefault(req.TTSAddress, flags.TTSAddress),
+		TTSListenAddress: notDefault(req.TTSListenAddress, flags.TTSListenAddress),
+		TTSCallbackURL:   notDefault(req.TTSCallbackURL, flags.TTSCallbackURL),
 	})
 }
+
+// withFlags sets the TTS values not saved in the settings yet to the values of the flags
+func (r *settings) withFlags(set *entitiesSettings.Settings) *entitiesSettings.Settings {
+	if set.TTSAddress == "" {
+		set.TTSAddress = r.cfg.TTSAddress
+	}
+	if set.TTSListenAddress == "" {
+		set.TTSListenAddress = r.cfg.TTSListenAddress
+	}
+	if set.TTSCallbackURL == "" {
+		set.TTSCallbackURL = r.cfg.TTSCallbackURL
+	}
+	return set
+}
+
+func notDefault(value, defaultValue string) string {
+	if value == defaultValue {
+		return ""
+	}
+	return value
+}
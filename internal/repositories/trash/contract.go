@@ -0,0 +1,23 @@
+package trash
+
+import (
+	"time"
+
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesTrash "github.com/HardDie/DeckBuilder/internal/entities/trash"
+)
+
+type Trash interface {
+	Put(target entitiesHistory.Target) error
+	GetAll() ([]*entitiesTrash.Item, error)
+	GetByID(itemID int64) (*entitiesTrash.Item, error)
+	Restore(itemID int64, req RestoreRequest) (entitiesHistory.Target, error)
+	DeleteByID(itemID int64) error
+	DeleteAll() error
+	DeleteExpired(deletedBefore time.Time) (int, error)
+}
+
+type RestoreRequest struct {
+	// Optional. Allows you to restore the entity if its name has been reused
+	Name string
+}
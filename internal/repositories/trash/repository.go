@@ -0,0 +1,274 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesTrash "github.com/HardDie/DeckBuilder/internal/entities/trash"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+type trash struct {
+	cfg        *config.Config
+	trash      dbTrash.Trash
+	game       dbGame.Game
+	collection dbCollection.Collection
+	deck       dbDeck.Deck
+	card       dbCard.Card
+}
+
+func New(cfg *config.Config, t dbTrash.Trash, g dbGame.Game, c dbCollection.Collection, d dbDeck.Deck, cd dbCard.Card) Trash {
+	return &trash{
+		cfg:        cfg,
+		trash:      t,
+		game:       g,
+		collection: c,
+		deck:       d,
+		card:       cd,
+	}
+}
+
+// Put moves the entity with all nested entities and revisions into the trash
+func (r *trash) Put(target entitiesHistory.Target) error {
+	if target.IsCard() {
+		return r.putCard(target)
+	}
+
+	// Make sure the entity exists and get its name
+	var name string
+	switch {
+	case target.IsDeck():
+		item, err := r.deck.Get(context.Background(), target.GameID, target.CollectionID, target.DeckID)
+		if err != nil {
+			return err
+		}
+		name = item.Name
+	case target.IsCollection():
+		item, err := r.collection.Get(context.Background(), target.GameID, target.CollectionID)
+		if err != nil {
+			return err
+		}
+		name = item.Name
+	default:
+		item, err := r.game.Get(context.Background(), target.GameID)
+		if err != nil {
+			return err
+		}
+		name = item.Name
+	}
+
+	_, err := r.trash.Create(context.Background(), dbTrash.CreateRequest{
+		Target: target,
+		Name:   name,
+	})
+	return err
+}
+func (r *trash) GetAll() ([]*entitiesTrash.Item, error) {
+	return r.trash.List(context.Background())
+}
+func (r *trash) GetByID(itemID int64) (*entitiesTrash.Item, error) {
+	return r.trash.Get(context.Background(), itemID)
+}
+
+// Restore returns the entity to its original place.
+// If the name has been reused since the entity was deleted, a new name must be passed.
+func (r *trash) Restore(itemID int64, req RestoreRequest) (entitiesHistory.Target, error) {
+	item, err := r.trash.Get(context.Background(), itemID)
+	if err != nil {
+		return entitiesHistory.Target{}, err
+	}
+
+	if item.Target.IsCard() {
+		return r.restoreCard(item, req.Name)
+	}
+
+	target := item.Target
+	name := item.Name
+	if req.Name != "" {
+		newID := utils.NameToID(req.Name)
+		if newID == "" {
+			return target, er.BadName
+		}
+		name = req.Name
+		switch {
+		case target.IsDeck():
+			target.DeckID = newID
+		case target.IsCollection():
+			target.CollectionID = newID
+		default:
+			target.GameID = newID
+		}
+	}
+
+	err = r.checkDestination(target)
+	if err != nil {
+		return target, err
+	}
+
+	err = r.trash.Restore(context.Background(), dbTrash.RestoreRequest{
+		ItemID: itemID,
+		Target: target,
+		Name:   name,
+	})
+	if err != nil {
+		return target, err
+	}
+	return target, nil
+}
+func (r *trash) DeleteByID(itemID int64) error {
+	return r.trash.Delete(context.Background(), itemID)
+}
+func (r *trash) DeleteAll() error {
+	items, err := r.trash.List(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		err = r.trash.Delete(context.Background(), item.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteExpired permanently removes items deleted before the specified time
+func (r *trash) DeleteExpired(deletedBefore time.Time) (int, error) {
+	items, err := r.trash.List(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	for _, item := range items {
+		if !item.DeletedAt.Before(deletedBefore) {
+			continue
+		}
+		err = r.trash.Delete(context.Background(), item.ID)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (r *trash) putCard(target entitiesHistory.Target) error {
+	item, err := r.card.Get(context.Background(), target.GameID, target.CollectionID, target.DeckID, target.CardID)
+	if err != nil {
+		return err
+	}
+	data, err := r.card.ImageGet(context.Background(), target.GameID, target.CollectionID, target.DeckID, target.CardID)
+	if err != nil && !errors.Is(err, er.CardImageNotExists) {
+		return err
+	}
+
+	_, err = r.trash.Create(context.Background(), dbTrash.CreateRequest{
+		Target:    target,
+		Name:      item.Name,
+		Card:      item,
+		ImageFile: data,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The card is a part of the deck file, so it is removed only after it has been saved in the trash
+	if data != nil {
+		err = r.card.ImageDelete(context.Background(), target.GameID, target.CollectionID, target.DeckID, target.CardID)
+		if err != nil {
+			return err
+		}
+	}
+	return r.card.Delete(context.Background(), target.GameID, target.CollectionID, target.DeckID, target.CardID)
+}
+func (r *trash) restoreCard(item *entitiesTrash.Item, name string) (entitiesHistory.Target, error) {
+	target := item.Target
+	if name == "" {
+		name = item.Name
+	}
+
+	// Card identifiers are reused, so the card is created again with a new identifier
+	c, err := r.card.Create(context.Background(), dbCard.CreateRequest{
+		GameID:       target.GameID,
+		CollectionID: target.CollectionID,
+		DeckID:       target.DeckID,
+		Name:         name,
+		Description:  item.Card.Description,
+		Image:        item.Card.Image,
+		Variables:    item.Card.Variables,
+		Count:        item.Card.Count,
+		CreatedAt:    utils.Allocate(item.Card.CreatedAt),
+		UpdatedAt:    utils.Allocate(item.Card.UpdatedAt),
+	})
+	if err != nil {
+		return target, err
+	}
+	target.CardID = c.ID
+
+	if item.HasImage {
+		data, err := r.trash.ImageGet(context.Background(), item.ID)
+		if err != nil {
+			return target, err
+		}
+		err = r.card.ImageCreate(context.Background(), target.GameID, target.CollectionID, target.DeckID, target.CardID, data)
+		if err != nil {
+			return target, err
+		}
+	}
+
+	err = r.trash.Restore(context.Background(), dbTrash.RestoreRequest{
+		ItemID: item.ID,
+		Target: target,
+		Name:   name,
+	})
+	if err != nil {
+		return target, err
+	}
+	return target, nil
+}
+
+// checkDestination makes sure the parent entity exists and the name is not taken
+func (r *trash) checkDestination(target entitiesHistory.Target) error {
+	var err error
+	switch {
+	case target.IsDeck():
+		_, err = r.collection.Get(context.Background(), target.GameID, target.CollectionID)
+		if err != nil {
+			return err
+		}
+		_, err = r.deck.Get(context.Background(), target.GameID, target.CollectionID, target.DeckID)
+		if err == nil {
+			return er.DeckExist.AddMessage("The name is already in use, choose a new name for the restored deck")
+		} else if !errors.Is(err, er.DeckNotExists) {
+			return err
+		}
+	case target.IsCollection():
+		_, err = r.game.Get(context.Background(), target.GameID)
+		if err != nil {
+			return err
+		}
+		_, err = r.collection.Get(context.Background(), target.GameID, target.CollectionID)
+		if err == nil {
+			return er.CollectionExist.AddMessage("The name is already in use, choose a new name for the restored collection")
+		} else if !errors.Is(err, er.CollectionNotExists) {
+			return err
+		}
+	default:
+		_, err = r.game.Get(context.Background(), target.GameID)
+		if err == nil {
+			return er.GameExist.AddMessage("The name is already in use, choose a new name for the restored game")
+		} else if !errors.Is(err, er.GameNotExists) {
+			return err
+		}
+	}
+	return nil
+}
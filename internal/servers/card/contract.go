@@ -8,4 +8,6 @@ type Card interface {
 	ItemHandler(w http.ResponseWriter, r *http.Request)
 	ListHandler(w http.ResponseWriter, r *http.Request)
 	UpdateHandler(w http.ResponseWriter, r *http.Request)
+	CopyHandler(w http.ResponseWriter, r *http.Request)
+	MoveHandler(w http.ResponseWriter, r *http.Request)
 }
@@ -232,7 +232,90 @@ func (s *card) UpdateHandler(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:   item.UpdatedAt,
 	})
 }
+func (s *card) CopyHandler(w http.ResponseWriter, r *http.Request) {
+	type copyRequest struct {
+		Cards      []int64 `json:"cards"`
+		Game       string  `json:"game"`
+		Collection string  `json:"collection"`
+		Deck       string  `json:"deck"`
+	}
+	gameID := mux.Vars(r)["game"]
+	collectionID := mux.Vars(r)["collection"]
+	deckID := mux.Vars(r)["deck"]
+	dtoObject := &copyRequest{}
+	e := network.RequestToObject(r.Body, &dtoObject)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	items, e := s.serviceCard.Copy(gameID, collectionID, deckID, servicesCard.CopyRequest{
+		CardIDs:      dtoObject.Cards,
+		GameID:       dtoObject.Game,
+		CollectionID: dtoObject.Collection,
+		DeckID:       dtoObject.Deck,
+	})
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	s.responseCardList(w, items)
+}
+func (s *card) MoveHandler(w http.ResponseWriter, r *http.Request) {
+	type moveRequest struct {
+		Cards      []int64 `json:"cards"`
+		Game       string  `json:"game"`
+		Collection string  `json:"collection"`
+		Deck       string  `json:"deck"`
+	}
+	gameID := mux.Vars(r)["game"]
+	collectionID := mux.Vars(r)["collection"]
+	deckID := mux.Vars(r)["deck"]
+	dtoObject := &moveRequest{}
+	e := network.RequestToObject(r.Body, &dtoObject)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	items, e := s.serviceCard.Move(gameID, collectionID, deckID, servicesCard.MoveRequest{
+		CardIDs:      dtoObject.Cards,
+		GameID:       dtoObject.Game,
+		CollectionID: dtoObject.Collection,
+		DeckID:       dtoObject.Deck,
+	})
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
 
+	s.responseCardList(w, items)
+}
+
+func (s *card) responseCardList(w http.ResponseWriter, items []*entitiesCard.Card) {
+	respItems := make([]*dto.Card, 0, len(items))
+	var cardsTotal int
+	for _, item := range items {
+		cardsTotal += item.Count
+		respItems = append(respItems, &dto.Card{
+			ID:          item.ID,
+			Name:        item.Name,
+			Description: item.Description,
+			Image:       item.Image,
+			CachedImage: s.calculateCachedImage(*item),
+			Variables:   item.Variables,
+			Count:       item.Count,
+			CreatedAt:   item.CreatedAt,
+			UpdatedAt:   item.UpdatedAt,
+		})
+	}
+
+	network.ResponseWithMeta(w, respItems, &network.Meta{
+		Total:      len(respItems),
+		CardsTotal: cardsTotal,
+	})
+}
 func (s *card) calculateCachedImage(card entitiesCard.Card) string {
 	return fmt.Sprintf(s.cfg.CardImagePath+"?%s", card.GameID, card.CollectionID, card.DeckID, card.ID, utils.HashForTime(&card.UpdatedAt))
 }
@@ -5,6 +5,7 @@ import "net/http"
 type Collection interface {
 	CreateHandler(w http.ResponseWriter, r *http.Request)
 	DeleteHandler(w http.ResponseWriter, r *http.Request)
+	DuplicateHandler(w http.ResponseWriter, r *http.Request)
 	ItemHandler(w http.ResponseWriter, r *http.Request)
 	ListHandler(w http.ResponseWriter, r *http.Request)
 	UpdateHandler(w http.ResponseWriter, r *http.Request)
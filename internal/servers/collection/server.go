@@ -80,6 +80,39 @@ func (s *collection) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 		network.ResponseError(w, e)
 	}
 }
+func (s *collection) DuplicateHandler(w http.ResponseWriter, r *http.Request) {
+	type duplicateRequest struct {
+		Name string `json:"name"`
+		Game string `json:"game"`
+	}
+	gameID := mux.Vars(r)["game"]
+	collectionID := mux.Vars(r)["collection"]
+	dtoObject := &duplicateRequest{}
+	e := network.RequestToObject(r.Body, &dtoObject)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	item, e := s.serviceCollection.Duplicate(gameID, collectionID, servicesCollection.DuplicateRequest{
+		Name:   dtoObject.Name,
+		GameID: dtoObject.Game,
+	})
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	network.Response(w, dto.Collection{
+		ID:          item.ID,
+		Name:        item.Name,
+		Description: item.Description,
+		Image:       item.Image,
+		CachedImage: s.calculateCachedImage(item.GameID, *item),
+		CreatedAt:   item.CreatedAt,
+		UpdatedAt:   item.UpdatedAt,
+	})
+}
 func (s *collection) ItemHandler(w http.ResponseWriter, r *http.Request) {
 	gameID := mux.Vars(r)["game"]
 	collectionID := mux.Vars(r)["collection"]
@@ -6,6 +6,7 @@ type Deck interface {
 	AllDecksHandler(w http.ResponseWriter, r *http.Request)
 	CreateHandler(w http.ResponseWriter, r *http.Request)
 	DeleteHandler(w http.ResponseWriter, r *http.Request)
+	DuplicateHandler(w http.ResponseWriter, r *http.Request)
 	ItemHandler(w http.ResponseWriter, r *http.Request)
 	ListHandler(w http.ResponseWriter, r *http.Request)
 	UpdateHandler(w http.ResponseWriter, r *http.Request)
@@ -105,6 +105,42 @@ func (s *deck) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 		network.ResponseError(w, e)
 	}
 }
+func (s *deck) DuplicateHandler(w http.ResponseWriter, r *http.Request) {
+	type duplicateRequest struct {
+		Name       string `json:"name"`
+		Game       string `json:"game"`
+		Collection string `json:"collection"`
+	}
+	gameID := mux.Vars(r)["game"]
+	collectionID := mux.Vars(r)["collection"]
+	deckID := mux.Vars(r)["deck"]
+	dtoObject := &duplicateRequest{}
+	e := network.RequestToObject(r.Body, &dtoObject)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	item, e := s.serviceDeck.Duplicate(gameID, collectionID, deckID, servicesDeck.DuplicateRequest{
+		Name:         dtoObject.Name,
+		GameID:       dtoObject.Game,
+		CollectionID: dtoObject.Collection,
+	})
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	network.Response(w, dto.Deck{
+		ID:          item.ID,
+		Name:        item.Name,
+		Description: item.Description,
+		Image:       item.Image,
+		CachedImage: s.calculateCachedImage(*item),
+		CreatedAt:   item.CreatedAt,
+		UpdatedAt:   item.UpdatedAt,
+	})
+}
 func (s *deck) ItemHandler(w http.ResponseWriter, r *http.Request) {
 	gameID := mux.Vars(r)["game"]
 	collectionID := mux.Vars(r)["collection"]
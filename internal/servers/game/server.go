@@ -1,6 +1,7 @@
 package game
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -16,6 +17,11 @@ import (
 	"github.com/HardDie/DeckBuilder/internal/utils"
 )
 
+const (
+	// The space reserved for the form fields of the import request in addition to the archive itself
+	multipartOverhead = 1 << 20
+)
+
 type game struct {
 	cfg          config.Config
 	serviceGame  servicesGame.Game
@@ -109,39 +115,57 @@ func (s *game) DuplicateHandler(w http.ResponseWriter, r *http.Request) {
 }
 func (s *game) ExportHandler(w http.ResponseWriter, r *http.Request) {
 	gameID := mux.Vars(r)["game"]
-	archive, e := s.serviceGame.Export(gameID)
+	archive := &archiveWriter{w: w}
+	e := s.serviceGame.Export(gameID, archive)
 	if e != nil {
+		if archive.written {
+			// Part of the archive has already been sent, it is too late to respond with an error
+			er.IfErrorLog(e)
+			return
+		}
 		network.ResponseError(w, e)
 		return
 	}
-	w.Header().Set("Content-Type", "application/zip")
-	if _, err := w.Write(archive); err != nil {
-		er.IfErrorLog(err)
-	}
 }
 func (s *game) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.MaxImportSize > 0 {
+		// Interrupt the upload as soon as it exceeds the limit
+		r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxImportSize+multipartOverhead)
+	}
 	e := r.ParseMultipartForm(0)
 	if e != nil {
 		er.IfErrorLog(e)
-		e = er.InternalError.HTTP(http.StatusBadRequest).AddMessage(e.Error())
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(e, &maxBytesErr) {
+			e = er.ArchiveTooLarge.AddMessage(fmt.Sprintf("The archive must not be larger than %d bytes", s.cfg.MaxImportSize))
+		} else {
+			e = er.InternalError.HTTP(http.StatusBadRequest).AddMessage(e.Error())
+		}
 		network.ResponseError(w, e)
 		return
 	}
 
 	name := r.FormValue("name")
 
-	data, e := utils.GetFileFromMultipart("file", r)
+	// The uploaded file is stored in a temporary file and read from there on demand
+	file, size, e := utils.GetFileReaderFromMultipart("file", r)
 	if e != nil {
 		network.ResponseError(w, e)
 		return
 	}
-	if data == nil {
+	if file == nil {
 		e = er.BadArchive.AddMessage("The file must be passed as an argument")
 		network.ResponseError(w, e)
 		return
 	}
+	defer func() { er.IfErrorLog(file.Close()) }()
+	if s.cfg.MaxImportSize > 0 && size > s.cfg.MaxImportSize {
+		e = er.ArchiveTooLarge.AddMessage(fmt.Sprintf("The archive must not be larger than %d bytes", s.cfg.MaxImportSize))
+		network.ResponseError(w, e)
+		return
+	}
 
-	item, e := s.serviceGame.Import(data, name)
+	item, e := s.serviceGame.Import(file, size, name)
 	if e != nil {
 		network.ResponseError(w, e)
 		return
@@ -245,3 +269,17 @@ func (s *game) UpdateHandler(w http.ResponseWriter, r *http.Request) {
 func (s *game) calculateCachedImage(game entitiesGame.Game) string {
 	return fmt.Sprintf(s.cfg.GameImagePath+"?%s", game.ID, utils.HashForTime(&game.UpdatedAt))
 }
+
+// archiveWriter streams the archive into the response and remembers if the sending has already started
+type archiveWriter struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+func (a *archiveWriter) Write(data []byte) (int, error) {
+	if !a.written {
+		a.written = true
+		a.w.Header().Set("Content-Type", "application/zip")
+	}
+	return a.w.Write(data)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/HardDie/DeckBuilder/internal/network"
 	servicesGenerator "github.com/HardDie/DeckBuilder/internal/services/generator"
+	servicesTTS "github.com/HardDie/DeckBuilder/internal/services/tts"
 )
 
 type generator struct {
@@ -23,6 +24,7 @@ func (s *generator) GameHandler(w http.ResponseWriter, r *http.Request) {
 	type game struct {
 		SortOrder string `json:"sortOrder"`
 		Scale     int    `json:"scale"`
+		Mode      string `json:"mode"`
 	}
 	dtoObject := &game{}
 	e := network.RequestToObject(r.Body, &dtoObject)
@@ -34,11 +36,15 @@ func (s *generator) GameHandler(w http.ResponseWriter, r *http.Request) {
 	if dtoObject.Scale < 1 {
 		dtoObject.Scale = 1
 	}
+	if dtoObject.Mode == "" {
+		dtoObject.Mode = servicesTTS.ModeSpawn
+	}
 
 	gameID := mux.Vars(r)["game"]
 	e = s.serviceGenerator.GenerateGame(gameID, servicesGenerator.GenerateGameRequest{
 		SortOrder: dtoObject.SortOrder,
 		Scale:     dtoObject.Scale,
+		Mode:      dtoObject.Mode,
 	})
 	if e != nil {
 		network.ResponseError(w, e)
@@ -0,0 +1,10 @@
+package history
+
+import "net/http"
+
+type History interface {
+	DiffHandler(w http.ResponseWriter, r *http.Request)
+	ImageHandler(w http.ResponseWriter, r *http.Request)
+	ListHandler(w http.ResponseWriter, r *http.Request)
+	RestoreHandler(w http.ResponseWriter, r *http.Request)
+}
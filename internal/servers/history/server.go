@@ -0,0 +1,155 @@
+package history
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/dto"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+	"github.com/HardDie/DeckBuilder/internal/network"
+	servicesHistory "github.com/HardDie/DeckBuilder/internal/services/history"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+type history struct {
+	serviceHistory servicesHistory.History
+}
+
+func New(serviceHistory servicesHistory.History) History {
+	return &history{
+		serviceHistory: serviceHistory,
+	}
+}
+
+func (s *history) DiffHandler(w http.ResponseWriter, r *http.Request) {
+	target, e := s.target(r)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	revisionID, e := fs.StringToInt64(mux.Vars(r)["revision"])
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	revision, changes, e := s.serviceHistory.Diff(target, revisionID)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	respChanges := make([]*dto.RevisionChange, 0, len(changes))
+	for _, change := range changes {
+		respChanges = append(respChanges, &dto.RevisionChange{
+			Field:    change.Field,
+			Revision: change.Revision,
+			Current:  change.Current,
+		})
+	}
+
+	network.Response(w, dto.RevisionDiff{
+		Revision: s.revisionToDTO(revision),
+		Changes:  respChanges,
+	})
+}
+func (s *history) ImageHandler(w http.ResponseWriter, r *http.Request) {
+	target, e := s.target(r)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	revisionID, e := fs.StringToInt64(mux.Vars(r)["revision"])
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	img, imgType, e := s.serviceHistory.GetImage(target, revisionID)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	network.ResponseImage(w, r, img, imgType, utils.HashForData(img), time.Time{})
+}
+func (s *history) ListHandler(w http.ResponseWriter, r *http.Request) {
+	target, e := s.target(r)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	items, e := s.serviceHistory.List(target)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	respItems := make([]*dto.Revision, 0, len(items))
+	for _, item := range items {
+		respItem := s.revisionToDTO(item)
+		respItems = append(respItems, &respItem)
+	}
+
+	network.ResponseWithMeta(w, respItems, &network.Meta{
+		Total: len(respItems),
+	})
+}
+func (s *history) RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	target, e := s.target(r)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	revisionID, e := fs.StringToInt64(mux.Vars(r)["revision"])
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	newTarget, e := s.serviceHistory.Restore(target, revisionID)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	network.Response(w, dto.RevisionRestore{
+		Game:       newTarget.GameID,
+		Collection: newTarget.CollectionID,
+		Deck:       newTarget.DeckID,
+		Card:       newTarget.CardID,
+	})
+}
+
+// The same handlers are used for all entities, the type of entity depends on the route
+func (s *history) target(r *http.Request) (entitiesHistory.Target, error) {
+	vars := mux.Vars(r)
+	target := entitiesHistory.Target{
+		GameID:       vars["game"],
+		CollectionID: vars["collection"],
+		DeckID:       vars["deck"],
+	}
+	if cardID, ok := vars["card"]; ok {
+		id, err := fs.StringToInt64(cardID)
+		if err != nil {
+			return target, err
+		}
+		target.CardID = id
+	}
+	return target, nil
+}
+func (s *history) revisionToDTO(item *entitiesHistory.Revision) dto.Revision {
+	return dto.Revision{
+		ID:          item.ID,
+		Name:        item.Name,
+		Description: item.Description,
+		Image:       item.Image,
+		Variables:   item.Variables,
+		Count:       item.Count,
+		HasImage:    item.HasImage,
+		CreatedAt:   item.CreatedAt,
+	}
+}
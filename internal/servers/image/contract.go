@@ -6,5 +6,6 @@ type Image interface {
 	CardHandler(w http.ResponseWriter, r *http.Request)
 	CollectionHandler(w http.ResponseWriter, r *http.Request)
 	DeckHandler(w http.ResponseWriter, r *http.Request)
+	EditHandler(w http.ResponseWriter, r *http.Request)
 	GameHandler(w http.ResponseWriter, r *http.Request)
 }
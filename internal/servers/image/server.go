@@ -2,62 +2,73 @@ package image
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 
+	"github.com/HardDie/DeckBuilder/internal/dto"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
 	"github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/fs"
+	"github.com/HardDie/DeckBuilder/internal/images"
 	"github.com/HardDie/DeckBuilder/internal/network"
-	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
-	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
-	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
-	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
+	servicesImage "github.com/HardDie/DeckBuilder/internal/services/image"
 )
 
 type image struct {
-	serviceGame       servicesGame.Game
-	serviceCollection servicesCollection.Collection
-	serviceDeck       servicesDeck.Deck
-	serviceCard       servicesCard.Card
+	serviceImage servicesImage.Image
 }
 
-func New(
-	serviceGame servicesGame.Game,
-	serviceCollection servicesCollection.Collection,
-	serviceDeck servicesDeck.Deck,
-	serviceCard servicesCard.Card,
-) Image {
+func New(serviceImage servicesImage.Image) Image {
 	return &image{
-		serviceGame:       serviceGame,
-		serviceCollection: serviceCollection,
-		serviceDeck:       serviceDeck,
-		serviceCard:       serviceCard,
+		serviceImage: serviceImage,
 	}
 }
 
 func (s *image) CardHandler(w http.ResponseWriter, r *http.Request) {
-	gameID := mux.Vars(r)["game"]
-	collectionID := mux.Vars(r)["collection"]
-	deckID := mux.Vars(r)["deck"]
-	cardID, e := fs.StringToInt64(mux.Vars(r)["card"])
+	s.get(w, r)
+}
+func (s *image) CollectionHandler(w http.ResponseWriter, r *http.Request) {
+	s.get(w, r)
+}
+func (s *image) DeckHandler(w http.ResponseWriter, r *http.Request) {
+	s.get(w, r)
+}
+func (s *image) EditHandler(w http.ResponseWriter, r *http.Request) {
+	dtoObject := &dto.EditImage{}
+	e := network.RequestToObject(r.Body, &dtoObject)
 	if e != nil {
 		network.ResponseError(w, e)
 		return
 	}
-	img, imgType, e := s.serviceCard.GetImage(gameID, collectionID, deckID, cardID)
+
+	target, e := s.target(r)
 	if e != nil {
 		network.ResponseError(w, e)
 		return
 	}
-	w.Header().Set("Content-Type", "image/"+imgType)
-	if _, err := w.Write(img); err != nil {
-		errors.IfErrorLog(err)
+
+	operations := make([]images.Operation, 0, len(dtoObject.Operations))
+	for _, op := range dtoObject.Operations {
+		if op == nil {
+			continue
+		}
+		operations = append(operations, images.Operation{
+			Type:       op.Type,
+			X:          op.X,
+			Y:          op.Y,
+			Width:      op.Width,
+			Height:     op.Height,
+			Angle:      op.Angle,
+			Direction:  op.Direction,
+			Percentage: op.Percentage,
+		})
 	}
-}
-func (s *image) CollectionHandler(w http.ResponseWriter, r *http.Request) {
-	gameID := mux.Vars(r)["game"]
-	collectionID := mux.Vars(r)["collection"]
-	img, imgType, e := s.serviceCollection.GetImage(gameID, collectionID)
+
+	img, imgType, e := s.serviceImage.Edit(target, servicesImage.EditRequest{
+		Operations: operations,
+		Preview:    dtoObject.Preview,
+	})
 	if e != nil {
 		network.ResponseError(w, e)
 		return
@@ -67,29 +78,49 @@ func (s *image) CollectionHandler(w http.ResponseWriter, r *http.Request) {
 		errors.IfErrorLog(err)
 	}
 }
-func (s *image) DeckHandler(w http.ResponseWriter, r *http.Request) {
-	gameID := mux.Vars(r)["game"]
-	collectionID := mux.Vars(r)["collection"]
-	deckID := mux.Vars(r)["deck"]
-	img, imgType, e := s.serviceDeck.GetImage(gameID, collectionID, deckID)
+func (s *image) GameHandler(w http.ResponseWriter, r *http.Request) {
+	s.get(w, r)
+}
+
+// get writes the image or its thumbnail if the size is set, the type of entity depends on the route
+func (s *image) get(w http.ResponseWriter, r *http.Request) {
+	target, e := s.target(r)
 	if e != nil {
 		network.ResponseError(w, e)
 		return
 	}
-	w.Header().Set("Content-Type", "image/"+imgType)
-	if _, err := w.Write(img); err != nil {
-		errors.IfErrorLog(err)
+	var size int
+	if val := r.URL.Query().Get("size"); val != "" {
+		var err error
+		size, err = strconv.Atoi(val)
+		if err != nil || size < 1 {
+			network.ResponseError(w, errors.BadThumbnailSize.AddMessage("the size must be a positive number"))
+			return
+		}
 	}
-}
-func (s *image) GameHandler(w http.ResponseWriter, r *http.Request) {
-	gameID := mux.Vars(r)["game"]
-	img, imgType, e := s.serviceGame.GetImage(gameID)
+
+	img, e := s.serviceImage.Get(target, servicesImage.GetRequest{
+		Size: size,
+	})
 	if e != nil {
 		network.ResponseError(w, e)
 		return
 	}
-	w.Header().Set("Content-Type", "image/"+imgType)
-	if _, err := w.Write(img); err != nil {
-		errors.IfErrorLog(err)
+	network.ResponseImage(w, r, img.Data, img.Type, img.ETag, img.ModTime)
+}
+func (s *image) target(r *http.Request) (entitiesHistory.Target, error) {
+	vars := mux.Vars(r)
+	target := entitiesHistory.Target{
+		GameID:       vars["game"],
+		CollectionID: vars["collection"],
+		DeckID:       vars["deck"],
+	}
+	if cardID, ok := vars["card"]; ok {
+		id, err := fs.StringToInt64(cardID)
+		if err != nil {
+			return target, err
+		}
+		target.CardID = id
 	}
+	return target, nil
 }
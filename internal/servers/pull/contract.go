@@ -0,0 +1,8 @@
+package pull
+
+import "net/http"
+
+type Pull interface {
+	ChangesHandler(w http.ResponseWriter, r *http.Request)
+	ApplyHandler(w http.ResponseWriter, r *http.Request)
+}
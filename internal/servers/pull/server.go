@@ -0,0 +1,91 @@
+package pull
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/dto"
+	"github.com/HardDie/DeckBuilder/internal/network"
+	servicesPull "github.com/HardDie/DeckBuilder/internal/services/pull"
+)
+
+type pull struct {
+	servicePull servicesPull.Pull
+}
+
+func New(servicePull servicesPull.Pull) Pull {
+	return &pull{
+		servicePull: servicePull,
+	}
+}
+
+func (s *pull) ChangesHandler(w http.ResponseWriter, r *http.Request) {
+	changes, e := s.servicePull.Changes(mux.Vars(r)["game"])
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	respItems := make([]*dto.TTSChange, 0, len(changes))
+	for _, change := range changes {
+		respItems = append(respItems, &dto.TTSChange{
+			Collection: change.CollectionID,
+			Deck:       change.DeckID,
+			Card:       change.CardID,
+			CardName:   change.CardName,
+			GUID:       change.GUID,
+			Field:      change.Field,
+			Variable:   change.Variable,
+			Old:        change.Old,
+			New:        change.New,
+			Removed:    change.Removed,
+		})
+	}
+	network.ResponseWithMeta(w, respItems, &network.Meta{
+		Total: len(respItems),
+	})
+}
+func (s *pull) ApplyHandler(w http.ResponseWriter, r *http.Request) {
+	var dtoObjects []*dto.TTSChange
+	e := network.RequestToObject(r.Body, &dtoObjects)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	changes := make([]*servicesPull.Change, 0, len(dtoObjects))
+	for _, item := range dtoObjects {
+		changes = append(changes, &servicesPull.Change{
+			CollectionID: item.Collection,
+			DeckID:       item.Deck,
+			CardID:       item.Card,
+			Field:        item.Field,
+			Variable:     item.Variable,
+			New:          item.New,
+			Removed:      item.Removed,
+		})
+	}
+	items, e := s.servicePull.Apply(mux.Vars(r)["game"], changes)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	respItems := make([]*dto.Card, 0, len(items))
+	for _, item := range items {
+		respItems = append(respItems, &dto.Card{
+			ID:          item.ID,
+			Name:        item.Name,
+			Description: item.Description,
+			Image:       item.Image,
+			Variables:   item.Variables,
+			Count:       item.Count,
+			CreatedAt:   item.CreatedAt,
+			UpdatedAt:   item.UpdatedAt,
+		})
+	}
+	network.ResponseWithMeta(w, respItems, &network.Meta{
+		Total: len(respItems),
+	})
+}
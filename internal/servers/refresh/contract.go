@@ -0,0 +1,8 @@
+package refresh
+
+import "net/http"
+
+type Refresh interface {
+	StartHandler(w http.ResponseWriter, r *http.Request)
+	ReportHandler(w http.ResponseWriter, r *http.Request)
+}
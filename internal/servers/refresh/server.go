@@ -0,0 +1,89 @@
+package refresh
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/dto"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesRefresh "github.com/HardDie/DeckBuilder/internal/entities/refresh"
+	"github.com/HardDie/DeckBuilder/internal/network"
+	servicesRefresh "github.com/HardDie/DeckBuilder/internal/services/refresh"
+)
+
+type refresh struct {
+	serviceRefresh servicesRefresh.Refresh
+}
+
+func New(serviceRefresh servicesRefresh.Refresh) Refresh {
+	return &refresh{
+		serviceRefresh: serviceRefresh,
+	}
+}
+
+func (s *refresh) StartHandler(w http.ResponseWriter, r *http.Request) {
+	type start struct {
+		OnlyChanged bool `json:"onlyChanged"`
+	}
+	dtoObject := &start{}
+	e := network.RequestToObject(r.Body, &dtoObject)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	// The same handler is used for all entities, the type of entity depends on the route
+	vars := mux.Vars(r)
+	report, e := s.serviceRefresh.Start(entitiesHistory.Target{
+		GameID:       vars["game"],
+		CollectionID: vars["collection"],
+		DeckID:       vars["deck"],
+	}, servicesRefresh.StartRequest{
+		OnlyChanged: dtoObject.OnlyChanged,
+	})
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	network.Response(w, s.reportToDTO(report))
+}
+func (s *refresh) ReportHandler(w http.ResponseWriter, r *http.Request) {
+	report, e := s.serviceRefresh.Report()
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	network.Response(w, s.reportToDTO(report))
+}
+
+func (s *refresh) reportToDTO(report *entitiesRefresh.Report) dto.RefreshReport {
+	items := make([]*dto.RefreshItem, 0, len(report.Items))
+	for _, item := range report.Items {
+		items = append(items, &dto.RefreshItem{
+			Type:       item.Type(),
+			Name:       item.Name,
+			Image:      item.Image,
+			Game:       item.Target.GameID,
+			Collection: item.Target.CollectionID,
+			Deck:       item.Target.DeckID,
+			Card:       item.Target.CardID,
+			Status:     item.Status,
+			Error:      item.Error,
+		})
+	}
+	return dto.RefreshReport{
+		Game:        report.Target.GameID,
+		Collection:  report.Target.CollectionID,
+		Deck:        report.Target.DeckID,
+		OnlyChanged: report.OnlyChanged,
+		InProgress:  report.InProgress,
+		Total:       len(report.Items),
+		Changed:     report.Count(entitiesRefresh.StatusChanged),
+		Unchanged:   report.Count(entitiesRefresh.StatusUnchanged),
+		Failed:      report.Count(entitiesRefresh.StatusFailed),
+		Items:       items,
+		StartedAt:   report.StartedAt,
+		FinishedAt:  report.FinishedAt,
+	}
+}
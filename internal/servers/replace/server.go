@@ -71,13 +71,18 @@ func (s *replace) ReplaceHandler(w http.ResponseWriter, r *http.Request) {
 		network.ResponseError(w, e)
 		return
 	}
-	if mapping == nil {
-		e = er.BadArchive.AddMessage("The mapping must be passed as an argument")
+	baseURL := r.FormValue("baseURL")
+	if mapping == nil && baseURL == "" {
+		e = er.BadArchive.AddMessage("The mapping or the base url must be passed as an argument")
 		network.ResponseError(w, e)
 		return
 	}
 
-	resp, e := s.serviceReplace.Replace(data, mapping)
+	resp, e := s.serviceReplace.Replace(data, servicesReplace.ReplaceRequest{
+		Mapping: mapping,
+		BaseURL: baseURL,
+		Spawn:   r.FormValue("spawn") == "true",
+	})
 	if e != nil {
 		network.ResponseError(w, e)
 		return
@@ -72,7 +72,10 @@ func (s *system) GetSettingsHandler(w http.ResponseWriter, _ *http.Request) {
 }
 func (s *system) UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	type updateSettings struct {
-		Lang string `json:"lang"`
+		Lang             string `json:"lang"`
+		TTSAddress       string `json:"tts_address"`
+		TTSListenAddress string `json:"tts_listen_address"`
+		TTSCallbackURL   string `json:"tts_callback_url"`
 	}
 	dtoObject := &updateSettings{}
 	e := network.RequestToObject(r.Body, &dtoObject)
@@ -82,7 +85,10 @@ func (s *system) UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	setting, e := s.serviceSystem.UpdateSettings(servicesSystem.UpdateSettingsRequest{
-		Lang: dtoObject.Lang,
+		Lang:             dtoObject.Lang,
+		TTSAddress:       dtoObject.TTSAddress,
+		TTSListenAddress: dtoObject.TTSListenAddress,
+		TTSCallbackURL:   dtoObject.TTSCallbackURL,
 	})
 	if e != nil {
 		network.ResponseError(w, e)
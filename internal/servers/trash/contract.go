@@ -0,0 +1,10 @@
+package trash
+
+import "net/http"
+
+type Trash interface {
+	DeleteHandler(w http.ResponseWriter, r *http.Request)
+	ListHandler(w http.ResponseWriter, r *http.Request)
+	PurgeHandler(w http.ResponseWriter, r *http.Request)
+	RestoreHandler(w http.ResponseWriter, r *http.Request)
+}
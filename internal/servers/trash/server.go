@@ -0,0 +1,96 @@
+package trash
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/dto"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+	"github.com/HardDie/DeckBuilder/internal/network"
+	servicesTrash "github.com/HardDie/DeckBuilder/internal/services/trash"
+)
+
+type trash struct {
+	serviceTrash servicesTrash.Trash
+}
+
+func New(serviceTrash servicesTrash.Trash) Trash {
+	return &trash{
+		serviceTrash: serviceTrash,
+	}
+}
+
+func (s *trash) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	itemID, e := fs.StringToInt64(mux.Vars(r)["item"])
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	e = s.serviceTrash.Delete(itemID)
+	if e != nil {
+		network.ResponseError(w, e)
+	}
+}
+func (s *trash) ListHandler(w http.ResponseWriter, r *http.Request) {
+	items, e := s.serviceTrash.List()
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	respItems := make([]*dto.TrashItem, 0, len(items))
+	for _, item := range items {
+		respItems = append(respItems, &dto.TrashItem{
+			ID:         item.ID,
+			Type:       item.Type(),
+			Name:       item.Name,
+			Game:       item.Target.GameID,
+			Collection: item.Target.CollectionID,
+			Deck:       item.Target.DeckID,
+			Card:       item.Target.CardID,
+			DeletedAt:  item.DeletedAt,
+		})
+	}
+
+	network.ResponseWithMeta(w, respItems, &network.Meta{
+		Total: len(respItems),
+	})
+}
+func (s *trash) PurgeHandler(w http.ResponseWriter, r *http.Request) {
+	e := s.serviceTrash.Purge()
+	if e != nil {
+		network.ResponseError(w, e)
+	}
+}
+func (s *trash) RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	type restoreRequest struct {
+		Name string `json:"name"`
+	}
+	itemID, e := fs.StringToInt64(mux.Vars(r)["item"])
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	dtoObject := &restoreRequest{}
+	e = network.RequestToObject(r.Body, &dtoObject)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	target, e := s.serviceTrash.Restore(itemID, servicesTrash.RestoreRequest{
+		Name: dtoObject.Name,
+	})
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	network.Response(w, dto.TrashRestore{
+		Game:       target.GameID,
+		Collection: target.CollectionID,
+		Deck:       target.DeckID,
+		Card:       target.CardID,
+	})
+}
@@ -4,4 +4,14 @@ import "net/http"
 
 type TTS interface {
 	DataHandler(w http.ResponseWriter, r *http.Request)
+	HistoryHandler(w http.ResponseWriter, r *http.Request)
+	StatusHandler(w http.ResponseWriter, r *http.Request)
+	ScriptsHandler(w http.ResponseWriter, r *http.Request)
+	SaveAndPlayHandler(w http.ResponseWriter, r *http.Request)
+	MessageHandler(w http.ResponseWriter, r *http.Request)
+	ExecuteHandler(w http.ResponseWriter, r *http.Request)
+	LogHandler(w http.ResponseWriter, r *http.Request)
+	LogStreamHandler(w http.ResponseWriter, r *http.Request)
+	TestHandler(w http.ResponseWriter, r *http.Request)
+	PingHandler(w http.ResponseWriter, r *http.Request)
 }
@@ -1,10 +1,17 @@
 package tts
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 
+	"github.com/gorilla/mux"
+
+	"github.com/HardDie/DeckBuilder/internal/dto"
+	"github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/network"
 	servicesTTS "github.com/HardDie/DeckBuilder/internal/services/tts"
+	"github.com/HardDie/DeckBuilder/internal/tts_editor"
 )
 
 type tts struct {
@@ -18,7 +25,7 @@ func New(serviceTTS servicesTTS.TTS) TTS {
 }
 
 func (s *tts) DataHandler(w http.ResponseWriter, r *http.Request) {
-	resp, err := s.serviceTTS.DataForTTS()
+	resp, err := s.serviceTTS.DataForTTS(mux.Vars(r)["token"])
 	if err != nil {
 		network.ResponseError(w, err)
 		return
@@ -27,3 +34,187 @@ func (s *tts) DataHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Write(resp)
 }
+func (s *tts) HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	items := s.serviceTTS.History()
+	res := make([]dto.TTSSent, 0, len(items))
+	for _, item := range items {
+		res = append(res, dto.TTSSent{
+			Token:     item.Token,
+			Name:      item.Name,
+			Size:      item.Size,
+			Delivered: item.Delivered,
+			SentAt:    item.SentAt,
+			ExpiresAt: item.ExpiresAt,
+			Fetches:   item.Fetches,
+			FetchedAt: item.FetchedAt,
+			Expired:   item.Expired,
+		})
+	}
+	network.Response(w, res)
+}
+func (s *tts) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	status := s.serviceTTS.Status()
+	network.Response(w, dto.TTSStatus{
+		Connected:     status.Connected,
+		Listening:     status.Listening,
+		LastMessageAt: status.LastMessageAt,
+	})
+}
+func (s *tts) ScriptsHandler(w http.ResponseWriter, r *http.Request) {
+	states, e := s.serviceTTS.Scripts()
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	network.Response(w, scriptsToDTO(states))
+}
+func (s *tts) SaveAndPlayHandler(w http.ResponseWriter, r *http.Request) {
+	var scripts []dto.TTSScript
+	e := network.RequestToObject(r.Body, &scripts)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	states := make([]tts_editor.ScriptState, 0, len(scripts))
+	for _, script := range scripts {
+		if script.GUID == "" {
+			network.ResponseError(w, errors.BadId.AddMessage("the guid of the object is empty"))
+			return
+		}
+		states = append(states, tts_editor.ScriptState{
+			Name:   script.Name,
+			GUID:   script.GUID,
+			Script: script.Script,
+			UI:     script.UI,
+		})
+	}
+	e = s.serviceTTS.SaveAndPlay(states)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	network.Response(w, nil)
+}
+func (s *tts) MessageHandler(w http.ResponseWriter, r *http.Request) {
+	var message json.RawMessage
+	e := network.RequestToObject(r.Body, &message)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	if len(message) == 0 {
+		message = json.RawMessage("{}")
+	}
+
+	e = s.serviceTTS.CustomMessage(message)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	network.Response(w, nil)
+}
+func (s *tts) ExecuteHandler(w http.ResponseWriter, r *http.Request) {
+	dtoObject := &dto.TTSExecute{}
+	e := network.RequestToObject(r.Body, &dtoObject)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+
+	result, e := s.serviceTTS.Execute(dtoObject.GUID, dtoObject.Script)
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	network.Response(w, dto.TTSExecuteResult{
+		Result: result,
+	})
+}
+func (s *tts) LogHandler(w http.ResponseWriter, r *http.Request) {
+	messages := s.serviceTTS.Log()
+	res := make([]dto.TTSEvent, 0, len(messages))
+	for _, msg := range messages {
+		res = append(res, eventToDTO(msg))
+	}
+	network.Response(w, res)
+}
+
+// LogStreamHandler sends the messages received from TTS as server-sent events until the client disconnects
+func (s *tts) LogStreamHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		network.ResponseError(w, errors.InternalError.AddMessage("streaming is not supported"))
+		return
+	}
+	messages, unsubscribe := s.serviceTTS.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg := <-messages:
+			data, err := json.Marshal(eventToDTO(msg))
+			if err != nil {
+				errors.IfErrorLog(err)
+				continue
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", tts_editor.EventName(msg.MessageID), data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+func (s *tts) TestHandler(w http.ResponseWriter, r *http.Request) {
+	res := s.serviceTTS.Test()
+	network.Response(w, dto.TTSTest{
+		Connected:         res.Connected,
+		CallbackURL:       res.CallbackURL,
+		CallbackReachable: res.CallbackReachable,
+	})
+}
+func (s *tts) PingHandler(w http.ResponseWriter, r *http.Request) {
+	e := s.serviceTTS.Ping(r.URL.Query().Get("token"))
+	if e != nil {
+		network.ResponseError(w, e)
+		return
+	}
+	network.Response(w, nil)
+}
+
+func scriptsToDTO(states []tts_editor.ScriptState) []dto.TTSScript {
+	res := make([]dto.TTSScript, 0, len(states))
+	for _, state := range states {
+		res = append(res, dto.TTSScript{
+			Name:   state.Name,
+			GUID:   state.GUID,
+			Script: state.Script,
+			UI:     state.UI,
+		})
+	}
+	return res
+}
+func eventToDTO(msg tts_editor.Message) dto.TTSEvent {
+	event := dto.TTSEvent{
+		Type:          tts_editor.EventName(msg.MessageID),
+		Message:       msg.Message,
+		GUID:          msg.GUID,
+		CustomMessage: msg.CustomMessage,
+		ReceivedAt:    msg.ReceivedAt,
+	}
+	if msg.Error != "" {
+		event.Error = msg.ErrorMessagePrefix + msg.Error
+	}
+	if len(msg.ScriptStates) > 0 {
+		event.Scripts = scriptsToDTO(msg.ScriptStates)
+	}
+	return event
+}
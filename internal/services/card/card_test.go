@@ -10,18 +10,26 @@ import (
 	"github.com/HardDie/fsentry"
 
 	"github.com/HardDie/DeckBuilder/internal/config"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
 	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
 	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
 	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
 	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
 	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
 	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
 	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/images"
 	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
 	repositoriesCollection "github.com/HardDie/DeckBuilder/internal/repositories/collection"
 	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
 	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
 	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
 	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
 	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
@@ -36,6 +44,8 @@ type cardTest struct {
 	serviceCollection servicesCollection.Collection
 	serviceDeck       servicesDeck.Deck
 	serviceCard       Card
+
+	repositoryHistory repositoriesHistory.History
 }
 
 func newCardTest(t testing.TB) *cardTest {
@@ -52,16 +62,22 @@ func newCardTest(t testing.TB) *cardTest {
 
 	fs := fsentry.NewFSEntry(cfg.Games())
 
-	core := dbCore.New(fs)
-	game := dbGame.New(fs)
-	collection := dbCollection.New(fs, game)
-	deck := dbDeck.New(fs, collection)
-	card := dbCard.New(fs, deck)
-
-	repositoryGame := repositoriesGame.New(cfg, game)
-	repositoryCollection := repositoriesCollection.New(cfg, collection)
-	repositoryDeck := repositoriesDeck.New(cfg, collection, deck)
-	repositoryCard := repositoriesCard.New(cfg, card)
+	core := dbCore.New(fs, cfg.Games())
+	images := dbImage.New(fs, cfg.Games())
+	game := dbGame.New(fs, images)
+	collection := dbCollection.New(fs, images, game)
+	deck := dbDeck.New(fs, images, collection)
+	card := dbCard.New(fs, images, deck)
+	history := dbHistory.New(fs, images)
+	trash := dbTrash.New(fs, images, cfg.Games())
+	archive := dbArchive.New(cfg, fs, transfer.Files(fs, images), images)
+
+	repositoryHistory := repositoriesHistory.New(cfg, history, game, collection, deck, card)
+	repositoryTrash := repositoriesTrash.New(cfg, trash, game, collection, deck, card)
+	repositoryGame := repositoriesGame.New(cfg, game, archive, repositoryHistory, repositoryTrash)
+	repositoryDeck := repositoriesDeck.New(cfg, collection, deck, card, repositoryHistory, repositoryTrash)
+	repositoryCollection := repositoriesCollection.New(cfg, collection, repositoryDeck, repositoryHistory, repositoryTrash)
+	repositoryCard := repositoriesCard.New(cfg, card, repositoryHistory, repositoryTrash)
 
 	return &cardTest{
 		gameID:       "test_card__game",
@@ -74,6 +90,8 @@ func newCardTest(t testing.TB) *cardTest {
 		serviceCollection: servicesCollection.New(cfg, repositoryCollection),
 		serviceDeck:       servicesDeck.New(cfg, repositoryDeck),
 		serviceCard:       New(cfg, repositoryCard),
+
+		repositoryHistory: repositoryHistory,
 	}
 }
 
@@ -582,6 +600,225 @@ func (tt *cardTest) testImageBin(t *testing.T) {
 	}
 }
 
+func (tt *cardTest) testCopy(t *testing.T) {
+	srcDeckID := tt.deckID + "_copy"
+	dstDeckID := tt.deckID + "_copy_target"
+
+	pngImage, err := images.ImageToPng(images.CreateImage(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Empty list of cards
+	_, err = tt.serviceCard.Copy(tt.gameID, tt.collectionID, srcDeckID, CopyRequest{
+		DeckID: dstDeckID,
+	})
+	if !errors.Is(err, er.BadId) {
+		t.Fatal(err)
+	}
+
+	// Card not exist error
+	_, err = tt.serviceCard.Copy(tt.gameID, tt.collectionID, srcDeckID, CopyRequest{
+		CardIDs: []int64{1},
+		DeckID:  dstDeckID,
+	})
+	if !errors.Is(err, er.CardNotExists) {
+		t.Fatal(err)
+	}
+
+	// Create card
+	card, err := tt.serviceCard.Create(tt.gameID, tt.collectionID, srcDeckID, CreateRequest{
+		Name:      "copy_one",
+		Variables: map[string]string{"key": "value"},
+		Count:     2,
+		ImageFile: pngImage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Destination deck not exist error, nothing is returned
+	items, err := tt.serviceCard.Copy(tt.gameID, tt.collectionID, srcDeckID, CopyRequest{
+		CardIDs: []int64{card.ID},
+		DeckID:  dstDeckID + "_not_exist",
+	})
+	if !errors.Is(err, er.DeckNotExists) {
+		t.Fatal(err)
+	}
+	if items != nil {
+		t.Fatal("Error, the failed copy must not return cards")
+	}
+
+	// Copy card, duplicate identifiers must be ignored
+	items, err = tt.serviceCard.Copy(tt.gameID, tt.collectionID, srcDeckID, CopyRequest{
+		CardIDs: []int64{card.ID, card.ID},
+		DeckID:  dstDeckID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatal("Error, bad number of copied cards! [got]", len(items), "[want] 1")
+	}
+	if items[0].DeckID != dstDeckID {
+		t.Fatal("Error, bad deck! [got]", items[0].DeckID, "[want]", dstDeckID)
+	}
+	if items[0].Name != card.Name || items[0].Count != card.Count || items[0].Variables["key"] != "value" {
+		t.Fatal("Error, copied card doesn't match the original")
+	}
+	if !items[0].CreatedAt.Equal(card.CreatedAt) {
+		t.Fatal("Error, creation time was not preserved")
+	}
+
+	// Check image was copied
+	_, imgType, err := tt.serviceCard.GetImage(tt.gameID, tt.collectionID, dstDeckID, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imgType != "png" {
+		t.Fatal("Image type error! [got]", imgType, "[want] png")
+	}
+
+	// Source card still exists
+	_, err = tt.serviceCard.Item(tt.gameID, tt.collectionID, srcDeckID, card.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Copy into the same deck creates a new card
+	items, err = tt.serviceCard.Copy(tt.gameID, tt.collectionID, srcDeckID, CopyRequest{
+		CardIDs: []int64{card.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[0].ID == card.ID {
+		t.Fatal("Error, copy must have a new identifier")
+	}
+	list, err := tt.serviceCard.List(tt.gameID, tt.collectionID, srcDeckID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatal("Error, bad number of cards! [got]", len(list), "[want] 2")
+	}
+}
+func (tt *cardTest) testMove(t *testing.T) {
+	srcDeckID := tt.deckID + "_move"
+	dstDeckID := tt.deckID + "_move_target"
+
+	pngImage, err := images.ImageToPng(images.CreateImage(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create cards
+	first, err := tt.serviceCard.Create(tt.gameID, tt.collectionID, srcDeckID, CreateRequest{
+		Name:      "move_one",
+		ImageFile: pngImage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := tt.serviceCard.Create(tt.gameID, tt.collectionID, srcDeckID, CreateRequest{
+		Name: "move_two",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The change is recorded as a revision
+	second, err = tt.serviceCard.Update(tt.gameID, tt.collectionID, srcDeckID, second.ID, UpdateRequest{
+		Name:        second.Name,
+		Description: "moved with the revision",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// If one of the cards does not exist, nothing should be moved
+	_, err = tt.serviceCard.Move(tt.gameID, tt.collectionID, srcDeckID, MoveRequest{
+		CardIDs: []int64{first.ID, second.ID + 100},
+		DeckID:  dstDeckID,
+	})
+	if !errors.Is(err, er.CardNotExists) {
+		t.Fatal(err)
+	}
+	list, err := tt.serviceCard.List(tt.gameID, tt.collectionID, srcDeckID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatal("Error, bad number of cards! [got]", len(list), "[want] 2")
+	}
+
+	// Destination deck not exist error
+	_, err = tt.serviceCard.Move(tt.gameID, tt.collectionID, srcDeckID, MoveRequest{
+		CardIDs: []int64{first.ID},
+		DeckID:  dstDeckID + "_not_exist",
+	})
+	if !errors.Is(err, er.DeckNotExists) {
+		t.Fatal(err)
+	}
+
+	// Move cards
+	items, err := tt.serviceCard.Move(tt.gameID, tt.collectionID, srcDeckID, MoveRequest{
+		CardIDs: []int64{first.ID, second.ID},
+		DeckID:  dstDeckID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatal("Error, bad number of moved cards! [got]", len(items), "[want] 2")
+	}
+
+	// Source deck is empty
+	list, err = tt.serviceCard.List(tt.gameID, tt.collectionID, srcDeckID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatal("Error, bad number of cards! [got]", len(list), "[want] 0")
+	}
+
+	// Destination deck contains cards and image
+	list, err = tt.serviceCard.List(tt.gameID, tt.collectionID, dstDeckID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatal("Error, bad number of cards! [got]", len(list), "[want] 2")
+	}
+	for _, item := range items {
+		if item.Name != first.Name {
+			continue
+		}
+		_, _, err = tt.serviceCard.GetImage(tt.gameID, tt.collectionID, dstDeckID, item.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The revisions are moved together with the card
+	for _, item := range items {
+		if item.Name != second.Name {
+			continue
+		}
+		revisions, err := tt.repositoryHistory.GetAll(entitiesHistory.Target{
+			GameID:       tt.gameID,
+			CollectionID: tt.collectionID,
+			DeckID:       dstDeckID,
+			CardID:       item.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 1 {
+			t.Fatal("Error, bad number of revisions! [got]", len(revisions), "[want] 1")
+		}
+	}
+}
+
 func TestCard(t *testing.T) {
 	t.Parallel()
 
@@ -636,7 +873,7 @@ func TestCard(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	decks := []string{"_create", "_delete", "_update", "_list", "_item", "_image"}
+	decks := []string{"_create", "_delete", "_update", "_list", "_item", "_image", "_copy", "_copy_target", "_move", "_move_target"}
 	for _, deck := range decks {
 		// Create deck
 		_, err = tt.serviceDeck.Create(tt.gameID, tt.collectionID, servicesDeck.CreateRequest{
@@ -654,6 +891,8 @@ func TestCard(t *testing.T) {
 	t.Run("item", tt.testItem)
 	t.Run("image", tt.testImage)
 	t.Run("image_bin", tt.testImageBin)
+	t.Run("copy", tt.testCopy)
+	t.Run("move", tt.testMove)
 }
 
 func (tt *cardTest) fuzzCleanup() {
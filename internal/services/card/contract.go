@@ -11,6 +11,8 @@ type Card interface {
 	Update(gameID, collectionID, deckID string, cardID int64, req UpdateRequest) (*entitiesCard.Card, error)
 	Delete(gameID, collectionID, deckID string, cardID int64) error
 	GetImage(gameID, collectionID, deckID string, cardID int64) ([]byte, string, error)
+	Copy(gameID, collectionID, deckID string, req CopyRequest) ([]*entitiesCard.Card, error)
+	Move(gameID, collectionID, deckID string, req MoveRequest) ([]*entitiesCard.Card, error)
 }
 
 type CreateRequest struct {
@@ -30,3 +32,19 @@ type UpdateRequest struct {
 	Count       int
 	ImageFile   []byte
 }
+
+type CopyRequest struct {
+	CardIDs []int64
+	// Destination. Empty values are replaced with the source values
+	GameID       string
+	CollectionID string
+	DeckID       string
+}
+
+type MoveRequest struct {
+	CardIDs []int64
+	// Destination. Empty values are replaced with the source values
+	GameID       string
+	CollectionID string
+	DeckID       string
+}
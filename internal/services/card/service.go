@@ -5,6 +5,7 @@ import (
 
 	"github.com/HardDie/DeckBuilder/internal/config"
 	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
 	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
 	"github.com/HardDie/DeckBuilder/internal/utils"
 )
@@ -82,3 +83,95 @@ func (s *card) Delete(gameID, collectionID, deckID string, cardID int64) error {
 func (s *card) GetImage(gameID, collectionID, deckID string, cardID int64) ([]byte, string, error) {
 	return s.repositoryCard.GetImage(gameID, collectionID, deckID, cardID)
 }
+func (s *card) Copy(gameID, collectionID, deckID string, req CopyRequest) ([]*entitiesCard.Card, error) {
+	cardIDs, err := s.validateCardIDs(gameID, collectionID, deckID, req.CardIDs)
+	if err != nil {
+		return nil, err
+	}
+	dstGameID, dstCollectionID, dstDeckID := s.destination(gameID, collectionID, deckID, req.GameID, req.CollectionID, req.DeckID)
+	err = s.validateDestination(dstGameID, dstCollectionID, dstDeckID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*entitiesCard.Card, 0, len(cardIDs))
+	for _, cardID := range cardIDs {
+		item, err := s.repositoryCard.Copy(gameID, collectionID, deckID, cardID, repositoriesCard.CopyRequest{
+			GameID:       dstGameID,
+			CollectionID: dstCollectionID,
+			DeckID:       dstDeckID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+func (s *card) Move(gameID, collectionID, deckID string, req MoveRequest) ([]*entitiesCard.Card, error) {
+	cardIDs, err := s.validateCardIDs(gameID, collectionID, deckID, req.CardIDs)
+	if err != nil {
+		return nil, err
+	}
+	dstGameID, dstCollectionID, dstDeckID := s.destination(gameID, collectionID, deckID, req.GameID, req.CollectionID, req.DeckID)
+	err = s.validateDestination(dstGameID, dstCollectionID, dstDeckID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*entitiesCard.Card, 0, len(cardIDs))
+	for _, cardID := range cardIDs {
+		item, err := s.repositoryCard.Move(gameID, collectionID, deckID, cardID, repositoriesCard.MoveRequest{
+			GameID:       dstGameID,
+			CollectionID: dstCollectionID,
+			DeckID:       dstDeckID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Remove duplicate identifiers and check that all cards exist before starting to copy or move them.
+// So we don't end up with half of the cards processed because of a typo in one identifier.
+func (s *card) validateCardIDs(gameID, collectionID, deckID string, cardIDs []int64) ([]int64, error) {
+	if len(cardIDs) == 0 {
+		return nil, er.BadId.AddMessage("The list of cards must not be empty")
+	}
+
+	uniq := make(map[int64]struct{})
+	res := make([]int64, 0, len(cardIDs))
+	for _, cardID := range cardIDs {
+		if _, ok := uniq[cardID]; ok {
+			continue
+		}
+		uniq[cardID] = struct{}{}
+
+		_, err := s.repositoryCard.GetByID(gameID, collectionID, deckID, cardID)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, cardID)
+	}
+	return res, nil
+}
+
+// The destination deck is checked before the first card is written, so a wrong deck doesn't fail in the middle of the list
+func (s *card) validateDestination(gameID, collectionID, deckID string) error {
+	_, err := s.repositoryCard.GetAll(gameID, collectionID, deckID)
+	return err
+}
+func (s *card) destination(gameID, collectionID, deckID, dstGameID, dstCollectionID, dstDeckID string) (string, string, string) {
+	if dstGameID == "" {
+		dstGameID = gameID
+	}
+	if dstCollectionID == "" {
+		dstCollectionID = collectionID
+	}
+	if dstDeckID == "" {
+		dstDeckID = deckID
+	}
+	return dstGameID, dstCollectionID, dstDeckID
+}
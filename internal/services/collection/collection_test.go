@@ -10,14 +10,27 @@ import (
 	"github.com/HardDie/fsentry"
 
 	"github.com/HardDie/DeckBuilder/internal/config"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
 	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
 	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
 	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
 	entitiesCollection "github.com/HardDie/DeckBuilder/internal/entities/collection"
 	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/images"
+	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
 	repositoriesCollection "github.com/HardDie/DeckBuilder/internal/repositories/collection"
+	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
 	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
+	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
+	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
 	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
 	"github.com/HardDie/DeckBuilder/internal/utils"
 )
@@ -29,6 +42,8 @@ type collectionTest struct {
 
 	serviceGame       servicesGame.Game
 	serviceCollection Collection
+	serviceDeck       servicesDeck.Deck
+	serviceCard       servicesCard.Card
 }
 
 func newCollectionTest(t testing.TB) *collectionTest {
@@ -45,12 +60,22 @@ func newCollectionTest(t testing.TB) *collectionTest {
 
 	fs := fsentry.NewFSEntry(cfg.Games())
 
-	core := dbCore.New(fs)
-	game := dbGame.New(fs)
-	collection := dbCollection.New(fs, game)
-
-	repositoryGame := repositoriesGame.New(cfg, game)
-	repositoryCollection := repositoriesCollection.New(cfg, collection)
+	core := dbCore.New(fs, cfg.Games())
+	images := dbImage.New(fs, cfg.Games())
+	game := dbGame.New(fs, images)
+	collection := dbCollection.New(fs, images, game)
+	deck := dbDeck.New(fs, images, collection)
+	card := dbCard.New(fs, images, deck)
+	history := dbHistory.New(fs, images)
+	trash := dbTrash.New(fs, images, cfg.Games())
+	archive := dbArchive.New(cfg, fs, transfer.Files(fs, images), images)
+
+	repositoryHistory := repositoriesHistory.New(cfg, history, game, collection, deck, card)
+	repositoryTrash := repositoriesTrash.New(cfg, trash, game, collection, deck, card)
+	repositoryGame := repositoriesGame.New(cfg, game, archive, repositoryHistory, repositoryTrash)
+	repositoryDeck := repositoriesDeck.New(cfg, collection, deck, card, repositoryHistory, repositoryTrash)
+	repositoryCollection := repositoriesCollection.New(cfg, collection, repositoryDeck, repositoryHistory, repositoryTrash)
+	repositoryCard := repositoriesCard.New(cfg, card, repositoryHistory, repositoryTrash)
 
 	return &collectionTest{
 		gameID: "test_collection__game",
@@ -59,6 +84,8 @@ func newCollectionTest(t testing.TB) *collectionTest {
 
 		serviceGame:       servicesGame.New(cfg, repositoryGame),
 		serviceCollection: New(cfg, repositoryCollection),
+		serviceDeck:       servicesDeck.New(cfg, repositoryDeck),
+		serviceCard:       servicesCard.New(cfg, repositoryCard),
 	}
 }
 
@@ -573,6 +600,129 @@ func (tt *collectionTest) testImageBin(t *testing.T) {
 	}
 }
 
+func (tt *collectionTest) testDuplicate(t *testing.T) {
+	collectionName := "duplicate_one"
+	desc := "best collection ever"
+	dstGameID := tt.gameID + "_duplicate"
+
+	pngImage, err := images.ImageToPng(images.CreateImage(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Collection not exist error
+	_, err = tt.serviceCollection.Duplicate(tt.gameID, collectionName, DuplicateRequest{
+		Name: collectionName + "_copy",
+	})
+	if !errors.Is(err, er.CollectionNotExists) {
+		t.Fatal(err)
+	}
+
+	// Create collection with image, decks and cards
+	collection, err := tt.serviceCollection.Create(tt.gameID, CreateRequest{
+		Name:        collectionName,
+		Description: desc,
+		ImageFile:   pngImage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, deckName := range []string{"deck_one", "deck_two"} {
+		deck, err := tt.serviceDeck.Create(tt.gameID, collection.ID, servicesDeck.CreateRequest{
+			Name:      deckName,
+			ImageFile: pngImage,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tt.serviceCard.Create(tt.gameID, collection.ID, deck.ID, servicesCard.CreateRequest{
+			Name:      "card",
+			ImageFile: pngImage,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkContent := func(gameID, collectionID string) {
+		_, _, err := tt.serviceCollection.GetImage(gameID, collectionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decks, err := tt.serviceDeck.List(gameID, collectionID, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decks) != 2 {
+			t.Fatal("Bad number of decks [got]", len(decks), "[want] 2")
+		}
+		for _, deck := range decks {
+			_, _, err = tt.serviceDeck.GetImage(gameID, collectionID, deck.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cards, err := tt.serviceCard.List(gameID, collectionID, deck.ID, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cards) != 1 {
+				t.Fatal("Bad number of cards [got]", len(cards), "[want] 1")
+			}
+		}
+	}
+
+	// Duplicate into the same game
+	newCollection, err := tt.serviceCollection.Duplicate(tt.gameID, collection.ID, DuplicateRequest{
+		Name: collectionName + "_copy",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newCollection.Description != desc {
+		t.Fatal("Bad description [got]", newCollection.Description, "[want]", desc)
+	}
+	checkContent(tt.gameID, newCollection.ID)
+
+	// Try to duplicate with an existing name
+	_, err = tt.serviceCollection.Duplicate(tt.gameID, collection.ID, DuplicateRequest{
+		Name: collectionName + "_copy",
+	})
+	if !errors.Is(err, er.CollectionExist) {
+		t.Fatal(err)
+	}
+
+	// Destination game not exist error
+	_, err = tt.serviceCollection.Duplicate(tt.gameID, collection.ID, DuplicateRequest{
+		Name:   collectionName,
+		GameID: dstGameID,
+	})
+	if !errors.Is(err, er.GameNotExists) {
+		t.Fatal(err)
+	}
+
+	// Duplicate into another game with the same name
+	_, err = tt.serviceGame.Create(servicesGame.CreateRequest{
+		Name: dstGameID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCollection, err = tt.serviceCollection.Duplicate(tt.gameID, collection.ID, DuplicateRequest{
+		Name:   collectionName,
+		GameID: dstGameID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newCollection.GameID != dstGameID {
+		t.Fatal("Bad game [got]", newCollection.GameID, "[want]", dstGameID)
+	}
+	checkContent(dstGameID, newCollection.ID)
+
+	// Source collection is untouched
+	checkContent(tt.gameID, collection.ID)
+}
+
 func TestCollection(t *testing.T) {
 	t.Parallel()
 
@@ -610,6 +760,7 @@ func TestCollection(t *testing.T) {
 	t.Run("item", tt.testItem)
 	t.Run("image", tt.testImage)
 	t.Run("image_bin", tt.testImageBin)
+	t.Run("duplicate", tt.testDuplicate)
 }
 
 func (tt *collectionTest) fuzzCleanup() {
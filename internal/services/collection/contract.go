@@ -11,6 +11,7 @@ type Collection interface {
 	Update(gameID, collectionID string, req UpdateRequest) (*entitiesCollection.Collection, error)
 	Delete(gameID, collectionID string) error
 	GetImage(gameID, collectionID string) ([]byte, string, error)
+	Duplicate(gameID, collectionID string, req DuplicateRequest) (*entitiesCollection.Collection, error)
 }
 
 type CreateRequest struct {
@@ -26,3 +27,9 @@ type UpdateRequest struct {
 	Image       string
 	ImageFile   []byte
 }
+
+type DuplicateRequest struct {
+	Name string
+	// Optional. By default, the copy is created in the same game
+	GameID string
+}
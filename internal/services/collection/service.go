@@ -75,3 +75,12 @@ func (s *collection) Delete(gameID, collectionID string) error {
 func (s *collection) GetImage(gameID, collectionID string) ([]byte, string, error) {
 	return s.repositoryCollection.GetImage(gameID, collectionID)
 }
+func (s *collection) Duplicate(gameID, collectionID string, req DuplicateRequest) (*entitiesCollection.Collection, error) {
+	if req.GameID == "" {
+		req.GameID = gameID
+	}
+	return s.repositoryCollection.Duplicate(gameID, collectionID, repositoriesCollection.DuplicateRequest{
+		Name:   req.Name,
+		GameID: req.GameID,
+	})
+}
@@ -12,6 +12,7 @@ type Deck interface {
 	Delete(gameID, collectionID, deckID string) error
 	GetImage(gameID, collectionID, deckID string) ([]byte, string, error)
 	ListAllUnique(gameID string) ([]*entitiesDeck.Deck, error)
+	Duplicate(gameID, collectionID, deckID string, req DuplicateRequest) (*entitiesDeck.Deck, error)
 }
 
 type CreateRequest struct {
@@ -27,3 +28,10 @@ type UpdateRequest struct {
 	Image       string
 	ImageFile   []byte
 }
+
+type DuplicateRequest struct {
+	Name string
+	// Optional. By default, the copy is created in the same game and collection
+	GameID       string
+	CollectionID string
+}
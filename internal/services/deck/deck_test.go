@@ -10,16 +10,26 @@ import (
 	"github.com/HardDie/fsentry"
 
 	"github.com/HardDie/DeckBuilder/internal/config"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
 	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
 	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
 	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
 	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
 	entitiesDeck "github.com/HardDie/DeckBuilder/internal/entities/deck"
 	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/images"
+	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
 	repositoriesCollection "github.com/HardDie/DeckBuilder/internal/repositories/collection"
 	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
 	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
+	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
 	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
 	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
 	"github.com/HardDie/DeckBuilder/internal/utils"
@@ -33,6 +43,7 @@ type deckTest struct {
 	serviceGame       servicesGame.Game
 	serviceCollection servicesCollection.Collection
 	serviceDeck       Deck
+	serviceCard       servicesCard.Card
 }
 
 func newDeckTest(t testing.TB) *deckTest {
@@ -49,14 +60,22 @@ func newDeckTest(t testing.TB) *deckTest {
 
 	fs := fsentry.NewFSEntry(cfg.Games())
 
-	core := dbCore.New(fs)
-	game := dbGame.New(fs)
-	collection := dbCollection.New(fs, game)
-	deck := dbDeck.New(fs, collection)
-
-	repositoryGame := repositoriesGame.New(cfg, game)
-	repositoryCollection := repositoriesCollection.New(cfg, collection)
-	repositoryDeck := repositoriesDeck.New(cfg, collection, deck)
+	core := dbCore.New(fs, cfg.Games())
+	images := dbImage.New(fs, cfg.Games())
+	game := dbGame.New(fs, images)
+	collection := dbCollection.New(fs, images, game)
+	deck := dbDeck.New(fs, images, collection)
+	card := dbCard.New(fs, images, deck)
+	history := dbHistory.New(fs, images)
+	trash := dbTrash.New(fs, images, cfg.Games())
+	archive := dbArchive.New(cfg, fs, transfer.Files(fs, images), images)
+
+	repositoryHistory := repositoriesHistory.New(cfg, history, game, collection, deck, card)
+	repositoryTrash := repositoriesTrash.New(cfg, trash, game, collection, deck, card)
+	repositoryGame := repositoriesGame.New(cfg, game, archive, repositoryHistory, repositoryTrash)
+	repositoryDeck := repositoriesDeck.New(cfg, collection, deck, card, repositoryHistory, repositoryTrash)
+	repositoryCollection := repositoriesCollection.New(cfg, collection, repositoryDeck, repositoryHistory, repositoryTrash)
+	repositoryCard := repositoriesCard.New(cfg, card, repositoryHistory, repositoryTrash)
 
 	return &deckTest{
 		gameID:       "test_deck__game",
@@ -67,6 +86,7 @@ func newDeckTest(t testing.TB) *deckTest {
 		serviceGame:       servicesGame.New(cfg, repositoryGame),
 		serviceCollection: servicesCollection.New(cfg, repositoryCollection),
 		serviceDeck:       New(cfg, repositoryDeck),
+		serviceCard:       servicesCard.New(cfg, repositoryCard),
 	}
 }
 
@@ -567,6 +587,144 @@ func (tt *deckTest) testImageBin(t *testing.T) {
 	}
 }
 
+func (tt *deckTest) testDuplicate(t *testing.T) {
+	deckName := "duplicate_one"
+	desc := "best deck ever"
+	dstCollectionID := tt.collectionID + "_duplicate"
+
+	pngImage, err := images.ImageToPng(images.CreateImage(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deck not exist error
+	_, err = tt.serviceDeck.Duplicate(tt.gameID, tt.collectionID, deckName, DuplicateRequest{
+		Name: deckName + "_copy",
+	})
+	if !errors.Is(err, er.DeckNotExists) {
+		t.Fatal(err)
+	}
+
+	// Create deck with image and cards
+	deck, err := tt.serviceDeck.Create(tt.gameID, tt.collectionID, CreateRequest{
+		Name:        deckName,
+		Description: desc,
+		ImageFile:   pngImage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"removed", "one", "two"} {
+		_, err = tt.serviceCard.Create(tt.gameID, tt.collectionID, deck.ID, servicesCard.CreateRequest{
+			Name:      name,
+			ImageFile: pngImage,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The identifiers of the cards have a gap
+	err = tt.serviceCard.Delete(tt.gameID, tt.collectionID, deck.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Duplicate into the same collection
+	newDeck, err := tt.serviceDeck.Duplicate(tt.gameID, tt.collectionID, deck.ID, DuplicateRequest{
+		Name: deckName + "_copy",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newDeck.Description != desc {
+		t.Fatal("Bad description [got]", newDeck.Description, "[want]", desc)
+	}
+	_, _, err = tt.serviceDeck.GetImage(tt.gameID, tt.collectionID, newDeck.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cards, err := tt.serviceCard.List(tt.gameID, tt.collectionID, newDeck.ID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 2 {
+		t.Fatal("Bad number of cards [got]", len(cards), "[want] 2")
+	}
+	srcCards, err := tt.serviceCard.List(tt.gameID, tt.collectionID, deck.ID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, card := range cards {
+		_, _, err = tt.serviceCard.GetImage(tt.gameID, tt.collectionID, newDeck.ID, card.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The copies keep the identifiers and the time of the source cards
+		for _, src := range srcCards {
+			if src.Name != card.Name {
+				continue
+			}
+			if src.ID != card.ID {
+				t.Fatal("Bad card ID [got]", card.ID, "[want]", src.ID)
+			}
+			if !src.CreatedAt.Equal(card.CreatedAt) || !src.UpdatedAt.Equal(card.UpdatedAt) {
+				t.Fatal("Bad card time [got]", card.CreatedAt, card.UpdatedAt, "[want]", src.CreatedAt, src.UpdatedAt)
+			}
+		}
+	}
+
+	// Try to duplicate with an existing name
+	_, err = tt.serviceDeck.Duplicate(tt.gameID, tt.collectionID, deck.ID, DuplicateRequest{
+		Name: deckName + "_copy",
+	})
+	if !errors.Is(err, er.DeckExist) {
+		t.Fatal(err)
+	}
+
+	// Destination collection not exist error
+	_, err = tt.serviceDeck.Duplicate(tt.gameID, tt.collectionID, deck.ID, DuplicateRequest{
+		Name:         deckName,
+		CollectionID: dstCollectionID,
+	})
+	if !errors.Is(err, er.CollectionNotExists) {
+		t.Fatal(err)
+	}
+
+	// Duplicate into another collection with the same name
+	_, err = tt.serviceCollection.Create(tt.gameID, servicesCollection.CreateRequest{
+		Name: dstCollectionID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newDeck, err = tt.serviceDeck.Duplicate(tt.gameID, tt.collectionID, deck.ID, DuplicateRequest{
+		Name:         deckName,
+		CollectionID: dstCollectionID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newDeck.CollectionID != dstCollectionID {
+		t.Fatal("Bad collection [got]", newDeck.CollectionID, "[want]", dstCollectionID)
+	}
+	cards, err = tt.serviceCard.List(tt.gameID, dstCollectionID, newDeck.ID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 2 {
+		t.Fatal("Bad number of cards [got]", len(cards), "[want] 2")
+	}
+
+	// Source deck is untouched
+	cards, err = tt.serviceCard.List(tt.gameID, tt.collectionID, deck.ID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 2 {
+		t.Fatal("Bad number of cards [got]", len(cards), "[want] 2")
+	}
+}
+
 func TestDeck(t *testing.T) {
 	t.Parallel()
 
@@ -620,6 +778,7 @@ func TestDeck(t *testing.T) {
 	t.Run("item", tt.testItem)
 	t.Run("image", tt.testImage)
 	t.Run("image_bin", tt.testImageBin)
+	t.Run("duplicate", tt.testDuplicate)
 }
 
 func (tt *deckTest) fuzzCleanup() {
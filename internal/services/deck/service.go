@@ -83,3 +83,16 @@ func (s *deck) ListAllUnique(gameID string) ([]*entitiesDeck.Deck, error) {
 	utils.Sort(&items, "name")
 	return items, nil
 }
+func (s *deck) Duplicate(gameID, collectionID, deckID string, req DuplicateRequest) (*entitiesDeck.Deck, error) {
+	if req.GameID == "" {
+		req.GameID = gameID
+	}
+	if req.CollectionID == "" {
+		req.CollectionID = collectionID
+	}
+	return s.repositoryDeck.Duplicate(gameID, collectionID, deckID, repositoriesDeck.DuplicateRequest{
+		Name:         req.Name,
+		GameID:       req.GameID,
+		CollectionID: req.CollectionID,
+	})
+}
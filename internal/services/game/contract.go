@@ -1,6 +1,8 @@
 package game
 
 import (
+	"io"
+
 	entitiesGame "github.com/HardDie/DeckBuilder/internal/entities/game"
 )
 
@@ -12,8 +14,8 @@ type Game interface {
 	Delete(gameID string) error
 	GetImage(gameID string) ([]byte, string, error)
 	Duplicate(gameID string, req DuplicateRequest) (*entitiesGame.Game, error)
-	Export(gameID string) ([]byte, error)
-	Import(data []byte, name string) (*entitiesGame.Game, error)
+	Export(gameID string, w io.Writer) error
+	Import(r io.ReaderAt, size int64, name string) (*entitiesGame.Game, error)
 }
 
 type CreateRequest struct {
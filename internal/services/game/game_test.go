@@ -10,12 +10,22 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/HardDie/DeckBuilder/internal/config"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
 	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
 	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
 	entitiesGame "github.com/HardDie/DeckBuilder/internal/entities/game"
 	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/images"
 	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
 	"github.com/HardDie/DeckBuilder/internal/utils"
 )
 
@@ -38,10 +48,19 @@ func newGameTest(t testing.TB) *gameTest {
 
 	fs := fsentry.NewFSEntry(cfg.Games())
 
-	core := dbCore.New(fs)
-	game := dbGame.New(fs)
-
-	repositoryGame := repositoriesGame.New(cfg, game)
+	core := dbCore.New(fs, cfg.Games())
+	images := dbImage.New(fs, cfg.Games())
+	game := dbGame.New(fs, images)
+	collection := dbCollection.New(fs, images, game)
+	deck := dbDeck.New(fs, images, collection)
+	card := dbCard.New(fs, images, deck)
+	history := dbHistory.New(fs, images)
+	trash := dbTrash.New(fs, images, cfg.Games())
+	archive := dbArchive.New(cfg, fs, transfer.Files(fs, images), images)
+
+	repositoryHistory := repositoriesHistory.New(cfg, history, game, collection, deck, card)
+	repositoryTrash := repositoriesTrash.New(cfg, trash, game, collection, deck, card)
+	repositoryGame := repositoriesGame.New(cfg, game, archive, repositoryHistory, repositoryTrash)
 
 	return &gameTest{
 		cfg:  cfg,
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"io"
 	"strings"
 
 	"github.com/HardDie/DeckBuilder/internal/config"
@@ -80,9 +81,9 @@ func (s *game) Duplicate(gameID string, req DuplicateRequest) (*entitiesGame.Gam
 		Name: req.Name,
 	})
 }
-func (s *game) Export(gameID string) ([]byte, error) {
-	return s.repositoryGame.Export(gameID)
+func (s *game) Export(gameID string, w io.Writer) error {
+	return s.repositoryGame.Export(gameID, w)
 }
-func (s *game) Import(data []byte, name string) (*entitiesGame.Game, error) {
-	return s.repositoryGame.Import(data, name)
+func (s *game) Import(r io.ReaderAt, size int64, name string) (*entitiesGame.Game, error) {
+	return s.repositoryGame.Import(r, size, name)
 }
@@ -7,4 +7,6 @@ type Generator interface {
 type GenerateGameRequest struct {
 	SortOrder string
 	Scale     int
+	// How the generated game is sent to TTS: spawn, update or respawn
+	Mode string
 }
@@ -2,8 +2,10 @@ package generator
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image/jpeg"
+	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -12,6 +14,7 @@ import (
 	"github.com/HardDie/DeckBuilder/internal/config"
 	entitiesGame "github.com/HardDie/DeckBuilder/internal/entities/game"
 	entitiesSettings "github.com/HardDie/DeckBuilder/internal/entities/settings"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/fs"
 	"github.com/HardDie/DeckBuilder/internal/images"
 	"github.com/HardDie/DeckBuilder/internal/logger"
@@ -21,9 +24,12 @@ import (
 	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
 	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
 	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
+	servicesPull "github.com/HardDie/DeckBuilder/internal/services/pull"
+	servicesReplace "github.com/HardDie/DeckBuilder/internal/services/replace"
 	servicesSystem "github.com/HardDie/DeckBuilder/internal/services/system"
 	servicesTTS "github.com/HardDie/DeckBuilder/internal/services/tts"
 	"github.com/HardDie/DeckBuilder/internal/tts_entity"
+	"github.com/HardDie/DeckBuilder/internal/uploader"
 	"github.com/HardDie/DeckBuilder/internal/utils"
 )
 
@@ -35,6 +41,8 @@ type generator struct {
 	serviceCard       servicesCard.Card
 	serviceSystem     servicesSystem.System
 	serviceTTS        servicesTTS.TTS
+	// nil if the images are not uploaded
+	uploader uploader.Uploader
 }
 
 func New(
@@ -45,6 +53,7 @@ func New(
 	serviceCard servicesCard.Card,
 	serviceSystem servicesSystem.System,
 	serviceTTS servicesTTS.TTS,
+	uploader uploader.Uploader,
 ) Generator {
 	return &generator{
 		cfg:               cfg,
@@ -54,6 +63,7 @@ func New(
 		serviceCard:       serviceCard,
 		serviceSystem:     serviceSystem,
 		serviceTTS:        serviceTTS,
+		uploader:          uploader,
 	}
 }
 
@@ -66,6 +76,12 @@ func (s *generator) GenerateGame(gameID string, req GenerateGameRequest) error {
 
 	pr := progress.GetProgress()
 
+	switch req.Mode {
+	case servicesTTS.ModeSpawn, servicesTTS.ModeUpdate, servicesTTS.ModeRespawn:
+	default:
+		return er.TTSBadMode.AddMessage("unknown mode: " + req.Mode)
+	}
+
 	// Check if the game exists
 	gameItem, err := s.serviceGame.Item(gameID)
 	if err != nil {
@@ -77,6 +93,15 @@ func (s *generator) GenerateGame(gameID string, req GenerateGameRequest) error {
 		return err
 	}
 
+	// The update mode needs the layout of the previous generation, it is removed with the results folder
+	var prev *servicesPull.Manifest
+	if req.Mode == servicesTTS.ModeUpdate {
+		prev, err = servicesPull.ReadManifest(s.cfg, gameItem.ID)
+		if err != nil {
+			logger.Warn.Println("Generator: can't read the previous manifest:", err.Error())
+		}
+	}
+
 	// Cleanup before generation
 	err = fs.RemoveFolder(s.cfg.Results())
 	if err != nil {
@@ -92,7 +117,7 @@ func (s *generator) GenerateGame(gameID string, req GenerateGameRequest) error {
 	pr.SetType("Image generation")
 	pr.SetStatus(progress.StatusInProgress)
 	go func() {
-		err = s.generateBody(gameItem, deckArray, order, req.Scale, cfg)
+		err = s.generateBody(gameItem, deckArray, order, req.Scale, req.Mode, prev, cfg)
 		if err != nil {
 			pr.SetStatus(progress.StatusError)
 			logger.Error.Println("Generator:", err.Error())
@@ -171,6 +196,8 @@ func (s *generator) generateBody(
 	decks map[Deck][]Card,
 	order []Deck,
 	scale int,
+	mode string,
+	prev *servicesPull.Manifest,
 	cfg *entitiesSettings.Settings,
 ) error {
 	pr := progress.GetProgress()
@@ -181,8 +208,13 @@ func (s *generator) generateBody(
 	if err != nil {
 		return err
 	}
+	// Upload images to the image hosting
+	urls, err := s.uploadImages(gameItem, imageMapping)
+	if err != nil {
+		return err
+	}
 	// Generate json description
-	err = s.generateJson(gameItem, decks, order, imageMapping, cfg)
+	err = s.generateJson(gameItem, decks, order, imageMapping, urls, mode, prev, cfg)
 	if err != nil {
 		return err
 	}
@@ -305,14 +337,111 @@ func (s *generator) generateImages(
 	return images, nil
 }
 
+// uploadImages uploads all pages and backsides, if the uploader is set.
+//
+// output:
+//   - map[path_to_image] = hosted_url
+//   - mapping file in the result folder, it can be used to replace the local urls with the replace service
+func (s *generator) uploadImages(gameItem *entitiesGame.Game, imageMapping map[string]PageInfo) (map[string]string, error) {
+	if s.uploader == nil {
+		return s.servedImages(imageMapping)
+	}
+	pr := progress.GetProgress()
+	pr.SetMessage("Uploading the image pages...")
+	pr.SetProgress(0)
+
+	// The backside is shared by all pages of the deck
+	var paths []string
+	urls := make(map[string]string)
+	for _, pageInfo := range imageMapping {
+		for _, path := range []string{pageInfo.Image, pageInfo.Backside} {
+			if _, ok := urls[path]; !ok {
+				urls[path] = ""
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	var mapping servicesReplace.Mapping
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, er.InternalError.AddMessage(err.Error())
+		}
+		// TTS caches the images by the URL, so the changed page must get a new URL
+		name := gameItem.ID + "/" + utils.HashForData(data)[:16] + "_" + filepath.Base(path)
+		url, err := s.uploader.Upload(context.Background(), name, data)
+		if err != nil {
+			return nil, err
+		}
+		urls[path] = url
+		mapping.Data = append(mapping.Data, servicesReplace.Couple{
+			Key:   s.imageURL(path, nil),
+			Value: url,
+		})
+		pr.SetProgress(float32(i+1) / float32(len(paths)) * 100)
+	}
+
+	err := fs.CreateAndProcess(filepath.Join(s.cfg.Results(), gameItem.ID+"_mapping.json"), mapping, fs.JsonToWriter[servicesReplace.Mapping])
+	if err != nil {
+		return nil, err
+	}
+	pr.SetMessage("All image pages were successfully uploaded!")
+	return urls, nil
+}
+
+// servedImages returns the URLs of the pages and backsides served by the application, if the results host is set.
+// TTS caches the images by the URL, so the URL has the version of the content and the changed page gets a new URL.
+func (s *generator) servedImages(imageMapping map[string]PageInfo) (map[string]string, error) {
+	if s.cfg.ResultsHost == "" {
+		return nil, nil
+	}
+	urls := make(map[string]string)
+	for _, pageInfo := range imageMapping {
+		for _, path := range []string{pageInfo.Image, pageInfo.Backside} {
+			if _, ok := urls[path]; ok {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, er.InternalError.AddMessage(err.Error())
+			}
+			urls[path] = s.cfg.ResultsURL(path) + "?v=" + utils.HashForData(data)[:16]
+		}
+	}
+	return urls, nil
+}
+
+// imageURL returns the hosted URL of the image if it was uploaded, the URL of the image served by the application
+// if the results host is set, or the URL of the local file
+func (s *generator) imageURL(path string, urls map[string]string) string {
+	if url, ok := urls[path]; ok && url != "" {
+		return url
+	}
+	if s.cfg.ResultsHost != "" {
+		return s.cfg.ResultsURL(path)
+	}
+	return "file:///" + path
+}
+
 func (s *generator) generateJson(
 	gameItem *entitiesGame.Game,
 	decks map[Deck][]Card,
 	order []Deck,
 	imageMapping map[string]PageInfo,
+	urls map[string]string,
+	mode string,
+	prev *servicesPull.Manifest,
 	cfg *entitiesSettings.Settings,
 ) error {
+	// The GUIDs are stable, so the spawned objects can be updated after the next generation
+	guids := tts_entity.NewGUIDs()
 	bag := tts_entity.NewBag(gameItem.Name)
+	bag.GUID = guids.GUID(gameItem.ID)
+	manifest := servicesPull.Manifest{
+		GameID: gameItem.ID,
+	}
 	collectionBags := make(map[string]*tts_entity.Bag)
 	var deck tts_entity.DeckObject
 
@@ -347,12 +476,13 @@ func (s *generator) generateJson(
 
 		pageInfo := imageMapping[deckInfo.ID+"_"+strconv.Itoa(page.GetIndex())]
 		deckDescription := tts_entity.DeckDescription{
-			FaceURL:   "file:///" + pageInfo.Image,
-			BackURL:   "file:///" + pageInfo.Backside,
+			FaceURL:   s.imageURL(pageInfo.Image, urls),
+			BackURL:   s.imageURL(pageInfo.Backside, urls),
 			NumWidth:  pageInfo.Columns,
 			NumHeight: pageInfo.Rows,
 		}
 		deck.CustomDeck[page.GetIndex()+deckIdOffset] = deckDescription
+		manifest.Sheets = append(manifest.Sheets, page.GetIndex()+deckIdOffset)
 
 		var prevCollection string
 		var prevCollectionDeck string
@@ -371,20 +501,22 @@ func (s *generator) generateJson(
 
 				pageInfo = imageMapping[deckInfo.ID+"_"+strconv.Itoa(page.GetIndex())]
 				deckDescription = tts_entity.DeckDescription{
-					FaceURL:   "file:///" + pageInfo.Image,
-					BackURL:   "file:///" + pageInfo.Backside,
+					FaceURL:   s.imageURL(pageInfo.Image, urls),
+					BackURL:   s.imageURL(pageInfo.Backside, urls),
 					NumWidth:  pageInfo.Columns,
 					NumHeight: pageInfo.Rows,
 				}
 				deck.CustomDeck[page.GetIndex()+deckIdOffset] = deckDescription
+				manifest.Sheets = append(manifest.Sheets, page.GetIndex()+deckIdOffset)
 			}
 
 			if card.CollectionID+deckInfo.ID != prevCollectionDeck {
 				prevCollectionDeck = card.CollectionID + deckInfo.ID
 
 				if _, ok := collectionBags[prevCollection]; !ok {
-					collectionBags[prevCollection] = utils.Allocate(tts_entity.NewBag(prevCollection))
+					collectionBags[prevCollection] = newCollectionBag(guids, gameItem.ID, prevCollection)
 				}
+				deck.GUID = guids.GUID(gameItem.ID, prevCollection, deckInfo.ID)
 				switch {
 				case len(deck.ContainedObjects) == 1:
 					// We cannot create a deck object with a single card. We must create a card object.
@@ -419,7 +551,7 @@ func (s *generator) generateJson(
 				return err
 			}
 
-			cardGUID := fmt.Sprintf("%06d", commonIndex)
+			cardGUID := guids.GUID(card.GameID, card.CollectionID, deckInfo.ID, strconv.FormatInt(card.ID, 10))
 			commonIndex++
 			cardObject := tts_entity.NewCard(
 				cardGUID,
@@ -435,6 +567,14 @@ func (s *generator) generateJson(
 					ScaleZ: cfg.CardSize.ScaleZ,
 				},
 			)
+			manifest.Cards = append(manifest.Cards, servicesPull.ManifestCard{
+				GUID:         cardGUID,
+				CollectionID: card.CollectionID,
+				DeckID:       deckInfo.ID,
+				CardID:       card.ID,
+				TTSCardID:    cardObject.CardID,
+				FaceURL:      deckDescription.FaceURL,
+			})
 			for i := 0; i < cardItem.Count; i++ {
 				// Add a card to the deck as many times as set in the count variable
 				deck.AddCard(cardObject)
@@ -443,8 +583,9 @@ func (s *generator) generateJson(
 
 		if !page.IsEmpty() {
 			if _, ok := collectionBags[prevCollection]; !ok {
-				collectionBags[prevCollection] = utils.Allocate(tts_entity.NewBag(prevCollection))
+				collectionBags[prevCollection] = newCollectionBag(guids, gameItem.ID, prevCollection)
 			}
+			deck.GUID = guids.GUID(gameItem.ID, prevCollection, deckInfo.ID)
 			switch {
 			case len(deck.ContainedObjects) == 1:
 				// We cannot create a deck object with a single card. We must create a card object.
@@ -475,9 +616,61 @@ func (s *generator) generateJson(
 	if err != nil {
 		return err
 	}
+	// The manifest matches the objects in TTS with the cards, so the changes made in TTS can be pulled back
+	err = fs.CreateAndProcess(servicesPull.ManifestPath(s.cfg, gameItem.ID), manifest, fs.JsonToWriter[servicesPull.Manifest])
+	if err != nil {
+		return err
+	}
+
+	if mode == servicesTTS.ModeUpdate && !sameLayout(prev, &manifest) {
+		// The update replaces the sheets by ID in the spawned objects, the cards would show the wrong images
+		logger.Warn.Println("Generator: the pages of the game were changed since the last generation, the game is spawned instead of the update")
+		mode = servicesTTS.ModeSpawn
+	}
 
-	// Try to upload to TTS if it's possible
-	s.serviceTTS.SendToTTS(bag)
+	// Upload to TTS, the generated files stay in the results folder if TTS is not running
+	if mode == servicesTTS.ModeSpawn {
+		err = s.serviceTTS.SendToTTS(bag)
+	} else {
+		err = s.serviceTTS.UpdateInTTS(bag, mode)
+	}
+	if err != nil {
+		message := err.Error()
+		if val, ok := err.(*er.Err); ok {
+			message = val.GetMessage()
+		}
+		logger.Warn.Println("Generator: can't send the game to TTS:", message)
+		pr := progress.GetProgress()
+		pr.SetMessage("The game was generated, but it was not sent to TTS: " + message)
+	}
 
 	return nil
 }
+
+// sameLayout checks that the cards stay on the same sheets and places as in the previous generation
+func sameLayout(prev, manifest *servicesPull.Manifest) bool {
+	if prev == nil || len(prev.Sheets) != len(manifest.Sheets) {
+		return false
+	}
+	for i := range prev.Sheets {
+		if prev.Sheets[i] != manifest.Sheets[i] {
+			return false
+		}
+	}
+	cards := make(map[string]int, len(prev.Cards))
+	for _, card := range prev.Cards {
+		cards[card.GUID] = card.TTSCardID
+	}
+	for _, card := range manifest.Cards {
+		if ttsCardID, ok := cards[card.GUID]; ok && ttsCardID != card.TTSCardID {
+			return false
+		}
+	}
+	return true
+}
+
+func newCollectionBag(guids *tts_entity.GUIDs, gameID, collectionID string) *tts_entity.Bag {
+	bag := tts_entity.NewBag(collectionID)
+	bag.GUID = guids.GUID(gameID, collectionID)
+	return &bag
+}
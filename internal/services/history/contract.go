@@ -0,0 +1,12 @@
+package history
+
+import (
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+)
+
+type History interface {
+	List(target entitiesHistory.Target) ([]*entitiesHistory.Revision, error)
+	Diff(target entitiesHistory.Target, revisionID int64) (*entitiesHistory.Revision, []*entitiesHistory.Change, error)
+	Restore(target entitiesHistory.Target, revisionID int64) (entitiesHistory.Target, error)
+	GetImage(target entitiesHistory.Target, revisionID int64) ([]byte, string, error)
+}
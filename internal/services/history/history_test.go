@@ -0,0 +1,418 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/HardDie/fsentry"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/images"
+	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
+	repositoriesCollection "github.com/HardDie/DeckBuilder/internal/repositories/collection"
+	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
+	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
+	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
+	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
+	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
+	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
+)
+
+type historyTest struct {
+	gameID, collectionID, deckID string
+	cfg                          *config.Config
+	core                         dbCore.Core
+
+	serviceGame       servicesGame.Game
+	serviceCollection servicesCollection.Collection
+	serviceDeck       servicesDeck.Deck
+	serviceCard       servicesCard.Card
+	serviceHistory    History
+}
+
+func newHistoryTest(t testing.TB) *historyTest {
+	dir, err := os.MkdirTemp("", "history_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	cfg := config.Get(false, "")
+	cfg.SetDataPath(dir)
+	cfg.HistoryLimit = 3
+
+	fs := fsentry.NewFSEntry(cfg.Games())
+
+	core := dbCore.New(fs, cfg.Games())
+	images := dbImage.New(fs, cfg.Games())
+	game := dbGame.New(fs, images)
+	collection := dbCollection.New(fs, images, game)
+	deck := dbDeck.New(fs, images, collection)
+	card := dbCard.New(fs, images, deck)
+	history := dbHistory.New(fs, images)
+	trash := dbTrash.New(fs, images, cfg.Games())
+	archive := dbArchive.New(cfg, fs, transfer.Files(fs, images), images)
+
+	repositoryHistory := repositoriesHistory.New(cfg, history, game, collection, deck, card)
+	repositoryTrash := repositoriesTrash.New(cfg, trash, game, collection, deck, card)
+	repositoryGame := repositoriesGame.New(cfg, game, archive, repositoryHistory, repositoryTrash)
+	repositoryDeck := repositoriesDeck.New(cfg, collection, deck, card, repositoryHistory, repositoryTrash)
+	repositoryCollection := repositoriesCollection.New(cfg, collection, repositoryDeck, repositoryHistory, repositoryTrash)
+	repositoryCard := repositoriesCard.New(cfg, card, repositoryHistory, repositoryTrash)
+
+	return &historyTest{
+		gameID:       "test_history__game",
+		collectionID: "test_history__collection",
+		deckID:       "test_history__deck",
+		cfg:          cfg,
+		core:         core,
+
+		serviceGame:       servicesGame.New(cfg, repositoryGame),
+		serviceCollection: servicesCollection.New(cfg, repositoryCollection),
+		serviceDeck:       servicesDeck.New(cfg, repositoryDeck),
+		serviceCard:       servicesCard.New(cfg, repositoryCard),
+		serviceHistory:    New(cfg, repositoryHistory),
+	}
+}
+
+func (tt *historyTest) testGame(t *testing.T) {
+	gameName := "game_one"
+	target := entitiesHistory.Target{GameID: gameName}
+
+	pngImage, err := images.ImageToPng(images.CreateImage(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jpegImage, err := images.ImageToJpeg(images.CreateImage(50, 50))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Game not exist error
+	_, err = tt.serviceHistory.List(target)
+	if !errors.Is(err, er.GameNotExists) {
+		t.Fatal(err)
+	}
+
+	// Create game
+	game, err := tt.serviceGame.Create(servicesGame.CreateRequest{
+		Name:        gameName,
+		Description: "first",
+		ImageFile:   pngImage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No revisions yet
+	revisions, err := tt.serviceHistory.List(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 0 {
+		t.Fatal("Bad number of revisions [got]", len(revisions), "[want] 0")
+	}
+
+	// Update without changes doesn't create revision
+	_, err = tt.serviceGame.Update(game.ID, servicesGame.UpdateRequest{
+		Name:        gameName,
+		Description: "first",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err = tt.serviceHistory.List(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 0 {
+		t.Fatal("Bad number of revisions [got]", len(revisions), "[want] 0")
+	}
+
+	// Replace description and image
+	_, err = tt.serviceGame.Update(game.ID, servicesGame.UpdateRequest{
+		Name:        gameName,
+		Description: "second",
+		ImageFile:   jpegImage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err = tt.serviceHistory.List(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 {
+		t.Fatal("Bad number of revisions [got]", len(revisions), "[want] 1")
+	}
+	if revisions[0].Description != "first" || !revisions[0].HasImage {
+		t.Fatal("Revision doesn't contain the previous state")
+	}
+
+	// Diff with the current state
+	revision, changes, err := tt.serviceHistory.Diff(target, revisions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision.ID != revisions[0].ID {
+		t.Fatal("Bad revision [got]", revision.ID, "[want]", revisions[0].ID)
+	}
+	fields := make(map[string]*entitiesHistory.Change)
+	for _, change := range changes {
+		fields[change.Field] = change
+	}
+	if len(fields) != 2 || fields["description"] == nil || fields["imageFile"] == nil {
+		t.Fatal("Bad list of changes", changes)
+	}
+	if fields["description"].Revision != "first" || fields["description"].Current != "second" {
+		t.Fatal("Bad description change", fields["description"])
+	}
+
+	// Previous image is available
+	data, imgType, err := tt.serviceHistory.GetImage(target, revisions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imgType != "png" || !bytes.Equal(data, pngImage) {
+		t.Fatal("Bad revision image [got]", imgType, "[want] png")
+	}
+
+	// Restore previous state
+	_, err = tt.serviceHistory.Restore(target, revisions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game, err = tt.serviceGame.Item(game.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Description != "first" {
+		t.Fatal("Bad description [got]", game.Description, "[want] first")
+	}
+	data, _, err = tt.serviceGame.GetImage(game.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, pngImage) {
+		t.Fatal("Image was not restored")
+	}
+
+	// Restore is recorded too, so it can be undone
+	revisions, err = tt.serviceHistory.List(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 {
+		t.Fatal("Bad number of revisions [got]", len(revisions), "[want] 2")
+	}
+	if revisions[0].Description != "second" {
+		t.Fatal("Bad revision order [got]", revisions[0].Description, "[want] second")
+	}
+
+	// Revision not exist error
+	_, _, err = tt.serviceHistory.Diff(target, 100)
+	if !errors.Is(err, er.RevisionNotExists) {
+		t.Fatal(err)
+	}
+
+	// Rename game, history follows the game
+	game, err = tt.serviceGame.Update(game.ID, servicesGame.UpdateRequest{
+		Name:        gameName + "_renamed",
+		Description: "first",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTarget := entitiesHistory.Target{GameID: game.ID}
+	revisions, err = tt.serviceHistory.List(newTarget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 {
+		t.Fatal("Bad number of revisions [got]", len(revisions), "[want] 3")
+	}
+
+	// Restore the old name
+	restoredTarget, err := tt.serviceHistory.Restore(newTarget, revisions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredTarget.GameID != gameName {
+		t.Fatal("Bad restored game [got]", restoredTarget.GameID, "[want]", gameName)
+	}
+
+	// Retention limit
+	revisions, err = tt.serviceHistory.List(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != tt.cfg.HistoryLimit {
+		t.Fatal("Bad number of revisions [got]", len(revisions), "[want]", tt.cfg.HistoryLimit)
+	}
+
+	// Delete game removes history
+	err = tt.serviceGame.Delete(gameName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceGame.Create(servicesGame.CreateRequest{
+		Name: gameName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err = tt.serviceHistory.List(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 0 {
+		t.Fatal("Bad number of revisions [got]", len(revisions), "[want] 0")
+	}
+}
+func (tt *historyTest) testCard(t *testing.T) {
+	// Create card
+	card, err := tt.serviceCard.Create(tt.gameID, tt.collectionID, tt.deckID, servicesCard.CreateRequest{
+		Name:      "card_one",
+		Variables: map[string]string{"attack": "1"},
+		Count:     1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := entitiesHistory.Target{
+		GameID:       tt.gameID,
+		CollectionID: tt.collectionID,
+		DeckID:       tt.deckID,
+		CardID:       card.ID,
+	}
+
+	// Update card
+	_, err = tt.serviceCard.Update(tt.gameID, tt.collectionID, tt.deckID, card.ID, servicesCard.UpdateRequest{
+		Name:      "card_one",
+		Variables: map[string]string{"attack": "2", "health": "3"},
+		Count:     2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := tt.serviceHistory.List(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 {
+		t.Fatal("Bad number of revisions [got]", len(revisions), "[want] 1")
+	}
+
+	_, changes, err := tt.serviceHistory.Diff(target, revisions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := make(map[string]*entitiesHistory.Change)
+	for _, change := range changes {
+		fields[change.Field] = change
+	}
+	if len(fields) != 3 || fields["count"] == nil || fields["variables.attack"] == nil || fields["variables.health"] == nil {
+		t.Fatal("Bad list of changes", changes)
+	}
+
+	// Restore card
+	_, err = tt.serviceHistory.Restore(target, revisions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err = tt.serviceCard.Item(tt.gameID, tt.collectionID, tt.deckID, card.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Count != 1 || card.Variables["attack"] != "1" || len(card.Variables) != 1 {
+		t.Fatal("Card was not restored", card)
+	}
+
+	// Renaming the deck keeps the history of the cards
+	deck, err := tt.serviceDeck.Update(tt.gameID, tt.collectionID, tt.deckID, servicesDeck.UpdateRequest{
+		Name: tt.deckID + "_renamed",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.DeckID = deck.ID
+	revisions, err = tt.serviceHistory.List(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 {
+		t.Fatal("Bad number of revisions [got]", len(revisions), "[want] 2")
+	}
+	deckRevisions, err := tt.serviceHistory.List(entitiesHistory.Target{
+		GameID:       tt.gameID,
+		CollectionID: tt.collectionID,
+		DeckID:       deck.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deckRevisions) != 1 || deckRevisions[0].Name != tt.deckID {
+		t.Fatal("Bad deck revisions", deckRevisions)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+
+	tt := newHistoryTest(t)
+
+	if err := tt.core.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := tt.core.Drop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Create game
+	_, err := tt.serviceGame.Create(servicesGame.CreateRequest{
+		Name: tt.gameID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create collection
+	_, err = tt.serviceCollection.Create(tt.gameID, servicesCollection.CreateRequest{
+		Name: tt.collectionID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create deck
+	_, err = tt.serviceDeck.Create(tt.gameID, tt.collectionID, servicesDeck.CreateRequest{
+		Name: tt.deckID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("game", tt.testGame)
+	t.Run("card", tt.testCard)
+}
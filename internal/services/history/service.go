@@ -0,0 +1,119 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	"github.com/HardDie/DeckBuilder/internal/images"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+)
+
+type history struct {
+	cfg               *config.Config
+	repositoryHistory repositoriesHistory.History
+}
+
+func New(cfg *config.Config, repositoryHistory repositoriesHistory.History) History {
+	return &history{
+		cfg:               cfg,
+		repositoryHistory: repositoryHistory,
+	}
+}
+
+func (s *history) List(target entitiesHistory.Target) ([]*entitiesHistory.Revision, error) {
+	return s.repositoryHistory.GetAll(target)
+}
+func (s *history) Diff(target entitiesHistory.Target, revisionID int64) (*entitiesHistory.Revision, []*entitiesHistory.Change, error) {
+	revision, err := s.repositoryHistory.GetByID(target, revisionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var revisionImage []byte
+	if revision.HasImage {
+		revisionImage, err = s.repositoryHistory.GetImage(target, revisionID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	current, currentImage, err := s.repositoryHistory.Current(target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := make([]*entitiesHistory.Change, 0)
+	addChange := func(field, revisionValue, currentValue string) {
+		if revisionValue == currentValue {
+			return
+		}
+		changes = append(changes, &entitiesHistory.Change{
+			Field:    field,
+			Revision: revisionValue,
+			Current:  currentValue,
+		})
+	}
+
+	addChange("name", revision.Name, current.Name)
+	addChange("description", revision.Description, current.Description)
+	addChange("image", revision.Image, current.Image)
+	if target.IsCard() {
+		addChange("count", strconv.Itoa(revision.Count), strconv.Itoa(current.Count))
+
+		// Collect keys from both sides, so removed and added variables are visible
+		keys := make(map[string]struct{})
+		for key := range revision.Variables {
+			keys[key] = struct{}{}
+		}
+		for key := range current.Variables {
+			keys[key] = struct{}{}
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+		for _, key := range sortedKeys {
+			addChange("variables."+key, revision.Variables[key], current.Variables[key])
+		}
+	}
+	if !bytes.Equal(revisionImage, currentImage) {
+		changes = append(changes, &entitiesHistory.Change{
+			Field:    "imageFile",
+			Revision: describeImage(revisionImage),
+			Current:  describeImage(currentImage),
+		})
+	}
+
+	return revision, changes, nil
+}
+func (s *history) Restore(target entitiesHistory.Target, revisionID int64) (entitiesHistory.Target, error) {
+	return s.repositoryHistory.Restore(target, revisionID)
+}
+func (s *history) GetImage(target entitiesHistory.Target, revisionID int64) ([]byte, string, error) {
+	data, err := s.repositoryHistory.GetImage(target, revisionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	imgType, err := images.ValidateImage(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, imgType, nil
+}
+
+func describeImage(data []byte) string {
+	if data == nil {
+		return ""
+	}
+	imgType, err := images.ValidateImage(data)
+	if err != nil {
+		return fmt.Sprintf("unknown image, %d bytes", len(data))
+	}
+	return fmt.Sprintf("%s image, %d bytes", imgType, len(data))
+}
@@ -0,0 +1,34 @@
+package image
+
+import (
+	"time"
+
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	"github.com/HardDie/DeckBuilder/internal/images"
+)
+
+type Image interface {
+	// Get returns the image or its thumbnail with the validators for the cache
+	Get(target entitiesHistory.Target, req GetRequest) (*GetResponse, error)
+	Edit(target entitiesHistory.Target, req EditRequest) ([]byte, string, error)
+}
+
+type GetRequest struct {
+	// The maximal side of the thumbnail, the original image is returned if 0
+	Size int
+}
+
+type GetResponse struct {
+	Data []byte
+	Type string
+	// The hash of the stored image and the size of the thumbnail, it is changed together with the image
+	ETag string
+	// The time of the last update of the entity
+	ModTime time.Time
+}
+
+type EditRequest struct {
+	Operations []images.Operation
+	// Return the result without saving it
+	Preview bool
+}
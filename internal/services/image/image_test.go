@@ -0,0 +1,253 @@
+package image
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HardDie/fsentry"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	"github.com/HardDie/DeckBuilder/internal/images"
+	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
+	repositoriesCollection "github.com/HardDie/DeckBuilder/internal/repositories/collection"
+	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
+	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
+	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
+	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
+	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
+	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
+	servicesHistory "github.com/HardDie/DeckBuilder/internal/services/history"
+)
+
+func TestEdit(t *testing.T) {
+	t.Parallel()
+
+	dir, err := os.MkdirTemp("", "image_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	cfg := config.Get(false, "")
+	cfg.SetDataPath(dir)
+
+	fs := fsentry.NewFSEntry(cfg.Games())
+
+	core := dbCore.New(fs, cfg.Games())
+	imagesDB := dbImage.New(fs, cfg.Games())
+	game := dbGame.New(fs, imagesDB)
+	collection := dbCollection.New(fs, imagesDB, game)
+	deck := dbDeck.New(fs, imagesDB, collection)
+	card := dbCard.New(fs, imagesDB, deck)
+	history := dbHistory.New(fs, imagesDB)
+	trash := dbTrash.New(fs, imagesDB, cfg.Games())
+	archive := dbArchive.New(cfg, fs, transfer.Files(fs, imagesDB), imagesDB)
+
+	repositoryHistory := repositoriesHistory.New(cfg, history, game, collection, deck, card)
+	repositoryTrash := repositoriesTrash.New(cfg, trash, game, collection, deck, card)
+	repositoryGame := repositoriesGame.New(cfg, game, archive, repositoryHistory, repositoryTrash)
+	repositoryDeck := repositoriesDeck.New(cfg, collection, deck, card, repositoryHistory, repositoryTrash)
+	repositoryCollection := repositoriesCollection.New(cfg, collection, repositoryDeck, repositoryHistory, repositoryTrash)
+	repositoryCard := repositoriesCard.New(cfg, card, repositoryHistory, repositoryTrash)
+
+	err = core.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serviceGame := servicesGame.New(cfg, repositoryGame)
+	serviceCollection := servicesCollection.New(cfg, repositoryCollection)
+	serviceDeck := servicesDeck.New(cfg, repositoryDeck)
+	serviceCard := servicesCard.New(cfg, repositoryCard)
+	serviceHistory := servicesHistory.New(cfg, repositoryHistory)
+	serviceImage := New(cfg, serviceGame, serviceCollection, serviceDeck, serviceCard)
+
+	cardImage, err := images.ImageToPng(images.CreateImage(30, 40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	artImage, err := images.ImageToPng(images.CreateImage(100, 50))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gameID, collectionID, deckID := "test_image__game", "test_image__collection", "test_image__deck"
+	_, err = serviceGame.Create(servicesGame.CreateRequest{Name: gameID, ImageFile: artImage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = serviceCollection.Create(gameID, servicesCollection.CreateRequest{Name: collectionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = serviceDeck.Create(gameID, collectionID, servicesDeck.CreateRequest{Name: deckID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = serviceCard.Create(gameID, collectionID, deckID, servicesCard.CreateRequest{
+		Name: "first", ImageFile: cardImage, Count: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCard, err := serviceCard.Create(gameID, collectionID, deckID, servicesCard.CreateRequest{
+		Name: "second", ImageFile: artImage, Count: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cardTarget := entitiesHistory.Target{GameID: gameID, CollectionID: collectionID, DeckID: deckID, CardID: newCard.ID}
+
+	t.Run("preview", func(t *testing.T) {
+		data, _, err := serviceImage.Edit(cardTarget, EditRequest{
+			Operations: []images.Operation{{Type: images.OperationRotate, Angle: 90}},
+			Preview:    true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		width, height, err := images.ImageSize(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if width != 50 || height != 100 {
+			t.Fatalf("Bad size [got] %dx%d [want] 50x100", width, height)
+		}
+
+		// The stored image is not changed
+		stored, _, err := serviceCard.GetImage(gameID, collectionID, deckID, newCard.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(stored, artImage) {
+			t.Fatal("The preview should not change the image")
+		}
+	})
+
+	t.Run("fit_to_deck", func(t *testing.T) {
+		data, _, err := serviceImage.Edit(cardTarget, EditRequest{
+			Operations: []images.Operation{{Type: images.OperationFit}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, _, err := serviceCard.GetImage(gameID, collectionID, deckID, newCard.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(stored, data) {
+			t.Fatal("The edited image is not saved")
+		}
+		width, height, err := images.ImageSize(stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if width != 30 || height != 40 {
+			t.Fatalf("Bad size [got] %dx%d [want] 30x40", width, height)
+		}
+
+		// The previous image can be restored from the history
+		revisions, err := serviceHistory.List(cardTarget)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 1 || !revisions[0].HasImage {
+			t.Fatal("The previous card image should be in the history")
+		}
+	})
+
+	t.Run("thumbnail", func(t *testing.T) {
+		gameTarget := entitiesHistory.Target{GameID: gameID}
+		res, err := serviceImage.Get(gameTarget, GetRequest{Size: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		width, height, err := images.ImageSize(res.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if width != 20 || height != 10 || res.Type != images.FormatPNG {
+			t.Fatalf("Bad thumbnail [got] %dx%d %s [want] 20x10 png", width, height, res.Type)
+		}
+		item, err := serviceGame.Item(gameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.ETag != item.ImageHash+"_20" || !res.ModTime.Equal(item.UpdatedAt) {
+			t.Fatal("Bad validators", res.ETag, res.ModTime)
+		}
+
+		// The second request is served from the cache
+		cached, err := serviceImage.Get(gameTarget, GetRequest{Size: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(cached.Data, res.Data) || cached.ETag != res.ETag || !cached.ModTime.Equal(res.ModTime) {
+			t.Fatal("The cached thumbnail differs")
+		}
+
+		// The thumbnail of the changed image is generated again, the previous one is removed
+		_, _, err = serviceImage.Edit(gameTarget, EditRequest{
+			Operations: []images.Operation{{Type: images.OperationRotate, Angle: 90}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed, err := serviceImage.Get(gameTarget, GetRequest{Size: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		width, height, err = images.ImageSize(changed.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if width != 10 || height != 20 {
+			t.Fatalf("Bad size of the changed thumbnail [got] %dx%d [want] 10x20", width, height)
+		}
+		if changed.ETag == res.ETag {
+			t.Fatal("The ETag of the changed image is not changed")
+		}
+		files, err := os.ReadDir(filepath.Join(cfg.Thumbnails(), gameID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].Name() != changed.ETag {
+			t.Fatal("The thumbnails of the previous image are not removed", files)
+		}
+
+		// The original image is returned with the hash of the stored image
+		original, err := serviceImage.Get(gameTarget, GetRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if original.ETag+"_20" != changed.ETag {
+			t.Fatal("Bad ETag of the original image", original.ETag)
+		}
+	})
+
+	t.Run("fit_game_without_size", func(t *testing.T) {
+		_, _, err := serviceImage.Edit(entitiesHistory.Target{GameID: gameID}, EditRequest{
+			Operations: []images.Operation{{Type: images.OperationFit}},
+		})
+		if err == nil {
+			t.Fatal("The fit of the game image without the size should fail")
+		}
+	})
+}
@@ -0,0 +1,291 @@
+package image
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+	"github.com/HardDie/DeckBuilder/internal/images"
+	"github.com/HardDie/DeckBuilder/internal/logger"
+	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
+	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
+	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
+	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+type image struct {
+	cfg               *config.Config
+	locks             *utils.KeyMutex
+	serviceGame       servicesGame.Game
+	serviceCollection servicesCollection.Collection
+	serviceDeck       servicesDeck.Deck
+	serviceCard       servicesCard.Card
+}
+
+func New(
+	cfg *config.Config,
+	serviceGame servicesGame.Game,
+	serviceCollection servicesCollection.Collection,
+	serviceDeck servicesDeck.Deck,
+	serviceCard servicesCard.Card,
+) Image {
+	return &image{
+		cfg:               cfg,
+		locks:             utils.NewKeyMutex(),
+		serviceGame:       serviceGame,
+		serviceCollection: serviceCollection,
+		serviceDeck:       serviceDeck,
+		serviceCard:       serviceCard,
+	}
+}
+
+// Get returns the image by the hash stored in the entity, so the image is read only if the client doesn't have it
+// and the thumbnail isn't cached. The thumbnails are cached in the folder of the entity by the hash of the image,
+// the thumbnails of the previous image are removed when the thumbnail of the new one is created.
+func (s *image) Get(target entitiesHistory.Target, req GetRequest) (*GetResponse, error) {
+	hash, modTime, err := s.imageInfo(target)
+	if err != nil {
+		return nil, err
+	}
+	if hash == "" || req.Size == 0 {
+		data, imgType, err := s.getImage(target)
+		if err != nil {
+			return nil, err
+		}
+		return &GetResponse{Data: data, Type: imgType, ETag: hash, ModTime: modTime}, nil
+	}
+
+	folder := s.thumbnailFolder(target)
+	defer s.locks.Lock(folder)()
+
+	name := hash + "_" + strconv.Itoa(req.Size)
+	thumbnail, err := os.ReadFile(filepath.Join(folder, name))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn.Println("Unable to read the thumbnail:", err.Error())
+		}
+		data, _, err := s.getImage(target)
+		if err != nil {
+			return nil, err
+		}
+		// The image smaller than the thumbnail is returned as is
+		thumbnail, err = images.Thumbnail(data, req.Size)
+		if err != nil {
+			return nil, err
+		}
+		// The cache is only a speed up, the thumbnail will be generated again next time
+		er.IfErrorLog(s.cacheThumbnail(folder, name, hash, thumbnail))
+	}
+
+	thumbnailType, err := images.ValidateImage(thumbnail)
+	if err != nil {
+		return nil, err
+	}
+	return &GetResponse{Data: thumbnail, Type: thumbnailType, ETag: name, ModTime: modTime}, nil
+}
+
+// Edit applies the operations to the image of the entity. The result replaces the image like an uploaded file,
+// so the image URL is cleared and the previous image can be restored from the history.
+func (s *image) Edit(target entitiesHistory.Target, req EditRequest) ([]byte, string, error) {
+	if len(req.Operations) == 0 {
+		return nil, "", er.BadImageOperation.AddMessage("no operations")
+	}
+
+	data, _, err := s.getImage(target)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The fit operation without the size uses the size of the cards of the deck
+	operations := make([]images.Operation, 0, len(req.Operations))
+	for _, op := range req.Operations {
+		if op.Type == images.OperationFit && op.Width == 0 && op.Height == 0 {
+			op.Width, op.Height, err = s.cardSize(target)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+		operations = append(operations, op)
+	}
+
+	data, err = images.Edit(data, operations, s.cfg.MaxImagePixels)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err = images.Normalize(data, s.cfg.MaxImagePixels, s.cfg.ImageFormat)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !req.Preview {
+		err = s.setImage(target, data)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	imgType, err := images.ValidateImage(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, imgType, nil
+}
+
+func (s *image) getImage(target entitiesHistory.Target) ([]byte, string, error) {
+	switch {
+	case target.IsCard():
+		return s.serviceCard.GetImage(target.GameID, target.CollectionID, target.DeckID, target.CardID)
+	case target.IsDeck():
+		return s.serviceDeck.GetImage(target.GameID, target.CollectionID, target.DeckID)
+	case target.IsCollection():
+		return s.serviceCollection.GetImage(target.GameID, target.CollectionID)
+	default:
+		return s.serviceGame.GetImage(target.GameID)
+	}
+}
+func (s *image) setImage(target entitiesHistory.Target, data []byte) error {
+	switch {
+	case target.IsCard():
+		item, err := s.serviceCard.Item(target.GameID, target.CollectionID, target.DeckID, target.CardID)
+		if err != nil {
+			return err
+		}
+		_, err = s.serviceCard.Update(target.GameID, target.CollectionID, target.DeckID, target.CardID, servicesCard.UpdateRequest{
+			Name:        item.Name,
+			Description: item.Description,
+			Variables:   item.Variables,
+			Count:       item.Count,
+			ImageFile:   data,
+		})
+		return err
+	case target.IsDeck():
+		item, err := s.serviceDeck.Item(target.GameID, target.CollectionID, target.DeckID)
+		if err != nil {
+			return err
+		}
+		_, err = s.serviceDeck.Update(target.GameID, target.CollectionID, target.DeckID, servicesDeck.UpdateRequest{
+			Name:        item.Name,
+			Description: item.Description,
+			ImageFile:   data,
+		})
+		return err
+	case target.IsCollection():
+		item, err := s.serviceCollection.Item(target.GameID, target.CollectionID)
+		if err != nil {
+			return err
+		}
+		_, err = s.serviceCollection.Update(target.GameID, target.CollectionID, servicesCollection.UpdateRequest{
+			Name:        item.Name,
+			Description: item.Description,
+			ImageFile:   data,
+		})
+		return err
+	default:
+		item, err := s.serviceGame.Item(target.GameID)
+		if err != nil {
+			return err
+		}
+		_, err = s.serviceGame.Update(target.GameID, servicesGame.UpdateRequest{
+			Name:        item.Name,
+			Description: item.Description,
+			ImageFile:   data,
+		})
+		return err
+	}
+}
+
+// imageInfo returns the hash of the image and the time of the last update of the entity, the hash is empty without the image
+func (s *image) imageInfo(target entitiesHistory.Target) (string, time.Time, error) {
+	switch {
+	case target.IsCard():
+		item, err := s.serviceCard.Item(target.GameID, target.CollectionID, target.DeckID, target.CardID)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return item.ImageHash, item.UpdatedAt, nil
+	case target.IsDeck():
+		item, err := s.serviceDeck.Item(target.GameID, target.CollectionID, target.DeckID)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return item.ImageHash, item.UpdatedAt, nil
+	case target.IsCollection():
+		item, err := s.serviceCollection.Item(target.GameID, target.CollectionID)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return item.ImageHash, item.UpdatedAt, nil
+	default:
+		item, err := s.serviceGame.Item(target.GameID)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return item.ImageHash, item.UpdatedAt, nil
+	}
+}
+
+// thumbnailFolder returns the folder of the thumbnails of the entity, the folders of the nested entities are inside it
+func (s *image) thumbnailFolder(target entitiesHistory.Target) string {
+	path := []string{s.cfg.Thumbnails(), target.GameID}
+	if target.CollectionID != "" {
+		path = append(path, target.CollectionID)
+	}
+	if target.DeckID != "" {
+		path = append(path, target.DeckID)
+	}
+	if target.IsCard() {
+		path = append(path, strconv.FormatInt(target.CardID, 10))
+	}
+	return filepath.Join(path...)
+}
+
+// cacheThumbnail writes the thumbnail and removes the thumbnails of the other images of the entity
+func (s *image) cacheThumbnail(folder, name, hash string, thumbnail []byte) error {
+	err := fs.CreateFolderIfNotExist(folder)
+	if err != nil {
+		return err
+	}
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		return er.InternalError.AddMessage(err.Error())
+	}
+	for _, file := range files {
+		if file.Type().IsRegular() && !strings.HasPrefix(file.Name(), hash+"_") {
+			er.IfErrorLog(os.Remove(filepath.Join(folder, file.Name())))
+		}
+	}
+	return fs.ReplaceFile(filepath.Join(folder, name), func(w io.Writer) error {
+		_, err := w.Write(thumbnail)
+		return err
+	})
+}
+
+// cardSize returns the size of the first card image of the deck, the generator resizes all cards of the deck to it
+func (s *image) cardSize(target entitiesHistory.Target) (int, int, error) {
+	if target.IsGame() || target.IsCollection() {
+		return 0, 0, er.BadImageOperation.AddMessage("the size is required to fit the image of a game or a collection")
+	}
+
+	cards, err := s.serviceCard.List(target.GameID, target.CollectionID, target.DeckID, "", "")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, card := range cards {
+		data, _, err := s.serviceCard.GetImage(target.GameID, target.CollectionID, target.DeckID, card.ID)
+		if err != nil {
+			// Skip the cards without an image
+			continue
+		}
+		return images.ImageSize(data)
+	}
+	return 0, 0, er.BadImageOperation.AddMessage("the deck has no card images to take the size from")
+}
@@ -0,0 +1,54 @@
+package pull
+
+import (
+	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
+)
+
+type Pull interface {
+	// Changes compares the cards spawned in the running TTS with the stored cards
+	Changes(gameID string) ([]*Change, error)
+	// Apply saves the selected changes to the stored cards
+	Apply(gameID string, changes []*Change) ([]*entitiesCard.Card, error)
+}
+
+// The changed fields of the card
+const (
+	FieldName        = "name"
+	FieldDescription = "description"
+	FieldVariable    = "variable"
+)
+
+type Change struct {
+	CollectionID string
+	DeckID       string
+	CardID       int64
+	// The stored name of the card
+	CardName string
+	// The GUID of the object in TTS
+	GUID  string
+	Field string
+	// The name of the changed variable
+	Variable string
+	Old      string
+	New      string
+	// The variable was removed in TTS
+	Removed bool
+}
+
+// Manifest is written by the generator next to the generated json, it matches the generated objects with the source cards
+type Manifest struct {
+	GameID string         `json:"gameID"`
+	Cards  []ManifestCard `json:"cards"`
+	// The IDs of the sheets of the cards in TTS in the order of the pages, the update mode replaces the sheets by them
+	Sheets []int `json:"sheets"`
+}
+
+type ManifestCard struct {
+	GUID         string `json:"guid"`
+	CollectionID string `json:"collectionID"`
+	DeckID       string `json:"deckID"`
+	CardID       int64  `json:"cardID"`
+	// The ID of the card in TTS and the URL of its sheet, they identify the card when TTS changes the GUID of the copy
+	TTSCardID int    `json:"ttsCardID"`
+	FaceURL   string `json:"faceURL"`
+}
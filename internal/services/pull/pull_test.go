@@ -0,0 +1,257 @@
+package pull
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/HardDie/fsentry"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/fs"
+	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
+	repositoriesCollection "github.com/HardDie/DeckBuilder/internal/repositories/collection"
+	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
+	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
+	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
+	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
+	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
+	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
+	servicesTTS "github.com/HardDie/DeckBuilder/internal/services/tts"
+	"github.com/HardDie/DeckBuilder/internal/tts_editor"
+	"github.com/HardDie/DeckBuilder/internal/tts_entity"
+)
+
+const testFaceURL = "http://192.168.1.10:5000/results/deck_1.png"
+
+// fakeTTS answers the code with the objects on the table
+func fakeTTS(t *testing.T, editorAddress string, objects []map[string]any) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { er.IfErrorLog(listener.Close()) })
+
+	encoded, err := json.Marshal(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// TTS returns the string returned by the code
+	returnValue, err := json.Marshal(string(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			var msg tts_editor.Message
+			err = json.NewDecoder(conn).Decode(&msg)
+			er.IfErrorLog(conn.Close())
+			if err != nil || msg.MessageID != tts_editor.RequestExecute {
+				continue
+			}
+
+			back, err := net.Dial("tcp", editorAddress)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			err = json.NewEncoder(back).Encode(tts_editor.Message{
+				MessageID:   tts_editor.EventReturn,
+				ReturnID:    msg.ReturnID,
+				ReturnValue: returnValue,
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			er.IfErrorLog(back.Close())
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestPull(t *testing.T) {
+	t.Parallel()
+
+	dir, err := os.MkdirTemp("", "pull_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	cfg := config.Get(false, "")
+	cfg.SetDataPath(dir)
+
+	fsEntry := fsentry.NewFSEntry(cfg.Games())
+	core := dbCore.New(fsEntry, cfg.Games())
+	imagesDB := dbImage.New(fsEntry, cfg.Games())
+	game := dbGame.New(fsEntry, imagesDB)
+	collection := dbCollection.New(fsEntry, imagesDB, game)
+	deck := dbDeck.New(fsEntry, imagesDB, collection)
+	card := dbCard.New(fsEntry, imagesDB, deck)
+	history := dbHistory.New(fsEntry, imagesDB)
+	trash := dbTrash.New(fsEntry, imagesDB, cfg.Games())
+	archive := dbArchive.New(cfg, fsEntry, transfer.Files(fsEntry, imagesDB), imagesDB)
+
+	repositoryHistory := repositoriesHistory.New(cfg, history, game, collection, deck, card)
+	repositoryTrash := repositoriesTrash.New(cfg, trash, game, collection, deck, card)
+	repositoryGame := repositoriesGame.New(cfg, game, archive, repositoryHistory, repositoryTrash)
+	repositoryDeck := repositoriesDeck.New(cfg, collection, deck, card, repositoryHistory, repositoryTrash)
+	repositoryCollection := repositoriesCollection.New(cfg, collection, repositoryDeck, repositoryHistory, repositoryTrash)
+	repositoryCard := repositoriesCard.New(cfg, card, repositoryHistory, repositoryTrash)
+
+	err = core.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serviceGame := servicesGame.New(cfg, repositoryGame)
+	serviceCollection := servicesCollection.New(cfg, repositoryCollection)
+	serviceDeck := servicesDeck.New(cfg, repositoryDeck)
+	serviceCard := servicesCard.New(cfg, repositoryCard)
+
+	gameID, collectionID, deckID := "test_pull__game", "test_pull__collection", "test_pull__deck"
+	_, err = serviceGame.Create(servicesGame.CreateRequest{Name: gameID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = serviceCollection.Create(gameID, servicesCollection.CreateRequest{Name: collectionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = serviceDeck.Create(gameID, collectionID, servicesDeck.CreateRequest{Name: deckID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	goblin, err := serviceCard.Create(gameID, collectionID, deckID, servicesCard.CreateRequest{
+		Name: "Goblin", Image: "", Variables: map[string]string{"HP": "2", "AT": "1"}, Count: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	guid := tts_entity.GUID(gameID, collectionID, deckID, strconv.FormatInt(goblin.ID, 10))
+
+	// The game was generated
+	err = fs.CreateFolder(cfg.Results())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fs.CreateAndProcess(ManifestPath(cfg, gameID), Manifest{
+		GameID: gameID,
+		Cards: []ManifestCard{{
+			GUID: guid, CollectionID: collectionID, DeckID: deckID, CardID: goblin.ID, TTSCardID: 100, FaceURL: testFaceURL,
+		}},
+	}, fs.JsonToWriter[Manifest])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customDeck := map[string]any{"1": map[string]any{"FaceURL": testFaceURL, "BackURL": "", "NumWidth": 2, "NumHeight": 2}}
+	objects := []map[string]any{
+		// The deck with the renamed card
+		{"GUID": "abcdef", "Name": "Deck", "ContainedObjects": []any{
+			map[string]any{"GUID": guid, "Name": "Card", "CardID": 100, "CustomDeck": customDeck,
+				"Nickname": "Goblin King", "Description": "", "LuaScript": "HP=\"3\"\nAT='1'"},
+		}},
+		// The copy taken out of the deck gets a new GUID
+		{"GUID": "ffffff", "Name": "Card", "CardID": 100, "CustomDeck": customDeck,
+			"Nickname": "Goblin", "Description": "Runs away", "LuaScript": "HP=\"2\"\nfunction onLoad() end"},
+		// The object which is not generated
+		{"GUID": "012345", "Name": "Card", "CardID": 200, "Nickname": "Other"},
+	}
+
+	// The messages from the fake TTS are received on a free port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.TTSListenAddress = listener.Addr().String()
+	er.IfErrorLog(listener.Close())
+	cfg.TTSAddress = fakeTTS(t, cfg.TTSListenAddress, objects)
+
+	serviceTTS := servicesTTS.New(cfg)
+	err = serviceTTS.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	servicePull := New(cfg, serviceTTS, serviceCard)
+
+	_, err = servicePull.Changes("unknown")
+	if err == nil {
+		t.Fatal("The game without the manifest is compared")
+	}
+
+	changes, err := servicePull.Changes(gameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type short struct {
+		Field, Variable, Old, New string
+		Removed                   bool
+	}
+	var got []short
+	for _, change := range changes {
+		if change.CardID != goblin.ID || change.CardName != "Goblin" {
+			t.Fatal("Bad card of the change:", change)
+		}
+		got = append(got, short{change.Field, change.Variable, change.Old, change.New, change.Removed})
+	}
+	want := []short{
+		{FieldDescription, "", "", "Runs away", false},
+		{FieldName, "", "Goblin", "Goblin King", false},
+		{FieldVariable, "AT", "1", "", true},
+		{FieldVariable, "HP", "2", "3", false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("Bad changes [got]", got, "[want]", want)
+	}
+
+	// The removal of the variable is not selected
+	items, err := servicePull.Apply(gameID, []*Change{changes[0], changes[1], changes[3]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatal("Bad list of updated cards:", items)
+	}
+	item, err := serviceCard.Item(gameID, collectionID, deckID, goblin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "Goblin King" || item.Description != "Runs away" || item.Count != 2 ||
+		!reflect.DeepEqual(item.Variables, map[string]string{"HP": "3", "AT": "1"}) {
+		t.Fatal("Bad updated card:", item)
+	}
+}
+
+func TestParseVariables(t *testing.T) {
+	t.Parallel()
+
+	got := parseVariables("HP=\"2\"\n  AT = 'x y';\nLevel=3\n-- comment\nfunction onLoad()\n  x = 1\nend\nBad=\"\\q\"")
+	want := map[string]string{"HP": "2", "AT": "x y", "Level": "3", "x": "1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("Bad variables [got]", got, "[want]", want)
+	}
+}
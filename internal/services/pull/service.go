@@ -0,0 +1,320 @@
+package pull
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	entitiesCard "github.com/HardDie/DeckBuilder/internal/entities/card"
+	"github.com/HardDie/DeckBuilder/internal/errors"
+	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
+	servicesTTS "github.com/HardDie/DeckBuilder/internal/services/tts"
+	"github.com/HardDie/DeckBuilder/internal/tts_editor"
+	"github.com/HardDie/DeckBuilder/internal/tts_entity"
+)
+
+// The script returns the data of all objects on the table, the cards inside the decks and the bags are in the contained objects
+const objectsScript = `
+local objects = {}
+for _, object in ipairs(getAllObjects()) do
+	table.insert(objects, object.getData())
+end
+return JSON.encode(objects)`
+
+// The generated variables look like name="value", the edited ones can use single quotes or numbers
+var variableLine = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*?)\s*;?$`)
+
+type pull struct {
+	cfg         *config.Config
+	serviceTTS  servicesTTS.TTS
+	serviceCard servicesCard.Card
+}
+
+func New(cfg *config.Config, serviceTTS servicesTTS.TTS, serviceCard servicesCard.Card) Pull {
+	return &pull{
+		cfg:         cfg,
+		serviceTTS:  serviceTTS,
+		serviceCard: serviceCard,
+	}
+}
+
+// ManifestPath returns the path of the manifest of the generated game
+func ManifestPath(cfg *config.Config, gameID string) string {
+	return filepath.Join(cfg.Results(), gameID+"_manifest.json")
+}
+
+// ReadManifest reads the manifest written by the last generation of the game
+func ReadManifest(cfg *config.Config, gameID string) (*Manifest, error) {
+	data, err := os.ReadFile(ManifestPath(cfg, gameID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.TTSManifestNotExists
+		}
+		errors.IfErrorLog(err)
+		return nil, errors.InternalError.AddMessage(err.Error())
+	}
+	manifest := &Manifest{}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, errors.InternalError.AddMessage("bad manifest: " + err.Error())
+	}
+	return manifest, nil
+}
+
+func (s *pull) Changes(gameID string) ([]*Change, error) {
+	manifest, err := ReadManifest(s.cfg, gameID)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := s.objects()
+	if err != nil {
+		return nil, err
+	}
+
+	byGUID := make(map[string]ManifestCard)
+	bySheet := make(map[string]ManifestCard)
+	for _, card := range manifest.Cards {
+		byGUID[card.GUID] = card
+		bySheet[sheetKey(card.FaceURL, card.TTSCardID)] = card
+	}
+
+	res := make([]*Change, 0)
+	uniq := make(map[string]struct{})
+	cards := make(map[ManifestCard]*entitiesCard.Card)
+	var walkErr error
+	tts_entity.WalkObjects(objects, func(guid string, object map[string]any) {
+		if walkErr != nil || !isCard(object) {
+			return
+		}
+		source, ok := byGUID[guid]
+		if !ok {
+			// The copies of the card taken out of the deck get new GUIDs, but keep the card ID and the sheet
+			source, ok = bySheet[objectSheetKey(object)]
+		}
+		if !ok {
+			return
+		}
+
+		item, ok := cards[source]
+		if !ok {
+			item, walkErr = s.serviceCard.Item(gameID, source.CollectionID, source.DeckID, source.CardID)
+			if walkErr != nil {
+				return
+			}
+			cards[source] = item
+		}
+		for _, change := range compare(item, object) {
+			change.CollectionID = source.CollectionID
+			change.DeckID = source.DeckID
+			change.CardID = source.CardID
+			change.CardName = item.Name
+			change.GUID = guid
+			// The copies of the card usually have the same changes
+			key := strings.Join([]string{change.CollectionID, change.DeckID, strconv.FormatInt(change.CardID, 10),
+				change.Field, change.Variable, change.New, strconv.FormatBool(change.Removed)}, "\x00")
+			if _, ok := uniq[key]; ok {
+				continue
+			}
+			uniq[key] = struct{}{}
+			res = append(res, change)
+		}
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].CollectionID != res[j].CollectionID {
+			return res[i].CollectionID < res[j].CollectionID
+		}
+		if res[i].DeckID != res[j].DeckID {
+			return res[i].DeckID < res[j].DeckID
+		}
+		if res[i].CardID != res[j].CardID {
+			return res[i].CardID < res[j].CardID
+		}
+		if res[i].Field != res[j].Field {
+			return res[i].Field < res[j].Field
+		}
+		return res[i].Variable < res[j].Variable
+	})
+	return res, nil
+}
+
+func (s *pull) Apply(gameID string, changes []*Change) ([]*entitiesCard.Card, error) {
+	type cardKey struct {
+		CollectionID string
+		DeckID       string
+		CardID       int64
+	}
+	// The changes of the same card are saved at once, so the card gets a single revision
+	var order []cardKey
+	byCard := make(map[cardKey][]*Change)
+	for _, change := range changes {
+		switch change.Field {
+		case FieldName, FieldDescription:
+		case FieldVariable:
+			if change.Variable == "" {
+				return nil, errors.BadName.AddMessage("the name of the variable is empty")
+			}
+		default:
+			return nil, errors.BadName.AddMessage("unknown field: " + change.Field)
+		}
+		key := cardKey{change.CollectionID, change.DeckID, change.CardID}
+		if _, ok := byCard[key]; !ok {
+			order = append(order, key)
+		}
+		byCard[key] = append(byCard[key], change)
+	}
+
+	items := make([]*entitiesCard.Card, 0, len(order))
+	for _, key := range order {
+		item, err := s.serviceCard.Item(gameID, key.CollectionID, key.DeckID, key.CardID)
+		if err != nil {
+			return items, err
+		}
+		variables := make(map[string]string, len(item.Variables))
+		for name, value := range item.Variables {
+			variables[name] = value
+		}
+		req := servicesCard.UpdateRequest{
+			Name:        item.Name,
+			Description: item.Description,
+			Image:       item.Image,
+			Variables:   variables,
+			Count:       item.Count,
+		}
+		for _, change := range byCard[key] {
+			switch change.Field {
+			case FieldName:
+				req.Name = change.New
+			case FieldDescription:
+				req.Description = change.New
+			case FieldVariable:
+				if change.Removed {
+					delete(req.Variables, change.Variable)
+				} else {
+					req.Variables[change.Variable] = change.New
+				}
+			}
+		}
+		item, err = s.serviceCard.Update(gameID, key.CollectionID, key.DeckID, key.CardID, req)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// objects returns the data of the objects on the table of the running TTS
+func (s *pull) objects() (any, error) {
+	result, err := s.serviceTTS.Execute(tts_editor.GlobalGUID, objectsScript)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, errors.TTSTimeout
+	}
+	// The returned string is the JSON of the objects
+	var encoded string
+	if json.Unmarshal(result, &encoded) == nil {
+		result = json.RawMessage(encoded)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.UseNumber()
+	var objects any
+	err = decoder.Decode(&objects)
+	if err != nil {
+		return nil, errors.TTSScriptError.AddMessage("bad list of objects: " + err.Error())
+	}
+	return objects, nil
+}
+
+// compare returns the changes of the card made in TTS
+func compare(item *entitiesCard.Card, object map[string]any) []*Change {
+	var res []*Change
+	if name, _ := object["Nickname"].(string); name != item.Name {
+		res = append(res, &Change{Field: FieldName, Old: item.Name, New: name})
+	}
+	if description, _ := object["Description"].(string); description != item.Description {
+		res = append(res, &Change{Field: FieldDescription, Old: item.Description, New: description})
+	}
+
+	script, _ := object["LuaScript"].(string)
+	variables := parseVariables(script)
+	names := make([]string, 0, len(variables)+len(item.Variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	for name := range item.Variables {
+		if _, ok := variables[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		old, stored := item.Variables[name]
+		value, ok := variables[name]
+		switch {
+		case !ok:
+			res = append(res, &Change{Field: FieldVariable, Variable: name, Old: old, Removed: true})
+		case !stored || old != value:
+			res = append(res, &Change{Field: FieldVariable, Variable: name, Old: old, New: value})
+		}
+	}
+	return res
+}
+
+// parseVariables returns the variables of the card script, the lines which are not assignments are skipped
+func parseVariables(script string) map[string]string {
+	res := make(map[string]string)
+	for _, line := range strings.Split(script, "\n") {
+		match := variableLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		value := match[2]
+		switch {
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				continue
+			}
+			value = unquoted
+		case len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'"):
+			value = value[1 : len(value)-1]
+		}
+		res[match[1]] = value
+	}
+	return res
+}
+
+func isCard(object map[string]any) bool {
+	name, _ := object["Name"].(string)
+	return strings.HasPrefix(name, "Card")
+}
+
+func sheetKey(faceURL string, ttsCardID int) string {
+	return faceURL + "#" + strconv.Itoa(ttsCardID)
+}
+
+// objectSheetKey returns the sheet key of the card object, the sheet is found by the deck ID, the first digits of the card ID
+func objectSheetKey(object map[string]any) string {
+	number, _ := object["CardID"].(json.Number)
+	cardID, err := number.Int64()
+	if err != nil {
+		return ""
+	}
+	decks, _ := object["CustomDeck"].(map[string]any)
+	deck, _ := decks[strconv.FormatInt(cardID/100, 10)].(map[string]any)
+	faceURL, _ := deck["FaceURL"].(string)
+	return sheetKey(faceURL, int(cardID))
+}
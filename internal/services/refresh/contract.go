@@ -0,0 +1,16 @@
+package refresh
+
+import (
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesRefresh "github.com/HardDie/DeckBuilder/internal/entities/refresh"
+)
+
+type Refresh interface {
+	Start(target entitiesHistory.Target, req StartRequest) (*entitiesRefresh.Report, error)
+	Report() (*entitiesRefresh.Report, error)
+}
+
+type StartRequest struct {
+	// Replace only the images whose downloaded content differs from the stored one
+	OnlyChanged bool
+}
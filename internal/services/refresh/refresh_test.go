@@ -0,0 +1,294 @@
+package refresh
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HardDie/fsentry"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesRefresh "github.com/HardDie/DeckBuilder/internal/entities/refresh"
+	"github.com/HardDie/DeckBuilder/internal/images"
+	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
+	repositoriesCollection "github.com/HardDie/DeckBuilder/internal/repositories/collection"
+	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
+	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
+	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
+	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
+	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
+	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
+	servicesHistory "github.com/HardDie/DeckBuilder/internal/services/history"
+)
+
+type refreshTest struct {
+	gameID, collectionID, deckID string
+
+	serviceGame       servicesGame.Game
+	serviceCollection servicesCollection.Collection
+	serviceDeck       servicesDeck.Deck
+	serviceCard       servicesCard.Card
+	serviceHistory    servicesHistory.History
+	serviceRefresh    Refresh
+
+	// The images served by the test server by path
+	mu     sync.Mutex
+	images map[string][]byte
+	server *httptest.Server
+}
+
+func newRefreshTest(t testing.TB) *refreshTest {
+	dir, err := os.MkdirTemp("", "refresh_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	cfg := config.Get(false, "")
+	cfg.SetDataPath(dir)
+
+	fs := fsentry.NewFSEntry(cfg.Games())
+
+	core := dbCore.New(fs, cfg.Games())
+	images := dbImage.New(fs, cfg.Games())
+	game := dbGame.New(fs, images)
+	collection := dbCollection.New(fs, images, game)
+	deck := dbDeck.New(fs, images, collection)
+	card := dbCard.New(fs, images, deck)
+	history := dbHistory.New(fs, images)
+	trash := dbTrash.New(fs, images, cfg.Games())
+	archive := dbArchive.New(cfg, fs, transfer.Files(fs, images), images)
+
+	repositoryHistory := repositoriesHistory.New(cfg, history, game, collection, deck, card)
+	repositoryTrash := repositoriesTrash.New(cfg, trash, game, collection, deck, card)
+	repositoryGame := repositoriesGame.New(cfg, game, archive, repositoryHistory, repositoryTrash)
+	repositoryDeck := repositoriesDeck.New(cfg, collection, deck, card, repositoryHistory, repositoryTrash)
+	repositoryCollection := repositoriesCollection.New(cfg, collection, repositoryDeck, repositoryHistory, repositoryTrash)
+	repositoryCard := repositoriesCard.New(cfg, card, repositoryHistory, repositoryTrash)
+
+	err = core.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := &refreshTest{
+		gameID:       "test_refresh__game",
+		collectionID: "test_refresh__collection",
+		deckID:       "test_refresh__deck",
+
+		serviceGame:       servicesGame.New(cfg, repositoryGame),
+		serviceCollection: servicesCollection.New(cfg, repositoryCollection),
+		serviceDeck:       servicesDeck.New(cfg, repositoryDeck),
+		serviceCard:       servicesCard.New(cfg, repositoryCard),
+		serviceHistory:    servicesHistory.New(cfg, repositoryHistory),
+		serviceRefresh:    New(cfg, repositoryGame, repositoryCollection, repositoryDeck, repositoryCard),
+
+		images: make(map[string][]byte),
+	}
+	tt.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tt.mu.Lock()
+		data, ok := tt.images[r.URL.Path]
+		tt.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(tt.server.Close)
+	return tt
+}
+
+func (tt *refreshTest) serve(path string, data []byte) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	if data == nil {
+		delete(tt.images, path)
+		return
+	}
+	tt.images[path] = data
+}
+
+// wait returns the report after the refresh has been finished
+func (tt *refreshTest) wait(t *testing.T) *entitiesRefresh.Report {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		report, err := tt.serviceRefresh.Report()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.InProgress {
+			return report
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("The refresh has not been finished")
+	return nil
+}
+
+func TestRefresh(t *testing.T) {
+	t.Parallel()
+
+	tt := newRefreshTest(t)
+
+	oldImage, err := images.ImageToPng(images.CreateImage(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newImage, err := images.ImageToPng(images.CreateImage(50, 50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt.serve("/game", oldImage)
+	tt.serve("/card", oldImage)
+	tt.serve("/missing", oldImage)
+
+	// The report doesn't exist before the first refresh
+	_, err = tt.serviceRefresh.Report()
+	if err == nil {
+		t.Fatal("The report should not exist")
+	}
+
+	_, err = tt.serviceGame.Create(servicesGame.CreateRequest{
+		Name:  tt.gameID,
+		Image: tt.server.URL + "/game",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceCollection.Create(tt.gameID, servicesCollection.CreateRequest{
+		Name: tt.collectionID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceDeck.Create(tt.gameID, tt.collectionID, servicesDeck.CreateRequest{
+		Name: tt.deckID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := tt.serviceCard.Create(tt.gameID, tt.collectionID, tt.deckID, servicesCard.CreateRequest{
+		Name:  "card",
+		Image: tt.server.URL + "/card",
+		Count: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceCard.Create(tt.gameID, tt.collectionID, tt.deckID, servicesCard.CreateRequest{
+		Name:  "missing",
+		Image: tt.server.URL + "/missing",
+		Count: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The artist has updated one image and removed another one
+	tt.serve("/card", newImage)
+	tt.serve("/missing", nil)
+
+	t.Run("only_changed", func(t *testing.T) {
+		report, err := tt.serviceRefresh.Start(entitiesHistory.Target{GameID: tt.gameID}, StartRequest{
+			OnlyChanged: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The collection and the deck have no image URL
+		if len(report.Items) != 3 {
+			t.Fatal("Bad number of items [got]", len(report.Items), "[want] 3")
+		}
+
+		report = tt.wait(t)
+		if report.Count(entitiesRefresh.StatusChanged) != 1 ||
+			report.Count(entitiesRefresh.StatusUnchanged) != 1 ||
+			report.Count(entitiesRefresh.StatusFailed) != 1 {
+			t.Fatal("Bad report", report.Items)
+		}
+		for _, item := range report.Items {
+			if item.Status == entitiesRefresh.StatusFailed && item.Name != "missing" {
+				t.Fatal("Bad failed item [got]", item.Name, "[want] missing")
+			}
+			if item.Status == entitiesRefresh.StatusChanged && item.Target.CardID != card.ID {
+				t.Fatal("Bad changed item [got]", item.Name, "[want] card")
+			}
+		}
+
+		// The card has the new image and the old one is in the history
+		data, _, err := tt.serviceCard.GetImage(tt.gameID, tt.collectionID, tt.deckID, card.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, newImage) {
+			t.Fatal("The card image has not been refreshed")
+		}
+		revisions, err := tt.serviceHistory.List(entitiesHistory.Target{
+			GameID:       tt.gameID,
+			CollectionID: tt.collectionID,
+			DeckID:       tt.deckID,
+			CardID:       card.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 1 || !revisions[0].HasImage {
+			t.Fatal("The previous card image should be in the history")
+		}
+
+		// The game image hasn't changed, so there is no revision
+		revisions, err = tt.serviceHistory.List(entitiesHistory.Target{GameID: tt.gameID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 0 {
+			t.Fatal("Bad number of game revisions [got]", len(revisions), "[want] 0")
+		}
+	})
+
+	t.Run("deck", func(t *testing.T) {
+		_, err := tt.serviceRefresh.Start(entitiesHistory.Target{
+			GameID:       tt.gameID,
+			CollectionID: tt.collectionID,
+			DeckID:       tt.deckID,
+		}, StartRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report := tt.wait(t)
+		if len(report.Items) != 2 {
+			t.Fatal("Bad number of items [got]", len(report.Items), "[want] 2")
+		}
+		if report.Count(entitiesRefresh.StatusUnchanged) != 1 || report.Count(entitiesRefresh.StatusFailed) != 1 {
+			t.Fatal("Bad report", report.Items)
+		}
+	})
+
+	t.Run("not_exists", func(t *testing.T) {
+		_, err := tt.serviceRefresh.Start(entitiesHistory.Target{GameID: "not_exists"}, StartRequest{})
+		if err == nil {
+			t.Fatal("The refresh of a nonexistent game should fail")
+		}
+	})
+}
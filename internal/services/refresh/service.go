@@ -0,0 +1,270 @@
+package refresh
+
+import (
+	"sync"
+	"time"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesRefresh "github.com/HardDie/DeckBuilder/internal/entities/refresh"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/logger"
+	"github.com/HardDie/DeckBuilder/internal/progress"
+	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
+	repositoriesCollection "github.com/HardDie/DeckBuilder/internal/repositories/collection"
+	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
+	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+type refresh struct {
+	cfg                  *config.Config
+	repositoryGame       repositoriesGame.Game
+	repositoryCollection repositoriesCollection.Collection
+	repositoryDeck       repositoriesDeck.Deck
+	repositoryCard       repositoriesCard.Card
+
+	// The report of the last refresh, the items are updated while the refresh is running
+	mu     sync.Mutex
+	report *entitiesRefresh.Report
+}
+
+func New(
+	cfg *config.Config,
+	repositoryGame repositoriesGame.Game,
+	repositoryCollection repositoriesCollection.Collection,
+	repositoryDeck repositoriesDeck.Deck,
+	repositoryCard repositoriesCard.Card,
+) Refresh {
+	return &refresh{
+		cfg:                  cfg,
+		repositoryGame:       repositoryGame,
+		repositoryCollection: repositoryCollection,
+		repositoryDeck:       repositoryDeck,
+		repositoryCard:       repositoryCard,
+	}
+}
+
+// Start collects the entities with an image URL and downloads their images again in the background
+func (s *refresh) Start(target entitiesHistory.Target, req StartRequest) (*entitiesRefresh.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report != nil && s.report.InProgress {
+		return nil, er.RefreshInProgress
+	}
+
+	items, err := s.collect(target)
+	if err != nil {
+		return nil, err
+	}
+
+	s.report = &entitiesRefresh.Report{
+		Target:      target,
+		OnlyChanged: req.OnlyChanged,
+		InProgress:  true,
+		Items:       items,
+		StartedAt:   time.Now(),
+	}
+
+	pr := progress.GetProgress()
+	pr.SetType("Image refresh")
+	pr.SetMessage(target.GameID)
+	pr.SetProgress(0)
+	pr.SetStatus(progress.StatusInProgress)
+	go s.run(s.report)
+
+	return s.copyReport(), nil
+}
+func (s *refresh) Report() (*entitiesRefresh.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report == nil {
+		return nil, er.RefreshNotExists
+	}
+	return s.copyReport(), nil
+}
+
+// collect returns the entity itself and all nested entities that have an image URL
+func (s *refresh) collect(target entitiesHistory.Target) ([]*entitiesRefresh.Item, error) {
+	var items []*entitiesRefresh.Item
+	add := func(t entitiesHistory.Target, name, image string) {
+		if image == "" {
+			// The image was uploaded as a file or not set, there is nothing to download
+			return
+		}
+		items = append(items, &entitiesRefresh.Item{
+			Target: t,
+			Name:   name,
+			Image:  image,
+			Status: entitiesRefresh.StatusPending,
+		})
+	}
+
+	var collectionIDs []string
+	switch {
+	case target.IsGame():
+		g, err := s.repositoryGame.GetByID(target.GameID)
+		if err != nil {
+			return nil, err
+		}
+		add(target, g.Name, g.Image)
+
+		collections, err := s.repositoryCollection.GetAll(target.GameID)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range collections {
+			collectionIDs = append(collectionIDs, c.ID)
+		}
+	case target.IsCollection():
+		collectionIDs = append(collectionIDs, target.CollectionID)
+	}
+
+	for _, collectionID := range collectionIDs {
+		c, err := s.repositoryCollection.GetByID(target.GameID, collectionID)
+		if err != nil {
+			return nil, err
+		}
+		add(entitiesHistory.Target{GameID: target.GameID, CollectionID: c.ID}, c.Name, c.Image)
+
+		decks, err := s.repositoryDeck.GetAll(target.GameID, c.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range decks {
+			deckItems, err := s.collectDeck(entitiesHistory.Target{GameID: target.GameID, CollectionID: c.ID, DeckID: d.ID})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, deckItems...)
+		}
+	}
+
+	if target.IsDeck() {
+		deckItems, err := s.collectDeck(target)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, deckItems...)
+	}
+	return items, nil
+}
+func (s *refresh) collectDeck(target entitiesHistory.Target) ([]*entitiesRefresh.Item, error) {
+	var items []*entitiesRefresh.Item
+
+	d, err := s.repositoryDeck.GetByID(target.GameID, target.CollectionID, target.DeckID)
+	if err != nil {
+		return nil, err
+	}
+	if d.Image != "" {
+		items = append(items, &entitiesRefresh.Item{
+			Target: target,
+			Name:   d.Name,
+			Image:  d.Image,
+			Status: entitiesRefresh.StatusPending,
+		})
+	}
+
+	cards, err := s.repositoryCard.GetAll(target.GameID, target.CollectionID, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cards {
+		if c.Image == "" {
+			continue
+		}
+		items = append(items, &entitiesRefresh.Item{
+			Target: entitiesHistory.Target{
+				GameID:       target.GameID,
+				CollectionID: target.CollectionID,
+				DeckID:       d.ID,
+				CardID:       c.ID,
+			},
+			Name:   c.Name,
+			Image:  c.Image,
+			Status: entitiesRefresh.StatusPending,
+		})
+	}
+	return items, nil
+}
+
+// run refreshes the images of the report, the number of workers matches the number of parallel downloads
+func (s *refresh) run(report *entitiesRefresh.Report) {
+	workers := s.cfg.DownloadConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan *entitiesRefresh.Item)
+	wg := sync.WaitGroup{}
+	processed := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				changed, err := s.refreshItem(item.Target, report.OnlyChanged)
+
+				s.mu.Lock()
+				switch {
+				case err != nil:
+					item.Status = entitiesRefresh.StatusFailed
+					item.Error = err.Error()
+				case changed:
+					item.Status = entitiesRefresh.StatusChanged
+				default:
+					item.Status = entitiesRefresh.StatusUnchanged
+				}
+				processed++
+				progress.GetProgress().SetProgress(float32(processed) / float32(len(report.Items)) * 100)
+				s.mu.Unlock()
+			}
+		}()
+	}
+	for _, item := range report.Items {
+		queue <- item
+	}
+	close(queue)
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report.InProgress = false
+	report.FinishedAt = utils.Allocate(time.Now())
+
+	failed := report.Count(entitiesRefresh.StatusFailed)
+	logger.Info.Printf("Image refresh: %d changed, %d unchanged, %d failed",
+		report.Count(entitiesRefresh.StatusChanged), report.Count(entitiesRefresh.StatusUnchanged), failed)
+	pr := progress.GetProgress()
+	pr.SetProgress(100)
+	if failed > 0 {
+		pr.SetStatus(progress.StatusError)
+		return
+	}
+	pr.SetStatus(progress.StatusDone)
+}
+func (s *refresh) refreshItem(target entitiesHistory.Target, onlyChanged bool) (bool, error) {
+	switch {
+	case target.IsCard():
+		return s.repositoryCard.RefreshImage(target.GameID, target.CollectionID, target.DeckID, target.CardID, onlyChanged)
+	case target.IsDeck():
+		return s.repositoryDeck.RefreshImage(target.GameID, target.CollectionID, target.DeckID, onlyChanged)
+	case target.IsCollection():
+		return s.repositoryCollection.RefreshImage(target.GameID, target.CollectionID, onlyChanged)
+	default:
+		return s.repositoryGame.RefreshImage(target.GameID, onlyChanged)
+	}
+}
+
+// copyReport returns a snapshot of the current report, the lock must be held
+func (s *refresh) copyReport() *entitiesRefresh.Report {
+	report := *s.report
+	report.Items = make([]*entitiesRefresh.Item, 0, len(s.report.Items))
+	for _, item := range s.report.Items {
+		itemCopy := *item
+		report.Items = append(report.Items, &itemCopy)
+	}
+	return &report
+}
@@ -1,10 +1,17 @@
 package replace
 
-import "github.com/HardDie/DeckBuilder/internal/tts_entity"
-
 type Replace interface {
 	Prepare(data []byte) ([]Couple, error)
-	Replace(data, mapping []byte) (*tts_entity.RootObjects, error)
+	Replace(data []byte, req ReplaceRequest) (map[string]any, error)
+}
+
+type ReplaceRequest struct {
+	// The mapping in the JSON format returned by Prepare or CSV: local file, URL
+	Mapping []byte
+	// The URLs without the mapping are replaced with the file name under the base URL
+	BaseURL string
+	// The replaced object is spawned in TTS, the failure to send it is only logged
+	Spawn bool
 }
 
 type Couple struct {
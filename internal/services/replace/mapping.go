@@ -0,0 +1,139 @@
+package replace
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/logger"
+)
+
+// The names of the columns of the CSV header: local file, URL
+var csvHeaders = [][2]string{
+	{"file", "url"},
+	{"key", "value"},
+}
+
+// mapper finds the new URL for the URL of the object. The URL is looked up by the full key,
+// then the local file is looked up by the file name (so the mapping can list the files without the local folder),
+// and at last the file name is appended to the base URL. The remote URLs without the mapping are kept as is.
+type mapper struct {
+	byKey map[string]string
+	// The URLs by the file name, empty if the files with the same name are mapped to different URLs
+	byName  map[string]string
+	baseURL string
+}
+
+func newMapper(req ReplaceRequest) (*mapper, error) {
+	m := &mapper{
+		byKey:   make(map[string]string),
+		byName:  make(map[string]string),
+		baseURL: strings.TrimSuffix(req.BaseURL, "/"),
+	}
+	if m.baseURL != "" {
+		if res, err := url.Parse(m.baseURL); err != nil || (res.Scheme != "http" && res.Scheme != "https") || res.Host == "" {
+			return nil, errors.ErrorInvalidMapping.AddMessage("bad base url: " + req.BaseURL)
+		}
+	}
+
+	couples, err := parseMapping(req.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	for _, couple := range couples {
+		if couple.Value == "" {
+			// The URL was not filled in after the prepare request
+			continue
+		}
+		m.byKey[couple.Key] = couple.Value
+		name := fileName(couple.Key)
+		if res, ok := m.byName[name]; ok && res != couple.Value {
+			// The file name is ambiguous, the file can be found by the full key only
+			m.byName[name] = ""
+			continue
+		}
+		m.byName[name] = couple.Value
+	}
+	return m, nil
+}
+
+// lookup returns false if the local file has no mapping
+func (m *mapper) lookup(link string) (string, bool) {
+	if res, ok := m.byKey[link]; ok {
+		return res, true
+	}
+	if !isLocal(link) {
+		return link, true
+	}
+	name := fileName(link)
+	if res, ok := m.byName[name]; ok {
+		return res, res != ""
+	}
+	if m.baseURL != "" {
+		return m.baseURL + "/" + (&url.URL{Path: name}).EscapedPath(), true
+	}
+	return "", false
+}
+
+// parseMapping reads the mapping in the format returned by the prepare request (it is also written by the uploader
+// after the generation) or the CSV file with the local file and the URL in each line
+func parseMapping(data []byte) ([]Couple, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if data[0] == '{' {
+		var m Mapping
+		err := json.Unmarshal(data, &m)
+		if err != nil {
+			logger.Info.Printf("error parsing mapping file: %s", err.Error())
+			return nil, errors.ErrorInvalidMapping
+		}
+		return m.Data, nil
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	var res []Couple
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logger.Info.Printf("error parsing mapping file: %s", err.Error())
+			return nil, errors.ErrorInvalidMapping.AddMessage(err.Error())
+		}
+		if line == 1 && isCSVHeader(record) {
+			continue
+		}
+		res = append(res, Couple{Key: record[0], Value: record[1]})
+	}
+	return res, nil
+}
+
+func isCSVHeader(record []string) bool {
+	for _, header := range csvHeaders {
+		if strings.EqualFold(record[0], header[0]) && strings.EqualFold(record[1], header[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocal checks if the URL is the local file: file:// or the path without the scheme
+func isLocal(link string) bool {
+	return strings.HasPrefix(strings.ToLower(link), "file://") || !strings.Contains(link, "://")
+}
+
+// fileName returns the name of the file from the URL or the local path of any OS
+func fileName(link string) string {
+	return path.Base(strings.ReplaceAll(link, `\`, "/"))
+}
@@ -0,0 +1,282 @@
+package replace
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	servicesTTS "github.com/HardDie/DeckBuilder/internal/services/tts"
+)
+
+// A hand-edited save: a bag inside a bag, a card with states, a custom token and a custom model
+const testSave = `{
+	"SaveName": "test",
+	"ObjectStates": [{
+		"Name": "Bag",
+		"Unknown": {"Precise": 0.30000000000000004},
+		"ContainedObjects": [{
+			"Name": "Bag",
+			"ContainedObjects": [{
+				"Name": "Card",
+				"CardID": 100,
+				"CustomDeck": {"1": {"FaceURL": "file:///face.png", "BackURL": "file:///back.png", "NumWidth": 10}},
+				"States": {"2": {
+					"Name": "Card",
+					"CustomDeck": {"2": {"FaceURL": "file:///state.png", "BackURL": "file:///back.png"}}
+				}}
+			}]
+		}, {
+			"Name": "Custom_Token",
+			"CustomImage": {"ImageURL": "file:///token.png", "ImageSecondaryURL": "", "CustomToken": {"Thickness": 0.2}}
+		}, {
+			"Name": "Custom_Model",
+			"CustomMesh": {"MeshURL": "file:///model.obj", "DiffuseURL": "file:///diffuse.png", "NormalURL": ""}
+		}]
+	}]
+}`
+
+func TestPrepare(t *testing.T) {
+	t.Parallel()
+
+	res, err := newService(t).Prepare([]byte(testSave))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Couple{
+		{Key: "file:///back.png"},
+		{Key: "file:///diffuse.png"},
+		{Key: "file:///face.png"},
+		{Key: "file:///model.obj"},
+		{Key: "file:///state.png"},
+		{Key: "file:///token.png"},
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Fatal("Bad list of URLs [got]", res, "[want]", want)
+	}
+
+	_, err = newService(t).Prepare([]byte(`[1, 2]`))
+	if !errors.Is(err, er.ErrorInvalidDeckDescription) {
+		t.Fatal("The data which is not an object should be rejected", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	t.Parallel()
+
+	couples, err := newService(t).Prepare([]byte(testSave))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range couples {
+		couples[i].Value = "https://example.com/" + couples[i].Key[len("file:///"):]
+	}
+	mapping, err := json.Marshal(Mapping{Data: couples})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := newService(t).Replace([]byte(testSave), ReplaceRequest{Mapping: mapping})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The URLs are replaced and the rest of the save is not changed
+	want := testSave
+	for _, couple := range couples {
+		want = strings.ReplaceAll(want, couple.Key, couple.Value)
+	}
+	var got, wantObject any
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal([]byte(want), &wantObject); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, wantObject) {
+		t.Fatal("Bad result [got]", string(data))
+	}
+	if !strings.Contains(string(data), "0.30000000000000004") {
+		t.Fatal("The numbers should be kept as they are written", string(data))
+	}
+
+	// Every URL must be mapped, all unmapped URLs are reported
+	_, err = newService(t).Replace([]byte(testSave), ReplaceRequest{
+		Mapping: []byte(`{"data":[{"key":"file:///back.png","value":"https://example.com/back.png"}]}`),
+	})
+	if !errors.Is(err, er.ErrorInvalidMapping) {
+		t.Fatal("The URL without the mapping should be rejected", err)
+	}
+	for _, couple := range couples[1:] {
+		if !strings.Contains(err.Error(), couple.Key) {
+			t.Fatal("The unmapped URL is not reported", couple.Key, err)
+		}
+	}
+}
+
+func TestReplaceSources(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		req  ReplaceRequest
+	}{
+		{
+			name: "csv",
+			req: ReplaceRequest{
+				Mapping: []byte("file,url\n" +
+					"file:///back.png,https://cdn.example.com/back.png\n" +
+					"face.png, https://cdn.example.com/face.png\n"),
+				BaseURL: "https://example.com/images/",
+			},
+		},
+		{
+			name: "base_url",
+			req: ReplaceRequest{
+				Mapping: []byte(`{"data":[{"key":"file:///back.png","value":"https://cdn.example.com/back.png"},` +
+					`{"key":"file:///face.png","value":"https://cdn.example.com/face.png"},{"key":"file:///state.png","value":""}]}`),
+				BaseURL: "https://example.com/images",
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			root, err := newService(t).Replace([]byte(testSave), test.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The replaced URLs are collected the same way as the local ones
+			couples, err := newService(t).Prepare(mustMarshal(t, root))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var urls []string
+			for _, couple := range couples {
+				urls = append(urls, couple.Key)
+			}
+			want := []string{
+				"https://cdn.example.com/back.png",
+				"https://cdn.example.com/face.png",
+				"https://example.com/images/diffuse.png",
+				"https://example.com/images/model.obj",
+				"https://example.com/images/state.png",
+				"https://example.com/images/token.png",
+			}
+			if !reflect.DeepEqual(urls, want) {
+				t.Fatal("Bad URLs [got]", urls, "[want]", want)
+			}
+		})
+	}
+
+	_, err := newService(t).Replace([]byte(testSave), ReplaceRequest{})
+	if !errors.Is(err, er.ErrorInvalidMapping) {
+		t.Fatal("The replace without the mapping should fail", err)
+	}
+}
+
+func TestMapper(t *testing.T) {
+	t.Parallel()
+
+	m, err := newMapper(ReplaceRequest{
+		Mapping: []byte("front.png,/cdn/front.png\n" +
+			"file:///a/face.png,https://cdn.example.com/a/face.png\n" +
+			"file:///b/face.png,https://cdn.example.com/b/face.png\n" +
+			"https://old.example.com/back.png,https://cdn.example.com/back.png\n"),
+		BaseURL: "https://example.com/images",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		link string
+		want string
+		ok   bool
+	}{
+		// The first line without the header names is the data
+		{"front.png", "/cdn/front.png", true},
+		{"file:///a/face.png", "https://cdn.example.com/a/face.png", true},
+		// The file name is ambiguous
+		{`C:\cards\face.png`, "", false},
+		{"https://old.example.com/back.png", "https://cdn.example.com/back.png", true},
+		// The remote URL is not moved under the base URL
+		{"https://old.example.com/token.png", "https://old.example.com/token.png", true},
+		{"file:///token.png", "https://example.com/images/token.png", true},
+		{`C:\cards\my token.png`, "https://example.com/images/my%20token.png", true},
+	}
+	for _, test := range tests {
+		got, ok := m.lookup(test.link)
+		if got != test.want || ok != test.ok {
+			t.Fatal("Bad lookup of", test.link, "[got]", got, ok, "[want]", test.want, test.ok)
+		}
+	}
+
+	// The local files are reported without the base URL
+	m, err = newMapper(ReplaceRequest{Mapping: []byte("File,URL\nface.png,https://cdn.example.com/face.png\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.lookup("file:///back.png"); ok {
+		t.Fatal("The local file without the mapping must be reported")
+	}
+	if len(m.byKey) != 1 {
+		t.Fatal("The header must be skipped", m.byKey)
+	}
+}
+
+func TestReplaceNotConnected(t *testing.T) {
+	t.Parallel()
+
+	// The replaced save can't be spawned, the data is returned anyway
+	cfg := config.Get(false, "")
+	cfg.TTSAddress = "127.0.0.1:1"
+	res, err := New(servicesTTS.New(cfg)).Replace([]byte(testSave), ReplaceRequest{BaseURL: "https://example.com/images", Spawn: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := string(mustMarshal(t, res)); !strings.Contains(data, "https://example.com/images/face.png") {
+		t.Fatal("The URLs are not replaced:", data)
+	}
+}
+
+// newService sends the replaced objects to the listener accepting everything instead of TTS
+func newService(t *testing.T) Replace {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		er.IfErrorLog(listener.Close())
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = io.Copy(io.Discard, conn)
+			er.IfErrorLog(conn.Close())
+		}
+	}()
+
+	cfg := config.Get(false, "")
+	cfg.TTSAddress = listener.Addr().String()
+	return New(servicesTTS.New(cfg))
+}
+
+func mustMarshal(t *testing.T, obj any) []byte {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -1,15 +1,15 @@
 package replace
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/logger"
 	servicesTTS "github.com/HardDie/DeckBuilder/internal/services/tts"
 	"github.com/HardDie/DeckBuilder/internal/tts_entity"
-	"github.com/HardDie/DeckBuilder/internal/utils"
 )
 
 type replace struct {
@@ -22,45 +22,23 @@ func New(serviceTTS servicesTTS.TTS) Replace {
 	}
 }
 
-type Request struct {
-	ObjectStates []struct {
-		ContainedObjects []struct {
-			ContainedObjects []struct {
-				CustomDeck map[string]struct {
-					FaceURL string `json:"FaceURL"`
-					BackURL string `json:"BackURL"`
-				} `json:"CustomDeck"`
-			} `json:"containedObjects"`
-		} `json:"ContainedObjects"`
-	} `json:"ObjectStates"`
-}
-
 func (s *replace) Prepare(data []byte) ([]Couple, error) {
-	req := Request{}
-	err := json.Unmarshal(data, &req)
+	root, err := decodeObject(data)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(req.ObjectStates) != 1 {
-		return nil, errors.ErrorInvalidDeckDescription
-	}
-
 	var res []Couple
-	uniq := make(map[string]string)
-	for _, collectionBag := range req.ObjectStates[0].ContainedObjects {
-		for _, item := range collectionBag.ContainedObjects {
-			for _, val := range item.CustomDeck {
-				if _, ok := uniq[val.BackURL]; !ok {
-					res = append(res, Couple{Key: val.BackURL})
-					uniq[val.BackURL] = ""
-				}
-				if _, ok := uniq[val.FaceURL]; !ok {
-					res = append(res, Couple{Key: val.FaceURL})
-					uniq[val.FaceURL] = ""
-				}
-			}
+	uniq := make(map[string]struct{})
+	err = tts_entity.WalkURLs(root, func(url string) (string, error) {
+		if _, ok := uniq[url]; !ok {
+			res = append(res, Couple{Key: url})
+			uniq[url] = struct{}{}
 		}
+		return url, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	sort.SliceStable(res, func(i, j int) bool {
 		return res[i].Key < res[j].Key
@@ -72,152 +50,81 @@ type Mapping struct {
 	Data []Couple `json:"data"`
 }
 
-func replaceCustomDeck(customDeck map[int]tts_entity.DeckDescription, mm map[string]string) error {
-	for key, val := range customDeck {
-		// Replace back image
-		newUrl, ok := mm[val.BackURL]
-		if !ok {
-			return errors.ErrorInvalidDeckDescription.AddMessage(fmt.Sprintf("can't find mapping for %q back url", val.BackURL))
-		}
-		val.BackURL = newUrl
-
-		// Replace front image
-		newUrl, ok = mm[val.FaceURL]
-		if !ok {
-			return errors.ErrorInvalidDeckDescription.AddMessage(fmt.Sprintf("can't find mapping for %q face url", val.FaceURL))
-		}
-		val.FaceURL = newUrl
-
-		customDeck[key] = val
+func (s *replace) Replace(data []byte, req ReplaceRequest) (map[string]any, error) {
+	if len(bytes.TrimSpace(req.Mapping)) == 0 && req.BaseURL == "" {
+		return nil, errors.ErrorInvalidMapping.AddMessage("the mapping or the base url must be set")
 	}
-	return nil
-}
-
-func (s *replace) Replace(data, mapping []byte) (*tts_entity.RootObjects, error) {
-	var m Mapping
-	err := json.Unmarshal(mapping, &m)
+	m, err := newMapper(req)
 	if err != nil {
-		logger.Info.Printf("error parsing mapping file: %s", err.Error())
-		return nil, errors.ErrorInvalidMapping
+		return nil, err
 	}
 
-	var root tts_entity.RootObjects
-	err = json.Unmarshal(data, &root)
+	root, err := decodeObject(data)
 	if err != nil {
-		logger.Info.Printf("error parsing data file: %s", err.Error())
-		return nil, errors.ErrorInvalidDeckDescription
+		return nil, err
 	}
 
-	// Convert items into map
-	mm := make(map[string]string)
-	for _, val := range m.Data {
-		mm[val.Key] = val.Value
+	// All local files without the mapping are reported at once
+	var unmapped []string
+	uniq := make(map[string]struct{})
+	err = tts_entity.WalkURLs(root, func(url string) (string, error) {
+		newURL, ok := m.lookup(url)
+		if !ok {
+			if _, ok = uniq[url]; !ok {
+				uniq[url] = struct{}{}
+				unmapped = append(unmapped, url)
+			}
+			return url, nil
+		}
+		return newURL, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	if len(root.ObjectStates) != 1 {
-		logger.Info.Println("should be single root object")
-		return nil, errors.ErrorInvalidDeckDescription
+	if len(unmapped) > 0 {
+		sort.Strings(unmapped)
+		return nil, errors.ErrorInvalidMapping.AddMessage("can't find mapping for the urls: " + strings.Join(unmapped, ", "))
 	}
 
-	var newContained []any
-	for _, collectionBagTemp := range root.ObjectStates[0].ContainedObjects {
-		colName, err := getName(collectionBagTemp)
-		if err != nil {
-			logger.Info.Printf("error get collection name: %T", collectionBagTemp)
-			return nil, err
-		}
-		if colName != "Bag" {
-			logger.Info.Printf("error collection bag parsing %v", err)
-			return nil, errors.ErrorInvalidDeckDescription
-		}
-
-		var collectionBag tts_entity.Bag
-		err = utils.ObjectJSONObject(collectionBagTemp, &collectionBag)
-		if err != nil {
-			logger.Info.Printf("error collection bag parsing %v", err)
-			return nil, errors.ErrorInvalidDeckDescription
-		}
-
-		var collectionBagContaind []any
-		for _, item := range collectionBag.ContainedObjects {
-			itemName, err := getName(item)
-			if err != nil {
-				logger.Info.Printf("error get item name: %T", collectionBagTemp)
-				return nil, err
-			}
-
-			switch itemName {
-			case "Deck":
-				var deck tts_entity.DeckObject
-				err = utils.ObjectJSONObject(item, &deck)
-				if err != nil {
-					logger.Info.Printf("error deck parsing %v", err)
-					return nil, errors.ErrorInvalidDeckDescription
-				}
-
-				// Replace for custom deck
-				err = replaceCustomDeck(deck.CustomDeck, mm)
-				if err != nil {
-					return nil, err
-				}
-
-				// Replace for cards inside deck
-				for i, card := range deck.ContainedObjects {
-					err = replaceCustomDeck(card.CustomDeck, mm)
-					if err != nil {
-						return nil, err
-					}
-					deck.ContainedObjects[i] = card
-				}
-
-				collectionBagContaind = append(collectionBagContaind, deck)
-			case "Card":
-				var card tts_entity.Card
-				err = utils.ObjectJSONObject(item, &card)
-				if err != nil {
-					logger.Info.Printf("error card parsing %v", err)
-					return nil, errors.ErrorInvalidDeckDescription
-				}
+	if req.Spawn {
+		s.spawn(root)
+	}
 
-				// Replace for custom deck
-				err = replaceCustomDeck(card.CustomDeck, mm)
-				if err != nil {
-					return nil, err
-				}
+	return root, nil
+}
 
-				collectionBagContaind = append(collectionBagContaind, card)
-			default:
-				logger.Info.Printf("unknown object: %q", itemName)
-				return nil, errors.ErrorInvalidDeckDescription
-			}
-		}
-		if len(collectionBagContaind) > 0 {
-			collectionBag.ContainedObjects = collectionBagContaind
-			newContained = append(newContained, collectionBag)
+// spawn sends the replaced data to TTS, the data is returned even if TTS is not running.
+// TTS can spawn a single object: the only object of the save or the object itself.
+func (s *replace) spawn(root map[string]any) {
+	var err error
+	if states, ok := root["ObjectStates"].([]any); ok {
+		if len(states) != 1 {
+			logger.Warn.Println("the save with several objects can't be sent to TTS")
+			return
 		}
+		err = s.serviceTTS.SendToTTS(states[0])
+	} else if _, ok := root["Name"]; ok {
+		err = s.serviceTTS.SendToTTS(root)
+	}
+	if err != nil {
+		logger.Warn.Println("can't send the replaced object to TTS:", err.Error())
 	}
-
-	root.ObjectStates[0].ContainedObjects = newContained
-	s.serviceTTS.SendToTTS(root.ObjectStates[0])
-
-	return &root, nil
 }
 
-func getName(obj any) (string, error) {
-	tmp, ok := obj.(map[string]any)
-	if !ok {
-		logger.Info.Printf("unknown object type: %T", obj)
-		return "", errors.ErrorInvalidDeckDescription
-	}
-	name, ok := tmp["Name"]
-	if !ok {
-		logger.Info.Println("object don't have Name field")
-		return "", errors.ErrorInvalidDeckDescription
+// decodeObject reads the save or the saved object as is, the numbers are kept as they are written
+func decodeObject(data []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var root map[string]any
+	err := decoder.Decode(&root)
+	if err != nil {
+		logger.Info.Printf("error parsing data file: %s", err.Error())
+		return nil, errors.ErrorInvalidDeckDescription
 	}
-	nameStr, ok := name.(string)
-	if !ok {
-		logger.Info.Println("Name field is not string")
-		return "", errors.ErrorInvalidDeckDescription
+	if root == nil {
+		logger.Info.Println("the data file is not an object")
+		return nil, errors.ErrorInvalidDeckDescription
 	}
-	return nameStr, nil
+	return root, nil
 }
@@ -12,4 +12,8 @@ type System interface {
 
 type UpdateSettingsRequest struct {
 	Lang string
+	// Empty - the value is not changed
+	TTSAddress       string
+	TTSListenAddress string
+	TTSCallbackURL   string
 }
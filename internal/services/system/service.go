@@ -7,16 +7,21 @@ import (
 	"github.com/HardDie/DeckBuilder/internal/config"
 	dbSettings "github.com/HardDie/DeckBuilder/internal/db/settings"
 	entitiesSettings "github.com/HardDie/DeckBuilder/internal/entities/settings"
+	"github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/logger"
 	repositoriesSettings "github.com/HardDie/DeckBuilder/internal/repositories/settings"
+	servicesTTS "github.com/HardDie/DeckBuilder/internal/services/tts"
 )
 
 type system struct {
 	repositorySettings repositoriesSettings.Settings
+	serviceTTS         servicesTTS.TTS
 }
 
-func New(cfg *config.Config, settings dbSettings.Settings) System {
+func New(cfg *config.Config, settings dbSettings.Settings, serviceTTS servicesTTS.TTS) System {
 	return &system{
 		repositorySettings: repositoriesSettings.New(cfg, settings),
+		serviceTTS:         serviceTTS,
 	}
 }
 
@@ -45,6 +50,9 @@ func (s *system) GetSettings() (*entitiesSettings.Settings, error) {
 	settings.CardSize.ScaleX = set.CardSize.ScaleX
 	settings.CardSize.ScaleY = set.CardSize.ScaleY
 	settings.CardSize.ScaleZ = set.CardSize.ScaleZ
+	settings.TTSAddress = set.TTSAddress
+	settings.TTSListenAddress = set.TTSListenAddress
+	settings.TTSCallbackURL = set.TTSCallbackURL
 	return &settings, nil
 }
 func (s *system) UpdateSettings(req UpdateSettingsRequest) (*entitiesSettings.Settings, error) {
@@ -61,11 +69,46 @@ func (s *system) UpdateSettings(req UpdateSettingsRequest) (*entitiesSettings.Se
 			isUpdated = true
 		}
 	}
+
+	isTTSUpdated := false
+	isLoopback := config.IsLoopbackURL(set.TTSCallbackURL)
+	for _, field := range []struct {
+		value *string
+		req   string
+	}{
+		{&set.TTSAddress, req.TTSAddress},
+		{&set.TTSListenAddress, req.TTSListenAddress},
+		{&set.TTSCallbackURL, req.TTSCallbackURL},
+	} {
+		if field.req != "" && *field.value != field.req {
+			*field.value = field.req
+			isTTSUpdated = true
+		}
+	}
+	if isTTSUpdated {
+		err = config.ValidateTTS(set.TTSAddress, set.TTSListenAddress, set.TTSCallbackURL)
+		if err != nil {
+			return nil, errors.TTSBadSettings.AddMessage(err.Error())
+		}
+		isUpdated = true
+	}
+
 	if isUpdated {
 		err = s.repositorySettings.Save(set)
 		if err != nil {
 			return nil, err
 		}
 	}
+	if isTTSUpdated {
+		// The new addresses are used right away, the messages from TTS are received on the new address
+		err = s.serviceTTS.Configure(set.TTSAddress, set.TTSListenAddress, set.TTSCallbackURL)
+		if err != nil {
+			return nil, err
+		}
+		if config.IsLoopbackURL(set.TTSCallbackURL) != isLoopback {
+			// The application listens on all interfaces only if it was started with the callback URL in the network
+			logger.Warn.Println("The application must be restarted to use the TTS callback URL", set.TTSCallbackURL)
+		}
+	}
 	return set, nil
 }
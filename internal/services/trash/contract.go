@@ -0,0 +1,18 @@
+package trash
+
+import (
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesTrash "github.com/HardDie/DeckBuilder/internal/entities/trash"
+)
+
+type Trash interface {
+	List() ([]*entitiesTrash.Item, error)
+	Restore(itemID int64, req RestoreRequest) (entitiesHistory.Target, error)
+	Delete(itemID int64) error
+	Purge() error
+	PurgeExpired() (int, error)
+}
+
+type RestoreRequest struct {
+	Name string
+}
@@ -0,0 +1,47 @@
+package trash
+
+import (
+	"time"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	entitiesHistory "github.com/HardDie/DeckBuilder/internal/entities/history"
+	entitiesTrash "github.com/HardDie/DeckBuilder/internal/entities/trash"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
+)
+
+type trash struct {
+	cfg             *config.Config
+	repositoryTrash repositoriesTrash.Trash
+}
+
+func New(cfg *config.Config, repositoryTrash repositoriesTrash.Trash) Trash {
+	return &trash{
+		cfg:             cfg,
+		repositoryTrash: repositoryTrash,
+	}
+}
+
+func (s *trash) List() ([]*entitiesTrash.Item, error) {
+	return s.repositoryTrash.GetAll()
+}
+func (s *trash) Restore(itemID int64, req RestoreRequest) (entitiesHistory.Target, error) {
+	return s.repositoryTrash.Restore(itemID, repositoriesTrash.RestoreRequest{
+		Name: req.Name,
+	})
+}
+func (s *trash) Delete(itemID int64) error {
+	return s.repositoryTrash.DeleteByID(itemID)
+}
+func (s *trash) Purge() error {
+	return s.repositoryTrash.DeleteAll()
+}
+
+// PurgeExpired permanently removes items that have been in the trash longer than the retention period
+func (s *trash) PurgeExpired() (int, error) {
+	if s.cfg.TrashRetentionDays <= 0 {
+		// Items are kept until the trash is purged manually
+		return 0, nil
+	}
+	deletedBefore := time.Now().AddDate(0, 0, -s.cfg.TrashRetentionDays)
+	return s.repositoryTrash.DeleteExpired(deletedBefore)
+}
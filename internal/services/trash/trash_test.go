@@ -0,0 +1,413 @@
+package trash
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/HardDie/fsentry"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbArchive "github.com/HardDie/DeckBuilder/internal/db/archive"
+	dbCard "github.com/HardDie/DeckBuilder/internal/db/card"
+	dbCollection "github.com/HardDie/DeckBuilder/internal/db/collection"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbDeck "github.com/HardDie/DeckBuilder/internal/db/deck"
+	dbGame "github.com/HardDie/DeckBuilder/internal/db/game"
+	dbHistory "github.com/HardDie/DeckBuilder/internal/db/history"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
+	entitiesTrash "github.com/HardDie/DeckBuilder/internal/entities/trash"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/images"
+	repositoriesCard "github.com/HardDie/DeckBuilder/internal/repositories/card"
+	repositoriesCollection "github.com/HardDie/DeckBuilder/internal/repositories/collection"
+	repositoriesDeck "github.com/HardDie/DeckBuilder/internal/repositories/deck"
+	repositoriesGame "github.com/HardDie/DeckBuilder/internal/repositories/game"
+	repositoriesHistory "github.com/HardDie/DeckBuilder/internal/repositories/history"
+	repositoriesTrash "github.com/HardDie/DeckBuilder/internal/repositories/trash"
+	servicesCard "github.com/HardDie/DeckBuilder/internal/services/card"
+	servicesCollection "github.com/HardDie/DeckBuilder/internal/services/collection"
+	servicesDeck "github.com/HardDie/DeckBuilder/internal/services/deck"
+	servicesGame "github.com/HardDie/DeckBuilder/internal/services/game"
+	servicesHistory "github.com/HardDie/DeckBuilder/internal/services/history"
+)
+
+type trashTest struct {
+	gameID, collectionID, deckID string
+	cfg                          *config.Config
+	core                         dbCore.Core
+
+	serviceGame       servicesGame.Game
+	serviceCollection servicesCollection.Collection
+	serviceDeck       servicesDeck.Deck
+	serviceCard       servicesCard.Card
+	serviceHistory    servicesHistory.History
+	serviceTrash      Trash
+}
+
+func newTrashTest(t testing.TB) *trashTest {
+	dir, err := os.MkdirTemp("", "trash_test")
+	if err != nil {
+		t.Fatal("error creating temp dir", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	cfg := config.Get(false, "")
+	cfg.SetDataPath(dir)
+
+	fs := fsentry.NewFSEntry(cfg.Games())
+
+	core := dbCore.New(fs, cfg.Games())
+	images := dbImage.New(fs, cfg.Games())
+	game := dbGame.New(fs, images)
+	collection := dbCollection.New(fs, images, game)
+	deck := dbDeck.New(fs, images, collection)
+	card := dbCard.New(fs, images, deck)
+	history := dbHistory.New(fs, images)
+	trash := dbTrash.New(fs, images, cfg.Games())
+	archive := dbArchive.New(cfg, fs, transfer.Files(fs, images), images)
+
+	repositoryHistory := repositoriesHistory.New(cfg, history, game, collection, deck, card)
+	repositoryTrash := repositoriesTrash.New(cfg, trash, game, collection, deck, card)
+	repositoryGame := repositoriesGame.New(cfg, game, archive, repositoryHistory, repositoryTrash)
+	repositoryDeck := repositoriesDeck.New(cfg, collection, deck, card, repositoryHistory, repositoryTrash)
+	repositoryCollection := repositoriesCollection.New(cfg, collection, repositoryDeck, repositoryHistory, repositoryTrash)
+	repositoryCard := repositoriesCard.New(cfg, card, repositoryHistory, repositoryTrash)
+
+	return &trashTest{
+		gameID:       "test_trash__game",
+		collectionID: "test_trash__collection",
+		deckID:       "test_trash__deck",
+		cfg:          cfg,
+		core:         core,
+
+		serviceGame:       servicesGame.New(cfg, repositoryGame),
+		serviceCollection: servicesCollection.New(cfg, repositoryCollection),
+		serviceDeck:       servicesDeck.New(cfg, repositoryDeck),
+		serviceCard:       servicesCard.New(cfg, repositoryCard),
+		serviceHistory:    servicesHistory.New(cfg, repositoryHistory),
+		serviceTrash:      New(cfg, repositoryTrash),
+	}
+}
+
+func (tt *trashTest) testGame(t *testing.T) {
+	gameName := "game_one"
+	collectionName := "collection_one"
+
+	// Create game with collection and history
+	_, err := tt.serviceGame.Create(servicesGame.CreateRequest{
+		Name:        gameName,
+		Description: "first",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceGame.Update(gameName, servicesGame.UpdateRequest{
+		Name:        gameName,
+		Description: "second",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceCollection.Create(gameName, servicesCollection.CreateRequest{
+		Name: collectionName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Delete game
+	err = tt.serviceGame.Delete(gameName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceGame.Item(gameName)
+	if !errors.Is(err, er.GameNotExists) {
+		t.Fatal(err)
+	}
+
+	// Game is in the trash
+	items, err := tt.serviceTrash.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatal("Bad number of items [got]", len(items), "[want] 1")
+	}
+	item := items[0]
+	if item.Type() != entitiesTrash.TypeGame || item.Name != gameName || item.Target.GameID != gameName {
+		t.Fatal("Bad trash item", item)
+	}
+
+	// Reuse the name
+	_, err = tt.serviceGame.Create(servicesGame.CreateRequest{
+		Name: gameName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Conflict with the new game
+	_, err = tt.serviceTrash.Restore(item.ID, RestoreRequest{})
+	if !errors.Is(err, er.GameExist) {
+		t.Fatal(err)
+	}
+
+	// Restore with a new name
+	target, err := tt.serviceTrash.Restore(item.ID, RestoreRequest{
+		Name: gameName + " restored",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.GameID != gameName+"_restored" {
+		t.Fatal("Bad restored game [got]", target.GameID, "[want]", gameName+"_restored")
+	}
+	game, err := tt.serviceGame.Item(target.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Name != gameName+" restored" || game.Description != "second" {
+		t.Fatal("Bad restored game", game)
+	}
+
+	// Nested entities and history are restored too
+	_, err = tt.serviceCollection.Item(target.GameID, collectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := tt.serviceHistory.List(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].Description != "first" {
+		t.Fatal("Bad restored revisions", revisions)
+	}
+
+	// Trash is empty
+	items, err = tt.serviceTrash.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Fatal("Bad number of items [got]", len(items), "[want] 0")
+	}
+
+	// Restore collection without the game
+	err = tt.serviceCollection.Delete(target.GameID, collectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tt.serviceGame.Delete(target.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err = tt.serviceTrash.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[1].Type() != entitiesTrash.TypeCollection {
+		t.Fatal("Bad trash items", items)
+	}
+	_, err = tt.serviceTrash.Restore(items[1].ID, RestoreRequest{})
+	if !errors.Is(err, er.GameNotExists) {
+		t.Fatal(err)
+	}
+
+	// The parent must be restored first
+	_, err = tt.serviceTrash.Restore(items[0].ID, RestoreRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceTrash.Restore(items[1].ID, RestoreRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceCollection.Item(target.GameID, collectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+func (tt *trashTest) testCard(t *testing.T) {
+	pngImage, err := images.ImageToPng(images.CreateImage(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create card with image and history
+	card, err := tt.serviceCard.Create(tt.gameID, tt.collectionID, tt.deckID, servicesCard.CreateRequest{
+		Name:      "card_one",
+		Variables: map[string]string{"attack": "1"},
+		Count:     1,
+		ImageFile: pngImage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceCard.Update(tt.gameID, tt.collectionID, tt.deckID, card.ID, servicesCard.UpdateRequest{
+		Name:      "card_one",
+		Variables: map[string]string{"attack": "2"},
+		Count:     2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Delete card
+	err = tt.serviceCard.Delete(tt.gameID, tt.collectionID, tt.deckID, card.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceCard.Item(tt.gameID, tt.collectionID, tt.deckID, card.ID)
+	if !errors.Is(err, er.CardNotExists) {
+		t.Fatal(err)
+	}
+	items, err := tt.serviceTrash.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Type() != entitiesTrash.TypeCard || !items[0].HasImage {
+		t.Fatal("Bad trash items", items)
+	}
+
+	// Restore card
+	target, err := tt.serviceTrash.Restore(items[0].ID, RestoreRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := tt.serviceCard.Item(target.GameID, target.CollectionID, target.DeckID, target.CardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Name != "card_one" || restored.Count != 2 || restored.Variables["attack"] != "2" {
+		t.Fatal("Bad restored card", restored)
+	}
+	if !restored.CreatedAt.Equal(card.CreatedAt) {
+		t.Fatal("Bad created at [got]", restored.CreatedAt, "[want]", card.CreatedAt)
+	}
+	data, _, err := tt.serviceCard.GetImage(target.GameID, target.CollectionID, target.DeckID, target.CardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, pngImage) {
+		t.Fatal("Image was not restored")
+	}
+	revisions, err := tt.serviceHistory.List(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].Count != 1 {
+		t.Fatal("Bad restored revisions", revisions)
+	}
+}
+func (tt *trashTest) testPurge(t *testing.T) {
+	// Create and delete decks
+	for _, name := range []string{"deck_one", "deck_two", "deck_three"} {
+		_, err := tt.serviceDeck.Create(tt.gameID, tt.collectionID, servicesDeck.CreateRequest{
+			Name: name,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tt.serviceDeck.Delete(tt.gameID, tt.collectionID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := tt.serviceTrash.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatal("Bad number of items [got]", len(items), "[want] 3")
+	}
+
+	// Recently deleted items are not expired
+	count, err := tt.serviceTrash.PurgeExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatal("Bad number of purged items [got]", count, "[want] 0")
+	}
+
+	// Delete one item
+	err = tt.serviceTrash.Delete(items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tt.serviceTrash.Delete(items[0].ID)
+	if !errors.Is(err, er.TrashItemNotExists) {
+		t.Fatal(err)
+	}
+	_, err = tt.serviceTrash.Restore(items[0].ID, RestoreRequest{})
+	if !errors.Is(err, er.TrashItemNotExists) {
+		t.Fatal(err)
+	}
+
+	// Purge all
+	err = tt.serviceTrash.Purge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err = tt.serviceTrash.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Fatal("Bad number of items [got]", len(items), "[want] 0")
+	}
+}
+
+func TestTrash(t *testing.T) {
+	t.Parallel()
+
+	tt := newTrashTest(t)
+
+	if err := tt.core.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := tt.core.Drop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Each subtest expects the trash to be empty
+	t.Run("game", tt.testGame)
+	if err := tt.serviceTrash.Purge(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create game
+	_, err := tt.serviceGame.Create(servicesGame.CreateRequest{
+		Name: tt.gameID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create collection
+	_, err = tt.serviceCollection.Create(tt.gameID, servicesCollection.CreateRequest{
+		Name: tt.collectionID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create deck
+	_, err = tt.serviceDeck.Create(tt.gameID, tt.collectionID, servicesDeck.CreateRequest{
+		Name: tt.deckID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("card", tt.testCard)
+	t.Run("purge", tt.testPurge)
+}
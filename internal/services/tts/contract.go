@@ -1,6 +1,82 @@
 package tts
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/HardDie/DeckBuilder/internal/tts_editor"
+)
+
+// How the object is sent to TTS
+const (
+	// A new copy of the object is spawned
+	ModeSpawn = "spawn"
+	// The images of the objects spawned earlier with the same GUIDs are replaced, the rest of their state is kept
+	ModeUpdate = "update"
+	// The objects spawned earlier with the same GUIDs are replaced with the new ones at the same positions
+	ModeRespawn = "respawn"
+)
+
 type TTS interface {
-	SendToTTS(data any)
-	DataForTTS() ([]byte, error)
+	// SendToTTS puts the object into the queue and asks TTS to download and spawn it,
+	// the error is returned if TTS doesn't accept the message
+	SendToTTS(data any) error
+	// UpdateInTTS updates the objects spawned earlier in the update or the respawn mode,
+	// the object is spawned if none of them is found on the table
+	UpdateInTTS(data any, mode string) error
+	// DataForTTS returns the queued object, it can be downloaded many times until it expires
+	DataForTTS(token string) ([]byte, error)
+	// History returns the sent objects, the newest first
+	History() []Sent
+
+	// Listen starts receiving the messages from TTS
+	Listen() error
+	// Configure changes the addresses of TTS and the callback URL set in the settings,
+	// the messages are received on the new address
+	Configure(address, listenAddress, callbackURL string) error
+	// CallbackURL returns the base URL TTS downloads the sent objects from
+	CallbackURL() string
+	Status() Status
+	Scripts() ([]tts_editor.ScriptState, error)
+	SaveAndPlay(states []tts_editor.ScriptState) error
+	CustomMessage(data json.RawMessage) error
+	Execute(guid, script string) (json.RawMessage, error)
+	// Log returns the last messages received from TTS, the oldest first
+	Log() []tts_editor.Message
+	Subscribe() (<-chan tts_editor.Message, func())
+
+	// Test checks that TTS accepts the messages and can download the data from the application by the callback URL
+	Test() TestResult
+	// Ping is requested by TTS during the test
+	Ping(token string) error
+}
+
+type Status struct {
+	// TTS is running and accepts the messages
+	Connected bool
+	// The address the messages from TTS are received on, empty if the port is busy
+	Listening     string
+	LastMessageAt *time.Time
+}
+
+type TestResult struct {
+	Connected bool
+	// The base URL TTS downloads the sent objects from
+	CallbackURL       string
+	CallbackReachable bool
+}
+
+type Sent struct {
+	Token string
+	// The name of the sent object
+	Name string
+	Size int
+	// The message was delivered to TTS
+	Delivered bool
+	SentAt    time.Time
+	ExpiresAt time.Time
+	// The number of downloads by TTS and the time of the last one
+	Fetches   int
+	FetchedAt *time.Time
+	Expired   bool
 }
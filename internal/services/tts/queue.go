@@ -0,0 +1,135 @@
+package tts
+
+import (
+	"encoding/json"
+	"time"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/utils"
+)
+
+// The number of sent objects kept in the history
+const historySize = 50
+
+type queueItem struct {
+	Sent
+	// The data is released when the item expires, the item itself stays in the history
+	data []byte
+}
+
+// enqueue puts the data into the queue and returns the token TTS downloads it by, the name is shown in the history.
+// The objects waiting to be downloaded are never dropped, so the error is returned if there are too many of them.
+func (s *tts) enqueue(name string, data []byte) (string, error) {
+	now := time.Now()
+	item := &queueItem{
+		Sent: Sent{
+			Token:     utils.RandomToken(),
+			Name:      name,
+			Size:      len(data),
+			SentAt:    now,
+			ExpiresAt: now.Add(s.cfg.TTSDataTTL),
+		},
+		data: data,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(now)
+	pending := 0
+	for _, queued := range s.queue {
+		if queued.pending() {
+			pending++
+		}
+	}
+	if pending >= historySize {
+		return "", er.TTSQueueFull
+	}
+	s.queue = append(s.queue, item)
+
+	// Only the downloaded and the expired objects are dropped from the history, the oldest first
+	for i := 0; len(s.queue) > historySize && i < len(s.queue); {
+		if s.queue[i].pending() {
+			i++
+			continue
+		}
+		s.queue = append(s.queue[:i], s.queue[i+1:]...)
+	}
+	return item.Token, nil
+}
+
+// pending is true while the object waits to be downloaded by TTS
+func (item *queueItem) pending() bool {
+	return !item.Expired && item.Fetches == 0
+}
+
+// objectName returns the name of the object shown in the history,
+// the saved object is named by Nickname, the bare object by Name
+func objectName(data []byte) string {
+	var object struct {
+		Name     string
+		Nickname string
+	}
+	_ = json.Unmarshal(data, &object)
+	if object.Nickname != "" {
+		return object.Nickname
+	}
+	return object.Name
+}
+
+func (s *tts) delivered(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, item := range s.queue {
+		if item.Token == token {
+			item.Delivered = true
+			return
+		}
+	}
+}
+
+func (s *tts) DataForTTS(token string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+	for _, item := range s.queue {
+		if item.Token != token {
+			continue
+		}
+		if item.Expired {
+			return nil, er.TTSDataNotExists.AddMessage("the data has expired")
+		}
+		item.Fetches++
+		item.FetchedAt = &now
+		return item.data, nil
+	}
+	return nil, er.TTSDataNotExists
+}
+
+func (s *tts) History() []Sent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+	res := make([]Sent, 0, len(s.queue))
+	for i := len(s.queue) - 1; i >= 0; i-- {
+		item := s.queue[i].Sent
+		if item.FetchedAt != nil {
+			fetchedAt := *item.FetchedAt
+			item.FetchedAt = &fetchedAt
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+// expire releases the data of the expired items, must be called under the lock
+func (s *tts) expire(now time.Time) {
+	for _, item := range s.queue {
+		if !item.Expired && now.After(item.ExpiresAt) {
+			item.Expired = true
+			item.data = nil
+		}
+	}
+}
@@ -2,47 +2,58 @@ package tts
 
 import (
 	"encoding/json"
-	"errors"
-	"net"
+	"fmt"
+	"sync"
+	"time"
 
+	"github.com/HardDie/DeckBuilder/internal/config"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
 	"github.com/HardDie/DeckBuilder/internal/logger"
+	"github.com/HardDie/DeckBuilder/internal/tts_editor"
+	"github.com/HardDie/DeckBuilder/internal/utils"
 )
 
+// The number of messages kept in the log
+const logSize = 200
+
 type tts struct {
-	dataForTTS []byte
-}
+	cfg    *config.Config
+	client *tts_editor.Client
+	// Subscribes the log to the messages of the client once
+	logOnce sync.Once
 
-type Message struct {
-	MessageID int    `json:"messageID"`
-	GUID      string `json:"guid"`
-	Script    string `json:"script"`
+	mu sync.Mutex
+	// The base URL TTS downloads the sent objects from, changed in the settings
+	callbackURL string
+	// The sent objects waiting to be downloaded by TTS and the history of the sent objects, the oldest first
+	queue []*queueItem
+	log   []tts_editor.Message
+	// The tests waiting for the request from TTS
+	pings map[string]chan struct{}
 }
 
-func New() TTS {
-	return &tts{}
+func New(cfg *config.Config) TTS {
+	return &tts{
+		cfg:         cfg,
+		client:      tts_editor.NewClient(cfg.TTSAddress, cfg.TTSListenAddress, tts_editor.DefaultTimeout),
+		callbackURL: cfg.TTSCallbackURL,
+		pings:       make(map[string]chan struct{}),
+	}
 }
 
-func (s *tts) SendToTTS(data any) {
-	// Try to open TCP socket
-	conn, err := net.Dial("tcp", "127.0.0.1:39999")
+func (s *tts) SendToTTS(data any) error {
+	dataForTTS, err := json.Marshal(data)
 	if err != nil {
-		logger.Info.Println("Can't connect to TTS via tcp connection '127.0.0.1:39999':", err.Error())
-		return
+		er.IfErrorLog(err)
+		return er.InternalError.AddMessage(err.Error())
 	}
-	defer func() { conn.Close() }()
-
-	dataForTTS, err := json.Marshal(data)
+	token, err := s.enqueue(objectName(dataForTTS), dataForTTS)
 	if err != nil {
-		logger.Warn.Println("error marshal data for TTS:", err.Error())
-		return
+		return err
 	}
-	s.dataForTTS = dataForTTS
 
-	msg := Message{
-		MessageID: 3,
-		GUID:      "-1",
-		Script: `
-WebRequest.get("http://127.0.0.1:5000/api/tts/data", function(request)
+	return s.execute(token, fmt.Sprintf(`
+WebRequest.get(%q, function(request)
 	if request.is_error then
 		print('Downloading json error: ', request.error)
 		return
@@ -54,27 +65,153 @@ WebRequest.get("http://127.0.0.1:5000/api/tts/data", function(request)
 			print('Object were spawned! Done!')
 		end
 	})
-end)`,
-	}
+end)`, config.TTSDataURL(s.CallbackURL(), token)))
+}
 
-	jsonData, err := json.Marshal(msg)
+// execute runs the script downloading the queued object in TTS
+func (s *tts) execute(token, script string) error {
+	// The code doesn't return anything, so the answer is not waited for
+	err := s.client.Send(tts_editor.Message{
+		MessageID: tts_editor.RequestExecute,
+		GUID:      tts_editor.GlobalGUID,
+		Script:    script,
+	})
 	if err != nil {
-		logger.Warn.Println("error marshal msg for TTS:", err.Error())
-		return
+		return err
 	}
+	s.delivered(token)
+	return nil
+}
+
+func (s *tts) Listen() error {
+	s.logOnce.Do(func() {
+		messages, _ := s.client.Subscribe()
+		go func() {
+			for msg := range messages {
+				if msg.MessageID == tts_editor.EventError {
+					logger.Warn.Println("TTS:", msg.ErrorMessagePrefix+msg.Error)
+				}
+				s.mu.Lock()
+				s.log = append(s.log, msg)
+				if len(s.log) > logSize {
+					s.log = s.log[len(s.log)-logSize:]
+				}
+				s.mu.Unlock()
+			}
+		}()
+	})
+	return s.client.Listen()
+}
+func (s *tts) Configure(address, listenAddress, callbackURL string) error {
+	s.mu.Lock()
+	s.callbackURL = callbackURL
+	s.mu.Unlock()
 
-	_, err = conn.Write(jsonData)
+	err := s.client.SetAddresses(address, listenAddress)
 	if err != nil {
-		logger.Warn.Println("error write message into TTS socket:", err.Error())
-		return
+		return err
+	}
+	if s.client.Listening() == "" {
+		// The port could be busy before or the new address is free
+		return s.Listen()
+	}
+	return nil
+}
+func (s *tts) CallbackURL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.callbackURL
+}
+
+func (s *tts) Status() Status {
+	status := Status{
+		Connected: s.client.Connected(),
+		Listening: s.client.Listening(),
 	}
+	if last := s.client.LastMessageAt(); !last.IsZero() {
+		status.LastMessageAt = &last
+	}
+	return status
+}
+
+func (s *tts) Scripts() ([]tts_editor.ScriptState, error) {
+	return s.client.Scripts()
 }
+func (s *tts) SaveAndPlay(states []tts_editor.ScriptState) error {
+	return s.client.SaveAndPlay(states)
+}
+func (s *tts) CustomMessage(data json.RawMessage) error {
+	return s.client.CustomMessage(data)
+}
+func (s *tts) Execute(guid, script string) (json.RawMessage, error) {
+	if guid == "" {
+		guid = tts_editor.GlobalGUID
+	}
+	return s.client.Execute(guid, script)
+}
+
+func (s *tts) Log() []tts_editor.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]tts_editor.Message, len(s.log))
+	copy(res, s.log)
+	return res
+}
+func (s *tts) Subscribe() (<-chan tts_editor.Message, func()) {
+	return s.client.Subscribe()
+}
+
+func (s *tts) Test() TestResult {
+	res := TestResult{
+		Connected:   s.client.Connected(),
+		CallbackURL: s.CallbackURL(),
+	}
+	if !res.Connected {
+		return res
+	}
+
+	// TTS requests the ping URL next to the data URL, the request proves that the sent objects can be downloaded
+	token := utils.RandomToken()
+	ch := make(chan struct{})
+	s.mu.Lock()
+	s.pings[token] = ch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pings, token)
+		s.mu.Unlock()
+	}()
 
-func (s *tts) DataForTTS() ([]byte, error) {
-	if s.dataForTTS == nil {
-		return nil, errors.New("there is nothing to serve")
+	err := s.client.Send(tts_editor.Message{
+		MessageID: tts_editor.RequestExecute,
+		GUID:      tts_editor.GlobalGUID,
+		Script: fmt.Sprintf(`
+WebRequest.get(%q, function(request)
+	if request.is_error then
+		print('DeckBuilder is not reachable: ', request.error)
+	end
+end)`, config.TTSPingURL(s.CallbackURL(), token)),
+	})
+	if err != nil {
+		res.Connected = false
+		return res
+	}
+
+	select {
+	case <-ch:
+		res.CallbackReachable = true
+	case <-time.After(tts_editor.DefaultTimeout):
+	}
+	return res
+}
+func (s *tts) Ping(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch, ok := s.pings[token]
+	if !ok {
+		return er.BadId.AddMessage("unknown token")
 	}
-	res := s.dataForTTS
-	s.dataForTTS = nil
-	return res, nil
+	close(ch)
+	delete(s.pings, token)
+	return nil
 }
@@ -0,0 +1,275 @@
+package tts
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/tts_editor"
+	"github.com/HardDie/DeckBuilder/internal/tts_entity"
+)
+
+// fakeTTS receives the messages on a random port instead of TTS
+func fakeTTS(t *testing.T) (string, <-chan tts_editor.Message) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { er.IfErrorLog(listener.Close()) })
+
+	messages := make(chan tts_editor.Message, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			var msg tts_editor.Message
+			// The connection check sends nothing
+			if json.NewDecoder(conn).Decode(&msg) == nil {
+				messages <- msg
+			}
+			er.IfErrorLog(conn.Close())
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestSendToTTS(t *testing.T) {
+	t.Parallel()
+
+	addr, messages := fakeTTS(t)
+	cfg := config.Get(false, "")
+	cfg.TTSAddress = addr
+	cfg.TTSCallbackURL = "http://192.168.1.10:8080"
+	s := New(cfg)
+
+	// The second object doesn't replace the first one
+	for _, object := range []map[string]string{{"Name": "Bag", "Nickname": "Munchkin"}, {"Name": "Card"}} {
+		if err := s.SendToTTS(object); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dataURL := regexp.MustCompile(`"http://192\.168\.1\.10:8080/api/tts/data/(\w+)"`)
+	var tokens []string
+	for i := 0; i < 2; i++ {
+		msg := <-messages
+		token := dataURL.FindStringSubmatch(msg.Script)
+		if msg.MessageID != tts_editor.RequestExecute || token == nil {
+			t.Fatal("Bad message:", msg)
+		}
+		tokens = append(tokens, token[1])
+	}
+
+	// The failed download can be repeated
+	for i := 0; i < 2; i++ {
+		data, err := s.DataForTTS(tokens[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `{"Name":"Bag","Nickname":"Munchkin"}` {
+			t.Fatal("Bad data:", string(data))
+		}
+	}
+	_, err := s.DataForTTS("unknown")
+	if !errors.Is(err, er.TTSDataNotExists) {
+		t.Fatal("Expected not exists error, got:", err)
+	}
+
+	history := s.History()
+	if len(history) != 2 {
+		t.Fatal("Bad history:", history)
+	}
+	if history[0].Name != "Card" || history[0].Fetches != 0 || !history[0].Delivered {
+		t.Fatal("Bad second object:", history[0])
+	}
+	if history[1].Name != "Munchkin" || history[1].Fetches != 2 || history[1].FetchedAt == nil {
+		t.Fatal("Bad first object:", history[1])
+	}
+}
+
+func TestSendToTTSExpired(t *testing.T) {
+	t.Parallel()
+
+	// TTS is not running, the object is still queued
+	cfg := config.Get(false, "")
+	cfg.TTSAddress = "127.0.0.1:1"
+	cfg.TTSDataTTL = -time.Second
+	s := New(cfg)
+
+	err := s.SendToTTS(map[string]string{"Name": "Bag"})
+	if !errors.Is(err, er.TTSNotConnected) {
+		t.Fatal("Expected not connected error, got:", err)
+	}
+	history := s.History()
+	if len(history) != 1 || history[0].Delivered || !history[0].Expired {
+		t.Fatal("Bad history:", history)
+	}
+	_, err = s.DataForTTS(history[0].Token)
+	if !errors.Is(err, er.TTSDataNotExists) {
+		t.Fatal("Expected not exists error, got:", err)
+	}
+}
+
+func TestQueueTrim(t *testing.T) {
+	t.Parallel()
+
+	s := New(config.Get(false, "")).(*tts)
+
+	var tokens []string
+	for i := 0; i < historySize; i++ {
+		token, err := s.enqueue("Bag", []byte(`{"Name":"Bag"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	// None of the objects is downloaded yet
+	_, err := s.enqueue("Bag", []byte(`{"Name":"Bag"}`))
+	if !errors.Is(err, er.TTSQueueFull) {
+		t.Fatal("Expected queue full error, got:", err)
+	}
+
+	// The downloaded objects make room for the new ones
+	for _, token := range tokens[:10] {
+		if _, err = s.DataForTTS(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		token, err := s.enqueue("Card", []byte(`{"Name":"Card"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	if history := s.History(); len(history) != historySize {
+		t.Fatal("Bad history size:", len(history))
+	}
+	for _, token := range tokens[10:] {
+		if _, err = s.DataForTTS(token); err != nil {
+			t.Fatal("The object waiting to be downloaded is dropped:", err)
+		}
+	}
+}
+
+func TestTest(t *testing.T) {
+	t.Parallel()
+
+	addr, messages := fakeTTS(t)
+	cfg := config.Get(false, "")
+	cfg.TTSAddress = addr
+	s := New(cfg)
+
+	// TTS requests the URL from the script
+	go func() {
+		msg := <-messages
+		token := regexp.MustCompile(`token=(\w+)`).FindStringSubmatch(msg.Script)
+		if token == nil {
+			t.Error("No ping URL in the script:", msg.Script)
+			return
+		}
+		er.IfErrorLog(s.Ping(token[1]))
+	}()
+	res := s.Test()
+	if !res.Connected || !res.CallbackReachable || res.CallbackURL != cfg.TTSCallbackURL {
+		t.Fatal("Bad test result:", res)
+	}
+
+	if err := s.Ping("unknown"); err == nil {
+		t.Fatal("The unknown token is accepted")
+	}
+}
+
+func TestUpdateInTTS(t *testing.T) {
+	t.Parallel()
+
+	addr, messages := fakeTTS(t)
+	cfg := config.Get(false, "")
+	cfg.TTSAddress = addr
+	s := New(cfg)
+
+	sheet := tts_entity.DeckDescription{FaceURL: "http://example.com/face.png", BackURL: "http://example.com/back.png", NumWidth: 2, NumHeight: 2}
+	card := tts_entity.NewCard(tts_entity.GUID("game", "collection", "deck", "1"), "Goblin", "", 1, 0, nil, sheet, tts_entity.Transform{ScaleX: 1, ScaleY: 1, ScaleZ: 1})
+	deck := tts_entity.NewDeck("Monsters", tts_entity.Transform{ScaleX: 1, ScaleY: 1, ScaleZ: 1})
+	deck.GUID = tts_entity.GUID("game", "collection", "deck")
+	deck.CustomDeck[1] = sheet
+	deck.AddCard(card)
+	deck.AddCard(card)
+	bag := tts_entity.NewBag("Munchkin")
+	bag.GUID = tts_entity.GUID("game")
+	bag.ContainedObjects = append(bag.ContainedObjects, deck)
+
+	err := s.UpdateInTTS(bag, ModeUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := <-messages
+	token := regexp.MustCompile(`/api/tts/data/(\w+)"`).FindStringSubmatch(msg.Script)
+	if token == nil {
+		t.Fatal("No data URL in the script:", msg.Script)
+	}
+	data, err := s.DataForTTS(token[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res struct {
+		Mode        string
+		Object      tts_entity.Bag
+		Objects     map[string]map[string]any
+		CustomDecks map[string]tts_entity.DeckDescription
+	}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Mode != ModeUpdate || res.Object.GUID != bag.GUID {
+		t.Fatal("Bad update:", res.Mode, res.Object)
+	}
+	if len(res.Objects) != 3 || res.Objects[deck.GUID]["Nickname"] != "Monsters" || res.Objects[card.GUID]["Nickname"] != "Goblin" {
+		t.Fatal("Bad objects:", res.Objects)
+	}
+	if res.CustomDecks["1"] != sheet {
+		t.Fatal("Bad sheets:", res.CustomDecks)
+	}
+	if name := s.History()[0].Name; name != "Munchkin (update)" {
+		t.Fatal("Bad name:", name)
+	}
+
+	// The GUIDs don't depend on the order of the generation
+	if tts_entity.GUID("game", "collection", "deck") != deck.GUID || len(deck.GUID) != 6 {
+		t.Fatal("Bad GUID:", deck.GUID)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	t.Parallel()
+
+	// The flags point to the closed port, the settings to the running TTS
+	addr, messages := fakeTTS(t)
+	cfg := config.Get(false, "")
+	cfg.TTSAddress = "127.0.0.1:1"
+	cfg.TTSListenAddress = "127.0.0.1:0"
+	s := New(cfg)
+	err := s.Configure(addr, "127.0.0.1:0", "http://192.168.1.10:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := s.Status(); !status.Connected || status.Listening == "" {
+		t.Fatal("Bad status:", status)
+	}
+
+	if err = s.SendToTTS(map[string]string{"Name": "Card"}); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-messages
+	if !regexp.MustCompile(`"http://192\.168\.1\.10:8080/api/tts/data/\w+"`).MatchString(msg.Script) {
+		t.Fatal("The new callback URL is not used:", msg.Script)
+	}
+}
@@ -0,0 +1,127 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/tts_entity"
+)
+
+// update is downloaded by TTS in the update and the respawn modes
+type update struct {
+	Mode string `json:"mode"`
+	// The whole object, it is spawned if none of the objects is found on the table
+	Object any `json:"object"`
+	// All objects of the tree by GUID
+	Objects map[string]any `json:"objects"`
+	// All sheets of the cards by the deck ID
+	CustomDecks map[string]any `json:"customDecks"`
+}
+
+// The script finds the objects by GUID among the objects on the table, the objects inside the containers are not checked.
+// The found object is destroyed and spawned again from the changed data, the GUID is released at the end of the frame,
+// so the new object is spawned in the next frame to keep the same GUID.
+const updateScript = `
+WebRequest.get(%q, function(request)
+	if request.is_error then
+		print('Downloading json error: ', request.error)
+		return
+	end
+	local update = JSON.decode(request.text)
+
+	-- Replace the sheets of the cards inside the object and inside its states
+	local function patch(data)
+		if data.CustomDeck ~= nil then
+			for id in pairs(data.CustomDeck) do
+				local deck = update.customDecks[tostring(id)]
+				if deck ~= nil then
+					data.CustomDeck[id] = deck
+				end
+			end
+		end
+		for _, child in ipairs(data.ContainedObjects or {}) do
+			patch(child)
+		end
+		for _, state in pairs(data.States or {}) do
+			patch(state)
+		end
+	end
+
+	local found = 0
+	for _, object in ipairs(getAllObjects()) do
+		local new = update.objects[object.getGUID()]
+		if new ~= nil then
+			found = found + 1
+			local data = new
+			if update.mode == 'update' then
+				data = object.getData()
+				patch(data)
+			end
+			local position, rotation = object.getPosition(), object.getRotation()
+			object.destruct()
+			Wait.frames(function()
+				spawnObjectData({data = data, position = position, rotation = rotation})
+			end, 1)
+		end
+	end
+	if found == 0 then
+		spawnObjectData({data = update.object})
+		print('No spawned objects were found, the object were spawned! Done!')
+		return
+	end
+	print(found .. ' objects were updated! Done!')
+end)`
+
+func (s *tts) UpdateInTTS(data any, mode string) error {
+	if mode == ModeSpawn {
+		return s.SendToTTS(data)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		er.IfErrorLog(err)
+		return er.InternalError.AddMessage(err.Error())
+	}
+	payload, err := newUpdate(raw, mode)
+	if err != nil {
+		er.IfErrorLog(err)
+		return er.InternalError.AddMessage(err.Error())
+	}
+	token, err := s.enqueue(fmt.Sprintf("%s (%s)", objectName(raw), mode), payload)
+	if err != nil {
+		return err
+	}
+	return s.execute(token, fmt.Sprintf(updateScript, config.TTSDataURL(s.CallbackURL(), token)))
+}
+
+func newUpdate(raw []byte, mode string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var object any
+	err := decoder.Decode(&object)
+	if err != nil {
+		return nil, err
+	}
+
+	res := update{
+		Mode:        mode,
+		Object:      object,
+		Objects:     make(map[string]any),
+		CustomDecks: make(map[string]any),
+	}
+	tts_entity.WalkObjects(object, func(guid string, obj map[string]any) {
+		// The same card can be added to the deck several times
+		if _, ok := res.Objects[guid]; !ok {
+			res.Objects[guid] = obj
+		}
+		if decks, ok := obj["CustomDeck"].(map[string]any); ok {
+			for id, deck := range decks {
+				res.CustomDecks[id] = deck
+			}
+		}
+	})
+	return json.Marshal(res)
+}
@@ -0,0 +1,299 @@
+package tts_editor
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+	"github.com/HardDie/DeckBuilder/internal/logger"
+)
+
+// How long the answer of TTS is waited for
+const DefaultTimeout = 5 * time.Second
+
+// Client talks to TTS with the External Editor API. The messages are sent to TTS one per connection,
+// and TTS connects back to the listened address to send the messages of the game.
+type Client struct {
+	timeout time.Duration
+
+	mu            sync.Mutex
+	sendAddress   string
+	listenAddress string
+	listener      net.Listener
+	// The code waiting for the answer of TTS
+	returnID   int
+	returns    map[int]chan Message
+	newGame    []chan Message
+	executions map[int]chan Message
+	// The receivers of all messages
+	subscriberID  int
+	subscribers   map[int]chan Message
+	lastMessageAt time.Time
+}
+
+func NewClient(sendAddress, listenAddress string, timeout time.Duration) *Client {
+	return &Client{
+		sendAddress:   sendAddress,
+		listenAddress: listenAddress,
+		timeout:       timeout,
+		returns:       make(map[int]chan Message),
+		executions:    make(map[int]chan Message),
+		subscribers:   make(map[int]chan Message),
+	}
+}
+
+// Listen starts receiving the messages from TTS
+func (c *Client) Listen() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.listener != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", c.listenAddress)
+	if err != nil {
+		return er.TTSNotConnected.AddMessage("can't listen on " + c.listenAddress + ": " + err.Error())
+	}
+	c.listener = listener
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				logger.Warn.Println("TTS connection:", err.Error())
+				continue
+			}
+			go c.receive(conn)
+		}
+	}()
+	return nil
+}
+
+// Close stops receiving the messages
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.close()
+}
+
+// SetAddresses changes the addresses of TTS, if the client is listening, the messages are received on the new address
+func (c *Client) SetAddresses(sendAddress, listenAddress string) error {
+	c.mu.Lock()
+	c.sendAddress = sendAddress
+	isChanged := c.listenAddress != listenAddress
+	c.listenAddress = listenAddress
+	if !isChanged || c.listener == nil {
+		c.mu.Unlock()
+		return nil
+	}
+	err := c.close()
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return c.Listen()
+}
+
+// Listening returns the address the messages are received on, empty if the client is not listening
+func (c *Client) Listening() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.listener == nil {
+		return ""
+	}
+	return c.listener.Addr().String()
+}
+
+// Connected checks if TTS accepts the messages
+func (c *Client) Connected() bool {
+	conn, err := net.DialTimeout("tcp", c.address(), c.timeout)
+	if err != nil {
+		return false
+	}
+	er.IfErrorLog(conn.Close())
+	return true
+}
+
+// LastMessageAt returns the time of the last message received from TTS
+func (c *Client) LastMessageAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastMessageAt
+}
+
+// Send sends the message without waiting for the answer
+func (c *Client) Send(msg Message) error {
+	conn, err := net.DialTimeout("tcp", c.address(), c.timeout)
+	if err != nil {
+		return er.TTSNotConnected.AddMessage(err.Error())
+	}
+	defer func() { er.IfErrorLog(conn.Close()) }()
+
+	er.IfErrorLog(conn.SetWriteDeadline(time.Now().Add(c.timeout)))
+	err = json.NewEncoder(conn).Encode(msg)
+	if err != nil {
+		return er.TTSNotConnected.AddMessage(err.Error())
+	}
+	return nil
+}
+
+// Scripts returns the scripts and the UI of the global and all objects of the current game
+func (c *Client) Scripts() ([]ScriptState, error) {
+	ch := make(chan Message, 1)
+	c.mu.Lock()
+	c.newGame = append(c.newGame, ch)
+	c.mu.Unlock()
+	defer c.removeNewGame(ch)
+
+	err := c.Send(Message{MessageID: RequestGetScripts})
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case msg := <-ch:
+		return msg.ScriptStates, nil
+	case <-time.After(c.timeout):
+		return nil, er.TTSTimeout
+	}
+}
+
+// SaveAndPlay updates the scripts and the UI of the objects and reloads the game.
+// The objects without the script state keep their scripts.
+func (c *Client) SaveAndPlay(states []ScriptState) error {
+	return c.Send(Message{MessageID: RequestSaveAndPlay, ScriptStates: states})
+}
+
+// CustomMessage passes the table to onExternalMessage of the global script
+func (c *Client) CustomMessage(data json.RawMessage) error {
+	return c.Send(Message{MessageID: RequestCustomMessage, CustomMessage: data})
+}
+
+// Execute runs the Lua code on the object (GlobalGUID for the global script) and returns the value returned by the code.
+// TTS sends nothing if the code returns nothing, so such code ends with the TTSTimeout error.
+func (c *Client) Execute(guid, script string) (json.RawMessage, error) {
+	ch := make(chan Message, 1)
+	c.mu.Lock()
+	c.returnID++
+	returnID := c.returnID
+	c.returns[returnID] = ch
+	c.executions[returnID] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.returns, returnID)
+		delete(c.executions, returnID)
+		c.mu.Unlock()
+	}()
+
+	err := c.Send(Message{MessageID: RequestExecute, ReturnID: returnID, GUID: guid, Script: script})
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case msg := <-ch:
+		if msg.MessageID == EventError {
+			return nil, er.TTSScriptError.AddMessage(msg.ErrorMessagePrefix + msg.Error)
+		}
+		return msg.ReturnValue, nil
+	case <-time.After(c.timeout):
+		return nil, er.TTSTimeout.AddMessage("no value was returned by the code")
+	}
+}
+
+// Subscribe returns the channel with all messages received from TTS, the function stops the subscription.
+// The messages are dropped if the receiver is too slow.
+func (c *Client) Subscribe() (<-chan Message, func()) {
+	ch := make(chan Message, 64)
+	c.mu.Lock()
+	c.subscriberID++
+	id := c.subscriberID
+	c.subscribers[id] = ch
+	c.mu.Unlock()
+	return ch, func() {
+		c.mu.Lock()
+		delete(c.subscribers, id)
+		c.mu.Unlock()
+	}
+}
+
+func (c *Client) address() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sendAddress
+}
+func (c *Client) close() error {
+	if c.listener == nil {
+		return nil
+	}
+	err := c.listener.Close()
+	c.listener = nil
+	return err
+}
+func (c *Client) receive(conn net.Conn) {
+	defer func() { er.IfErrorLog(conn.Close()) }()
+
+	decoder := json.NewDecoder(conn)
+	for {
+		var msg Message
+		err := decoder.Decode(&msg)
+		if err != nil {
+			if err != io.EOF {
+				logger.Warn.Println("Bad message from TTS:", err.Error())
+			}
+			return
+		}
+		msg.ReceivedAt = time.Now()
+		c.dispatch(msg)
+	}
+}
+
+func (c *Client) dispatch(msg Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastMessageAt = msg.ReceivedAt
+	switch msg.MessageID {
+	case EventNewGame:
+		for _, ch := range c.newGame {
+			send(ch, msg)
+		}
+		c.newGame = nil
+	case EventReturn:
+		if ch, ok := c.returns[msg.ReturnID]; ok {
+			send(ch, msg)
+		}
+	case EventError:
+		// The error has no return ID, so it is passed to all running code
+		for _, ch := range c.executions {
+			send(ch, msg)
+		}
+	}
+	for _, ch := range c.subscribers {
+		send(ch, msg)
+	}
+}
+
+func (c *Client) removeNewGame(ch chan Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, item := range c.newGame {
+		if item == ch {
+			c.newGame = append(c.newGame[:i], c.newGame[i+1:]...)
+			return
+		}
+	}
+}
+
+// send doesn't block the receiving of the messages
+func send(ch chan Message, msg Message) {
+	select {
+	case ch <- msg:
+	default:
+	}
+}
@@ -0,0 +1,249 @@
+package tts_editor
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	er "github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+// fakeTTS answers the requests like TTS does: the answers are sent to the editor over a new connection
+type fakeTTS struct {
+	t        *testing.T
+	listener net.Listener
+	client   *Client
+	states   []ScriptState
+	received chan Message
+}
+
+func newFakeTTS(t *testing.T) *fakeTTS {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tts := &fakeTTS{
+		t:        t,
+		listener: listener,
+		states:   []ScriptState{{Name: "Global", GUID: GlobalGUID, Script: "print('hi')", UI: "<Text/>"}},
+		received: make(chan Message, 10),
+	}
+	tts.client = NewClient(listener.Addr().String(), "127.0.0.1:0", time.Second)
+	err = tts.client.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		er.IfErrorLog(tts.client.Close())
+		er.IfErrorLog(listener.Close())
+	})
+	go tts.serve()
+	return tts
+}
+
+func (f *fakeTTS) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		var msg Message
+		err = json.NewDecoder(conn).Decode(&msg)
+		er.IfErrorLog(conn.Close())
+		if err == io.EOF {
+			// The connection check
+			continue
+		}
+		if err != nil {
+			f.t.Error(err)
+			continue
+		}
+		f.received <- msg
+
+		switch msg.MessageID {
+		case RequestGetScripts:
+			f.push(Message{MessageID: EventNewGame, ScriptStates: f.states})
+		case RequestExecute:
+			switch msg.Script {
+			case "return 1 + 1":
+				f.push(Message{MessageID: EventReturn, ReturnID: msg.ReturnID, ReturnValue: json.RawMessage("2")})
+			case "error()":
+				f.push(Message{MessageID: EventError, Error: "chunk_0:(1,0-7): error", GUID: msg.GUID, ErrorMessagePrefix: "Error in Global Script: "})
+			}
+		}
+	}
+}
+
+func (f *fakeTTS) push(msgs ...Message) {
+	conn, err := net.Dial("tcp", f.client.Listening())
+	if err != nil {
+		f.t.Error(err)
+		return
+	}
+	defer func() { er.IfErrorLog(conn.Close()) }()
+	for _, msg := range msgs {
+		err = json.NewEncoder(conn).Encode(msg)
+		if err != nil {
+			f.t.Error(err)
+			return
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	tts := newFakeTTS(t)
+	if !tts.client.Connected() {
+		t.Fatal("The client is not connected")
+	}
+
+	states, err := tts.client.Scripts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(states, tts.states) {
+		t.Fatal("Bad scripts [got]", states, "[want]", tts.states)
+	}
+	<-tts.received
+
+	res, err := tts.client.Execute(GlobalGUID, "return 1 + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "2" {
+		t.Fatal("Bad result:", string(res))
+	}
+	msg := <-tts.received
+	if msg.MessageID != RequestExecute || msg.GUID != GlobalGUID || msg.ReturnID == 0 {
+		t.Fatal("Bad execute request:", msg)
+	}
+
+	_, err = tts.client.Execute(GlobalGUID, "error()")
+	if !errors.Is(err, er.TTSScriptError) {
+		t.Fatal("Expected the script error, got:", err)
+	}
+	<-tts.received
+
+	// The code returns nothing
+	_, err = tts.client.Execute(GlobalGUID, "print(1)")
+	if !errors.Is(err, er.TTSTimeout) {
+		t.Fatal("Expected the timeout error, got:", err)
+	}
+	<-tts.received
+
+	err = tts.client.SaveAndPlay(tts.states)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = <-tts.received
+	if msg.MessageID != RequestSaveAndPlay || !reflect.DeepEqual(msg.ScriptStates, tts.states) {
+		t.Fatal("Bad save and play request:", msg)
+	}
+
+	err = tts.client.CustomMessage(json.RawMessage(`{"action":"spawn"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = <-tts.received
+	if msg.MessageID != RequestCustomMessage || string(msg.CustomMessage) != `{"action":"spawn"}` {
+		t.Fatal("Bad custom message:", msg)
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	t.Parallel()
+
+	tts := newFakeTTS(t)
+	messages, unsubscribe := tts.client.Subscribe()
+	defer unsubscribe()
+
+	tts.push(
+		Message{MessageID: EventPrint, Message: "hello"},
+		Message{MessageID: EventPushObject, ScriptStates: []ScriptState{{Name: "Card", GUID: "abc123"}}},
+		Message{MessageID: EventCustomMessage, CustomMessage: json.RawMessage(`{"a":1}`)},
+	)
+	want := []string{"print", "pushObject", "customMessage"}
+	for _, name := range want {
+		select {
+		case msg := <-messages:
+			if EventName(msg.MessageID) != name {
+				t.Fatal("Bad event [got]", EventName(msg.MessageID), "[want]", name)
+			}
+			if msg.ReceivedAt.IsZero() {
+				t.Fatal("The receive time is not set")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("The event is not received:", name)
+		}
+	}
+	if tts.client.LastMessageAt().IsZero() {
+		t.Fatal("The time of the last message is not set")
+	}
+}
+
+func TestClientNotConnected(t *testing.T) {
+	t.Parallel()
+
+	// Nothing listens on the port of the closed listener
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	er.IfErrorLog(listener.Close())
+
+	client := NewClient(addr, "127.0.0.1:0", time.Second)
+	if client.Connected() {
+		t.Fatal("The client must not be connected")
+	}
+	_, err = client.Scripts()
+	if !errors.Is(err, er.TTSNotConnected) {
+		t.Fatal("Expected not connected error, got:", err)
+	}
+}
+
+func TestClientSetAddresses(t *testing.T) {
+	t.Parallel()
+
+	tts := newFakeTTS(t)
+	sendAddress := tts.client.address()
+	err := tts.client.SetAddresses("127.0.0.1:1", tts.client.listenAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tts.client.Connected() {
+		t.Fatal("The client must not be connected to the old address")
+	}
+
+	// A free port for the new listen address
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listenAddress := listener.Addr().String()
+	er.IfErrorLog(listener.Close())
+
+	err = tts.client.SetAddresses(sendAddress, listenAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tts.client.Connected() {
+		t.Fatal("The client must be connected to the new address")
+	}
+	if got := tts.client.Listening(); got != listenAddress {
+		t.Fatal("Bad listen address [got]", got, "[want]", listenAddress)
+	}
+	messages, unsubscribe := tts.client.Subscribe()
+	defer unsubscribe()
+	tts.push(Message{MessageID: EventPrint, Message: "hi"})
+	select {
+	case <-messages:
+	case <-time.After(time.Second):
+		t.Fatal("The message is not received on the new address")
+	}
+}
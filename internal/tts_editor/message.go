@@ -0,0 +1,89 @@
+package tts_editor
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// The messages sent to TTS, see https://api.tabletopsimulator.com/externaleditorapi/
+const (
+	// TTS answers with EventNewGame with the scripts of all objects
+	RequestGetScripts = 0
+	// Updates the scripts and the UI and reloads the game
+	RequestSaveAndPlay = 1
+	// Calls onExternalMessage of the global script
+	RequestCustomMessage = 2
+	// Runs the Lua code, TTS answers with EventReturn if the code returns a value
+	RequestExecute = 3
+)
+
+// The messages received from TTS
+const (
+	// The scripts of the object were requested in the game
+	EventPushObject = 0
+	// A game was loaded
+	EventNewGame = 1
+	// print() in the game
+	EventPrint = 2
+	// An error in a script
+	EventError = 3
+	// sendExternalMessage() in the game
+	EventCustomMessage = 4
+	// The value returned by the code of RequestExecute
+	EventReturn = 5
+	// The game was saved
+	EventGameSaved = 6
+	// An object was created
+	EventObjectCreated = 7
+)
+
+// The GUID of the global script
+const GlobalGUID = "-1"
+
+type ScriptState struct {
+	Name   string `json:"name,omitempty"`
+	GUID   string `json:"guid"`
+	Script string `json:"script,omitempty"`
+	UI     string `json:"ui,omitempty"`
+}
+
+// Message is the message of the protocol in both directions, the set fields depend on the message ID
+type Message struct {
+	MessageID          int             `json:"messageID"`
+	ScriptStates       []ScriptState   `json:"scriptStates,omitempty"`
+	Message            string          `json:"message,omitempty"`
+	Error              string          `json:"error,omitempty"`
+	ErrorMessagePrefix string          `json:"errorMessagePrefix,omitempty"`
+	GUID               string          `json:"guid,omitempty"`
+	Script             string          `json:"script,omitempty"`
+	CustomMessage      json.RawMessage `json:"customMessage,omitempty"`
+	ReturnID           int             `json:"returnID,omitempty"`
+	ReturnValue        json.RawMessage `json:"returnValue,omitempty"`
+
+	// The time the message was received, it is not sent
+	ReceivedAt time.Time `json:"-"`
+}
+
+// EventName returns the name of the received message
+func EventName(messageID int) string {
+	switch messageID {
+	case EventPushObject:
+		return "pushObject"
+	case EventNewGame:
+		return "newGame"
+	case EventPrint:
+		return "print"
+	case EventError:
+		return "error"
+	case EventCustomMessage:
+		return "customMessage"
+	case EventReturn:
+		return "return"
+	case EventGameSaved:
+		return "gameSaved"
+	case EventObjectCreated:
+		return "objectCreated"
+	default:
+		return "unknown"
+	}
+}
@@ -1,6 +1,7 @@
 package tts_entity
 
 type DeckObject struct {
+	GUID             string                  `json:"GUID,omitempty"`
 	Name             string                  `json:"Name"`
 	Transform        Transform               `json:"Transform"`
 	Nickname         string                  `json:"Nickname"`
@@ -0,0 +1,73 @@
+package tts_entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GUID returns the stable GUID of the generated object by the IDs of its source, so the object spawned in TTS
+// can be found again after the next generation
+func GUID(ids ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(ids, "/")))
+	// TTS uses 6 hex digits
+	return hex.EncodeToString(hash[:3])
+}
+
+// GUIDs gives out the GUIDs unique within the save. TTS uses only 24 bits, so the GUIDs of different objects
+// can collide, the colliding GUID is hashed again with a counter until it is unique.
+// The same IDs always get the same GUID.
+type GUIDs struct {
+	byKey map[string]string
+	used  map[string]struct{}
+}
+
+func NewGUIDs() *GUIDs {
+	return &GUIDs{
+		byKey: make(map[string]string),
+		used:  make(map[string]struct{}),
+	}
+}
+
+func (g *GUIDs) GUID(ids ...string) string {
+	key := strings.Join(ids, "/")
+	if guid, ok := g.byKey[key]; ok {
+		return guid
+	}
+	guid := GUID(ids...)
+	for i := 1; ; i++ {
+		if _, ok := g.used[guid]; !ok {
+			break
+		}
+		guid = GUID(key, strconv.Itoa(i))
+	}
+	g.byKey[key] = guid
+	g.used[guid] = struct{}{}
+	return guid
+}
+
+// WalkObjects calls fn for every object with a GUID found at any depth of the object tree,
+// the tree is the result of unmarshalling JSON into any
+func WalkObjects(obj any, fn func(guid string, object map[string]any)) {
+	switch val := obj.(type) {
+	case map[string]any:
+		if guid, ok := val["GUID"].(string); ok && guid != "" {
+			fn(guid, val)
+		}
+		// The keys are sorted, so the objects are always visited in the same order
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			WalkObjects(val[key], fn)
+		}
+	case []any:
+		for _, item := range val {
+			WalkObjects(item, fn)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package tts_entity
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestGUIDs(t *testing.T) {
+	t.Parallel()
+
+	// Find two cards with the same GUID
+	seen := make(map[string]string)
+	var first, second string
+	for i := 0; first == ""; i++ {
+		id := strconv.Itoa(i)
+		guid := GUID("game", "collection", "deck", id)
+		if prev, ok := seen[guid]; ok {
+			first, second = prev, id
+		}
+		seen[guid] = id
+	}
+
+	guids := NewGUIDs()
+	firstGUID := guids.GUID("game", "collection", "deck", first)
+	secondGUID := guids.GUID("game", "collection", "deck", second)
+	if firstGUID != GUID("game", "collection", "deck", first) {
+		t.Fatal("The first object must keep the plain GUID:", firstGUID)
+	}
+	if secondGUID == firstGUID {
+		t.Fatal("The colliding GUID is not changed:", secondGUID)
+	}
+	if len(secondGUID) != 6 {
+		t.Fatal("Bad GUID:", secondGUID)
+	}
+	if guid := guids.GUID("game", "collection", "deck", second); guid != secondGUID {
+		t.Fatal("The same IDs must get the same GUID [got]", guid, "[want]", secondGUID)
+	}
+}
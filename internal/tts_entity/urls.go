@@ -0,0 +1,81 @@
+package tts_entity
+
+import "sort"
+
+// The fields with the URLs of the images and the models of the custom objects
+var (
+	deckURLFields = []string{"FaceURL", "BackURL"}
+	urlFields     = map[string][]string{
+		"CustomImage": {"ImageURL", "ImageSecondaryURL"},
+		"CustomMesh":  {"MeshURL", "DiffuseURL", "NormalURL", "ColliderURL"},
+	}
+)
+
+// WalkURLs calls fn for every URL of the custom decks, images and meshes found at any depth of the object tree
+// (contained objects, states, child objects, ...) and replaces the URL with the result. The tree is the result
+// of unmarshalling JSON into any, so all the fields unknown to the application are kept as is.
+func WalkURLs(obj any, fn func(url string) (string, error)) error {
+	switch val := obj.(type) {
+	case map[string]any:
+		// The keys are sorted, so the URLs are always visited in the same order
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			var err error
+			switch key {
+			case "CustomDeck":
+				// The descriptions of the sheets by the deck ID
+				if decks, ok := val[key].(map[string]any); ok {
+					ids := make([]string, 0, len(decks))
+					for id := range decks {
+						ids = append(ids, id)
+					}
+					sort.Strings(ids)
+					for _, id := range ids {
+						if err = replaceURLs(decks[id], deckURLFields, fn); err != nil {
+							return err
+						}
+					}
+				}
+			case "CustomImage", "CustomMesh":
+				err = replaceURLs(val[key], urlFields[key], fn)
+			}
+			if err != nil {
+				return err
+			}
+			if err = WalkURLs(val[key], fn); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if err := WalkURLs(item, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func replaceURLs(obj any, fields []string, fn func(url string) (string, error)) error {
+	object, ok := obj.(map[string]any)
+	if !ok {
+		return nil
+	}
+	for _, field := range fields {
+		url, ok := object[field].(string)
+		if !ok || url == "" {
+			continue
+		}
+		newURL, err := fn(url)
+		if err != nil {
+			return err
+		}
+		object[field] = newURL
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package uploader
+
+import (
+	"context"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+// Uploader puts the generated images to an image hosting, so TTS can load them on every player's computer
+type Uploader interface {
+	// Upload stores the file under the name and returns its public URL
+	Upload(ctx context.Context, name string, data []byte) (string, error)
+}
+
+// New returns the uploader selected in the config, nil if the upload is disabled
+func New(cfg *config.Config) (Uploader, error) {
+	switch cfg.Uploader {
+	case "":
+		return nil, nil
+	case config.UploaderS3:
+		return NewS3(cfg)
+	case config.UploaderHTTP:
+		return NewHTTP(cfg)
+	default:
+		return nil, errors.UploadFailed.AddMessage("unknown uploader: " + cfg.Uploader)
+	}
+}
@@ -0,0 +1,128 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+// The maximum size of the response of the image hosting, it only contains the link
+const maxResponseSize = 1 << 20
+
+// httpUploader posts the files as multipart/form-data, the way most of the image hostings accept them.
+// The link is taken from the JSON field of the response, from the Location header or from the plain text body.
+type httpUploader struct {
+	client        *http.Client
+	endpoint      string
+	field         string
+	urlField      string
+	authorization string
+}
+
+func NewHTTP(cfg *config.Config) (Uploader, error) {
+	endpoint, err := url.Parse(cfg.UploadURL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, errors.UploadFailed.AddMessage("bad upload endpoint: " + cfg.UploadURL)
+	}
+	field := cfg.UploadField
+	if field == "" {
+		field = config.DefaultUploadField
+	}
+	return &httpUploader{
+		client:        &http.Client{Timeout: cfg.UploadTimeout},
+		endpoint:      endpoint.String(),
+		field:         field,
+		urlField:      cfg.UploadURLField,
+		authorization: cfg.UploadAuthorization,
+	}, nil
+}
+
+func (u *httpUploader) Upload(ctx context.Context, name string, data []byte) (string, error) {
+	body := bytes.NewBuffer(nil)
+	form := multipart.NewWriter(body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(u.field), escapeQuotes(name)))
+	header.Set("Content-Type", contentType(name, data))
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return "", errors.UploadFailed.AddMessage(err.Error())
+	}
+	if _, err = part.Write(data); err != nil {
+		return "", errors.UploadFailed.AddMessage(err.Error())
+	}
+	if err = form.Close(); err != nil {
+		return "", errors.UploadFailed.AddMessage(err.Error())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.endpoint, body)
+	if err != nil {
+		return "", errors.UploadFailed.AddMessage(err.Error())
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if u.authorization != "" {
+		req.Header.Set("Authorization", u.authorization)
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", errors.UploadFailed.AddMessage(err.Error())
+	}
+	defer func() { errors.IfErrorLog(resp.Body.Close()) }()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return "", errors.UploadFailed.AddMessage(err.Error())
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", errors.UploadFailed.AddMessage(fmt.Sprintf("the image hosting responded %s: %s", resp.Status, strings.TrimSpace(string(respBody))))
+	}
+
+	link, err := u.link(resp, respBody)
+	if err != nil {
+		return "", err
+	}
+	if res, err := url.Parse(link); err != nil || (res.Scheme != "http" && res.Scheme != "https") {
+		return "", errors.UploadFailed.AddMessage("the image hosting returned a bad link: " + link)
+	}
+	return link, nil
+}
+
+func (u *httpUploader) link(resp *http.Response, body []byte) (string, error) {
+	if u.urlField == "" {
+		if location := resp.Header.Get("Location"); location != "" {
+			return location, nil
+		}
+		return strings.TrimSpace(string(body)), nil
+	}
+
+	// The field is set as a path of the keys separated by dots, e.g. data.link
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", errors.UploadFailed.AddMessage("the response of the image hosting is not JSON: " + err.Error())
+	}
+	for _, key := range strings.Split(u.urlField, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return "", errors.UploadFailed.AddMessage("the response of the image hosting has no field " + u.urlField)
+		}
+		value = object[key]
+	}
+	link, ok := value.(string)
+	if !ok {
+		return "", errors.UploadFailed.AddMessage("the response of the image hosting has no field " + u.urlField)
+	}
+	return link, nil
+}
+
+func escapeQuotes(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
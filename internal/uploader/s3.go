@@ -0,0 +1,192 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	"github.com/HardDie/DeckBuilder/internal/errors"
+)
+
+const (
+	s3Algorithm = "AWS4-HMAC-SHA256"
+	s3Service   = "s3"
+	s3Request   = "aws4_request"
+)
+
+// s3 puts the files with the S3 PutObject request signed with Signature Version 4.
+// The path-style URLs are used, they are supported by AWS and by the compatible storages (MinIO, R2, B2, ...).
+type s3 struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	acl       string
+	publicURL string
+	// For tests only
+	now func() time.Time
+}
+
+func NewS3(cfg *config.Config) (Uploader, error) {
+	endpoint, err := url.Parse(cfg.UploadURL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, errors.UploadFailed.AddMessage("bad S3 endpoint: " + cfg.UploadURL)
+	}
+	if cfg.UploadBucket == "" {
+		return nil, errors.UploadFailed.AddMessage("the S3 bucket is not set")
+	}
+	if cfg.UploadAccessKey == "" || cfg.UploadSecretKey == "" {
+		return nil, errors.UploadFailed.AddMessage("the S3 access key or secret key is not set")
+	}
+
+	publicURL := cfg.UploadPublicURL
+	if publicURL == "" {
+		publicURL = strings.TrimSuffix(endpoint.String(), "/") + "/" + cfg.UploadBucket
+	}
+	region := cfg.UploadRegion
+	if region == "" {
+		region = config.DefaultUploadRegion
+	}
+	return &s3{
+		client:    &http.Client{Timeout: cfg.UploadTimeout},
+		endpoint:  endpoint,
+		bucket:    cfg.UploadBucket,
+		region:    region,
+		accessKey: cfg.UploadAccessKey,
+		secretKey: cfg.UploadSecretKey,
+		acl:       cfg.UploadACL,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		now:       time.Now,
+	}, nil
+}
+
+func (u *s3) Upload(ctx context.Context, name string, data []byte) (string, error) {
+	objectPath := "/" + uriEncode(u.bucket, false) + "/" + uriEncode(name, false)
+	if prefix := strings.TrimSuffix(u.endpoint.EscapedPath(), "/"); prefix != "" {
+		objectPath = prefix + objectPath
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.endpoint.Scheme+"://"+u.endpoint.Host+objectPath, bytes.NewReader(data))
+	if err != nil {
+		return "", errors.UploadFailed.AddMessage(err.Error())
+	}
+	req.Header.Set("Content-Type", contentType(name, data))
+	if u.acl != "" {
+		req.Header.Set("x-amz-acl", u.acl)
+	}
+	u.sign(req, data)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", errors.UploadFailed.AddMessage(err.Error())
+	}
+	defer func() { errors.IfErrorLog(resp.Body.Close()) }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", errors.UploadFailed.AddMessage(fmt.Sprintf("S3 responded %s: %s", resp.Status, strings.TrimSpace(string(body))))
+	}
+	return u.publicURL + "/" + uriEncode(name, false), nil
+}
+
+// sign adds the Authorization header, see https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (u *s3) sign(req *http.Request, payload []byte) {
+	now := u.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256.Sum256(payload)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", hex.EncodeToString(payloadHash[:]))
+
+	// The host, content-type, range and x-amz-* headers are signed, the other headers can be changed by proxies
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+	for key, values := range req.Header {
+		key = strings.ToLower(key)
+		if key == "content-type" || strings.HasPrefix(key, "x-amz-") || key == "range" {
+			headers[key] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for key := range headers {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, key := range names {
+		canonicalHeaders.WriteString(key + ":" + headers[key] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := strings.Join([]string{date, u.region, s3Service, s3Request}, "/")
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+u.secretKey), date)
+	key = hmacSHA256(key, u.region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, s3Request)
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, u.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncode escapes everything except the unreserved characters, as the signature requires
+func uriEncode(value string, encodeSlash bool) string {
+	var res strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			res.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			res.WriteByte(b)
+		default:
+			fmt.Fprintf(&res, "%%%02X", b)
+		}
+	}
+	return res.String()
+}
+
+func contentType(name string, data []byte) string {
+	if res := mime.TypeByExtension(path.Ext(name)); res != "" {
+		return res
+	}
+	return http.DetectContentType(data)
+}
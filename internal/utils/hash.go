@@ -2,6 +2,8 @@ package utils
 
 import (
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"time"
@@ -16,3 +18,16 @@ func HashForTime(t *time.Time) string {
 	hashByte := md5.Sum(buf)
 	return hex.EncodeToString(hashByte[:])
 }
+
+// HashForData returns the sha256 of the data, it is used as the identifier of the content
+func HashForData(data []byte) string {
+	hashByte := sha256.Sum256(data)
+	return hex.EncodeToString(hashByte[:])
+}
+
+// RandomToken returns a random hex string, it is used to match the requests with the answers
+func RandomToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"sync"
+)
+
+// KeyMutex is a set of mutexes addressed by a key, so the operations with different keys don't block each other.
+// The mutex is allocated on the first lock and released after the last unlock.
+type KeyMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyMutexEntry
+}
+
+type keyMutexEntry struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func NewKeyMutex() *KeyMutex {
+	return &KeyMutex{
+		locks: make(map[string]*keyMutexEntry),
+	}
+}
+
+// Lock locks the key and returns the function that unlocks it
+func (m *KeyMutex) Lock(key string) func() {
+	m.mu.Lock()
+	entry, ok := m.locks[key]
+	if !ok {
+		entry = &keyMutexEntry{}
+		m.locks[key] = entry
+	}
+	entry.refs++
+	m.mu.Unlock()
+
+	entry.mu.Lock()
+	return func() {
+		entry.mu.Unlock()
+
+		m.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"errors"
 	"io"
+	"mime/multipart"
 	"net/http"
 
 	er "github.com/HardDie/DeckBuilder/internal/errors"
@@ -28,3 +29,19 @@ func GetFileFromMultipart(name string, r *http.Request) ([]byte, error) {
 	}
 	return data, nil
 }
+
+// GetFileReaderFromMultipart returns the uploaded file without reading it into memory.
+// Large files of the parsed form are stored in temporary files, which are removed when the request ends.
+func GetFileReaderFromMultipart(name string, r *http.Request) (multipart.File, int64, error) {
+	f, header, err := r.FormFile(name)
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			return nil, 0, nil
+		} else {
+			er.IfErrorLog(err)
+			err = er.InternalError.AddMessage(err.Error())
+			return nil, 0, err
+		}
+	}
+	return f, header.Size, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/HardDie/DeckBuilder/internal/config"
+	dbCore "github.com/HardDie/DeckBuilder/internal/db/core"
+	dbImage "github.com/HardDie/DeckBuilder/internal/db/image"
+	dbSQLite "github.com/HardDie/DeckBuilder/internal/db/sqlite"
+	"github.com/HardDie/DeckBuilder/internal/db/transfer"
+	dbTrash "github.com/HardDie/DeckBuilder/internal/db/trash"
+	internalFS "github.com/HardDie/DeckBuilder/internal/fs"
+)
+
+// Copies all games and settings between the file storage and the SQLite database.
+// The source storage is left untouched, the revisions are copied together with the games.
+func main() {
+	data := flag.String("data", "DeckBuilderData", "Path to the data folder of the application")
+	sqlite := flag.String("sqlite", "deck_builder.db", "Name of the database file inside the data folder")
+	to := flag.String("to", "", "Destination storage: "+config.StorageFiles+" or "+config.StorageSQLite)
+	flag.Parse()
+
+	// The application must not change the data during the migration
+	unlock, err := internalFS.LockFolder(*data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer unlock()
+
+	fs := internalFS.NewFSEntry(*data, true)
+	err = dbCore.New(fs, *data).Init()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := dbSQLite.Open(filepath.Join(*data, *sqlite))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	err = dbCore.NewSQLite(db, fs, *data).Init()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	images := dbImage.New(fs, *data)
+	var src, dst transfer.Store
+	var srcTrash dbTrash.Trash
+	switch *to {
+	case config.StorageSQLite:
+		src, dst = transfer.Files(fs, images), transfer.SQLite(db)
+		srcTrash = dbTrash.New(fs, images, *data)
+	case config.StorageFiles:
+		src, dst = transfer.SQLite(db), transfer.Files(fs, images)
+		srcTrash = dbTrash.NewSQLite(db)
+	default:
+		flag.Usage()
+		log.Fatal("unknown destination storage: ", *to)
+	}
+
+	ctx := context.Background()
+
+	// Deleted entities cannot be copied, the trash must be restored or purged first
+	items, err := srcTrash.List(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(items) > 0 {
+		log.Fatalf("the trash contains %d items, restore or purge them before the migration", len(items))
+	}
+
+	// Don't mix the games of both storages
+	games, err := dst.Game.List(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(games) > 0 {
+		log.Fatalf("the destination storage already contains %d games", len(games))
+	}
+
+	count, err := transfer.CopyAll(ctx, src, dst)
+	if err != nil {
+		log.Fatalf("%d games have been copied before the error: %s", count, err.Error())
+	}
+	fmt.Printf("%d games have been copied, run the application with -storage %s\n", count, *to)
+}